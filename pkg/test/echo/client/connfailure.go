@@ -0,0 +1,56 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ConnectionFailure is the way a forwarded call failed below L7, with no response received.
+type ConnectionFailure string
+
+const (
+	// ConnectionReset is a connection reset or closed by the peer, e.g. by a sidecar denying a TCP port.
+	ConnectionReset ConnectionFailure = "reset"
+	// ConnectionTimeout is a connection or a read which did not complete in time.
+	ConnectionTimeout ConnectionFailure = "timeout"
+	// ConnectionRefused is a connection refused by the target, e.g. with nothing listening on the port.
+	ConnectionRefused ConnectionFailure = "refused"
+)
+
+// An EOF read by the forwarder, rather than mentioned by one of its messages.
+var eofRegex = regexp.MustCompile(`(^|: |= )(unexpected )?EOF\b`)
+
+// ConnectionFailureOf returns the way the call forwarded by the echo server failed, from the error returned
+// by ForwardEcho. Returns false if the error is not a connection failure, e.g. an error status.
+func ConnectionFailureOf(err error) (ConnectionFailure, bool) {
+	if err == nil {
+		return "", false
+	}
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "connection refused"):
+		return ConnectionRefused, true
+	case strings.Contains(msg, "connection reset by peer"), strings.Contains(msg, "broken pipe"),
+		eofRegex.MatchString(msg):
+		return ConnectionReset, true
+	case strings.Contains(msg, "i/o timeout"), strings.Contains(msg, "deadline exceeded"),
+		strings.Contains(msg, "DeadlineExceeded"):
+		return ConnectionTimeout, true
+	default:
+		return "", false
+	}
+}
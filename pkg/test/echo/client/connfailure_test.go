@@ -0,0 +1,70 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"errors"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestConnectionFailureOf(t *testing.T) {
+	cases := []struct {
+		name   string
+		err    error
+		want   ConnectionFailure
+		wantOK bool
+	}{
+		{name: "nil", err: nil},
+		{name: "status", err: status.Error(codes.PermissionDenied, "RBAC: access denied")},
+		{
+			name:   "reset",
+			err:    status.Error(codes.Unknown, "read tcp 10.0.0.1:4000->10.0.0.2:9090: read: connection reset by peer"),
+			want:   ConnectionReset,
+			wantOK: true,
+		},
+		{name: "closed", err: status.Error(codes.Unknown, "EOF"), want: ConnectionReset, wantOK: true},
+		{name: "closed http", err: errors.New(`Get "http://b:80/": EOF`), want: ConnectionReset, wantOK: true},
+		{name: "no status in the response", err: errors.New("expect to recv message with StatusCode=200, got . Return EOF")},
+		{
+			name:   "timeout",
+			err:    status.Error(codes.Unknown, "read tcp 10.0.0.1:4000->10.0.0.2:9090: i/o timeout"),
+			want:   ConnectionTimeout,
+			wantOK: true,
+		},
+		{
+			name:   "deadline",
+			err:    status.Error(codes.DeadlineExceeded, "context deadline exceeded"),
+			want:   ConnectionTimeout,
+			wantOK: true,
+		},
+		{
+			name:   "refused",
+			err:    status.Error(codes.Unknown, "dial tcp 10.0.0.2:9090: connect: connection refused"),
+			want:   ConnectionRefused,
+			wantOK: true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := ConnectionFailureOf(tc.err)
+			if got != tc.want || ok != tc.wantOK {
+				t.Errorf("got %q, %v, want %q, %v", got, ok, tc.want, tc.wantOK)
+			}
+		})
+	}
+}
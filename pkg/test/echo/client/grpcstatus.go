@@ -0,0 +1,58 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"net/http"
+	"regexp"
+
+	"google.golang.org/grpc/codes"
+)
+
+var grpcStatusCodeRegex = regexp.MustCompile(`rpc error: code = (\w+)`)
+
+// GRPCStatusCode returns the status of the failed gRPC call forwarded by the echo server, which is the
+// grpc-status trailer received by the forwarder, from the error returned by ForwardEcho. The error of a
+// forwarded call is wrapped in the error of ForwardEcho, so the innermost status is returned. Returns false
+// if the error has no gRPC status.
+func GRPCStatusCode(err error) (codes.Code, bool) {
+	if err == nil {
+		return codes.OK, false
+	}
+	matches := grpcStatusCodeRegex.FindAllStringSubmatch(err.Error(), -1)
+	if len(matches) == 0 {
+		return codes.OK, false
+	}
+	name := matches[len(matches)-1][1]
+	for c := codes.OK; c <= codes.Unauthenticated; c++ {
+		if c.String() == name {
+			return c, true
+		}
+	}
+	return codes.OK, false
+}
+
+// GRPCTrailers returns the trailers of the failed gRPC call forwarded by the echo server, including its
+// grpc-status, from the error returned by ForwardEcho.
+func GRPCTrailers(err error) http.Header {
+	out := http.Header{}
+	if err == nil {
+		return out
+	}
+	for _, match := range responseTrailerFieldRegex.FindAllStringSubmatch(err.Error(), -1) {
+		out.Add(match[1], match[2])
+	}
+	return out
+}
@@ -0,0 +1,81 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestGRPCStatusCode(t *testing.T) {
+	forwarded := status.Error(codes.Unauthenticated, "Jwt is expired")
+	cases := []struct {
+		name   string
+		err    error
+		want   codes.Code
+		wantOK bool
+	}{
+		{name: "nil", err: nil, want: codes.OK},
+		{name: "no status", err: errors.New("connection refused"), want: codes.OK},
+		{name: "status", err: forwarded, want: codes.Unauthenticated, wantOK: true},
+		{
+			name:   "wrapped",
+			err:    fmt.Errorf("failed calling b: %v", status.Error(codes.Unknown, forwarded.Error())),
+			want:   codes.Unauthenticated,
+			wantOK: true,
+		},
+		{name: "permission denied", err: status.Error(codes.PermissionDenied, "RBAC: access denied"),
+			want: codes.PermissionDenied, wantOK: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, ok := GRPCStatusCode(tc.err)
+			if got != tc.want || ok != tc.wantOK {
+				t.Errorf("got %v, %v, want %v, %v", got, ok, tc.want, tc.wantOK)
+			}
+		})
+	}
+}
+
+func TestGRPCTrailers(t *testing.T) {
+	forwarded := fmt.Errorf("%v\n%s", status.Error(codes.Unauthenticated, "Jwt is expired"),
+		"[0] ResponseTrailer=x-trace:abc\n[0] ResponseTrailer=grpc-status:16\n")
+	cases := []struct {
+		name string
+		err  error
+		want http.Header
+	}{
+		{name: "nil", err: nil, want: http.Header{}},
+		{name: "no trailers", err: errors.New("connection refused"), want: http.Header{}},
+		{
+			name: "wrapped",
+			err:  fmt.Errorf("failed calling b: %v", status.Error(codes.Unknown, forwarded.Error())),
+			want: http.Header{"X-Trace": {"abc"}, "Grpc-Status": {"16"}},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := GRPCTrailers(tc.err); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -16,9 +16,12 @@ package client
 
 import (
 	"fmt"
+	"net/http"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/go-multierror"
 
@@ -36,6 +39,16 @@ var (
 	hostnameFieldRegex       = regexp.MustCompile(string(response.HostnameField) + "=(.*)")
 	URLFieldRegex            = regexp.MustCompile(string(response.URLField) + "=(.*)")
 	ClusterFieldRegex        = regexp.MustCompile(string(response.ClusterField) + "=(.*)")
+	localityFieldRegex       = regexp.MustCompile(string(response.LocalityField) + "=(.*)")
+	requestBodySizeRegex     = regexp.MustCompile(string(response.RequestBodySizeField) + "=(.*)")
+	requestTrailerFieldRegex = regexp.MustCompile(string(response.RequestTrailerField) + "=([^:]*):(.*)")
+	// The lines of the body of the response, as written by the forwarder.
+	responseBodyLineRegex = regexp.MustCompile(`(?m)^\[\d+ body\] (.*)$`)
+	// Only the lines written by the forwarder, not the body of the response.
+	responseHeaderFieldRegex  = regexp.MustCompile(`(?m)^\[\d+\] ` + string(response.ResponseHeaderField) + "=([^:]*):(.*)$")
+	responseTrailerFieldRegex = regexp.MustCompile(`(?m)^\[\d+\] ` + string(response.ResponseTrailerField) + "=([^:]*):(.*)$")
+	latencyFieldRegex         = regexp.MustCompile(`(?m)^\[\d+\] ` + string(response.LatencyField) + "=(.*)$")
+	rawHTTPResponseFieldRegex = regexp.MustCompile(`(?m)^\[\d+\] ` + string(response.RawHTTPResponseField) + "=(.*)$")
 )
 
 // ParsedResponse represents a response to a single echo request.
@@ -58,6 +71,24 @@ type ParsedResponse struct {
 	Hostname string
 	// The cluster where the server is deployed.
 	Cluster string
+	// The locality where the server is deployed, as region.zone.subzone
+	Locality string
+	// RequestBodySize is the number of bytes of the body of the request received by the server, if any
+	RequestBodySize string
+	// RequestTrailers are the trailers of the request received by the server, if any
+	RequestTrailers http.Header
+	// ResponseHeaders are the headers of the response received by the caller
+	ResponseHeaders http.Header
+	// ResponseTrailers are the trailers of the response received by the caller, if any
+	ResponseTrailers http.Header
+	// ResponseBody is the body of the response received by the caller, without its empty lines, e.g. the
+	// body of a denial by a proxy
+	ResponseBody string
+	// Latency is the time the caller took to get the response, or 0 if unknown
+	Latency time.Duration
+	// RawHTTPResponse is the response to a raw request, verbatim, e.g. with its status line and headers as
+	// sent by a proxy. Empty for the other requests.
+	RawHTTPResponse string
 	// RawResponse gives a map of all values returned in the response (headers, etc)
 	RawResponse map[string]string
 }
@@ -83,6 +114,8 @@ func (r *ParsedResponse) String() string {
 	out += fmt.Sprintf("Host:     %s\n", r.Host)
 	out += fmt.Sprintf("Hostname: %s\n", r.Hostname)
 	out += fmt.Sprintf("Cluster:  %s\n", r.Cluster)
+	out += fmt.Sprintf("Locality: %s\n", r.Locality)
+	out += fmt.Sprintf("Latency:  %v\n", r.Latency)
 
 	return out
 }
@@ -133,6 +166,36 @@ func (r ParsedResponses) CheckOKOrFail(t test.Failer) ParsedResponses {
 	return r
 }
 
+// CheckCode checks all the responses have the expected status code. If not, the error reports the number
+// of responses of each code received.
+func (r ParsedResponses) CheckCode(expected string) error {
+	if r.Len() == 0 {
+		return fmt.Errorf("no responses received")
+	}
+	counts := make(map[string]int)
+	for _, response := range r {
+		counts[response.Code]++
+	}
+	if counts[expected] == r.Len() {
+		return nil
+	}
+	codes := make([]string, 0, len(counts))
+	for code, count := range counts {
+		codes = append(codes, fmt.Sprintf("%s (%d)", code, count))
+	}
+	sort.Strings(codes)
+	return fmt.Errorf("expected status code %s for all %d responses, received %s",
+		expected, r.Len(), strings.Join(codes, ", "))
+}
+
+func (r ParsedResponses) CheckCodeOrFail(t test.Failer, expected string) ParsedResponses {
+	t.Helper()
+	if err := r.CheckCode(expected); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
 func (r ParsedResponses) CheckHost(expected string) error {
 	return r.Check(func(i int, response *ParsedResponse) error {
 		if response.Host != expected {
@@ -185,6 +248,123 @@ func (r ParsedResponses) CheckClusterOrFail(t test.Failer, expected string) Pars
 	return r
 }
 
+// CheckLocality checks all the responses came from workloads in the given locality. The locality is
+// matched as a prefix on locality boundaries, so that "region" matches "region.zone".
+func (r ParsedResponses) CheckLocality(expected string) error {
+	return r.Check(func(i int, response *ParsedResponse) error {
+		if response.Locality != expected && !strings.HasPrefix(response.Locality, expected+".") {
+			return fmt.Errorf("response[%d] Locality: expected %s, received %s (from %s)",
+				i, expected, response.Locality, response.Hostname)
+		}
+		return nil
+	})
+}
+
+func (r ParsedResponses) CheckLocalityOrFail(t test.Failer, expected string) ParsedResponses {
+	t.Helper()
+	if err := r.CheckLocality(expected); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// CheckVersion checks all the responses came from the workloads of the expected version, e.g. the subset
+// a request was routed to.
+func (r ParsedResponses) CheckVersion(expected string) error {
+	return r.Check(func(i int, response *ParsedResponse) error {
+		if response.Version != expected {
+			return fmt.Errorf("response[%d] Version: expected %s, received %s", i, expected, response.Version)
+		}
+		return nil
+	})
+}
+
+func (r ParsedResponses) CheckVersionOrFail(t test.Failer, expected string) ParsedResponses {
+	t.Helper()
+	if err := r.CheckVersion(expected); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// Distribution returns the number of responses for each value of key, e.g. the Hostname to count the
+// responses of each replica.
+func (r ParsedResponses) Distribution(key func(*ParsedResponse) string) map[string]int {
+	out := make(map[string]int)
+	for _, response := range r {
+		out[key(response)]++
+	}
+	return out
+}
+
+// CheckReachedVersions checks all the responses came from the expected versions, and each of them
+// served at least one response, e.g. to check the traffic is split across the versions.
+func (r ParsedResponses) CheckReachedVersions(expected ...string) error {
+	return r.checkReached("Version", func(response *ParsedResponse) string { return response.Version }, expected, true)
+}
+
+func (r ParsedResponses) CheckReachedVersionsOrFail(t test.Failer, expected ...string) ParsedResponses {
+	t.Helper()
+	if err := r.CheckReachedVersions(expected...); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// CheckReachedHostnames checks each of the expected hostnames, i.e. pod names, served at least one
+// response, e.g. to check the requests reached each replica. Other hostnames may have served responses too.
+func (r ParsedResponses) CheckReachedHostnames(expected ...string) error {
+	return r.checkReached("Hostname", func(response *ParsedResponse) string { return response.Hostname }, expected, false)
+}
+
+func (r ParsedResponses) CheckReachedHostnamesOrFail(t test.Failer, expected ...string) ParsedResponses {
+	t.Helper()
+	if err := r.CheckReachedHostnames(expected...); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// checkReached checks each of the expected values of the field served at least one response and, if
+// exclusive, that no other value did.
+func (r ParsedResponses) checkReached(field string, key func(*ParsedResponse) string, expected []string,
+	exclusive bool) error {
+	if r.Len() == 0 {
+		return fmt.Errorf("no responses received")
+	}
+	distribution := r.Distribution(key)
+	var missing, unexpected []string
+	want := make(map[string]bool, len(expected))
+	for _, e := range expected {
+		want[e] = true
+		if distribution[e] == 0 {
+			missing = append(missing, e)
+		}
+	}
+	if exclusive {
+		for value := range distribution {
+			if !want[value] {
+				unexpected = append(unexpected, value)
+			}
+		}
+	}
+	if len(missing) == 0 && len(unexpected) == 0 {
+		return nil
+	}
+	sort.Strings(unexpected)
+	return fmt.Errorf("%s: expected responses from %v, missing %v, unexpected %v, received %s",
+		field, expected, missing, unexpected, formatDistribution(distribution))
+}
+
+func formatDistribution(distribution map[string]int) string {
+	values := make([]string, 0, len(distribution))
+	for value, count := range distribution {
+		values = append(values, fmt.Sprintf("%q (%d)", value, count))
+	}
+	sort.Strings(values)
+	return strings.Join(values, ", ")
+}
+
 // Count occurrences of the given text within the bodies of all responses.
 func (r ParsedResponses) Count(text string) int {
 	count := 0
@@ -202,7 +382,8 @@ func (r ParsedResponses) String() string {
 	return out
 }
 
-func parseForwardedResponse(resp *proto.ForwardEchoResponse) ParsedResponses {
+// ParseForwardedResponse parses the output of a forwarded request, e.g. as printed by the echo client.
+func ParseForwardedResponse(resp *proto.ForwardEchoResponse) ParsedResponses {
 	responses := make([]*ParsedResponse, len(resp.Output))
 	for i, output := range resp.Output {
 		responses[i] = parseResponse(output)
@@ -210,6 +391,12 @@ func parseForwardedResponse(resp *proto.ForwardEchoResponse) ParsedResponses {
 	return responses
 }
 
+// ThroughProxy returns true if the request went through an Envoy proxy, e.g. a sidecar, which sets the
+// X-Request-Id header the server reports as ID. Only meaningful if the caller did not set the header.
+func (r *ParsedResponse) ThroughProxy() bool {
+	return r.ID != ""
+}
+
 func parseResponse(output string) *ParsedResponse {
 	out := ParsedResponse{
 		Body: output,
@@ -255,6 +442,51 @@ func parseResponse(output string) *ParsedResponse {
 		out.Cluster = match[1]
 	}
 
+	match = localityFieldRegex.FindStringSubmatch(output)
+	if match != nil {
+		out.Locality = match[1]
+	}
+
+	match = requestBodySizeRegex.FindStringSubmatch(output)
+	if match != nil {
+		out.RequestBodySize = match[1]
+	}
+
+	out.RequestTrailers = http.Header{}
+	for _, match := range requestTrailerFieldRegex.FindAllStringSubmatch(output, -1) {
+		out.RequestTrailers.Add(match[1], match[2])
+	}
+
+	out.ResponseHeaders = http.Header{}
+	for _, match := range responseHeaderFieldRegex.FindAllStringSubmatch(output, -1) {
+		out.ResponseHeaders.Add(match[1], match[2])
+	}
+
+	out.ResponseTrailers = http.Header{}
+	for _, match := range responseTrailerFieldRegex.FindAllStringSubmatch(output, -1) {
+		out.ResponseTrailers.Add(match[1], match[2])
+	}
+
+	var bodyLines []string
+	for _, match := range responseBodyLineRegex.FindAllStringSubmatch(output, -1) {
+		bodyLines = append(bodyLines, match[1])
+	}
+	out.ResponseBody = strings.Join(bodyLines, "\n")
+
+	match = latencyFieldRegex.FindStringSubmatch(output)
+	if match != nil {
+		if latency, err := time.ParseDuration(match[1]); err == nil {
+			out.Latency = latency
+		}
+	}
+
+	match = rawHTTPResponseFieldRegex.FindStringSubmatch(output)
+	if match != nil {
+		if raw, err := strconv.Unquote(match[1]); err == nil {
+			out.RawHTTPResponse = raw
+		}
+	}
+
 	out.RawResponse = map[string]string{}
 	for _, l := range strings.Split(output, "\n") {
 		prefixSplit := strings.Split(l, "body] ")
@@ -0,0 +1,274 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"istio.io/istio/pkg/test/echo/proto"
+)
+
+// output returns the output of the forwarder for a request answered by the given pod.
+func output(id int, code, version, hostname string, latency time.Duration) string {
+	lines := []string{
+		fmt.Sprintf("[%d] Url=http://b:80/path", id),
+		fmt.Sprintf("[%d] Header=X-Custom:value", id),
+		fmt.Sprintf("[%d] StatusCode=%s", id, code),
+		fmt.Sprintf("[%d] ResponseHeader=Content-Type:application/text", id),
+		fmt.Sprintf("[%d] ResponseHeader=X-Multi:a", id),
+		fmt.Sprintf("[%d] ResponseHeader=X-Multi:b:c", id),
+		fmt.Sprintf("[%d body] X-Request-Id=req-%d", id, id),
+		fmt.Sprintf("[%d body] ServiceVersion=%s", id, version),
+		fmt.Sprintf("[%d body] ServicePort=8090", id),
+		fmt.Sprintf("[%d body] Host=b:80", id),
+		fmt.Sprintf("[%d body] URL=/path", id),
+		fmt.Sprintf("[%d body] Cluster=0", id),
+		fmt.Sprintf("[%d body] Locality=region.zone.subzone", id),
+		fmt.Sprintf("[%d body] RequestBodySize=1024", id),
+		fmt.Sprintf("[%d body] Hostname=%s", id, hostname),
+		fmt.Sprintf("[%d] Latency=%v", id, latency),
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func TestParseForwardedResponse(t *testing.T) {
+	responses := ParseForwardedResponse(&proto.ForwardEchoResponse{Output: []string{
+		output(0, "200", "v1", "b-v1-abc", 15*time.Millisecond),
+		output(1, "503", "v2", "b-v2-def", 2*time.Second),
+	}})
+	if responses.Len() != 2 {
+		t.Fatalf("got %d responses, want 2", responses.Len())
+	}
+
+	r := responses[0]
+	checks := []struct {
+		field string
+		got   interface{}
+		want  interface{}
+	}{
+		{"ID", r.ID, "req-0"},
+		{"URL", r.URL, "/path"},
+		{"Version", r.Version, "v1"},
+		{"Port", r.Port, "8090"},
+		{"Code", r.Code, "200"},
+		{"Hostname", r.Hostname, "b-v1-abc"},
+		{"Cluster", r.Cluster, "0"},
+		{"Locality", r.Locality, "region.zone.subzone"},
+		{"RequestBodySize", r.RequestBodySize, "1024"},
+		{"Latency", r.Latency, 15 * time.Millisecond},
+		{"ResponseHeaders", r.ResponseHeaders, http.Header{
+			"Content-Type": {"application/text"},
+			"X-Multi":      {"a", "b:c"},
+		}},
+		{"RawResponse[ServiceVersion]", r.RawResponse["ServiceVersion"], "v1"},
+	}
+	for _, c := range checks {
+		if !reflect.DeepEqual(c.got, c.want) {
+			t.Errorf("%s: got %v, want %v", c.field, c.got, c.want)
+		}
+	}
+	if !r.IsOK() {
+		t.Errorf("response 0 is not OK")
+	}
+
+	if r := responses[1]; r.Code != "503" || r.Version != "v2" || r.Latency != 2*time.Second || r.IsOK() {
+		t.Errorf("unexpected response 1:\n%s", r)
+	}
+}
+
+func TestParseResponseWithoutOptionalFields(t *testing.T) {
+	r := parseResponse("[0] Url=tcp://b:9090\n[0 body] Hostname=b-v1-abc\n[0] Latency=not-a-duration\n")
+	if r.Code != "" || r.Locality != "" || r.RequestBodySize != "" || r.Latency != 0 {
+		t.Errorf("unexpected fields in response:\n%s", r)
+	}
+	if len(r.ResponseHeaders) != 0 {
+		t.Errorf("got response headers %v, want none", r.ResponseHeaders)
+	}
+	if r.Hostname != "b-v1-abc" {
+		t.Errorf("got hostname %q, want b-v1-abc", r.Hostname)
+	}
+}
+
+func TestResponseHeadersIgnoreBody(t *testing.T) {
+	// A header echoed in the body of the response is not a header of the response.
+	r := parseResponse("[0] StatusCode=200\n[0 body] ResponseHeader=X-Fake:value\n")
+	if len(r.ResponseHeaders) != 0 {
+		t.Errorf("got response headers %v, want none", r.ResponseHeaders)
+	}
+}
+
+func TestParseResponseBody(t *testing.T) {
+	r := parseResponse(output(0, "200", "v1", "b-v1-abc", time.Millisecond))
+	want := strings.Join([]string{
+		"X-Request-Id=req-0",
+		"ServiceVersion=v1",
+		"ServicePort=8090",
+		"Host=b:80",
+		"URL=/path",
+		"Cluster=0",
+		"Locality=region.zone.subzone",
+		"RequestBodySize=1024",
+		"Hostname=b-v1-abc",
+	}, "\n")
+	if r.ResponseBody != want {
+		t.Errorf("got response body %q, want %q", r.ResponseBody, want)
+	}
+
+	r = parseResponse("[0] StatusCode=403\n[0] ResponseHeader=Content-Length:19\n[0 body] RBAC: access denied\n[0] Latency=1ms\n")
+	if r.ResponseBody != "RBAC: access denied" {
+		t.Errorf("got denial body %q, want %q", r.ResponseBody, "RBAC: access denied")
+	}
+}
+
+func TestParseTrailers(t *testing.T) {
+	r := parseResponse(strings.Join([]string{
+		"[0] Url=http://b:80/",
+		"[0] Trailer=X-Checksum:abc",
+		"[0] StatusCode=200",
+		"[0] ResponseTrailer=X-Checksum:abc",
+		"[0] ResponseTrailer=X-Status:done:ok",
+		"[0 body] RequestTrailer=X-Checksum:abc",
+		"[0 body] Proto=HTTP/2.0",
+	}, "\n") + "\n")
+
+	if want := (http.Header{"X-Checksum": {"abc"}}); !reflect.DeepEqual(r.RequestTrailers, want) {
+		t.Errorf("got request trailers %v, want %v", r.RequestTrailers, want)
+	}
+	want := http.Header{"X-Checksum": {"abc"}, "X-Status": {"done:ok"}}
+	if !reflect.DeepEqual(r.ResponseTrailers, want) {
+		t.Errorf("got response trailers %v, want %v", r.ResponseTrailers, want)
+	}
+	if len(r.ResponseHeaders) != 0 {
+		t.Errorf("got response headers %v, want none", r.ResponseHeaders)
+	}
+}
+
+func TestParseRawHTTPResponse(t *testing.T) {
+	raw := "HTTP/1.1 400 Bad Request\r\ncontent-length: 11\r\nconnection: close\r\n\r\nBad Request"
+	r := parseResponse(strings.Join([]string{
+		"[0] Url=http://b:80/",
+		"[0] StatusCode=400",
+		"[0] ResponseHeader=Connection:close",
+		"[0 body] Bad Request",
+		"[0] RawHTTPResponse=" + strconv.Quote(raw),
+	}, "\n") + "\n")
+
+	if r.RawHTTPResponse != raw {
+		t.Errorf("got raw response %q, want %q", r.RawHTTPResponse, raw)
+	}
+	if r.Code != "400" || r.ResponseBody != "Bad Request" {
+		t.Errorf("got code %s and body %q, want 400 and %q", r.Code, r.ResponseBody, "Bad Request")
+	}
+	if r := parseResponse(output(0, "200", "v1", "b-v1-abc", time.Millisecond)); r.RawHTTPResponse != "" {
+		t.Errorf("got raw response %q for a request which is not raw", r.RawHTTPResponse)
+	}
+}
+
+func responsesFrom(pods ...string) ParsedResponses {
+	var out ParsedResponses
+	for _, pod := range pods {
+		version := strings.Split(pod, "-")[1]
+		out = append(out, &ParsedResponse{Code: "200", Version: version, Hostname: pod})
+	}
+	return out
+}
+
+func TestDistribution(t *testing.T) {
+	responses := responsesFrom("b-v1-a", "b-v1-a", "b-v2-b")
+	got := responses.Distribution(func(r *ParsedResponse) string { return r.Hostname })
+	want := map[string]int{"b-v1-a": 2, "b-v2-b": 1}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestCheckReachedVersions(t *testing.T) {
+	cases := []struct {
+		name      string
+		responses ParsedResponses
+		versions  []string
+		wantErr   string
+	}{
+		{"split", responsesFrom("b-v1-a", "b-v2-b", "b-v1-a"), []string{"v1", "v2"}, ""},
+		{"missing version", responsesFrom("b-v1-a", "b-v1-a"), []string{"v1", "v2"}, "missing [v2]"},
+		{"unexpected version", responsesFrom("b-v1-a", "b-v3-c"), []string{"v1"}, "unexpected [v3]"},
+		{"no responses", nil, []string{"v1"}, "no responses"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.responses.CheckReachedVersions(c.versions...)
+			checkError(t, err, c.wantErr)
+		})
+	}
+}
+
+func TestCheckReachedHostnames(t *testing.T) {
+	cases := []struct {
+		name      string
+		responses ParsedResponses
+		hostnames []string
+		wantErr   string
+	}{
+		{"each replica", responsesFrom("b-v1-a", "b-v1-b", "b-v1-a"), []string{"b-v1-a", "b-v1-b"}, ""},
+		{"other replicas allowed", responsesFrom("b-v1-a", "b-v1-c"), []string{"b-v1-a"}, ""},
+		{"missing replica", responsesFrom("b-v1-a", "b-v1-a"), []string{"b-v1-a", "b-v1-b"}, `"b-v1-a" (2)`},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.responses.CheckReachedHostnames(c.hostnames...)
+			checkError(t, err, c.wantErr)
+		})
+	}
+}
+
+func TestCheckVersion(t *testing.T) {
+	responses := responsesFrom("b-v2-a", "b-v2-b")
+	if err := responses.CheckVersion("v2"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	responses[1].Version = "v1"
+	checkError(t, responses.CheckVersion("v2"), "response[1] Version: expected v2, received v1")
+}
+
+func TestCheckCode(t *testing.T) {
+	responses := responsesFrom("b-v1-a", "b-v1-b")
+	if err := responses.CheckCode("200"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := responses.CheckOK(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	responses[1].Code = "503"
+	checkError(t, responses.CheckCode("200"), "received 200 (1), 503 (1)")
+}
+
+func checkError(t *testing.T, err error, want string) {
+	t.Helper()
+	if want == "" {
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		return
+	}
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("got error %v, want error containing %q", err, want)
+	}
+}
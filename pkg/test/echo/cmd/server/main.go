@@ -38,6 +38,7 @@ var (
 	uds         string
 	version     string
 	cluster     string
+	locality    string
 	crt         string
 	key         string
 
@@ -91,6 +92,7 @@ var (
 				TLSKey:    key,
 				Version:   version,
 				Cluster:   cluster,
+				Locality:  locality,
 				UDSServer: uds,
 			})
 
@@ -126,6 +128,7 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&uds, "uds", "", "HTTP server on unix domain socket")
 	rootCmd.PersistentFlags().StringVar(&version, "version", "", "Version string")
 	rootCmd.PersistentFlags().StringVar(&cluster, "cluster", "", "Cluster where this server is deployed")
+	rootCmd.PersistentFlags().StringVar(&locality, "locality", "", "Locality (region.zone.subzone) where this server is deployed")
 	rootCmd.PersistentFlags().StringVar(&crt, "crt", "", "gRPC TLS server-side certificate")
 	rootCmd.PersistentFlags().StringVar(&key, "key", "", "gRPC TLS server-side key")
 
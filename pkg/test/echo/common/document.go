@@ -0,0 +1,43 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import "time"
+
+const (
+	// DocumentPathPrefix is the path prefix of the documents served by the HTTP endpoints of the echo
+	// server, instead of echoing the request. It is followed by the name of the document.
+	DocumentPathPrefix = "/documents/"
+
+	// DocumentRequestsSuffix is appended to the path of a document to get its request log.
+	DocumentRequestsSuffix = "/requests"
+)
+
+// Document is a static response served by the HTTP endpoints of the echo server, e.g. to mock a JWKS
+// server.
+type Document struct {
+	// Code is the status code of the response. If not set, 200 is used.
+	Code int `json:"code,omitempty"`
+
+	// Headers of the response.
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// Body of the response.
+	Body string `json:"body,omitempty"`
+
+	// Delay, if set, is the time the response is held for, e.g. to mock a slow server. The request log
+	// records the time the request is received.
+	Delay time.Duration `json:"delay,omitempty"`
+}
@@ -0,0 +1,33 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+const (
+	// ExtAuthzPathPrefix is the path prefix of the requests the HTTP endpoints of the echo server answer as
+	// an HTTP ext_authz server, e.g. set as the path_prefix of the ext_authz filter of Envoy, which appends
+	// the path of the request checked.
+	ExtAuthzPathPrefix = "/ext-authz"
+
+	// ExtAuthzHeader is the header of the request checked deciding the answer: the requests with the
+	// value ExtAuthzDeny are denied with 403, the others are allowed. The ext_authz filter must forward it.
+	ExtAuthzHeader = "x-ext-authz"
+	ExtAuthzDeny   = "deny"
+
+	// ExtAuthzResultHeader is the header of the answers, ExtAuthzAllowed or ExtAuthzDenied, e.g. for the
+	// ext_authz filter to add it to the requests allowed.
+	ExtAuthzResultHeader = "x-ext-authz-check-result"
+	ExtAuthzAllowed      = "allowed"
+	ExtAuthzDenied       = "denied by ext_authz"
+)
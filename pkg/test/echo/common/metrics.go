@@ -15,6 +15,12 @@
 package common
 
 import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/prometheus/common/expfmt"
+
 	"istio.io/pkg/monitoring"
 )
 
@@ -24,8 +30,10 @@ type EchoMetrics struct {
 	TCPRequests  monitoring.Metric
 }
 
+const portLabelName = "port"
+
 var (
-	PortLabel = monitoring.MustCreateLabel("port")
+	PortLabel = monitoring.MustCreateLabel(portLabelName)
 	Metrics   = &EchoMetrics{
 		HTTPRequests: monitoring.NewSum(
 			"istio_echo_http_requests_total",
@@ -45,3 +53,34 @@ var (
 func init() {
 	monitoring.MustRegister(Metrics.HTTPRequests, Metrics.GrpcRequests, Metrics.TCPRequests)
 }
+
+// ParseRequestCount parses the metrics exposed by the echo server in the Prometheus text format and
+// returns the total number of requests (of any protocol) received on the given port.
+func ParseRequestCount(metrics io.Reader, port int) (int, error) {
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(metrics)
+	if err != nil {
+		return 0, fmt.Errorf("failed parsing echo metrics: %v", err)
+	}
+
+	count := 0
+	for _, name := range []string{
+		Metrics.HTTPRequests.Name(),
+		Metrics.GrpcRequests.Name(),
+		Metrics.TCPRequests.Name(),
+	} {
+		family, ok := families[name]
+		if !ok {
+			continue
+		}
+		for _, m := range family.Metric {
+			for _, l := range m.Label {
+				if l.GetName() == portLabelName && l.GetValue() == strconv.Itoa(port) {
+					// Sums are exported as untyped metrics.
+					count += int(m.GetUntyped().GetValue() + m.GetCounter().GetValue())
+				}
+			}
+		}
+	}
+	return count, nil
+}
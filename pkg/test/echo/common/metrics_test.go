@@ -0,0 +1,48 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseRequestCount(t *testing.T) {
+	metrics := `# HELP istio_echo_http_requests_total The number of http requests total
+# TYPE istio_echo_http_requests_total untyped
+istio_echo_http_requests_total{port="8090"} 3
+istio_echo_http_requests_total{port="8080"} 7
+# HELP istio_echo_grpc_requests_total The number of grpc requests total
+# TYPE istio_echo_grpc_requests_total untyped
+istio_echo_grpc_requests_total{port="8090"} 2
+`
+	cases := []struct {
+		port int
+		want int
+	}{
+		{port: 8090, want: 5},
+		{port: 8080, want: 7},
+		{port: 9090, want: 0},
+	}
+	for _, c := range cases {
+		got, err := ParseRequestCount(strings.NewReader(metrics), c.port)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != c.want {
+			t.Errorf("port %d: got %d, want %d", c.port, got, c.want)
+		}
+	}
+}
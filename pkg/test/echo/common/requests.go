@@ -0,0 +1,52 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"net/http"
+	"time"
+)
+
+const (
+	// ReceivedRequestsPath is the path of the request log on the metrics port of the echo server, an admin
+	// port the sidecar does not intercept. GET returns the JSON encoded list of the ReceivedRequest, oldest
+	// first, optionally only those received after the time given by the ReceivedRequestsSinceParam
+	// parameter. DELETE clears the log.
+	ReceivedRequestsPath = "/requests"
+
+	// ReceivedRequestsSinceParam is the query parameter of ReceivedRequestsPath giving a time, in the
+	// RFC 3339 format with nanoseconds.
+	ReceivedRequestsSinceParam = "since"
+
+	// RequestLogSize is the number of the last requests kept in the request log.
+	RequestLogSize = 512
+)
+
+// ReceivedRequest is a request received by the HTTP endpoints of the echo server, as recorded in its
+// request log.
+type ReceivedRequest struct {
+	Time time.Time `json:"time"`
+	// Hostname of the server, i.e. the name of the pod, which tells the workloads of an instance apart.
+	Hostname string `json:"hostname"`
+	// Port of the endpoint receiving the request, 0 for a unix domain socket.
+	Port   int    `json:"port"`
+	Method string `json:"method"`
+	// Path of the request, with its query if any.
+	Path string `json:"path"`
+	Host string `json:"host"`
+	// Peer is the address of the client, e.g. of the sidecar of the server.
+	Peer    string      `json:"peer"`
+	Headers http.Header `json:"headers,omitempty"`
+}
@@ -24,6 +24,12 @@ var (
 	StatusUnauthorized    = strconv.Itoa(http.StatusUnauthorized)
 	StatusCodeForbidden   = strconv.Itoa(http.StatusForbidden)
 	StatusCodeUnavailable = strconv.Itoa(http.StatusServiceUnavailable)
+	StatusCodeBadGateway  = strconv.Itoa(http.StatusBadGateway)
+
+	StatusCodeGatewayTimeout       = strconv.Itoa(http.StatusGatewayTimeout)
+	StatusCodeHeaderFieldsTooLarge = strconv.Itoa(http.StatusRequestHeaderFieldsTooLarge)
+	StatusCodeBadRequest           = strconv.Itoa(http.StatusBadRequest)
+	StatusCodeURITooLong           = strconv.Itoa(http.StatusRequestURITooLong)
 )
 
 // Field is a list of fields returned in responses from the Echo server.
@@ -38,4 +44,18 @@ const (
 	HostField           Field = "Host"
 	HostnameField       Field = "Hostname"
 	ClusterField        Field = "Cluster"
+	LocalityField       Field = "Locality"
+	// RequestBodySizeField is the number of bytes of the body of the request received by the server.
+	RequestBodySizeField Field = "RequestBodySize"
+	// RequestTrailerField is written by the server for each trailer of the request received, as name:value.
+	RequestTrailerField Field = "RequestTrailer"
+	// ResponseHeaderField is written by the forwarder for each header of the responses it receives.
+	ResponseHeaderField Field = "ResponseHeader"
+	// ResponseTrailerField is written by the forwarder for each trailer of the responses it receives.
+	ResponseTrailerField Field = "ResponseTrailer"
+	// LatencyField is written by the forwarder with the time it took to get each response.
+	LatencyField Field = "Latency"
+	// RawHTTPResponseField is written by the forwarder with the response to a raw request, as received,
+	// quoted as a Go string.
+	RawHTTPResponseField Field = "RawHTTPResponse"
 )
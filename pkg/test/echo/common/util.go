@@ -16,6 +16,7 @@ package common
 
 import (
 	"net/http"
+	"strings"
 	"time"
 
 	"istio.io/istio/pkg/test/echo/proto"
@@ -42,23 +43,78 @@ func GetTimeout(request *proto.ForwardEchoRequest) time.Duration {
 	return timeout
 }
 
-// GetCount returns the count value or DefaultCount if not set.
+// GetCount returns the number of header sets, if any, or the count value or DefaultCount if not set.
 func GetCount(request *proto.ForwardEchoRequest) int {
+	if len(request.HeaderSets) > 0 {
+		return len(request.HeaderSets)
+	}
 	if request.Count > 1 {
 		return int(request.Count)
 	}
 	return DefaultCount
 }
 
-// GetHeaders returns the headers for the message.
+// GetHeaders returns the headers for the message. The names are kept as given rather than canonicalized,
+// so that the HTTP requests can be sent with them in any case: look them up with GetHeader.
 func GetHeaders(request *proto.ForwardEchoRequest) http.Header {
 	headers := make(http.Header)
 	for _, h := range request.Headers {
-		headers.Add(h.Key, h.Value)
+		headers[h.Key] = append(headers[h.Key], h.Value)
 	}
 	return headers
 }
 
+// GetTrailers returns the trailers of the HTTP requests of the message, if any.
+func GetTrailers(request *proto.ForwardEchoRequest) http.Header {
+	if len(request.Trailers) == 0 {
+		return nil
+	}
+	trailers := make(http.Header)
+	for _, t := range request.Trailers {
+		trailers.Add(t.Key, t.Value)
+	}
+	return trailers
+}
+
+// GetHeader returns the first value of the header of the given name, in any case, or "" if none.
+func GetHeader(headers http.Header, name string) string {
+	for k, values := range headers {
+		if strings.EqualFold(k, name) && len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+// delHeader deletes the headers of the given name, in any case.
+func delHeader(headers http.Header, name string) {
+	for k := range headers {
+		if strings.EqualFold(k, name) {
+			delete(headers, k)
+		}
+	}
+}
+
+// GetHeaderSets returns the headers of each request of the message, if the message has header sets: the
+// headers of the message, replaced by the headers of the same name of the set.
+func GetHeaderSets(request *proto.ForwardEchoRequest) []http.Header {
+	if len(request.HeaderSets) == 0 {
+		return nil
+	}
+	sets := make([]http.Header, 0, len(request.HeaderSets))
+	for _, set := range request.HeaderSets {
+		headers := GetHeaders(request)
+		for _, h := range set.Headers {
+			delHeader(headers, h.Key)
+		}
+		for _, h := range set.Headers {
+			headers[h.Key] = append(headers[h.Key], h.Value)
+		}
+		sets = append(sets, headers)
+	}
+	return sets
+}
+
 // MicrosToDuration converts the given microseconds to a time.Duration.
 func MicrosToDuration(micros int64) time.Duration {
 	return time.Duration(micros) * time.Microsecond
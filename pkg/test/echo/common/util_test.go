@@ -0,0 +1,79 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"istio.io/istio/pkg/test/echo/proto"
+)
+
+func TestGetHeaderSets(t *testing.T) {
+	request := &proto.ForwardEchoRequest{
+		Count: 5,
+		Headers: []*proto.Header{
+			{Key: "X-Test", Value: "base"},
+			{Key: "Authorization", Value: "Bearer base"},
+		},
+		HeaderSets: []*proto.HeaderSet{
+			{Headers: []*proto.Header{{Key: "Authorization", Value: "Bearer first"}}},
+			{},
+		},
+	}
+
+	want := []http.Header{
+		{"X-Test": {"base"}, "Authorization": {"Bearer first"}},
+		{"X-Test": {"base"}, "Authorization": {"Bearer base"}},
+	}
+	if got := GetHeaderSets(request); !reflect.DeepEqual(got, want) {
+		t.Errorf("GetHeaderSets() = %v, want %v", got, want)
+	}
+	if got := GetCount(request); got != len(want) {
+		t.Errorf("GetCount() = %d, want %d", got, len(want))
+	}
+
+	request.HeaderSets = nil
+	if got := GetHeaderSets(request); got != nil {
+		t.Errorf("GetHeaderSets() without sets = %v, want nil", got)
+	}
+}
+
+func TestGetHeaders(t *testing.T) {
+	request := &proto.ForwardEchoRequest{
+		Headers: []*proto.Header{
+			{Key: "x-custom-token", Value: "lower"},
+			{Key: "X-CUSTOM-TOKEN", Value: "upper"},
+			{Key: "Host", Value: "b"},
+		},
+	}
+
+	want := http.Header{
+		"x-custom-token": {"lower"},
+		"X-CUSTOM-TOKEN": {"upper"},
+		"Host":           {"b"},
+	}
+	got := GetHeaders(request)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetHeaders() = %v, want %v", got, want)
+	}
+	if host := GetHeader(got, "host"); host != "b" {
+		t.Errorf("GetHeader(host) = %q, want %q", host, "b")
+	}
+	if missing := GetHeader(got, "Authorization"); missing != "" {
+		t.Errorf("GetHeader(Authorization) = %q, want none", missing)
+	}
+}
@@ -149,15 +149,51 @@ func (m *Header) GetValue() string {
 }
 
 type ForwardEchoRequest struct {
-	Count                int32     `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`
-	Qps                  int32     `protobuf:"varint,2,opt,name=qps,proto3" json:"qps,omitempty"`
-	TimeoutMicros        int64     `protobuf:"varint,3,opt,name=timeout_micros,json=timeoutMicros,proto3" json:"timeout_micros,omitempty"`
-	Url                  string    `protobuf:"bytes,4,opt,name=url,proto3" json:"url,omitempty"`
-	Headers              []*Header `protobuf:"bytes,5,rep,name=headers,proto3" json:"headers,omitempty"`
-	Message              string    `protobuf:"bytes,6,opt,name=message,proto3" json:"message,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
-	XXX_unrecognized     []byte    `json:"-"`
-	XXX_sizecache        int32     `json:"-"`
+	Count         int32     `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`
+	Qps           int32     `protobuf:"varint,2,opt,name=qps,proto3" json:"qps,omitempty"`
+	TimeoutMicros int64     `protobuf:"varint,3,opt,name=timeout_micros,json=timeoutMicros,proto3" json:"timeout_micros,omitempty"`
+	Url           string    `protobuf:"bytes,4,opt,name=url,proto3" json:"url,omitempty"`
+	Headers       []*Header `protobuf:"bytes,5,rep,name=headers,proto3" json:"headers,omitempty"`
+	Message       string    `protobuf:"bytes,6,opt,name=message,proto3" json:"message,omitempty"`
+	// If set, the local port the client socket is bound to.
+	SourcePort int32 `protobuf:"varint,7,opt,name=source_port,json=sourcePort,proto3" json:"source_port,omitempty"`
+	// If set, SO_REUSEADDR is set on the client socket.
+	ReuseAddress bool `protobuf:"varint,8,opt,name=reuse_address,json=reuseAddress,proto3" json:"reuse_address,omitempty"`
+	// If set, the request is sent as this number of messages on a bidirectional gRPC stream, each one
+	// echoed back before the next one is sent. Only applies to grpc:// URLs.
+	StreamMessages int32 `protobuf:"varint,9,opt,name=stream_messages,json=streamMessages,proto3" json:"stream_messages,omitempty"`
+	// If set, the requests are sent one after the other, reusing the connection, and each request is sent
+	// with the headers of the corresponding set, replacing the headers of the same name. The number of sets
+	// overrides count.
+	HeaderSets []*HeaderSet `protobuf:"bytes,10,rep,name=header_sets,json=headerSets,proto3" json:"header_sets,omitempty"`
+	// If set, the HTTP method of the request. Only applies to http:// and https:// URLs. GET is used by
+	// default.
+	Method string `protobuf:"bytes,11,opt,name=method,proto3" json:"method,omitempty"`
+	// If set, the HTTP requests are sent with a body of this number of bytes. Only applies to http:// and
+	// https:// URLs.
+	BodySize int32 `protobuf:"varint,12,opt,name=body_size,json=bodySize,proto3" json:"body_size,omitempty"`
+	// If set, each HTTP request is sent on a new connection, which is closed once the response is received
+	// (Connection: close). Only applies to http:// and https:// URLs.
+	CloseConnection bool `protobuf:"varint,13,opt,name=close_connection,json=closeConnection,proto3" json:"close_connection,omitempty"`
+	// If set, the requests are sent over the unix domain socket at this path, instead of the address of the
+	// URL, which is only used for the Host header. Only applies to http:// URLs.
+	Uds string `protobuf:"bytes,14,opt,name=uds,proto3" json:"uds,omitempty"`
+	// If set, the HTTP requests are sent with HTTP/2, with prior knowledge for http:// URLs (h2c). Only applies
+	// to http:// and https:// URLs.
+	Http2 bool `protobuf:"varint,15,opt,name=http2,proto3" json:"http2,omitempty"`
+	// If set, the HTTP requests are sent with these trailers, after their body. Only applies to http:// and
+	// https:// URLs.
+	Trailers []*Header `protobuf:"bytes,16,rep,name=trailers,proto3" json:"trailers,omitempty"`
+	// If set, the fragment of the URL, if any, is sent in the target of the HTTP requests, which clients must
+	// not do, e.g. to test how the servers handle it. Only applies to http:// and https:// URLs.
+	SendFragment bool `protobuf:"varint,17,opt,name=send_fragment,json=sendFragment,proto3" json:"send_fragment,omitempty"`
+	// If set, each request is this text, written as is on a new connection instead of a request built from
+	// the method, URL, headers and body, e.g. to send a malformed request line. The response is returned
+	// verbatim. Only applies to http:// URLs.
+	RawRequest           string   `protobuf:"bytes,18,opt,name=raw_request,json=rawRequest,proto3" json:"raw_request,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
 func (m *ForwardEchoRequest) Reset()         { *m = ForwardEchoRequest{} }
@@ -227,6 +263,90 @@ func (m *ForwardEchoRequest) GetMessage() string {
 	return ""
 }
 
+func (m *ForwardEchoRequest) GetSourcePort() int32 {
+	if m != nil {
+		return m.SourcePort
+	}
+	return 0
+}
+
+func (m *ForwardEchoRequest) GetReuseAddress() bool {
+	if m != nil {
+		return m.ReuseAddress
+	}
+	return false
+}
+
+func (m *ForwardEchoRequest) GetStreamMessages() int32 {
+	if m != nil {
+		return m.StreamMessages
+	}
+	return 0
+}
+
+func (m *ForwardEchoRequest) GetHeaderSets() []*HeaderSet {
+	if m != nil {
+		return m.HeaderSets
+	}
+	return nil
+}
+
+func (m *ForwardEchoRequest) GetMethod() string {
+	if m != nil {
+		return m.Method
+	}
+	return ""
+}
+
+func (m *ForwardEchoRequest) GetBodySize() int32 {
+	if m != nil {
+		return m.BodySize
+	}
+	return 0
+}
+
+func (m *ForwardEchoRequest) GetCloseConnection() bool {
+	if m != nil {
+		return m.CloseConnection
+	}
+	return false
+}
+
+func (m *ForwardEchoRequest) GetUds() string {
+	if m != nil {
+		return m.Uds
+	}
+	return ""
+}
+
+func (m *ForwardEchoRequest) GetHttp2() bool {
+	if m != nil {
+		return m.Http2
+	}
+	return false
+}
+
+func (m *ForwardEchoRequest) GetTrailers() []*Header {
+	if m != nil {
+		return m.Trailers
+	}
+	return nil
+}
+
+func (m *ForwardEchoRequest) GetSendFragment() bool {
+	if m != nil {
+		return m.SendFragment
+	}
+	return false
+}
+
+func (m *ForwardEchoRequest) GetRawRequest() string {
+	if m != nil {
+		return m.RawRequest
+	}
+	return ""
+}
+
 type ForwardEchoResponse struct {
 	Output               []string `protobuf:"bytes,1,rep,name=output,proto3" json:"output,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
@@ -266,37 +386,92 @@ func (m *ForwardEchoResponse) GetOutput() []string {
 	return nil
 }
 
+type HeaderSet struct {
+	Headers              []*Header `protobuf:"bytes,1,rep,name=headers,proto3" json:"headers,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
+}
+
+func (m *HeaderSet) Reset()         { *m = HeaderSet{} }
+func (m *HeaderSet) String() string { return proto.CompactTextString(m) }
+func (*HeaderSet) ProtoMessage()    {}
+func (*HeaderSet) Descriptor() ([]byte, []int) {
+	return fileDescriptor_08134aea513e0001, []int{5}
+}
+
+func (m *HeaderSet) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_HeaderSet.Unmarshal(m, b)
+}
+func (m *HeaderSet) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_HeaderSet.Marshal(b, m, deterministic)
+}
+func (m *HeaderSet) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_HeaderSet.Merge(m, src)
+}
+func (m *HeaderSet) XXX_Size() int {
+	return xxx_messageInfo_HeaderSet.Size(m)
+}
+func (m *HeaderSet) XXX_DiscardUnknown() {
+	xxx_messageInfo_HeaderSet.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_HeaderSet proto.InternalMessageInfo
+
+func (m *HeaderSet) GetHeaders() []*Header {
+	if m != nil {
+		return m.Headers
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*EchoRequest)(nil), "proto.EchoRequest")
 	proto.RegisterType((*EchoResponse)(nil), "proto.EchoResponse")
 	proto.RegisterType((*Header)(nil), "proto.Header")
 	proto.RegisterType((*ForwardEchoRequest)(nil), "proto.ForwardEchoRequest")
 	proto.RegisterType((*ForwardEchoResponse)(nil), "proto.ForwardEchoResponse")
+	proto.RegisterType((*HeaderSet)(nil), "proto.HeaderSet")
 }
 
 func init() { proto.RegisterFile("echo.proto", fileDescriptor_08134aea513e0001) }
 
 var fileDescriptor_08134aea513e0001 = []byte{
-	// 301 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x7c, 0x50, 0xcb, 0x4a, 0xf3, 0x40,
-	0x18, 0x25, 0x7f, 0x3a, 0x29, 0xfd, 0xfa, 0x57, 0x65, 0x5a, 0x64, 0xec, 0xaa, 0x04, 0xa4, 0xd9,
-	0x58, 0xa5, 0xbe, 0x82, 0x8a, 0x1b, 0x37, 0xa3, 0x7b, 0x89, 0xe9, 0x87, 0x09, 0x26, 0x9d, 0x74,
-	0x2e, 0x15, 0xdf, 0xc0, 0x47, 0xf2, 0xf1, 0x64, 0x2e, 0x85, 0x04, 0xc5, 0x55, 0xe6, 0x5c, 0x72,
-	0xe6, 0xcc, 0x01, 0xc0, 0xa2, 0x14, 0xab, 0x56, 0x0a, 0x2d, 0x28, 0x71, 0x9f, 0x74, 0x09, 0xe3,
-	0xdb, 0xa2, 0x14, 0x1c, 0x77, 0x06, 0x95, 0xa6, 0x0c, 0x86, 0x0d, 0x2a, 0x95, 0xbf, 0x22, 0x8b,
-	0x16, 0x51, 0x36, 0xe2, 0x07, 0x98, 0x66, 0xf0, 0xdf, 0x1b, 0x55, 0x2b, 0xb6, 0x0a, 0xff, 0x70,
-	0x5e, 0x41, 0x72, 0x8f, 0xf9, 0x06, 0x25, 0x3d, 0x81, 0xf8, 0x0d, 0x3f, 0x82, 0x6e, 0x8f, 0x74,
-	0x06, 0x64, 0x9f, 0xd7, 0x06, 0xd9, 0x3f, 0xc7, 0x79, 0x90, 0x7e, 0x45, 0x40, 0xef, 0x84, 0x7c,
-	0xcf, 0xe5, 0xa6, 0x5b, 0x66, 0x06, 0xa4, 0x10, 0x66, 0xab, 0x5d, 0x00, 0xe1, 0x1e, 0xd8, 0xd0,
-	0x5d, 0xab, 0x5c, 0x00, 0xe1, 0xf6, 0x48, 0xcf, 0xe1, 0x48, 0x57, 0x0d, 0x0a, 0xa3, 0x9f, 0x9b,
-	0xaa, 0x90, 0x42, 0xb1, 0x78, 0x11, 0x65, 0x31, 0x9f, 0x04, 0xf6, 0xc1, 0x91, 0xf6, 0x47, 0x23,
-	0x6b, 0x36, 0xf0, 0x6d, 0x8c, 0xac, 0xe9, 0x12, 0x86, 0xa5, 0x6b, 0xaa, 0x18, 0x59, 0xc4, 0xd9,
-	0x78, 0x3d, 0xf1, 0xe3, 0xac, 0x7c, 0x7f, 0x7e, 0x50, 0xbb, 0x8f, 0x4d, 0xfa, 0x8f, 0xbd, 0x80,
-	0x69, 0xaf, 0x79, 0x58, 0xe7, 0x14, 0x12, 0x61, 0x74, 0x6b, 0x6c, 0xf7, 0x38, 0x1b, 0xf1, 0x80,
-	0xd6, 0x9f, 0x11, 0x1c, 0x5b, 0xe3, 0x13, 0x2a, 0xfd, 0x88, 0x72, 0x5f, 0x15, 0x48, 0x2f, 0x61,
-	0x60, 0x29, 0x4a, 0xc3, 0xe5, 0x9d, 0x09, 0xe6, 0xd3, 0x1e, 0x17, 0xc2, 0x6f, 0x60, 0xdc, 0xb9,
-	0x93, 0x9e, 0x05, 0xcf, 0xcf, 0x05, 0xe7, 0xf3, 0xdf, 0x24, 0x9f, 0xf2, 0x92, 0x38, 0xe9, 0xfa,
-	0x3b, 0x00, 0x00, 0xff, 0xff, 0x11, 0x07, 0xc8, 0x61, 0x15, 0x02, 0x00, 0x00,
+	// 534 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x93, 0x4d, 0x6f, 0xd3, 0x40,
+	0x10, 0x86, 0x65, 0x12, 0xe7, 0x63, 0x9c, 0x2f, 0xb6, 0x55, 0xb5, 0x84, 0x03, 0x51, 0x10, 0x8a,
+	0x7b, 0xa0, 0x94, 0xc0, 0x85, 0x23, 0x02, 0x2a, 0x2e, 0x95, 0x90, 0xc3, 0xdd, 0x72, 0xed, 0xa1,
+	0xb6, 0x88, 0xbd, 0xee, 0xce, 0xba, 0x51, 0xfb, 0xf7, 0xf8, 0x09, 0xfc, 0x21, 0xb4, 0x1f, 0x89,
+	0x12, 0x51, 0x55, 0x3d, 0x65, 0xe7, 0x99, 0xdd, 0xd9, 0x77, 0xdf, 0xbc, 0x06, 0xc0, 0x34, 0x17,
+	0x67, 0xb5, 0x14, 0x4a, 0x30, 0xdf, 0xfc, 0xcc, 0x17, 0x10, 0x7c, 0x4b, 0x73, 0x11, 0xe1, 0x4d,
+	0x83, 0xa4, 0x18, 0x87, 0x6e, 0x89, 0x44, 0xc9, 0x35, 0x72, 0x6f, 0xe6, 0x85, 0xfd, 0x68, 0x5b,
+	0xce, 0x43, 0x18, 0xd8, 0x8d, 0x54, 0x8b, 0x8a, 0xf0, 0x91, 0x9d, 0xe7, 0xd0, 0xf9, 0x8e, 0x49,
+	0x86, 0x92, 0x4d, 0xa0, 0xf5, 0x1b, 0xef, 0x5c, 0x5f, 0x2f, 0xd9, 0x31, 0xf8, 0xb7, 0xc9, 0xba,
+	0x41, 0xfe, 0xcc, 0x30, 0x5b, 0xcc, 0xff, 0xb6, 0x81, 0x5d, 0x08, 0xb9, 0x49, 0x64, 0xb6, 0x2f,
+	0xe6, 0x18, 0xfc, 0x54, 0x34, 0x95, 0x32, 0x03, 0xfc, 0xc8, 0x16, 0x7a, 0xe8, 0x4d, 0x4d, 0x66,
+	0x80, 0x1f, 0xe9, 0x25, 0x7b, 0x03, 0x23, 0x55, 0x94, 0x28, 0x1a, 0x15, 0x97, 0x45, 0x2a, 0x05,
+	0xf1, 0xd6, 0xcc, 0x0b, 0x5b, 0xd1, 0xd0, 0xd1, 0x4b, 0x03, 0xf5, 0xc1, 0x46, 0xae, 0x79, 0xdb,
+	0xaa, 0x69, 0xe4, 0x9a, 0x2d, 0xa0, 0x9b, 0x1b, 0xa5, 0xc4, 0xfd, 0x59, 0x2b, 0x0c, 0x96, 0x43,
+	0x6b, 0xce, 0x99, 0xd5, 0x1f, 0x6d, 0xbb, 0xfb, 0x8f, 0xed, 0x1c, 0x3c, 0x96, 0xbd, 0x82, 0x80,
+	0x44, 0x23, 0x53, 0x8c, 0x6b, 0x21, 0x15, 0xef, 0x1a, 0x55, 0x60, 0xd1, 0x0f, 0x21, 0x15, 0x7b,
+	0x0d, 0x43, 0x89, 0x0d, 0x61, 0x9c, 0x64, 0x99, 0x44, 0x22, 0xde, 0x9b, 0x79, 0x61, 0x2f, 0x1a,
+	0x18, 0xf8, 0xd9, 0x32, 0xb6, 0x80, 0x31, 0x29, 0x89, 0x49, 0x19, 0xbb, 0xb9, 0xc4, 0xfb, 0x66,
+	0xd2, 0xc8, 0xe2, 0x4b, 0x47, 0xd9, 0x7b, 0x08, 0xac, 0xa6, 0x98, 0x50, 0x11, 0x07, 0xa3, 0x7a,
+	0x72, 0xa0, 0x7a, 0x85, 0x2a, 0x82, 0x7c, 0xbb, 0x24, 0x76, 0x02, 0x9d, 0x12, 0x55, 0x2e, 0x32,
+	0x1e, 0x18, 0xe9, 0xae, 0x62, 0x2f, 0xa1, 0x7f, 0x25, 0xb2, 0xbb, 0x98, 0x8a, 0x7b, 0xe4, 0x03,
+	0x73, 0x5b, 0x4f, 0x83, 0x55, 0x71, 0x8f, 0xec, 0x14, 0x26, 0xe9, 0x5a, 0x10, 0xc6, 0xa9, 0xa8,
+	0x2a, 0x4c, 0x55, 0x21, 0x2a, 0x3e, 0x34, 0xc2, 0xc7, 0x86, 0x7f, 0xd9, 0x61, 0x63, 0x6b, 0x46,
+	0x7c, 0xe4, 0x6c, 0xcd, 0x48, 0xff, 0x6f, 0xb9, 0x52, 0xf5, 0x92, 0x8f, 0xcd, 0x09, 0x5b, 0xb0,
+	0x53, 0xe8, 0x29, 0x99, 0x14, 0x6b, 0xed, 0xf6, 0xe4, 0x21, 0xb7, 0x77, 0x6d, 0xed, 0x19, 0x61,
+	0x95, 0xc5, 0xbf, 0x64, 0x72, 0x5d, 0x62, 0xa5, 0xf8, 0x73, 0xeb, 0x99, 0x86, 0x17, 0x8e, 0x69,
+	0xe7, 0x65, 0xb2, 0x89, 0xa5, 0x0d, 0x0b, 0x67, 0xe6, 0x7e, 0x90, 0xc9, 0xc6, 0xc5, 0x67, 0xfe,
+	0x16, 0x8e, 0x0e, 0x42, 0xe5, 0x82, 0x7b, 0x02, 0x1d, 0xd1, 0xa8, 0xba, 0xd1, 0xb1, 0x6a, 0x69,
+	0x3f, 0x6c, 0x35, 0xff, 0x08, 0xfd, 0x9d, 0x81, 0xfb, 0xc9, 0xf0, 0x1e, 0x4b, 0xc6, 0xf2, 0x8f,
+	0x07, 0x63, 0x3d, 0xfe, 0x27, 0x92, 0x5a, 0xa1, 0xbc, 0x2d, 0x52, 0x64, 0xef, 0xa0, 0xad, 0x11,
+	0x63, 0xee, 0xcc, 0x5e, 0xa6, 0xa7, 0x47, 0x07, 0xcc, 0x49, 0xfa, 0x0a, 0xc1, 0x9e, 0x52, 0xf6,
+	0xc2, 0xed, 0xf9, 0xff, 0x93, 0x98, 0x4e, 0x1f, 0x6a, 0xb9, 0x29, 0x9f, 0x00, 0x74, 0xbd, 0x32,
+	0x89, 0x79, 0xf2, 0xe5, 0xa1, 0x77, 0xee, 0x5d, 0x75, 0x0c, 0xff, 0xf0, 0x6f, 0x00, 0x87, 0xd1,
+	0xaa, 0x49, 0x21, 0x04, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -313,6 +488,7 @@ const _ = grpc.SupportPackageIsVersion4
 type EchoTestServiceClient interface {
 	Echo(ctx context.Context, in *EchoRequest, opts ...grpc.CallOption) (*EchoResponse, error)
 	ForwardEcho(ctx context.Context, in *ForwardEchoRequest, opts ...grpc.CallOption) (*ForwardEchoResponse, error)
+	EchoStream(ctx context.Context, opts ...grpc.CallOption) (EchoTestService_EchoStreamClient, error)
 }
 
 type echoTestServiceClient struct {
@@ -341,10 +517,42 @@ func (c *echoTestServiceClient) ForwardEcho(ctx context.Context, in *ForwardEcho
 	return out, nil
 }
 
+func (c *echoTestServiceClient) EchoStream(ctx context.Context, opts ...grpc.CallOption) (EchoTestService_EchoStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_EchoTestService_serviceDesc.Streams[0], "/proto.EchoTestService/EchoStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &echoTestServiceEchoStreamClient{stream}
+	return x, nil
+}
+
+type EchoTestService_EchoStreamClient interface {
+	Send(*EchoRequest) error
+	Recv() (*EchoResponse, error)
+	grpc.ClientStream
+}
+
+type echoTestServiceEchoStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *echoTestServiceEchoStreamClient) Send(m *EchoRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *echoTestServiceEchoStreamClient) Recv() (*EchoResponse, error) {
+	m := new(EchoResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // EchoTestServiceServer is the server API for EchoTestService service.
 type EchoTestServiceServer interface {
 	Echo(context.Context, *EchoRequest) (*EchoResponse, error)
 	ForwardEcho(context.Context, *ForwardEchoRequest) (*ForwardEchoResponse, error)
+	EchoStream(EchoTestService_EchoStreamServer) error
 }
 
 func RegisterEchoTestServiceServer(s *grpc.Server, srv EchoTestServiceServer) {
@@ -387,6 +595,32 @@ func _EchoTestService_ForwardEcho_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _EchoTestService_EchoStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(EchoTestServiceServer).EchoStream(&echoTestServiceEchoStreamServer{stream})
+}
+
+type EchoTestService_EchoStreamServer interface {
+	Send(*EchoResponse) error
+	Recv() (*EchoRequest, error)
+	grpc.ServerStream
+}
+
+type echoTestServiceEchoStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *echoTestServiceEchoStreamServer) Send(m *EchoResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *echoTestServiceEchoStreamServer) Recv() (*EchoRequest, error) {
+	m := new(EchoRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 var _EchoTestService_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "proto.EchoTestService",
 	HandlerType: (*EchoTestServiceServer)(nil),
@@ -400,6 +634,13 @@ var _EchoTestService_serviceDesc = grpc.ServiceDesc{
 			Handler:    _EchoTestService_ForwardEcho_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "EchoStream",
+			Handler:       _EchoTestService_EchoStream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
 	Metadata: "echo.proto",
 }
@@ -0,0 +1,132 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endpoint
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"istio.io/istio/pkg/test/echo/common"
+	"istio.io/pkg/log"
+)
+
+// documents are shared by all the HTTP endpoints of the server.
+var documents = &documentStore{
+	documents: map[string]*document{},
+}
+
+type documentStore struct {
+	mu        sync.Mutex
+	documents map[string]*document
+}
+
+type document struct {
+	common.Document
+	requests []time.Time
+}
+
+// isDocumentRequest returns true if the request is for a document rather than an echo, i.e. its path is
+// common.DocumentPathPrefix followed by the name of the document and optionally
+// common.DocumentRequestsSuffix.
+func isDocumentRequest(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, common.DocumentPathPrefix)
+}
+
+// serveDocument serves the document requests:
+//   - PUT <name> stores the JSON encoded common.Document in the body, replacing the previous one. The
+//     request log is kept, so that the requests before and after the update can be told apart.
+//   - GET <name> returns the document with its code and headers, after its delay if any, and logs the
+//     request.
+//   - GET <name>/requests returns the JSON encoded request log, i.e. the times of the requests for the
+//     document.
+func (s *documentStore) serveDocument(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, common.DocumentPathPrefix)
+	listRequests := strings.HasSuffix(name, common.DocumentRequestsSuffix)
+	name = strings.TrimSuffix(name, common.DocumentRequestsSuffix)
+	if name == "" || strings.Contains(name, "/") {
+		http.Error(w, "invalid document name", http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodPut && !listRequests:
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var doc common.Document
+		if err := json.Unmarshal(body, &doc); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.mu.Lock()
+		if d, ok := s.documents[name]; ok {
+			d.Document = doc
+		} else {
+			s.documents[name] = &document{Document: doc}
+		}
+		s.mu.Unlock()
+		log.Infof("Stored document %q", name)
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodGet:
+		s.mu.Lock()
+		d, ok := s.documents[name]
+		var requests []time.Time
+		if ok {
+			if listRequests {
+				requests = append(requests, d.requests...)
+			} else {
+				d.requests = append(d.requests, time.Now())
+			}
+		}
+		s.mu.Unlock()
+		if !ok {
+			http.Error(w, "document not found", http.StatusNotFound)
+			return
+		}
+		if listRequests {
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(requests); err != nil {
+				log.Warna(err)
+			}
+			return
+		}
+		if d.Delay > 0 {
+			select {
+			case <-time.After(d.Delay):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		for k, v := range d.Headers {
+			w.Header().Set(k, v)
+		}
+		code := d.Code
+		if code == 0 {
+			code = http.StatusOK
+		}
+		w.WriteHeader(code)
+		if _, err := w.Write([]byte(d.Body)); err != nil {
+			log.Warna(err)
+		}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endpoint
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"istio.io/istio/pkg/test/echo/common"
+)
+
+func TestServeDocument(t *testing.T) {
+	s := &documentStore{documents: map[string]*document{}}
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.serveDocument(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+
+	if w := do(http.MethodGet, "/documents/jwks", ""); w.Code != http.StatusNotFound {
+		t.Errorf("get missing document: got code %d, want %d", w.Code, http.StatusNotFound)
+	}
+
+	doc, _ := json.Marshal(common.Document{
+		Headers: map[string]string{"Cache-Control": "max-age=5"},
+		Body:    `{"keys":[]}`,
+	})
+	if w := do(http.MethodPut, "/documents/jwks", string(doc)); w.Code != http.StatusOK {
+		t.Fatalf("put document: got code %d, %s", w.Code, w.Body.String())
+	}
+	for i := 0; i < 2; i++ {
+		w := do(http.MethodGet, "/documents/jwks", "")
+		if w.Code != http.StatusOK || w.Body.String() != `{"keys":[]}` || w.Header().Get("Cache-Control") != "max-age=5" {
+			t.Errorf("get document: got code %d, headers %v, body %q", w.Code, w.Header(), w.Body.String())
+		}
+	}
+
+	doc, _ = json.Marshal(common.Document{Code: http.StatusInternalServerError})
+	do(http.MethodPut, "/documents/jwks", string(doc))
+	if w := do(http.MethodGet, "/documents/jwks", ""); w.Code != http.StatusInternalServerError {
+		t.Errorf("get failing document: got code %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+
+	w := do(http.MethodGet, "/documents/jwks/requests", "")
+	var requests []time.Time
+	if err := json.Unmarshal(w.Body.Bytes(), &requests); err != nil {
+		t.Fatalf("failed to parse request log %q: %v", w.Body.String(), err)
+	}
+	// The log is kept when the document is replaced.
+	if len(requests) != 3 {
+		t.Errorf("got %d requests in the log, want 3", len(requests))
+	}
+
+	if w := do(http.MethodPut, "/documents/jwks", "not json"); w.Code != http.StatusBadRequest {
+		t.Errorf("put invalid document: got code %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if w := do(http.MethodGet, "/documents/a/b", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("get invalid name: got code %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
+
+func TestServeDocumentDelay(t *testing.T) {
+	const delay = 100 * time.Millisecond
+	s := &documentStore{documents: map[string]*document{}}
+	doc, _ := json.Marshal(common.Document{Body: `{"keys":[]}`, Delay: delay})
+	w := httptest.NewRecorder()
+	s.serveDocument(w, httptest.NewRequest(http.MethodPut, "/documents/jwks", strings.NewReader(string(doc))))
+	if w.Code != http.StatusOK {
+		t.Fatalf("put document: got code %d, %s", w.Code, w.Body.String())
+	}
+
+	start := time.Now()
+	w = httptest.NewRecorder()
+	s.serveDocument(w, httptest.NewRequest(http.MethodGet, "/documents/jwks", nil))
+	if elapsed := time.Since(start); elapsed < delay {
+		t.Errorf("got the document after %v, want after %v", elapsed, delay)
+	}
+	if w.Code != http.StatusOK || w.Body.String() != `{"keys":[]}` {
+		t.Errorf("get delayed document: got code %d, body %q", w.Code, w.Body.String())
+	}
+}
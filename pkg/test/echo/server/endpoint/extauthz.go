@@ -0,0 +1,41 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endpoint
+
+import (
+	"net/http"
+	"strings"
+
+	"istio.io/istio/pkg/test/echo/common"
+)
+
+// isExtAuthzRequest returns true if the request is an ext_authz check request, i.e. its path is
+// common.ExtAuthzPathPrefix, optionally followed by the path of the request checked.
+func isExtAuthzRequest(r *http.Request) bool {
+	return r.URL.Path == common.ExtAuthzPathPrefix || strings.HasPrefix(r.URL.Path, common.ExtAuthzPathPrefix+"/")
+}
+
+// serveExtAuthz answers a check request as an HTTP ext_authz server: denied with 403 if its
+// common.ExtAuthzHeader is common.ExtAuthzDeny, allowed with 200 otherwise.
+func serveExtAuthz(w http.ResponseWriter, r *http.Request) {
+	if strings.EqualFold(r.Header.Get(common.ExtAuthzHeader), common.ExtAuthzDeny) {
+		w.Header().Set(common.ExtAuthzResultHeader, common.ExtAuthzDenied)
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(common.ExtAuthzDenied))
+		return
+	}
+	w.Header().Set(common.ExtAuthzResultHeader, common.ExtAuthzAllowed)
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,60 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endpoint
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"istio.io/istio/pkg/test/echo/common"
+)
+
+func TestServeExtAuthz(t *testing.T) {
+	cases := []struct {
+		name       string
+		path       string
+		header     string
+		isExtAuthz bool
+		wantCode   int
+		wantResult string
+	}{
+		{name: "allowed", path: "/ext-authz/api", isExtAuthz: true, wantCode: http.StatusOK, wantResult: common.ExtAuthzAllowed},
+		{name: "allowed root", path: "/ext-authz", isExtAuthz: true, wantCode: http.StatusOK, wantResult: common.ExtAuthzAllowed},
+		{name: "denied", path: "/ext-authz/", header: "Deny", isExtAuthz: true, wantCode: http.StatusForbidden,
+			wantResult: common.ExtAuthzDenied},
+		{name: "echo", path: "/ext-authzx"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.header != "" {
+				r.Header.Set(common.ExtAuthzHeader, tc.header)
+			}
+			if got := isExtAuthzRequest(r); got != tc.isExtAuthz {
+				t.Fatalf("isExtAuthzRequest(%s) = %v, want %v", tc.path, got, tc.isExtAuthz)
+			}
+			if !tc.isExtAuthz {
+				return
+			}
+			w := httptest.NewRecorder()
+			serveExtAuthz(w, r)
+			if w.Code != tc.wantCode || w.Header().Get(common.ExtAuthzResultHeader) != tc.wantResult {
+				t.Errorf("got code %d, result %q, want %d, %q",
+					w.Code, w.Header().Get(common.ExtAuthzResultHeader), tc.wantCode, tc.wantResult)
+			}
+		})
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"net"
 	"os"
 	"strconv"
@@ -124,6 +125,27 @@ type grpcHandler struct {
 
 func (h *grpcHandler) Echo(ctx context.Context, req *proto.EchoRequest) (*proto.EchoResponse, error) {
 	defer common.Metrics.GrpcRequests.With(common.PortLabel.Value(strconv.Itoa(h.Port.Port))).Increment()
+	return h.echo(ctx, req), nil
+}
+
+// EchoStream echoes each message received on the stream, until the client closes it.
+func (h *grpcHandler) EchoStream(stream proto.EchoTestService_EchoStreamServer) error {
+	defer common.Metrics.GrpcRequests.With(common.PortLabel.Value(strconv.Itoa(h.Port.Port))).Increment()
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(h.echo(stream.Context(), req)); err != nil {
+			return err
+		}
+	}
+}
+
+func (h *grpcHandler) echo(ctx context.Context, req *proto.EchoRequest) *proto.EchoResponse {
 	host := "-"
 	body := bytes.Buffer{}
 	md, ok := metadata.FromIncomingContext(ctx)
@@ -151,18 +173,20 @@ func (h *grpcHandler) Echo(ctx context.Context, req *proto.EchoRequest) (*proto.
 	writeField(&body, response.ServiceVersionField, h.Version)
 	writeField(&body, response.ServicePortField, strconv.Itoa(portNumber))
 	writeField(&body, response.ClusterField, h.Cluster)
+	writeField(&body, response.LocalityField, h.Locality)
 	writeField(&body, "Echo", req.GetMessage())
 
 	if hostname, err := os.Hostname(); err == nil {
 		writeField(&body, response.HostnameField, hostname)
 	}
 
-	return &proto.EchoResponse{Message: body.String()}, nil
+	return &proto.EchoResponse{Message: body.String()}
 }
 
 func (h *grpcHandler) ForwardEcho(ctx context.Context, req *proto.ForwardEchoRequest) (*proto.ForwardEchoResponse, error) {
 	instance, err := forwarder.New(forwarder.Config{
 		Request: req,
+		UDS:     req.GetUds(),
 		Dialer:  h.Dialer,
 		TLSCert: h.TLSCert,
 	})
@@ -19,6 +19,8 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"math/rand"
 	"net"
 	"net/http"
@@ -28,6 +30,8 @@ import (
 	"time"
 
 	"github.com/gorilla/websocket"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 
 	"istio.io/istio/pkg/test/echo/common"
 	"istio.io/istio/pkg/test/echo/common/response"
@@ -63,10 +67,16 @@ func newHTTP(config Config) Instance {
 }
 
 func (s *httpInstance) Start(onReady OnReadyFunc) error {
+	// Besides HTTP/1.1, the server accepts HTTP/2: with prior knowledge or upgraded in plain text (h2c), or
+	// negotiated with ALPN over TLS.
+	h2Server := &http2.Server{}
 	s.server = &http.Server{
-		Handler: &httpHandler{
+		Handler: h2c.NewHandler(&httpHandler{
 			Config: s.Config,
-		},
+		}, h2Server),
+	}
+	if err := http2.ConfigureServer(s.server, h2Server); err != nil {
+		return err
 	}
 
 	var listener net.Listener
@@ -80,7 +90,7 @@ func (s *httpInstance) Start(onReady OnReadyFunc) error {
 		if cerr != nil {
 			return fmt.Errorf("could not load TLS keys: %v", err)
 		}
-		config := &tls.Config{Certificates: []tls.Certificate{cert}}
+		config := &tls.Config{Certificates: []tls.Certificate{cert}, NextProtos: []string{"h2", "http/1.1"}}
 		// Listen on the given port and update the port if it changed from what was passed in.
 		listener, port, err = listenOnPortTLS(s.Port.Port, config)
 		// Store the actual listening port back to the argument.
@@ -194,9 +204,16 @@ func (h *httpHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if common.IsWebSocketRequest(r) {
+	if isDocumentRequest(r) {
+		documents.serveDocument(w, r)
+	} else if isExtAuthzRequest(r) {
+		h.recordRequest(r)
+		serveExtAuthz(w, r)
+	} else if common.IsWebSocketRequest(r) {
+		h.recordRequest(r)
 		h.webSocketEcho(w, r)
 	} else {
+		h.recordRequest(r)
 		h.echo(w, r)
 	}
 }
@@ -214,11 +231,23 @@ func (h *httpHandler) echo(w http.ResponseWriter, r *http.Request) {
 		writeError(&body, "ParseForm() error: "+err.Error())
 	}
 
+	// The trailers declared by the request, which are reflected, are declared in the response before its
+	// headers are written, for it to be sent chunked with HTTP/1.1.
+	for name := range r.Trailer {
+		w.Header().Add("Trailer", name)
+	}
+
 	// If the request has form ?headers=name:value[,name:value]* return those headers in response
 	if err := setHeaderResponseFromHeaders(r, w); err != nil {
 		writeError(&body, "response headers error: "+err.Error())
 	}
 
+	// If the request has form ?resp-header=name:value[&resp-header=name:value]* add those headers to the
+	// response. Unlike ?headers, the value may contain ',' and ':', and a name may be repeated.
+	if err := addResponseHeadersFromParams(r, w); err != nil {
+		writeError(&body, "response headers error: "+err.Error())
+	}
+
 	// If the request has form ?codes=code[:chance][,code[:chance]]* return those codes, rather than 200
 	// For example, ?codes=500:1,200:1 returns 500 1/2 times and 200 1/2 times
 	// For example, ?codes=500:90,200:10 returns 500 90% of times and 200 10% of times
@@ -228,10 +257,29 @@ func (h *httpHandler) echo(w http.ResponseWriter, r *http.Request) {
 
 	h.addResponsePayload(r, &body)
 
+	// Report the size of the body, if any and not consumed by ParseForm.
+	if n, err := io.Copy(ioutil.Discard, r.Body); err != nil {
+		writeError(&body, "read body error: "+err.Error())
+	} else if n > 0 {
+		writeField(&body, response.RequestBodySizeField, strconv.FormatInt(n, 10))
+	}
+	// The trailers of the request, received once the body is read, are reported and reflected as the
+	// trailers of the response.
+	for name, values := range r.Trailer {
+		for _, value := range values {
+			writeField(&body, response.RequestTrailerField, name+":"+value)
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/text")
 	if _, err := w.Write(body.Bytes()); err != nil {
 		log.Warna(err)
 	}
+	for name, values := range r.Trailer {
+		for _, value := range values {
+			w.Header().Add(http.TrailerPrefix+name, value)
+		}
+	}
 	log.Infof("Response Headers: %+v", w.Header())
 }
 
@@ -279,6 +327,7 @@ func (h *httpHandler) addResponsePayload(r *http.Request, body *bytes.Buffer) {
 	writeField(body, response.HostField, r.Host)
 	writeField(body, response.URLField, r.URL.String())
 	writeField(body, response.ClusterField, h.Cluster)
+	writeField(body, response.LocalityField, h.Locality)
 
 	writeField(body, "Method", r.Method)
 	writeField(body, "Proto", r.Proto)
@@ -314,6 +363,21 @@ func setHeaderResponseFromHeaders(request *http.Request, response http.ResponseW
 	return nil
 }
 
+// responseHeaderParam is the query parameter setting a header of the response, as name:value.
+const responseHeaderParam = "resp-header"
+
+func addResponseHeadersFromParams(request *http.Request, response http.ResponseWriter) error {
+	for _, responseHeader := range request.Form[responseHeaderParam] {
+		i := strings.Index(responseHeader, ":")
+		// require name:value format
+		if i <= 0 {
+			return fmt.Errorf("invalid %s %q (want name:value)", responseHeaderParam, responseHeader)
+		}
+		response.Header().Add(responseHeader[:i], responseHeader[i+1:])
+	}
+	return nil
+}
+
 func setResponseFromCodes(request *http.Request, response http.ResponseWriter) error {
 	responseCodes := request.FormValue("codes")
 
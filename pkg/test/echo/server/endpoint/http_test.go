@@ -0,0 +1,117 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endpoint
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/test/echo/common"
+)
+
+func TestAddResponseHeadersFromParams(t *testing.T) {
+	cases := []struct {
+		name    string
+		query   string
+		want    http.Header
+		wantErr bool
+	}{
+		{
+			name:  "none",
+			query: "",
+			want:  http.Header{},
+		},
+		{
+			name:  "single",
+			query: "resp-header=X-Foo:bar",
+			want:  http.Header{"X-Foo": {"bar"}},
+		},
+		{
+			name:  "value with separators",
+			query: "resp-header=Set-Cookie:a=b,c:d",
+			want:  http.Header{"Set-Cookie": {"a=b,c:d"}},
+		},
+		{
+			name:  "repeated",
+			query: "resp-header=X-Foo:bar&resp-header=X-Foo:baz",
+			want:  http.Header{"X-Foo": {"bar", "baz"}},
+		},
+		{
+			name:    "no value",
+			query:   "resp-header=X-Foo",
+			wantErr: true,
+		},
+		{
+			name:    "no name",
+			query:   "resp-header=:bar",
+			wantErr: true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/?"+c.query, nil)
+			if err := r.ParseForm(); err != nil {
+				t.Fatal(err)
+			}
+			w := httptest.NewRecorder()
+			err := addResponseHeadersFromParams(r, w)
+			if (err != nil) != c.wantErr {
+				t.Fatalf("got error %v, want error %v", err, c.wantErr)
+			}
+			if !c.wantErr && !reflect.DeepEqual(w.Header(), c.want) {
+				t.Errorf("got headers %v, want %v", w.Header(), c.want)
+			}
+		})
+	}
+}
+
+func TestEchoReflectsTrailers(t *testing.T) {
+	h := &httpHandler{Config: Config{
+		IsServerReady: func() bool { return true },
+		Port:          &common.Port{Protocol: protocol.HTTP},
+	}}
+	server := httptest.NewServer(h)
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodGet, server.URL, ioutil.NopCloser(strings.NewReader("")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Sent chunked, with the trailers after the empty body.
+	req.ContentLength = -1
+	req.TransferEncoding = []string{"chunked"}
+	req.Trailer = http.Header{"X-Checksum": {"abc"}}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(body), "RequestTrailer=X-Checksum:abc\n") {
+		t.Errorf("got body\n%s\nwant the request trailer reported", body)
+	}
+	if want := (http.Header{"X-Checksum": {"abc"}}); !reflect.DeepEqual(resp.Trailer, want) {
+		t.Errorf("got response trailers %v, want %v", resp.Trailer, want)
+	}
+}
@@ -33,6 +33,7 @@ type Config struct {
 	IsServerReady IsServerReadyFunc
 	Version       string
 	Cluster       string
+	Locality      string
 	TLSCert       string
 	TLSKey        string
 	UDSServer     string
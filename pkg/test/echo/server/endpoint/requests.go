@@ -0,0 +1,123 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endpoint
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"istio.io/istio/pkg/test/echo/common"
+	"istio.io/pkg/log"
+)
+
+// receivedRequests is the request log shared by all the HTTP endpoints of the server.
+var receivedRequests = newRequestLog(common.RequestLogSize)
+
+// requestLog is a ring buffer of the last received requests.
+type requestLog struct {
+	mu       sync.Mutex
+	requests []common.ReceivedRequest
+	// next is the index of the next request to record, i.e. of the oldest one once the buffer is full.
+	next int
+	full bool
+}
+
+func newRequestLog(size int) *requestLog {
+	return &requestLog{
+		requests: make([]common.ReceivedRequest, size),
+	}
+}
+
+func (l *requestLog) add(r common.ReceivedRequest) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.requests[l.next] = r
+	l.next++
+	if l.next == len(l.requests) {
+		l.next = 0
+		l.full = true
+	}
+}
+
+// list returns the requests received after since, oldest first.
+func (l *requestLog) list(since time.Time) []common.ReceivedRequest {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	ordered := l.requests[:l.next]
+	if l.full {
+		ordered = append(append([]common.ReceivedRequest{}, l.requests[l.next:]...), ordered...)
+	}
+	out := []common.ReceivedRequest{}
+	for _, r := range ordered {
+		if r.Time.After(since) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func (l *requestLog) clear() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.requests = make([]common.ReceivedRequest, len(l.requests))
+	l.next = 0
+	l.full = false
+}
+
+// recordRequest records a request received by the endpoint in the request log.
+func (h *httpHandler) recordRequest(r *http.Request) {
+	port := 0
+	if h.Port != nil {
+		port = h.Port.Port
+	}
+	hostname, _ := os.Hostname()
+	receivedRequests.add(common.ReceivedRequest{
+		Time:     time.Now(),
+		Hostname: hostname,
+		Port:     port,
+		Method:   r.Method,
+		Path:     r.URL.RequestURI(),
+		Host:     r.Host,
+		Peer:     r.RemoteAddr,
+		Headers:  r.Header.Clone(),
+	})
+}
+
+// ServeReceivedRequests serves the request log of the HTTP endpoints at common.ReceivedRequestsPath.
+func ServeReceivedRequests(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		var since time.Time
+		if s := r.URL.Query().Get(common.ReceivedRequestsSinceParam); s != "" {
+			var err error
+			if since, err = time.Parse(time.RFC3339Nano, s); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(receivedRequests.list(since)); err != nil {
+			log.Warna(err)
+		}
+	case http.MethodDelete:
+		receivedRequests.clear()
+		w.WriteHeader(http.StatusOK)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endpoint
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/test/echo/common"
+)
+
+func paths(requests []common.ReceivedRequest) []string {
+	out := []string{}
+	for _, r := range requests {
+		out = append(out, r.Path)
+	}
+	return out
+}
+
+func TestRequestLog(t *testing.T) {
+	start := time.Now()
+	at := func(i int) time.Time { return start.Add(time.Duration(i) * time.Second) }
+
+	l := newRequestLog(3)
+	for i, path := range []string{"/a", "/b"} {
+		l.add(common.ReceivedRequest{Time: at(i + 1), Path: path})
+	}
+	if got, want := paths(l.list(time.Time{})), []string{"/a", "/b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// The oldest requests are dropped once the log is full.
+	for i, path := range []string{"/c", "/d", "/e"} {
+		l.add(common.ReceivedRequest{Time: at(i + 3), Path: path})
+	}
+	if got, want := paths(l.list(time.Time{})), []string{"/c", "/d", "/e"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := paths(l.list(at(3))), []string{"/d", "/e"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("since %v: got %v, want %v", at(3), got, want)
+	}
+
+	l.clear()
+	if got := l.list(time.Time{}); len(got) != 0 {
+		t.Errorf("got %v after clear, want none", paths(got))
+	}
+	l.add(common.ReceivedRequest{Time: at(6), Path: "/f"})
+	if got, want := paths(l.list(time.Time{})), []string{"/f"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestServeReceivedRequests(t *testing.T) {
+	receivedRequests.clear()
+	defer receivedRequests.clear()
+
+	h := &httpHandler{Config: Config{
+		IsServerReady: func() bool { return true },
+		Port:          &common.Port{Port: 8090, Protocol: protocol.HTTP},
+	}}
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/first", nil))
+	since := time.Now()
+	r := httptest.NewRequest(http.MethodPost, "/second?x=1", nil)
+	r.Header.Set("X-Custom", "value")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	list := func(query string) []common.ReceivedRequest {
+		t.Helper()
+		w := httptest.NewRecorder()
+		ServeReceivedRequests(w, httptest.NewRequest(http.MethodGet, common.ReceivedRequestsPath+query, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("got code %d, body %s", w.Code, w.Body.String())
+		}
+		var out []common.ReceivedRequest
+		if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	if got, want := paths(list("")), []string{"/first", "/second?x=1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	got := list("?" + common.ReceivedRequestsSinceParam + "=" + since.Format(time.RFC3339Nano))
+	if len(got) != 1 || got[0].Method != http.MethodPost || got[0].Port != 8090 || got[0].Headers.Get("X-Custom") != "value" {
+		t.Errorf("got %+v, want the second request only", got)
+	}
+
+	w := httptest.NewRecorder()
+	ServeReceivedRequests(w, httptest.NewRequest(http.MethodDelete, common.ReceivedRequestsPath, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("delete: got code %d", w.Code)
+	}
+	if got := list(""); len(got) != 0 {
+		t.Errorf("got %v after delete, want none", paths(got))
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forwarder
+
+import (
+	"net"
+	"syscall"
+
+	"istio.io/istio/pkg/test/echo/proto"
+)
+
+// hasDialOptions returns true if the request sets any of the low-level dial options.
+func hasDialOptions(r *proto.ForwardEchoRequest) bool {
+	return r.SourcePort > 0 || r.ReuseAddress
+}
+
+// applyDialOptions sets the source port and socket options of the request on the dialer.
+func applyDialOptions(d *net.Dialer, r *proto.ForwardEchoRequest) {
+	if r.SourcePort > 0 {
+		d.LocalAddr = &net.TCPAddr{Port: int(r.SourcePort)}
+	}
+	if r.ReuseAddress {
+		d.Control = func(_, _ string, c syscall.RawConn) error {
+			var err error
+			if cerr := c.Control(func(fd uintptr) {
+				err = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_REUSEADDR, 1)
+			}); cerr != nil {
+				return cerr
+			}
+			return err
+		}
+	}
+}
@@ -18,16 +18,21 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"strconv"
 	"strings"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 
+	"istio.io/istio/pkg/test/echo/common/response"
 	"istio.io/istio/pkg/test/echo/proto"
 )
 
-var _ protocol = &grpcProtocol{}
+var _ streamProtocol = &grpcProtocol{}
+
+const grpcStatusTrailer = "grpc-status"
 
 type grpcProtocol struct {
 	conn   *grpc.ClientConn
@@ -38,17 +43,7 @@ func (c *grpcProtocol) makeRequest(ctx context.Context, req *request) (string, e
 	// Set the per-request timeout.
 	ctx, cancel := context.WithTimeout(ctx, req.Timeout)
 	defer cancel()
-
-	// Add headers to the request context.
-	outMD := make(metadata.MD)
-	for k, v := range req.Header {
-		// Exclude the Host header from the GRPC context.
-		if !strings.EqualFold(hostHeader, k) {
-			outMD.Set(k, v...)
-		}
-	}
-	outMD.Set("X-Request-Id", strconv.Itoa(req.RequestID))
-	ctx = metadata.NewOutgoingContext(ctx, outMD)
+	ctx = outgoingContext(ctx, req)
 
 	var outBuffer bytes.Buffer
 	grpcReq := &proto.EchoRequest{
@@ -56,20 +51,85 @@ func (c *grpcProtocol) makeRequest(ctx context.Context, req *request) (string, e
 	}
 	outBuffer.WriteString(fmt.Sprintf("[%d] grpcecho.Echo(%v)\n", req.RequestID, req))
 
-	resp, err := c.client.Echo(ctx, grpcReq)
+	var trailer metadata.MD
+	resp, err := c.client.Echo(ctx, grpcReq, grpc.Trailer(&trailer))
+	var trailers bytes.Buffer
+	for key, values := range trailer {
+		for _, value := range values {
+			trailers.WriteString(fmt.Sprintf("[%d] %s=%s:%s\n", req.RequestID, response.ResponseTrailerField, key, value))
+		}
+	}
+	// grpc-go takes the grpc-status trailer out of the trailers, to return it as the status of the call.
+	trailers.WriteString(fmt.Sprintf("[%d] %s=%s:%d\n", req.RequestID, response.ResponseTrailerField,
+		grpcStatusTrailer, status.Code(err)))
 	if err != nil {
-		return "", err
+		// The trailers of a failed call are reported with its error, see client.GRPCTrailers.
+		return "", fmt.Errorf("%v\n%s", err, trailers.String())
 	}
+	outBuffer.Write(trailers.Bytes())
 
 	// when the underlying HTTP2 request returns status 404, GRPC
 	// request does not return an error in grpc-go.
 	// instead it just returns an empty response
+	writeBody(&outBuffer, req.RequestID, resp)
+	return outBuffer.String(), nil
+}
+
+func (c *grpcProtocol) makeStreamRequest(ctx context.Context, req *request) ([]string, error) {
+	// Set the per-request timeout.
+	ctx, cancel := context.WithTimeout(ctx, req.Timeout)
+	defer cancel()
+	ctx = outgoingContext(ctx, req)
+
+	stream, err := c.client.EchoStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	outputs := make([]string, 0, req.StreamMessages)
+	for i := 0; i < req.StreamMessages; i++ {
+		grpcReq := &proto.EchoRequest{
+			Message: req.Message,
+		}
+		var outBuffer bytes.Buffer
+		outBuffer.WriteString(fmt.Sprintf("[%d] grpcecho.EchoStream(%v) message %d\n", req.RequestID, req, i))
+		if err := stream.Send(grpcReq); err != nil {
+			return nil, fmt.Errorf("stream failed after %d of %d messages echoed: %v", i, req.StreamMessages, err)
+		}
+		resp, err := stream.Recv()
+		if err != nil {
+			return nil, fmt.Errorf("stream failed after %d of %d messages echoed: %v", i, req.StreamMessages, err)
+		}
+		writeBody(&outBuffer, req.RequestID, resp)
+		outputs = append(outputs, outBuffer.String())
+	}
+	if err := stream.CloseSend(); err != nil {
+		return nil, err
+	}
+	if _, err := stream.Recv(); err != io.EOF {
+		return nil, fmt.Errorf("stream not closed after %d messages echoed: %v", req.StreamMessages, err)
+	}
+	return outputs, nil
+}
+
+// outgoingContext adds the headers of the request to the context.
+func outgoingContext(ctx context.Context, req *request) context.Context {
+	outMD := make(metadata.MD)
+	for k, v := range req.Header {
+		// Exclude the Host header from the GRPC context.
+		if !strings.EqualFold(hostHeader, k) {
+			outMD.Set(k, v...)
+		}
+	}
+	outMD.Set("X-Request-Id", strconv.Itoa(req.RequestID))
+	return metadata.NewOutgoingContext(ctx, outMD)
+}
+
+func writeBody(outBuffer *bytes.Buffer, requestID int, resp *proto.EchoResponse) {
 	for _, line := range strings.Split(resp.GetMessage(), "\n") {
 		if line != "" {
-			outBuffer.WriteString(fmt.Sprintf("[%d body] %s\n", req.RequestID, line))
+			outBuffer.WriteString(fmt.Sprintf("[%d body] %s\n", requestID, line))
 		}
 	}
-	return outBuffer.String(), nil
 }
 
 func (c *grpcProtocol) Close() error {
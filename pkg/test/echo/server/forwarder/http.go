@@ -18,10 +18,15 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"net/textproto"
+	"net/url"
 	"strings"
 
+	"golang.org/x/net/http2"
+
 	"istio.io/istio/pkg/test/echo/common"
 	"istio.io/istio/pkg/test/echo/common/response"
 )
@@ -39,16 +44,46 @@ func (c *httpProtocol) setHost(r *http.Request, host string) {
 	if r.URL.Scheme == "https" {
 		// Set SNI value to be same as the request Host
 		// For use with SNI routing tests
-		httpTransport := c.client.Transport.(*http.Transport)
-		httpTransport.TLSClientConfig.ServerName = host
+		switch t := c.client.Transport.(type) {
+		case *http.Transport:
+			t.TLSClientConfig.ServerName = host
+		case *http2.Transport:
+			t.TLSClientConfig.ServerName = host
+		}
 	}
 }
 
 func (c *httpProtocol) makeRequest(ctx context.Context, req *request) (string, error) {
-	httpReq, err := http.NewRequest("GET", req.URL, nil)
+	method := req.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	var body io.Reader
+	if req.BodySize > 0 {
+		body = bytes.NewReader(bytes.Repeat([]byte("a"), req.BodySize))
+	}
+	httpReq, err := http.NewRequest(method, req.URL, body)
 	if err != nil {
 		return "", err
 	}
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/octet-stream")
+	}
+	// Sends "Connection: close" and does not reuse the connection for another request.
+	httpReq.Close = req.CloseConnection
+	if req.SendFragment {
+		setRequestTarget(httpReq.URL)
+	}
+	if len(req.Trailer) > 0 {
+		// The trailers are sent after the body, which must then be of unknown length: chunked with HTTP/1.1,
+		// and not ended with the headers with HTTP/2, even if empty.
+		if body == nil {
+			httpReq.Body = ioutil.NopCloser(strings.NewReader(""))
+		}
+		httpReq.ContentLength = -1
+		httpReq.TransferEncoding = []string{"chunked"}
+		httpReq.Trailer = req.Trailer
+	}
 
 	// Set the per-request timeout.
 	ctx, cancel := context.WithTimeout(ctx, req.Timeout)
@@ -59,14 +94,21 @@ func (c *httpProtocol) makeRequest(ctx context.Context, req *request) (string, e
 	outBuffer.WriteString(fmt.Sprintf("[%d] Url=%s\n", req.RequestID, req.URL))
 	host := ""
 	writeHeaders(req.RequestID, req.Header, outBuffer, func(key string, value string) {
-		if key == hostHeader {
+		if textproto.CanonicalMIMEHeaderKey(key) == hostHeader {
 			host = value
 		} else {
-			httpReq.Header.Add(key, value)
+			// Not canonicalized with Header.Add, the header is sent with the name as given, e.g. to test
+			// the case-insensitive lookup of the headers by the proxies.
+			httpReq.Header[key] = append(httpReq.Header[key], value)
 		}
 	})
 
 	c.setHost(httpReq, host)
+	for key, values := range req.Trailer {
+		for _, value := range values {
+			outBuffer.WriteString(fmt.Sprintf("[%d] Trailer=%s:%s\n", req.RequestID, key, value))
+		}
+	}
 
 	httpResp, err := c.do(c.client, httpReq)
 	if err != nil {
@@ -77,7 +119,7 @@ func (c *httpProtocol) makeRequest(ctx context.Context, req *request) (string, e
 
 	for key, values := range httpResp.Header {
 		for _, value := range values {
-			outBuffer.WriteString(fmt.Sprintf("[%d] ResponseHeader=%s:%s\n", req.RequestID, key, value))
+			outBuffer.WriteString(fmt.Sprintf("[%d] %s=%s:%s\n", req.RequestID, response.ResponseHeaderField, key, value))
 		}
 	}
 
@@ -92,6 +134,13 @@ func (c *httpProtocol) makeRequest(ctx context.Context, req *request) (string, e
 		return outBuffer.String(), err
 	}
 
+	// The trailers are received once the body is read.
+	for key, values := range httpResp.Trailer {
+		for _, value := range values {
+			outBuffer.WriteString(fmt.Sprintf("[%d] %s=%s:%s\n", req.RequestID, response.ResponseTrailerField, key, value))
+		}
+	}
+
 	for _, line := range strings.Split(string(data), "\n") {
 		if line != "" {
 			outBuffer.WriteString(fmt.Sprintf("[%d body] %s\n", req.RequestID, line))
@@ -101,6 +150,23 @@ func (c *httpProtocol) makeRequest(ctx context.Context, req *request) (string, e
 	return outBuffer.String(), nil
 }
 
+// setRequestTarget sets the target of the request to the path of the URL with its query and fragment, if
+// any. The fragment is otherwise never sent, as required by RFC 7230.
+func setRequestTarget(u *url.URL) {
+	if u.Fragment == "" {
+		return
+	}
+	target := u.EscapedPath()
+	if target == "" {
+		target = "/"
+	}
+	if u.RawQuery != "" {
+		target += "?" + u.RawQuery
+		u.RawQuery = ""
+	}
+	u.Opaque = target + "#" + u.EscapedFragment()
+}
+
 func (c *httpProtocol) Close() error {
 	return nil
 }
@@ -16,6 +16,7 @@ package forwarder
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"net/http"
 	"time"
@@ -23,6 +24,7 @@ import (
 	"github.com/golang/sync/errgroup"
 
 	"istio.io/istio/pkg/test/echo/common"
+	"istio.io/istio/pkg/test/echo/common/response"
 	"istio.io/istio/pkg/test/echo/proto"
 	"istio.io/pkg/log"
 )
@@ -52,6 +54,20 @@ type Instance struct {
 	qps     int
 	header  http.Header
 	message string
+	// The HTTP method of the requests, if not GET.
+	method string
+	// The number of bytes of the body of the HTTP requests, if any.
+	bodySize int
+	// Whether each HTTP request is sent on a new connection.
+	closeConnection bool
+	// The number of messages of each request, if sent on a stream.
+	streamMessages int
+	// The headers of each request, if they differ. The requests are then sent one after the other.
+	headerSets []http.Header
+	// The trailers of each HTTP request, if any.
+	trailer http.Header
+	// Whether the fragment of the URL is sent in the target of the HTTP requests.
+	sendFragment bool
 }
 
 // New creates a new forwarder Instance.
@@ -62,22 +78,66 @@ func New(cfg Config) (*Instance, error) {
 	if err != nil {
 		return nil, err
 	}
+	if _, ok := p.(streamProtocol); cfg.Request.StreamMessages > 0 && !ok {
+		_ = p.Close()
+		return nil, fmt.Errorf("streaming is not supported for %s", cfg.Request.Url)
+	}
+	if cfg.Request.StreamMessages > 0 && len(cfg.Request.HeaderSets) > 0 {
+		_ = p.Close()
+		return nil, fmt.Errorf("header sets are not supported with streaming")
+	}
+	if _, ok := p.(*httpProtocol); cfg.Request.Method != "" && !ok {
+		_ = p.Close()
+		return nil, fmt.Errorf("method %s is not supported for %s", cfg.Request.Method, cfg.Request.Url)
+	}
+	if _, ok := p.(*httpProtocol); cfg.Request.BodySize != 0 && !ok {
+		_ = p.Close()
+		return nil, fmt.Errorf("request body is not supported for %s", cfg.Request.Url)
+	}
+	if _, ok := p.(*httpProtocol); cfg.Request.CloseConnection && !ok {
+		_ = p.Close()
+		return nil, fmt.Errorf("closing the connection is not supported for %s", cfg.Request.Url)
+	}
+	if _, ok := p.(*httpProtocol); cfg.Request.Http2 && !ok {
+		_ = p.Close()
+		return nil, fmt.Errorf("HTTP/2 is not supported for %s", cfg.Request.Url)
+	}
+	if _, ok := p.(*httpProtocol); len(cfg.Request.Trailers) > 0 && !ok {
+		_ = p.Close()
+		return nil, fmt.Errorf("trailers are not supported for %s", cfg.Request.Url)
+	}
+	if _, ok := p.(*httpProtocol); cfg.Request.SendFragment && !ok {
+		_ = p.Close()
+		return nil, fmt.Errorf("sending the fragment is not supported for %s", cfg.Request.Url)
+	}
+	if cfg.Request.BodySize < 0 {
+		_ = p.Close()
+		return nil, fmt.Errorf("invalid body size %d", cfg.Request.BodySize)
+	}
 
 	return &Instance{
-		p:       p,
-		url:     cfg.Request.Url,
-		timeout: common.GetTimeout(cfg.Request),
-		count:   common.GetCount(cfg.Request),
-		qps:     int(cfg.Request.Qps),
-		header:  common.GetHeaders(cfg.Request),
-		message: cfg.Request.Message,
+		p:               p,
+		url:             cfg.Request.Url,
+		timeout:         common.GetTimeout(cfg.Request),
+		count:           common.GetCount(cfg.Request),
+		qps:             int(cfg.Request.Qps),
+		header:          common.GetHeaders(cfg.Request),
+		message:         cfg.Request.Message,
+		method:          cfg.Request.Method,
+		bodySize:        int(cfg.Request.BodySize),
+		closeConnection: cfg.Request.CloseConnection,
+		streamMessages:  int(cfg.Request.StreamMessages),
+		headerSets:      common.GetHeaderSets(cfg.Request),
+		trailer:         common.GetTrailers(cfg.Request),
+		sendFragment:    cfg.Request.SendFragment,
 	}, nil
 }
 
-// Run the forwarder and collect the responses.
+// Run the forwarder and collect the responses. If the messages of the requests are sent on a stream, each
+// message echoed is a response.
 func (i *Instance) Run(ctx context.Context) (*proto.ForwardEchoResponse, error) {
 	g, _ := errgroup.WithContext(context.Background())
-	responses := make([]string, i.count)
+	responses := make([][]string, i.count)
 
 	var throttle *time.Ticker
 
@@ -89,24 +149,49 @@ func (i *Instance) Run(ctx context.Context) (*proto.ForwardEchoResponse, error)
 
 	for reqIndex := 0; reqIndex < i.count; reqIndex++ {
 		r := request{
-			RequestID: reqIndex,
-			URL:       i.url,
-			Message:   i.message,
-			Header:    i.header,
-			Timeout:   i.timeout,
+			RequestID:       reqIndex,
+			URL:             i.url,
+			Message:         i.message,
+			Method:          i.method,
+			BodySize:        i.bodySize,
+			CloseConnection: i.closeConnection,
+			Header:          i.header,
+			Timeout:         i.timeout,
+			StreamMessages:  i.streamMessages,
+			Trailer:         i.trailer,
+			SendFragment:    i.sendFragment,
 		}
 
 		if throttle != nil {
 			<-throttle.C
 		}
 
+		if i.headerSets != nil {
+			// Send the requests in order, so that they reuse the connection.
+			r.Header = i.headerSets[reqIndex]
+			resp, err := i.makeTimedRequest(ctx, &r)
+			if err != nil {
+				return nil, err
+			}
+			responses[r.RequestID] = []string{resp}
+			continue
+		}
+
 		// TODO(nmittler): Refactor this to limit the number of go routines.
 		g.Go(func() error {
-			resp, err := i.p.makeRequest(ctx, &r)
+			if r.StreamMessages > 0 {
+				resp, err := i.p.(streamProtocol).makeStreamRequest(ctx, &r)
+				if err != nil {
+					return err
+				}
+				responses[r.RequestID] = resp
+				return nil
+			}
+			resp, err := i.makeTimedRequest(ctx, &r)
 			if err != nil {
 				return err
 			}
-			responses[r.RequestID] = resp
+			responses[r.RequestID] = []string{resp}
 			return nil
 		})
 	}
@@ -115,11 +200,25 @@ func (i *Instance) Run(ctx context.Context) (*proto.ForwardEchoResponse, error)
 		return nil, err
 	}
 
+	output := make([]string, 0, i.count)
+	for _, resp := range responses {
+		output = append(output, resp...)
+	}
 	return &proto.ForwardEchoResponse{
-		Output: responses,
+		Output: output,
 	}, nil
 }
 
+// makeTimedRequest makes the request and adds its latency to the output.
+func (i *Instance) makeTimedRequest(ctx context.Context, r *request) (string, error) {
+	start := time.Now()
+	resp, err := i.p.makeRequest(ctx, r)
+	if err != nil {
+		return resp, err
+	}
+	return resp + fmt.Sprintf("[%d] %s=%v\n", r.RequestID, response.LatencyField, time.Since(start)), nil
+}
+
 func (i *Instance) Close() error {
 	if i != nil && i.p != nil {
 		return i.p.Close()
@@ -26,6 +26,7 @@ import (
 	"time"
 
 	"github.com/gorilla/websocket"
+	"golang.org/x/net/http2"
 
 	"google.golang.org/grpc"
 
@@ -40,6 +41,18 @@ type request struct {
 	RequestID int
 	Message   string
 	Timeout   time.Duration
+	// Method is the HTTP method of the request. If not set, GET is used.
+	Method string
+	// BodySize is the number of bytes of the body of the HTTP request, if any.
+	BodySize int
+	// CloseConnection sends the HTTP request on a new connection, closed once the response is received.
+	CloseConnection bool
+	// StreamMessages is the number of messages sent on a stream, if the request is streamed.
+	StreamMessages int
+	// Trailer is the trailers sent after the body of the HTTP request, if any.
+	Trailer http.Header
+	// SendFragment sends the fragment of the URL in the target of the HTTP request.
+	SendFragment bool
 }
 
 type protocol interface {
@@ -47,9 +60,21 @@ type protocol interface {
 	Close() error
 }
 
+// streamProtocol is a protocol able to send the messages of a request on a stream.
+type streamProtocol interface {
+	protocol
+	// makeStreamRequest sends req.StreamMessages messages on a stream and returns the output of each
+	// message echoed.
+	makeStreamRequest(ctx context.Context, req *request) ([]string, error)
+}
+
 func newProtocol(cfg Config) (protocol, error) {
+	timeout := common.GetTimeout(cfg.Request)
+	headers := common.GetHeaders(cfg.Request)
+
 	var httpDialContext func(ctx context.Context, network, addr string) (net.Conn, error)
 	var wsDialContext func(network, addr string) (net.Conn, error)
+	var grpcDialOptions []grpc.DialOption
 	if len(cfg.UDS) > 0 {
 		httpDialContext = func(_ context.Context, _, _ string) (net.Conn, error) {
 			return net.Dial("unix", cfg.UDS)
@@ -58,6 +83,16 @@ func newProtocol(cfg Config) (protocol, error) {
 		wsDialContext = func(_, _ string) (net.Conn, error) {
 			return net.Dial("unix", cfg.UDS)
 		}
+	} else if hasDialOptions(cfg.Request) {
+		dialer := &net.Dialer{
+			Timeout: timeout,
+		}
+		applyDialOptions(dialer, cfg.Request)
+		httpDialContext = dialer.DialContext
+		wsDialContext = dialer.Dial
+		grpcDialOptions = append(grpcDialOptions, grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "tcp", addr)
+		}))
 	}
 
 	rawURL := cfg.Request.Url
@@ -66,11 +101,40 @@ func newProtocol(cfg Config) (protocol, error) {
 		return nil, fmt.Errorf("failed parsing request URL %s: %v", cfg.Request.Url, err)
 	}
 
-	timeout := common.GetTimeout(cfg.Request)
-	headers := common.GetHeaders(cfg.Request)
+	if cfg.Request.RawRequest != "" {
+		if scheme.Instance(u.Scheme) != scheme.HTTP {
+			return nil, fmt.Errorf("raw requests are only supported for http:// URLs, got %s", rawURL)
+		}
+		address := u.Host
+		if u.Port() == "" {
+			address = net.JoinHostPort(u.Hostname(), "80")
+		}
+		dialer := net.Dialer{
+			Timeout: timeout,
+		}
+		applyDialOptions(&dialer, cfg.Request)
+		return &rawHTTPProtocol{
+			rawRequest: cfg.Request.RawRequest,
+			dial: func(ctx context.Context) (net.Conn, error) {
+				if len(cfg.UDS) > 0 {
+					return net.Dial("unix", cfg.UDS)
+				}
+				return cfg.Dialer.TCP(dialer, ctx, address)
+			},
+		}, nil
+	}
 
 	switch scheme.Instance(u.Scheme) {
 	case scheme.HTTP, scheme.HTTPS:
+		if cfg.Request.Http2 {
+			return &httpProtocol{
+				client: &http.Client{
+					Transport: newHTTP2Transport(scheme.Instance(u.Scheme), httpDialContext),
+					Timeout:   timeout,
+				},
+				do: cfg.Dialer.HTTP,
+			}, nil
+		}
 		return &httpProtocol{
 			client: &http.Client{
 				Transport: &http.Transport{
@@ -85,7 +149,7 @@ func newProtocol(cfg Config) (protocol, error) {
 		}, nil
 	case scheme.GRPC:
 		// grpc-go sets incorrect authority header
-		authority := headers.Get(hostHeader)
+		authority := common.GetHeader(headers, hostHeader)
 
 		// transport security
 		security := grpc.WithInsecure()
@@ -98,9 +162,11 @@ func newProtocol(cfg Config) (protocol, error) {
 		defer cancel()
 		grpcConn, err := cfg.Dialer.GRPC(ctx,
 			address,
-			security,
-			grpc.WithAuthority(authority),
-			grpc.WithBlock())
+			append([]grpc.DialOption{
+				security,
+				grpc.WithAuthority(authority),
+				grpc.WithBlock(),
+			}, grpcDialOptions...)...)
 		if err != nil {
 			return nil, err
 		}
@@ -123,6 +189,7 @@ func newProtocol(cfg Config) (protocol, error) {
 		dialer := net.Dialer{
 			Timeout: timeout,
 		}
+		applyDialOptions(&dialer, cfg.Request)
 		ctx, cancel := context.WithTimeout(context.Background(), common.ConnectionTimeout)
 		defer cancel()
 
@@ -138,3 +205,29 @@ func newProtocol(cfg Config) (protocol, error) {
 
 	return nil, fmt.Errorf("unrecognized protocol %q", u.String())
 }
+
+// newHTTP2Transport returns a transport sending the requests with HTTP/2: with prior knowledge for http://
+// URLs (h2c), or negotiated with ALPN over TLS for https:// URLs. dialContext, if set, opens the connections.
+func newHTTP2Transport(s scheme.Instance, dialContext func(ctx context.Context, network, addr string) (net.Conn, error)) *http2.Transport {
+	if dialContext == nil {
+		dialContext = (&net.Dialer{}).DialContext
+	}
+	return &http2.Transport{
+		TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: true,
+		},
+		AllowHTTP: s == scheme.HTTP,
+		DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+			conn, err := dialContext(context.Background(), network, addr)
+			if err != nil || s == scheme.HTTP {
+				return conn, err
+			}
+			tlsConn := tls.Client(conn, cfg)
+			if err := tlsConn.Handshake(); err != nil {
+				_ = conn.Close()
+				return nil, err
+			}
+			return tlsConn, nil
+		},
+	}
+}
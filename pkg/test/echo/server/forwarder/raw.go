@@ -0,0 +1,99 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package forwarder
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"istio.io/istio/pkg/test/echo/common/response"
+)
+
+var _ protocol = &rawHTTPProtocol{}
+
+// rawHTTPProtocol writes a raw HTTP request as is on a new connection for each request, bypassing the
+// validation and normalization of the HTTP client, e.g. to send a malformed request line.
+type rawHTTPProtocol struct {
+	rawRequest string
+	dial       func(ctx context.Context) (net.Conn, error)
+}
+
+func (c *rawHTTPProtocol) makeRequest(ctx context.Context, req *request) (string, error) {
+	var outBuffer bytes.Buffer
+	outBuffer.WriteString(fmt.Sprintf("[%d] Url=%s\n", req.RequestID, req.URL))
+
+	// Apply per-request timeout to calculate deadline for the connection, reads and writes.
+	ctx, cancel := context.WithTimeout(ctx, req.Timeout)
+	defer cancel()
+
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return outBuffer.String(), err
+	}
+	defer conn.Close()
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		return outBuffer.String(), err
+	}
+
+	if _, err := io.WriteString(conn, c.rawRequest); err != nil {
+		return outBuffer.String(), err
+	}
+
+	// Read one response, keeping the bytes as received. A response which is not valid HTTP, e.g. truncated,
+	// is still returned verbatim, without status code.
+	var raw bytes.Buffer
+	httpResp, err := http.ReadResponse(bufio.NewReader(io.TeeReader(conn, &raw)), nil)
+	if err == nil {
+		var data []byte
+		data, err = ioutil.ReadAll(httpResp.Body)
+		_ = httpResp.Body.Close()
+		writeRawHTTPResponse(&outBuffer, req.RequestID, httpResp, data)
+	}
+	if raw.Len() == 0 {
+		return outBuffer.String(), fmt.Errorf("no response to the raw request: %v", err)
+	}
+	outBuffer.WriteString(fmt.Sprintf("[%d] %s=%s\n", req.RequestID, response.RawHTTPResponseField,
+		strconv.Quote(raw.String())))
+	return outBuffer.String(), nil
+}
+
+// writeRawHTTPResponse writes the status code, headers and body of the response, as for the other HTTP
+// requests.
+func writeRawHTTPResponse(outBuffer *bytes.Buffer, requestID int, httpResp *http.Response, data []byte) {
+	outBuffer.WriteString(fmt.Sprintf("[%d] %s=%d\n", requestID, response.StatusCodeField, httpResp.StatusCode))
+	for key, values := range httpResp.Header {
+		for _, value := range values {
+			outBuffer.WriteString(fmt.Sprintf("[%d] %s=%s:%s\n", requestID, response.ResponseHeaderField, key, value))
+		}
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			outBuffer.WriteString(fmt.Sprintf("[%d body] %s\n", requestID, line))
+		}
+	}
+}
+
+func (c *rawHTTPProtocol) Close() error {
+	return nil
+}
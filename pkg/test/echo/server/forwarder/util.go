@@ -27,13 +27,14 @@ const (
 
 func writeHeaders(requestID int, header http.Header, outBuffer bytes.Buffer, addFn func(string, string)) {
 	for key, values := range header {
-		key = textproto.CanonicalMIMEHeaderKey(key)
+		// addFn is given the name as is, which may not be canonical.
+		canonicalKey := textproto.CanonicalMIMEHeaderKey(key)
 		for _, v := range values {
 			addFn(key, v)
-			if key == hostHeader {
+			if canonicalKey == hostHeader {
 				outBuffer.WriteString(fmt.Sprintf("[%d] Host=%s\n", requestID, v))
 			} else {
-				outBuffer.WriteString(fmt.Sprintf("[%d] Header=%s:%s\n", requestID, key, v))
+				outBuffer.WriteString(fmt.Sprintf("[%d] Header=%s:%s\n", requestID, canonicalKey, v))
 			}
 		}
 	}
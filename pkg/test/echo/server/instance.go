@@ -42,6 +42,7 @@ type Config struct {
 	Version   string
 	UDSServer string
 	Cluster   string
+	Locality  string
 	Dialer    common.Dialer
 }
 
@@ -117,6 +118,7 @@ func (s *Instance) newEndpoint(port *common.Port, udsServer string) (endpoint.In
 		IsServerReady: s.isReady,
 		Version:       s.Version,
 		Cluster:       s.Cluster,
+		Locality:      s.Locality,
 		TLSCert:       s.TLSCert,
 		TLSKey:        s.TLSKey,
 		Dialer:        s.Dialer,
@@ -177,6 +179,7 @@ func (s *Instance) startMetricsServer() {
 	}
 	view.RegisterExporter(exporter)
 	mux.Handle("/metrics", exporter)
+	mux.HandleFunc(common.ReceivedRequestsPath, endpoint.ServeReceivedRequests)
 	s.metricsServer = &http.Server{
 		Handler: mux,
 	}
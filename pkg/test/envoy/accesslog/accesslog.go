@@ -0,0 +1,208 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package accesslog parses the JSON access logs of Envoy proxies, to assert why a request got its
+// response.
+package accesslog
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+)
+
+const (
+	// Format is the JSON access log format the entries are parsed from. Unlike the default JSON format
+	// of Istio, it includes the response code details, e.g. the reason a request was rejected, and the
+	// dynamic metadata of the authentication filters.
+	Format = `{"start_time":"%START_TIME%","method":"%REQ(:METHOD)%",` +
+		`"path":"%REQ(X-ENVOY-ORIGINAL-PATH?:PATH)%","authority":"%REQ(:AUTHORITY)%",` +
+		`"request_id":"%REQ(X-REQUEST-ID)%","response_code":"%RESPONSE_CODE%",` +
+		`"response_code_details":"%RESPONSE_CODE_DETAILS%","response_flags":"%RESPONSE_FLAGS%",` +
+		`"upstream_cluster":"%UPSTREAM_CLUSTER%","upstream_host":"%UPSTREAM_HOST%",` +
+		`"jwt_authn_metadata":"%DYNAMIC_METADATA(envoy.filters.http.jwt_authn)%",` +
+		`"istio_authn_metadata":"%DYNAMIC_METADATA(istio_authn)%"}`
+
+	// The value Envoy logs for a field without value.
+	emptyField = "-"
+)
+
+// The HTTP filters which reject requests with a local reply, as returned by Entry.RejectedBy.
+const (
+	JWTFilter      = "jwt_authn"
+	RBACFilter     = "rbac"
+	ExtAuthzFilter = "ext_authz"
+)
+
+// MeshConfigPatch is the mesh config patch enabling the access logs of the proxies in Format.
+var MeshConfigPatch = fmt.Sprintf("accessLogFile: /dev/stdout\naccessLogEncoding: JSON\naccessLogFormat: %q\n", Format)
+
+// Enabled returns true if the mesh config enables the access logs in Format.
+func Enabled(mc *meshconfig.MeshConfig) bool {
+	return mc.AccessLogFile != "" && mc.AccessLogEncoding == meshconfig.MeshConfig_JSON &&
+		mc.AccessLogFormat == Format
+}
+
+// Entry is an access log entry of a proxy.
+type Entry struct {
+	// Pod identifies the proxy that logged the entry, e.g. the name of its pod.
+	Pod string
+
+	StartTime           time.Time
+	Method              string
+	Path                string
+	Authority           string
+	RequestID           string
+	ResponseCode        int
+	ResponseCodeDetails string
+	// ResponseFlags are the response flags, e.g. RBAC or UAEX, or empty if none.
+	ResponseFlags   []string
+	UpstreamCluster string
+	UpstreamHost    string
+	// JWTMetadata is the dynamic metadata of the JWT filter, envoy.filters.http.jwt_authn: the payload of
+	// the token keyed by its issuer. Nil if none.
+	JWTMetadata map[string]interface{}
+	// AuthnMetadata is the dynamic metadata of the Istio authn filter, istio_authn, e.g. source.principal
+	// (the identity of the peer) and request.auth.principal (the principal of the token). Nil if none.
+	AuthnMetadata map[string]interface{}
+
+	// Fields are all the fields of the entry, as logged.
+	Fields map[string]string
+}
+
+// HasResponseFlag returns true if the entry has the response flag.
+func (e Entry) HasResponseFlag(flag string) bool {
+	for _, f := range e.ResponseFlags {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// RejectedBy returns the HTTP filter which rejected the request with a local reply, e.g. JWTFilter, or ""
+// if none did. The JWT filter sets no response flag, so the filter is told by the response code details,
+// e.g. jwt_authn_access_denied or rbac_access_denied_matched_policy[none], then by the response flags.
+func (e Entry) RejectedBy() string {
+	for _, filter := range []string{JWTFilter, RBACFilter, ExtAuthzFilter} {
+		if strings.HasPrefix(e.ResponseCodeDetails, filter+"_") {
+			return filter
+		}
+	}
+	switch {
+	case e.HasResponseFlag("RBAC"):
+		return RBACFilter
+	case e.HasResponseFlag("UAEX"):
+		return ExtAuthzFilter
+	default:
+		return ""
+	}
+}
+
+// Entries is a list of access log entries, which can be filtered.
+type Entries []Entry
+
+// WithPath returns the entries of the requests with the given path, including the query.
+func (e Entries) WithPath(path string) Entries {
+	return e.filter(func(entry Entry) bool { return entry.Path == path })
+}
+
+// WithHost returns the entries of the requests with the given host.
+func (e Entries) WithHost(host string) Entries {
+	return e.filter(func(entry Entry) bool { return entry.Authority == host })
+}
+
+// WithRequestID returns the entries of the requests with the given x-request-id. Note that the gateways
+// generate a new request ID for the requests from outside of the mesh.
+func (e Entries) WithRequestID(id string) Entries {
+	return e.filter(func(entry Entry) bool { return entry.RequestID == id })
+}
+
+func (e Entries) filter(match func(Entry) bool) Entries {
+	var out Entries
+	for _, entry := range e {
+		if match(entry) {
+			out = append(out, entry)
+		}
+	}
+	return out
+}
+
+// Parse returns the access log entries in the logs of the pod which start at or after since. The lines
+// which are not JSON access log entries are skipped.
+func Parse(pod, logs string, since time.Time) Entries {
+	var out Entries
+	for _, line := range strings.Split(logs, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "{") {
+			continue
+		}
+		var raw map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &raw); err != nil {
+			continue
+		}
+		fields := make(map[string]string, len(raw))
+		for k, v := range raw {
+			if m, ok := v.(map[string]interface{}); ok {
+				// The dynamic metadata may be logged as an object, which is kept as JSON.
+				b, _ := json.Marshal(m)
+				fields[k] = string(b)
+			} else if s := fmt.Sprint(v); s != emptyField && v != nil {
+				fields[k] = s
+			}
+		}
+		startTime, err := time.Parse(time.RFC3339Nano, fields["start_time"])
+		if err != nil || startTime.Before(since) {
+			continue
+		}
+		code, _ := strconv.Atoi(fields["response_code"])
+		var flags []string
+		if f := fields["response_flags"]; f != "" {
+			flags = strings.Split(f, ",")
+		}
+		out = append(out, Entry{
+			Pod:                 pod,
+			StartTime:           startTime,
+			Method:              fields["method"],
+			Path:                fields["path"],
+			Authority:           fields["authority"],
+			RequestID:           fields["request_id"],
+			ResponseCode:        code,
+			ResponseCodeDetails: fields["response_code_details"],
+			ResponseFlags:       flags,
+			UpstreamCluster:     fields["upstream_cluster"],
+			UpstreamHost:        fields["upstream_host"],
+			JWTMetadata:         parseMetadata(fields["jwt_authn_metadata"]),
+			AuthnMetadata:       parseMetadata(fields["istio_authn_metadata"]),
+			Fields:              fields,
+		})
+	}
+	return out
+}
+
+// parseMetadata returns the dynamic metadata logged as JSON, or nil if there is none or it is not JSON.
+func parseMetadata(field string) map[string]interface{} {
+	if field == "" {
+		return nil
+	}
+	var out map[string]interface{}
+	if err := json.Unmarshal([]byte(field), &out); err != nil || len(out) == 0 {
+		return nil
+	}
+	return out
+}
@@ -0,0 +1,132 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package accesslog
+
+import (
+	"testing"
+	"time"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+)
+
+func TestParse(t *testing.T) {
+	logs := `2020-05-20T10:00:00.000000Z	info	Envoy proxy is ready
+{"start_time":"2020-05-20T10:00:01.000Z","method":"GET","path":"/old","authority":"example.com","request_id":"1","response_code":"200","response_code_details":"via_upstream","response_flags":"-"}
+{"start_time":"2020-05-20T10:00:03.000Z","method":"GET","path":"/a?x=1","authority":"example.com","request_id":"2","response_code":"401","response_code_details":"jwt_authn_access_denied","response_flags":"-","upstream_cluster":"-"}
+{"start_time":"2020-05-20T10:00:04.000Z","method":"GET","path":"/b","authority":"other.com","request_id":"3","response_code":403,"response_code_details":"rbac_access_denied","response_flags":"RBAC,UAEX","upstream_cluster":"inbound|8090|http|b.ns.svc.cluster.local"}
+{"start_time":"2020-05-20T10:00:05.000Z","path":"/c","request_id":"4","response_code":"200","jwt_authn_metadata":"{\"test-issuer-1@istio.io\":{\"sub\":\"sub-1\"}}","istio_authn_metadata":{"request.auth.principal":"test-issuer-1@istio.io/sub-1","source.principal":"cluster.local/ns/ns/sa/a"}}
+{"start_time":"not a time"}
+{not json
+`
+	since := time.Date(2020, 5, 20, 10, 0, 2, 0, time.UTC)
+	entries := Parse("gw-1", logs, since)
+	if len(entries) != 3 {
+		t.Fatalf("got %d entries, want 3: %v", len(entries), entries)
+	}
+
+	got := entries.WithPath("/a?x=1").WithHost("example.com")
+	if len(got) != 1 {
+		t.Fatalf("got %d entries for path /a?x=1, want 1: %v", len(got), entries)
+	}
+	e := got[0]
+	if e.Pod != "gw-1" || e.Method != "GET" || e.RequestID != "2" || e.ResponseCode != 401 ||
+		e.ResponseCodeDetails != "jwt_authn_access_denied" {
+		t.Errorf("unexpected entry %+v", e)
+	}
+	if _, ok := e.Fields["response_flags"]; ok || len(e.ResponseFlags) != 0 || e.UpstreamCluster != "" {
+		t.Errorf("field without value kept: %+v", e)
+	}
+
+	// Numbers are accepted as well as strings.
+	got = entries.WithRequestID("3")
+	if len(got) != 1 || got[0].ResponseCode != 403 {
+		t.Fatalf("got %v for request 3, want one entry with code 403", got)
+	}
+	if e := got[0]; !e.HasResponseFlag("RBAC") || !e.HasResponseFlag("UAEX") || e.HasResponseFlag("UF") ||
+		e.UpstreamCluster != "inbound|8090|http|b.ns.svc.cluster.local" {
+		t.Errorf("unexpected entry %+v", e)
+	}
+	if got := entries.WithHost("missing.com"); len(got) != 0 {
+		t.Errorf("got %v for host missing.com, want none", got)
+	}
+	if e.JWTMetadata != nil || e.AuthnMetadata != nil {
+		t.Errorf("got metadata for an entry without: %+v", e)
+	}
+
+	// The metadata is accepted as a JSON string as well as an object.
+	got = entries.WithRequestID("4")
+	if len(got) != 1 {
+		t.Fatalf("got %v for request 4, want one entry", got)
+	}
+	jwtPayload, ok := got[0].JWTMetadata["test-issuer-1@istio.io"].(map[string]interface{})
+	if !ok || jwtPayload["sub"] != "sub-1" {
+		t.Errorf("unexpected JWT metadata %v", got[0].JWTMetadata)
+	}
+	if p := got[0].AuthnMetadata["request.auth.principal"]; p != "test-issuer-1@istio.io/sub-1" {
+		t.Errorf("unexpected authn metadata %v", got[0].AuthnMetadata)
+	}
+	if p := got[0].AuthnMetadata["source.principal"]; p != "cluster.local/ns/ns/sa/a" {
+		t.Errorf("unexpected authn metadata %v", got[0].AuthnMetadata)
+	}
+}
+
+func TestRejectedBy(t *testing.T) {
+	cases := []struct {
+		name    string
+		details string
+		flags   []string
+		want    string
+	}{
+		{name: "allowed", details: "via_upstream"},
+		{name: "jwt", details: "jwt_authn_access_denied", want: JWTFilter},
+		{name: "jwt with reason", details: "jwt_authn_access_denied{Jwt_is_missing}", want: JWTFilter},
+		{name: "rbac", details: "rbac_access_denied", flags: []string{"RBAC"}, want: RBACFilter},
+		{name: "rbac with policy", details: "rbac_access_denied_matched_policy[none]", want: RBACFilter},
+		{name: "rbac flag only", flags: []string{"RBAC"}, want: RBACFilter},
+		{name: "ext authz", details: "ext_authz_denied", flags: []string{"UAEX"}, want: ExtAuthzFilter},
+		{name: "ext authz flag only", flags: []string{"UAEX"}, want: ExtAuthzFilter},
+		{name: "upstream failure", details: "upstream_reset_before_response_started{connection_failure}",
+			flags: []string{"UF"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			e := Entry{ResponseCodeDetails: c.details, ResponseFlags: c.flags}
+			if got := e.RejectedBy(); got != c.want {
+				t.Errorf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	cases := []struct {
+		name string
+		mc   *meshconfig.MeshConfig
+		want bool
+	}{
+		{"disabled", &meshconfig.MeshConfig{}, false},
+		{"text", &meshconfig.MeshConfig{AccessLogFile: "/dev/stdout", AccessLogFormat: Format}, false},
+		{"default json", &meshconfig.MeshConfig{AccessLogFile: "/dev/stdout", AccessLogEncoding: meshconfig.MeshConfig_JSON}, false},
+		{"enabled", &meshconfig.MeshConfig{AccessLogFile: "/dev/stdout", AccessLogEncoding: meshconfig.MeshConfig_JSON,
+			AccessLogFormat: Format}, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := Enabled(c.mc); got != c.want {
+				t.Errorf("got %v, want %v", got, c.want)
+			}
+		})
+	}
+}
@@ -40,6 +40,9 @@ var (
 	SidecarBootstrapOverride     = workloadAnnotation(annotation.SidecarBootstrapOverride.Name, "")
 	SidecarVolumeMount           = workloadAnnotation(annotation.SidecarUserVolumeMount.Name, "")
 	SidecarVolume                = workloadAnnotation(annotation.SidecarUserVolume.Name, "")
+	SidecarProxyConfig           = workloadAnnotation(annotation.ProxyConfig.Name, "")
+	SidecarProxyCPULimit         = workloadAnnotation(annotation.SidecarProxyCPULimit.Name, "")
+	SidecarProxyMemoryLimit      = workloadAnnotation(annotation.SidecarProxyMemoryLimit.Name, "")
 )
 
 type AnnotationValue struct {
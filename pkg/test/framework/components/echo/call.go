@@ -21,6 +21,16 @@ import (
 	"istio.io/istio/pkg/test/echo/common/scheme"
 )
 
+// Loopback is a mode of the calls of a workload to itself.
+type Loopback string
+
+const (
+	// LoopbackUDS calls the UDS server of the workload, see Config.UDSServer.
+	LoopbackUDS Loopback = "uds"
+	// LoopbackLocalhost calls the instance port of the workload on localhost.
+	LoopbackLocalhost Loopback = "localhost"
+)
+
 // CallOptions defines options for calling a Endpoint.
 type CallOptions struct {
 	// Target instance of the call. Required.
@@ -40,9 +50,48 @@ type CallOptions struct {
 	// default is chosen for the target Instance.
 	Host string
 
+	// DirectPodIP, if set, sends the request to the instance port on the address of the first workload
+	// of the Target (e.g. the pod IP), bypassing the service. Must not be combined with Host.
+	DirectPodIP bool
+
+	// Loopback, if set, sends the request from the workload of the caller to itself without leaving the
+	// pod, so that it goes through no sidecar: the Target must be the caller. Must not be combined with
+	// Host or DirectPodIP.
+	Loopback Loopback
+
 	// Path specifies the URL path for the HTTP(s) request.
 	Path string
 
+	// Method specifies the method of the HTTP(s) request. If not provided, GET is used.
+	Method string
+
+	// BodySize, if set, sends the HTTP(s) request with a body of this number of bytes. The server reports
+	// the size of the body it receives.
+	BodySize int
+
+	// CloseConnection, if set, sends each HTTP(s) request on a new connection, which the client closes once
+	// the response is received (Connection: close), e.g. to provoke connection churn on the sidecars.
+	CloseConnection bool
+
+	// HTTP2, if set, sends the HTTP(s) request with HTTP/2: with prior knowledge for HTTP (h2c), or negotiated
+	// with ALPN for HTTPS. The server reports the protocol of the request it receives as Proto.
+	HTTP2 bool
+
+	// Trailers, if set, are sent after the body of the HTTP(s) request. The server reports the trailers it
+	// receives and reflects them as the trailers of the response. A proxy only forwards them on HTTP/2, e.g.
+	// to a port declared HTTP2.
+	Trailers http.Header
+
+	// SendFragment, if set, sends the fragment of Path, if any, in the target of the HTTP(s) request. Clients
+	// must not send it (RFC 7230) and do not by default, e.g. to test how the proxies handle it.
+	SendFragment bool
+
+	// RawRequest, if set, is written as is on a new connection to the target instead of the HTTP request built
+	// from the other options, bypassing the normalization of the HTTP client, e.g. to send a malformed request
+	// line. It must include the Host header, as well as the token if any. The response is reported verbatim
+	// as RawHTTPResponse. Only supported with scheme HTTP.
+	RawRequest string
+
 	// Count indicates the number of exchanges that should be made with the service endpoint.
 	// If Count <= 0, defaults to 1.
 	Count int
@@ -50,9 +99,40 @@ type CallOptions struct {
 	// Headers indicates headers that should be sent in the request. Ignored for WebSocket calls.
 	Headers http.Header
 
+	// HeaderSets, if set, sends one request per set, in order and reusing the connection, each with the
+	// headers of the set replacing the headers of the same name in Headers, e.g. to swap the token of a
+	// client. Overrides Count.
+	HeaderSets []http.Header
+
+	// Cookies, if set, are sent in a single Cookie header of the request, ordered by name. Must not be
+	// combined with a Cookie header in Headers.
+	Cookies map[string]string
+
+	// Token, if set, is sent as a bearer token in the Authorization header of the request. Must not be
+	// combined with an Authorization header in Headers.
+	Token string
+
 	// Timeout used for each individual request. Must be > 0, otherwise 30 seconds is used.
 	Timeout time.Duration
 
 	// Message to be sent if this is a GRPC request
 	Message string
+
+	// SourcePort, if > 0, is the local port the client socket is bound to. Note that with a sidecar,
+	// this is the source port of the connection to the sidecar. Combine with ReuseAddress to rebind
+	// the port while the previous connection is in TIME_WAIT.
+	SourcePort int
+
+	// ReuseAddress sets SO_REUSEADDR on the client socket.
+	ReuseAddress bool
+
+	// StreamMessages, if > 0, sends each request as this number of messages on a bidirectional gRPC
+	// stream, each one echoed back before the next one is sent. Each message echoed is a response, so
+	// a call returns Count * StreamMessages responses. Only supported with the GRPC scheme.
+	StreamMessages int
+
+	// Concurrency, if > 1, is the number of callers sending the Count requests in parallel from the
+	// source, e.g. to surface races in the proxy of the Target. The responses of all the callers are
+	// returned, so a call returns Concurrency * Count responses.
+	Concurrency int
 }
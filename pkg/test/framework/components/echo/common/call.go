@@ -21,7 +21,11 @@ import (
 	"net"
 	"net/http"
 	"reflect"
+	"sort"
 	"strconv"
+	"strings"
+
+	"github.com/golang/sync/errgroup"
 
 	"istio.io/istio/pkg/config/protocol"
 	"istio.io/istio/pkg/test/echo/client"
@@ -31,6 +35,11 @@ import (
 	"istio.io/istio/pkg/test/framework/components/echo"
 )
 
+const (
+	authorizationHeader = "Authorization"
+	cookieHeader        = "Cookie"
+)
+
 var (
 	// IdentityOutboundPortSelector is an OutboundPortSelectorFunc that always returns the original service port.
 	IdentityOutboundPortSelector OutboundPortSelectorFunc = func(servicePort int) (int, error) {
@@ -47,9 +56,12 @@ func CallEcho(c *client.Instance, opts *echo.CallOptions, outboundPortSelector O
 		return nil, err
 	}
 
-	port, err := outboundPortSelector(opts.Port.ServicePort)
-	if err != nil {
-		return nil, err
+	port := opts.Port.InstancePort
+	if !opts.DirectPodIP && opts.Loopback == "" {
+		var err error
+		if port, err = outboundPortSelector(opts.Port.ServicePort); err != nil {
+			return nil, err
+		}
 	}
 
 	// Forward a request from 'this' service to the destination service.
@@ -68,29 +80,113 @@ func CallEcho(c *client.Instance, opts *echo.CallOptions, outboundPortSelector O
 	}
 	// Add headers in opts.Headers, e.g., authorization header, etc.
 	// If host header is set, it will override targetService.
-	for k := range opts.Headers {
-		protoHeaders = append(protoHeaders, &proto.Header{Key: k, Value: opts.Headers.Get(k)})
+	for k, values := range opts.Headers {
+		for _, v := range values {
+			protoHeaders = append(protoHeaders, &proto.Header{Key: k, Value: v})
+		}
+	}
+	if len(opts.Cookies) > 0 {
+		protoHeaders = append(protoHeaders, &proto.Header{Key: cookieHeader, Value: cookies(opts.Cookies)})
+	}
+	if opts.Token != "" {
+		protoHeaders = append(protoHeaders, &proto.Header{Key: authorizationHeader, Value: "Bearer " + opts.Token})
+	}
+
+	var trailers []*proto.Header
+	for k, values := range opts.Trailers {
+		for _, v := range values {
+			trailers = append(trailers, &proto.Header{Key: k, Value: v})
+		}
+	}
+
+	var headerSets []*proto.HeaderSet
+	for _, set := range opts.HeaderSets {
+		headers := make([]*proto.Header, 0, len(set))
+		for k, values := range set {
+			for _, v := range values {
+				headers = append(headers, &proto.Header{Key: k, Value: v})
+			}
+		}
+		headerSets = append(headerSets, &proto.HeaderSet{Headers: headers})
 	}
 
 	req := &proto.ForwardEchoRequest{
-		Url:           targetURL,
-		Count:         int32(opts.Count),
-		Headers:       protoHeaders,
-		TimeoutMicros: common.DurationToMicros(opts.Timeout),
-		Message:       opts.Message,
+		Url:             targetURL,
+		Count:           int32(opts.Count),
+		Headers:         protoHeaders,
+		TimeoutMicros:   common.DurationToMicros(opts.Timeout),
+		Message:         opts.Message,
+		SourcePort:      int32(opts.SourcePort),
+		ReuseAddress:    opts.ReuseAddress,
+		StreamMessages:  int32(opts.StreamMessages),
+		HeaderSets:      headerSets,
+		Method:          opts.Method,
+		BodySize:        int32(opts.BodySize),
+		CloseConnection: opts.CloseConnection,
+		Http2:           opts.HTTP2,
+		Trailers:        trailers,
+		SendFragment:    opts.SendFragment,
+		RawRequest:      opts.RawRequest,
+	}
+	if opts.Loopback == echo.LoopbackUDS {
+		req.Uds = opts.Target.Config().UDSServer
 	}
 
-	resp, err := c.ForwardEcho(context.Background(), req)
+	resp, err := forwardEcho(c, req, opts.Concurrency)
 	if err != nil {
 		return nil, err
 	}
 
-	if len(resp) != opts.Count {
-		return nil, fmt.Errorf("unexpected number of responses: expected %d, received %d", opts.Count, len(resp))
+	expected := opts.Count * opts.Concurrency
+	if opts.StreamMessages > 0 {
+		expected *= opts.StreamMessages
+	}
+	if len(resp) != expected {
+		return nil, fmt.Errorf("unexpected number of responses: expected %d, received %d", expected, len(resp))
 	}
 	return resp, err
 }
 
+// cookies returns the value of the Cookie header sending the given cookies.
+func cookies(values map[string]string) string {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	cookies := make([]string, 0, len(names))
+	for _, name := range names {
+		cookies = append(cookies, (&http.Cookie{Name: name, Value: values[name]}).String())
+	}
+	return strings.Join(cookies, "; ")
+}
+
+// forwardEcho sends the request from the given number of callers in parallel and returns the responses
+// of all of them.
+func forwardEcho(c *client.Instance, req *proto.ForwardEchoRequest, concurrency int) (client.ParsedResponses, error) {
+	if concurrency <= 1 {
+		return c.ForwardEcho(context.Background(), req)
+	}
+	responses := make([]client.ParsedResponses, concurrency)
+	var g errgroup.Group
+	for i := range responses {
+		i := i
+		g.Go(func() error {
+			resp, err := c.ForwardEcho(context.Background(), req)
+			responses[i] = resp
+			return err
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	var out client.ParsedResponses
+	for _, resp := range responses {
+		out = append(out, resp...)
+	}
+	return out, nil
+}
+
 func fillInCallOptions(opts *echo.CallOptions) error {
 	if opts.Target == nil {
 		return errors.New("callOptions: missing Target")
@@ -138,10 +234,78 @@ func fillInCallOptions(opts *echo.CallOptions) error {
 		}
 	}
 
+	if opts.StreamMessages > 0 && opts.Scheme != scheme.GRPC {
+		return fmt.Errorf("callOptions: StreamMessages is not supported with scheme %s", opts.Scheme)
+	}
+
+	if opts.Method != "" && opts.Scheme != scheme.HTTP && opts.Scheme != scheme.HTTPS {
+		return fmt.Errorf("callOptions: Method is not supported with scheme %s", opts.Scheme)
+	}
+
+	if opts.BodySize != 0 && opts.Scheme != scheme.HTTP && opts.Scheme != scheme.HTTPS {
+		return fmt.Errorf("callOptions: BodySize is not supported with scheme %s", opts.Scheme)
+	}
+
+	if opts.CloseConnection && opts.Scheme != scheme.HTTP && opts.Scheme != scheme.HTTPS {
+		return fmt.Errorf("callOptions: CloseConnection is not supported with scheme %s", opts.Scheme)
+	}
+
+	if opts.HTTP2 && opts.Scheme != scheme.HTTP && opts.Scheme != scheme.HTTPS {
+		return fmt.Errorf("callOptions: HTTP2 is not supported with scheme %s", opts.Scheme)
+	}
+
+	if len(opts.Trailers) > 0 && opts.Scheme != scheme.HTTP && opts.Scheme != scheme.HTTPS {
+		return fmt.Errorf("callOptions: Trailers are not supported with scheme %s", opts.Scheme)
+	}
+
+	if opts.SendFragment && opts.Scheme != scheme.HTTP && opts.Scheme != scheme.HTTPS {
+		return fmt.Errorf("callOptions: SendFragment is not supported with scheme %s", opts.Scheme)
+	}
+
+	if opts.RawRequest != "" && opts.Scheme != scheme.HTTP {
+		return fmt.Errorf("callOptions: RawRequest is not supported with scheme %s", opts.Scheme)
+	}
+
 	if opts.Headers == nil {
 		opts.Headers = make(http.Header)
 	}
 
+	if opts.Token != "" && opts.Headers.Get(authorizationHeader) != "" {
+		return errors.New("callOptions: Token and Authorization header are mutually exclusive")
+	}
+
+	if len(opts.Cookies) > 0 && opts.Headers.Get(cookieHeader) != "" {
+		return errors.New("callOptions: Cookies and Cookie header are mutually exclusive")
+	}
+
+	switch opts.Loopback {
+	case "":
+	case echo.LoopbackLocalhost, echo.LoopbackUDS:
+		if opts.Host != "" || opts.DirectPodIP {
+			return errors.New("callOptions: Loopback is mutually exclusive with Host and DirectPodIP")
+		}
+		if opts.Loopback == echo.LoopbackUDS && (opts.Target.Config().UDSServer == "" || opts.Scheme != scheme.HTTP) {
+			return errors.New("callOptions: Loopback uds requires an HTTP call to a Target with a UDS server")
+		}
+		opts.Host = "localhost"
+	default:
+		return fmt.Errorf("callOptions: unknown Loopback %q", opts.Loopback)
+	}
+
+	if opts.DirectPodIP {
+		if opts.Host != "" {
+			return errors.New("callOptions: Host and DirectPodIP are mutually exclusive")
+		}
+		workloads, err := opts.Target.Workloads()
+		if err != nil {
+			return fmt.Errorf("callOptions: failed to get workloads of Target: %v", err)
+		}
+		if len(workloads) == 0 {
+			return errors.New("callOptions: DirectPodIP requires at least one workload of Target")
+		}
+		opts.Host = workloads[0].Address()
+	}
+
 	if opts.Host == "" {
 		// No host specified, use the fully qualified domain name for the service.
 		opts.Host = opts.Target.Config().FQDN()
@@ -151,10 +315,21 @@ func fillInCallOptions(opts *echo.CallOptions) error {
 		opts.Timeout = common.DefaultRequestTimeout
 	}
 
+	if len(opts.HeaderSets) > 0 {
+		if opts.StreamMessages > 0 {
+			return errors.New("callOptions: HeaderSets and StreamMessages are mutually exclusive")
+		}
+		opts.Count = len(opts.HeaderSets)
+	}
+
 	if opts.Count <= 0 {
 		opts.Count = common.DefaultCount
 	}
 
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 1
+	}
+
 	return nil
 }
 
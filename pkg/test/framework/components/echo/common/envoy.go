@@ -15,12 +15,16 @@
 package common
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	envoyAdmin "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
 	"github.com/golang/protobuf/jsonpb"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
 
 	"istio.io/istio/pkg/test/framework/components/echo"
 	"istio.io/istio/pkg/test/util/retry"
@@ -136,6 +140,38 @@ func CheckOutboundConfig(source echo.Instance, target echo.Instance, port echo.P
 	return nil
 }
 
+// ParseStats parses the JSON output of the Envoy admin /stats endpoint into a map of stat name to
+// value. Histograms are ignored.
+func ParseStats(statsJSON string) (map[string]int, error) {
+	out := struct {
+		Stats []struct {
+			Name  string `json:"name"`
+			Value *int   `json:"value"`
+		} `json:"stats"`
+	}{}
+	if err := json.Unmarshal([]byte(statsJSON), &out); err != nil {
+		return nil, fmt.Errorf("failed parsing Envoy stats: %v", err)
+	}
+	stats := make(map[string]int, len(out.Stats))
+	for _, s := range out.Stats {
+		if s.Value != nil {
+			stats[s.Name] = *s.Value
+		}
+	}
+	return stats, nil
+}
+
+// ParsePrometheusStats parses the output of the Envoy admin /stats/prometheus endpoint into a map of
+// metric name to metric family.
+func ParsePrometheusStats(stats string) (map[string]*dto.MetricFamily, error) {
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(strings.NewReader(stats))
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing Envoy Prometheus stats: %v", err)
+	}
+	return families, nil
+}
+
 func clusterName(target echo.Instance, port echo.Port) string {
 	cfg := target.Config()
 	return fmt.Sprintf("outbound|%d||%s.%s.svc.%s", port.ServicePort, cfg.Service, cfg.Namespace.Name(), cfg.Domain)
@@ -19,7 +19,9 @@ import (
 	"context"
 	"fmt"
 	"io/ioutil"
+	"reflect"
 	"testing"
+	"time"
 
 	envoyAdmin "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
 	"github.com/golang/protobuf/jsonpb"
@@ -27,6 +29,7 @@ import (
 	"istio.io/istio/pkg/config/protocol"
 	"istio.io/istio/pkg/test"
 	"istio.io/istio/pkg/test/echo/client"
+	echoCommon "istio.io/istio/pkg/test/echo/common"
 	"istio.io/istio/pkg/test/echo/proto"
 	"istio.io/istio/pkg/test/framework/components/echo"
 	"istio.io/istio/pkg/test/framework/components/echo/common"
@@ -110,7 +113,55 @@ func TestCheckOutboundConfig(t *testing.T) {
 }
 
 var _ echo.Instance = &testConfig{}
-var _ echo.Workload = &testConfig{}
+var _ echo.Workload = &testWorkload{}
+
+func TestParseStats(t *testing.T) {
+	statsJSON := `{"stats":[
+		{"name":"http.inbound_0.0.0.0_8090.rbac.allowed","value":3},
+		{"name":"http.inbound_0.0.0.0_8090.rbac.denied","value":0},
+		{"histograms":{"supported_quantiles":[0,25,50]}}
+	]}`
+	stats, err := common.ParseStats(statsJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{
+		"http.inbound_0.0.0.0_8090.rbac.allowed": 3,
+		"http.inbound_0.0.0.0_8090.rbac.denied":  0,
+	}
+	if !reflect.DeepEqual(stats, want) {
+		t.Fatalf("got %v, want %v", stats, want)
+	}
+
+	if _, err := common.ParseStats("not json"); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestParsePrometheusStats(t *testing.T) {
+	stats := `# TYPE istio_requests_total counter
+istio_requests_total{reporter="destination",response_code="403",source_principal="spiffe://cluster.local/ns/ns/sa/a"} 2
+istio_requests_total{reporter="destination",response_code="200",source_principal="spiffe://cluster.local/ns/ns/sa/a"} 5
+`
+	families, err := common.ParsePrometheusStats(stats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	family, ok := families["istio_requests_total"]
+	if !ok {
+		t.Fatalf("istio_requests_total not found in %v", families)
+	}
+	if got := len(family.Metric); got != 2 {
+		t.Fatalf("got %d series, want 2", got)
+	}
+	if got := family.Metric[0].GetCounter().GetValue(); got != 2 {
+		t.Fatalf("got %v, want 2", got)
+	}
+
+	if _, err := common.ParsePrometheusStats("not prometheus {"); err == nil {
+		t.Fatal("expected error")
+	}
+}
 
 type testConfig struct {
 	protocol    protocol.Instance
@@ -153,7 +204,7 @@ func (e *testConfig) Config() echo.Config {
 }
 
 func (e *testConfig) Workloads() ([]echo.Workload, error) {
-	return []echo.Workload{e}, nil
+	return []echo.Workload{&testWorkload{e}}, nil
 }
 
 func (*testConfig) ID() resource.ID {
@@ -207,3 +258,37 @@ func (*testConfig) Logs() (string, error) {
 func (*testConfig) LogsOrFail(_ test.Failer) string {
 	panic("not implemented")
 }
+
+func (*testConfig) RequestCount(int) (int, error) {
+	panic("not implemented")
+}
+
+func (*testConfig) RequestCountOrFail(test.Failer, int) int {
+	panic("not implemented")
+}
+
+func (*testConfig) ReceivedRequests(time.Time, ...echo.ReceivedRequestFilter) ([]echoCommon.ReceivedRequest, error) {
+	panic("not implemented")
+}
+
+func (*testConfig) ReceivedRequestsOrFail(test.Failer, time.Time,
+	...echo.ReceivedRequestFilter) []echoCommon.ReceivedRequest {
+	panic("not implemented")
+}
+
+func (*testConfig) ClearReceivedRequests() error {
+	panic("not implemented")
+}
+
+func (*testConfig) ClearReceivedRequestsOrFail(test.Failer) {
+	panic("not implemented")
+}
+
+// testWorkload is the workload of a testConfig, whose ReceivedRequests differs from the one of echo.Instance.
+type testWorkload struct {
+	*testConfig
+}
+
+func (*testWorkload) ReceivedRequests(time.Time) ([]echoCommon.ReceivedRequest, error) {
+	panic("not implemented")
+}
@@ -0,0 +1,68 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+
+	"istio.io/istio/pkg/test/framework/components/echo"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+const readinessCheckDelay = time.Second
+
+// WaitUntilReady waits, in parallel, until the custom ReadinessCheck of each of the given instances
+// passes. Instances without a ReadinessCheck are skipped.
+func WaitUntilReady(instances ...echo.Instance) error {
+	wg := sync.WaitGroup{}
+	aggregateErrMux := &sync.Mutex{}
+	var aggregateErr error
+	for _, inst := range instances {
+		check := inst.Config().ReadinessCheck
+		if check == nil {
+			continue
+		}
+		wg.Add(1)
+
+		inst := inst
+		go func() {
+			defer wg.Done()
+
+			_, err := retry.Do(func() (result interface{}, completed bool, err error) {
+				ready, err := check(inst)
+				if err != nil {
+					return nil, false, err
+				}
+				if !ready {
+					return nil, false, fmt.Errorf("%s is not ready", inst.Config().Service)
+				}
+				return nil, true, nil
+			}, retry.Delay(readinessCheckDelay), retry.Timeout(inst.Config().ReadinessTimeout))
+			if err != nil {
+				aggregateErrMux.Lock()
+				aggregateErr = multierror.Append(aggregateErr, fmt.Errorf("readiness check for %s: %v",
+					inst.Config().Service, err))
+				aggregateErrMux.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	return aggregateErr
+}
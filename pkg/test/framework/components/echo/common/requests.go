@@ -0,0 +1,53 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"sort"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+
+	echoCommon "istio.io/istio/pkg/test/echo/common"
+	"istio.io/istio/pkg/test/framework/components/echo"
+)
+
+// ReceivedRequests returns the requests received by the given workloads after since and matching all the
+// filters, ordered by time, for the implementation of echo.Instance.ReceivedRequests.
+func ReceivedRequests(workloads []echo.Workload, since time.Time,
+	filters ...echo.ReceivedRequestFilter) ([]echoCommon.ReceivedRequest, error) {
+	var out []echoCommon.ReceivedRequest
+	for _, w := range workloads {
+		requests, err := w.ReceivedRequests(since)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, echo.FilterReceivedRequests(requests, filters...)...)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Time.Before(out[j].Time)
+	})
+	return out, nil
+}
+
+// ClearReceivedRequests clears the requests received by all the given workloads, for the implementation of
+// echo.Instance.ClearReceivedRequests.
+func ClearReceivedRequests(workloads []echo.Workload) error {
+	var err error
+	for _, w := range workloads {
+		err = multierror.Append(err, w.ClearReceivedRequests()).ErrorOrNil()
+	}
+	return err
+}
@@ -25,6 +25,16 @@ import (
 	"istio.io/istio/pkg/test/framework/resource"
 )
 
+// WorkloadKind is the kind of the Kubernetes workloads backing an echo instance.
+type WorkloadKind string
+
+const (
+	// DeploymentKind backs the instance with Deployments.
+	DeploymentKind WorkloadKind = "Deployment"
+	// StatefulSetKind backs the instance with StatefulSets, whose pods are named <service>-<version>-<ordinal>.
+	StatefulSetKind WorkloadKind = "StatefulSet"
+)
+
 // Config defines the options for creating an Echo component.
 // nolint: maligned
 type Config struct {
@@ -52,6 +62,11 @@ type Config struct {
 	// Headless (k8s only) indicates that no ClusterIP should be specified.
 	Headless bool
 
+	// WorkloadKind (k8s only) is the kind of the workloads of the subsets. If not provided, Deployment
+	// is used. A StatefulSet gives its pods stable names, the ordinal pod names, which are resolvable
+	// when the instance is also Headless.
+	WorkloadKind WorkloadKind
+
 	// ServiceAccount (k8s only) indicates that a service account should be created
 	// for the deployment.
 	ServiceAccount bool
@@ -75,6 +90,11 @@ type Config struct {
 	// become ready.
 	ReadinessTimeout time.Duration
 
+	// ReadinessCheck (optional) is an additional readiness predicate for the instance. Once the
+	// default readiness checks pass, Build waits until it reports the instance as ready or the
+	// ReadinessTimeout elapses.
+	ReadinessCheck ReadinessCheck
+
 	// Subsets contains the list of Subsets config belonging to this echo
 	// service instance.
 	Subsets []SubsetConfig
@@ -84,6 +104,10 @@ type Config struct {
 
 	// TLS settings for echo server
 	TLSSettings *common.TLSSettings
+
+	// UDSServer (k8s only), if set, is the path of a unix domain socket the application also serves HTTP
+	// on. It is only reachable from the workload itself, see CallOptions.Loopback.
+	UDSServer string
 }
 
 // SubsetConfig is the config for a group of Subsets (e.g. Kubernetes deployment).
@@ -92,6 +116,8 @@ type SubsetConfig struct {
 	Version string
 	// Annotations provides metadata hints for deployment of the instance.
 	Annotations Annotations
+	// Replicas (k8s only) is the number of pods of the deployment. If not provided, 1 is used.
+	Replicas int
 	// TODO: port more into workload config.
 }
 
@@ -112,6 +138,22 @@ func (c Config) FQDN() string {
 	return out
 }
 
+// WorkloadCount returns the number of workloads deployed for the instance, summed over the subsets.
+func (c Config) WorkloadCount() int {
+	if len(c.Subsets) == 0 {
+		return 1
+	}
+	count := 0
+	for _, s := range c.Subsets {
+		if s.Replicas > 0 {
+			count += s.Replicas
+		} else {
+			count++
+		}
+	}
+	return count
+}
+
 // ClusterIndex returns the index of the cluster or 0 (the default) if none specified.
 func (c Config) ClusterIndex() resource.ClusterIndex {
 	if c.Cluster != nil {
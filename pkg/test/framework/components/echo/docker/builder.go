@@ -21,6 +21,7 @@ import (
 
 	"istio.io/istio/pkg/test"
 	"istio.io/istio/pkg/test/framework/components/echo"
+	"istio.io/istio/pkg/test/framework/components/echo/common"
 	"istio.io/istio/pkg/test/framework/resource"
 )
 
@@ -54,6 +55,10 @@ func (b *builder) Build() error {
 		return err
 	}
 
+	if err := common.WaitUntilReady(instances...); err != nil {
+		return err
+	}
+
 	// Success... update the caller's references.
 	for i, inst := range instances {
 		*b.references[i] = inst
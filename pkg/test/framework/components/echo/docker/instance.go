@@ -26,6 +26,7 @@ import (
 	"istio.io/istio/pkg/config/protocol"
 	"istio.io/istio/pkg/test"
 	appEcho "istio.io/istio/pkg/test/echo/client"
+	echoCommon "istio.io/istio/pkg/test/echo/common"
 	"istio.io/istio/pkg/test/framework/components/echo"
 	"istio.io/istio/pkg/test/framework/components/echo/common"
 	"istio.io/istio/pkg/test/framework/components/environment/native"
@@ -215,6 +216,34 @@ func (i *instance) CallOrFail(t test.Failer, opts echo.CallOptions) appEcho.Pars
 	return r
 }
 
+func (i *instance) ReceivedRequests(since time.Time,
+	filters ...echo.ReceivedRequestFilter) ([]echoCommon.ReceivedRequest, error) {
+	workloads, _ := i.Workloads()
+	return common.ReceivedRequests(workloads, since, filters...)
+}
+
+func (i *instance) ReceivedRequestsOrFail(t test.Failer, since time.Time,
+	filters ...echo.ReceivedRequestFilter) []echoCommon.ReceivedRequest {
+	t.Helper()
+	out, err := i.ReceivedRequests(since, filters...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func (i *instance) ClearReceivedRequests() error {
+	workloads, _ := i.Workloads()
+	return common.ClearReceivedRequests(workloads)
+}
+
+func (i *instance) ClearReceivedRequestsOrFail(t test.Failer) {
+	t.Helper()
+	if err := i.ClearReceivedRequests(); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func (i *instance) Dump() {
 	scopes.CI.Errorf("=== Dumping state for Echo %s..,", i.cfg.FQDN())
 
@@ -24,6 +24,7 @@ import (
 	"github.com/golang/protobuf/jsonpb"
 	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes"
+	dto "github.com/prometheus/client_model/go"
 
 	"istio.io/istio/pkg/test"
 	"istio.io/istio/pkg/test/docker"
@@ -156,14 +157,64 @@ func (s *sidecar) ListenersOrFail(t test.Failer) *envoyAdmin.Listeners {
 	return listeners
 }
 
-func (s *sidecar) adminRequest(path string, out proto.Message) error {
+func (s *sidecar) Stats() (map[string]int, error) {
+	response, err := s.adminRequestRaw("stats?format=json")
+	if err != nil {
+		return nil, err
+	}
+	return common.ParseStats(response)
+}
+
+func (s *sidecar) StatsOrFail(t test.Failer) map[string]int {
+	t.Helper()
+	stats, err := s.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stats
+}
+
+func (s *sidecar) PrometheusStats() (map[string]*dto.MetricFamily, error) {
+	response, err := s.adminRequestRaw("stats/prometheus")
+	if err != nil {
+		return nil, err
+	}
+	return common.ParsePrometheusStats(response)
+}
+
+func (s *sidecar) PrometheusStatsOrFail(t test.Failer) map[string]*dto.MetricFamily {
+	t.Helper()
+	stats, err := s.PrometheusStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stats
+}
+
+func (s *sidecar) adminRequestRaw(path string) (string, error) {
+	result, err := s.adminExec(path)
+	if err != nil {
+		return "", err
+	}
+	return string(result.StdOut), nil
+}
+
+func (s *sidecar) adminExec(path string) (docker.ExecResult, error) {
 	// Exec onto the pod and make a curl request to the admin port, writing
 	arg := fmt.Sprintf("http://%s:%d/%s", localhost, proxyAdminPort, path)
 	result, err := s.container.Exec(context.Background(), "curl", arg)
 	if err != nil {
-		return fmt.Errorf("failed exec on container %s: %v. Command: curl %s. Output:\n%+v",
+		return result, fmt.Errorf("failed exec on container %s: %v. Command: curl %s. Output:\n%+v",
 			s.container.Name, err, arg, result)
 	}
+	return result, nil
+}
+
+func (s *sidecar) adminRequest(path string, out proto.Message) error {
+	result, err := s.adminExec(path)
+	if err != nil {
+		return err
+	}
 
 	jspb := jsonpb.Unmarshaler{AllowUnknownFields: true}
 	if err := jspb.Unmarshal(bytes.NewReader(result.StdOut), out); err != nil {
@@ -16,6 +16,7 @@ package docker
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -33,6 +34,7 @@ import (
 	"istio.io/api/mesh/v1alpha1"
 	"istio.io/istio/pkg/test/docker"
 	"istio.io/istio/pkg/test/echo/client"
+	"istio.io/istio/pkg/test/echo/common"
 	"istio.io/istio/pkg/test/envoy"
 	"istio.io/istio/pkg/test/framework/components/echo"
 	"istio.io/istio/pkg/test/framework/components/echo/docker/images"
@@ -327,3 +329,24 @@ func (w *workload) LogsOrFail(t test.Failer) string {
 	}
 	return logs
 }
+
+func (w *workload) RequestCount(int) (int, error) {
+	return 0, errors.New("request count is not supported in the native environment")
+}
+
+func (w *workload) RequestCountOrFail(t test.Failer, port int) int {
+	t.Helper()
+	count, err := w.RequestCount(port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+func (w *workload) ReceivedRequests(time.Time) ([]common.ReceivedRequest, error) {
+	return nil, errors.New("received requests are not supported in the native environment")
+}
+
+func (w *workload) ClearReceivedRequests() error {
+	return errors.New("received requests are not supported in the native environment")
+}
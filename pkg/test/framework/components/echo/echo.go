@@ -16,12 +16,15 @@ package echo
 
 import (
 	"context"
+	"time"
 
 	envoyAdmin "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
+	dto "github.com/prometheus/client_model/go"
 
 	"istio.io/istio/pkg/config/protocol"
 	"istio.io/istio/pkg/test"
 	"istio.io/istio/pkg/test/echo/client"
+	echoCommon "istio.io/istio/pkg/test/echo/common"
 	"istio.io/istio/pkg/test/echo/proto"
 	"istio.io/istio/pkg/test/framework/resource"
 	"istio.io/istio/pkg/test/util/retry"
@@ -32,7 +35,8 @@ import (
 //
 //     1. Are ready to receive traffic, and
 //     2. Can call every other Instance in the group (i.e. have received Envoy config
-//        from Pilot).
+//        from Pilot), and
+//     3. Pass their custom ReadinessCheck, if one was configured.
 //
 // If a test needs to verify that one Instance is NOT reachable from another, there are
 // a couple of options:
@@ -77,6 +81,17 @@ type Instance interface {
 	// Call makes a call from this Instance to a target Instance.
 	Call(options CallOptions) (client.ParsedResponses, error)
 	CallOrFail(t test.Failer, options CallOptions) client.ParsedResponses
+
+	// ReceivedRequests returns the requests received by the HTTP ports of all the workloads of this Instance
+	// after since and matching all the filters, ordered by time. Each workload only keeps its last
+	// echoCommon.RequestLogSize requests.
+	ReceivedRequests(since time.Time, filters ...ReceivedRequestFilter) ([]echoCommon.ReceivedRequest, error)
+	ReceivedRequestsOrFail(t test.Failer, since time.Time, filters ...ReceivedRequestFilter) []echoCommon.ReceivedRequest
+
+	// ClearReceivedRequests clears the requests received by all the workloads of this Instance, e.g. to scope
+	// the assertions on ReceivedRequests to a test case.
+	ClearReceivedRequests() error
+	ClearReceivedRequestsOrFail(t test.Failer)
 }
 
 // Workload port exposed by an Echo instance
@@ -127,6 +142,17 @@ type Workload interface {
 	Logs() (string, error)
 	// LogsOrFail returns the logs for the app container, or aborts if an error is found
 	LogsOrFail(t test.Failer) string
+
+	// RequestCount returns the number of requests received by the app container on the given instance port.
+	RequestCount(port int) (int, error)
+	RequestCountOrFail(t test.Failer, port int) int
+
+	// ReceivedRequests returns the requests received by the HTTP ports of the app container after since,
+	// oldest first, from its log of the last echoCommon.RequestLogSize requests.
+	ReceivedRequests(since time.Time) ([]echoCommon.ReceivedRequest, error)
+
+	// ClearReceivedRequests clears the log of the requests received by the app container.
+	ClearReceivedRequests() error
 }
 
 // Sidecar provides an interface to execute queries against a single Envoy sidecar.
@@ -156,6 +182,15 @@ type Sidecar interface {
 	Listeners() (*envoyAdmin.Listeners, error)
 	ListenersOrFail(t test.Failer) *envoyAdmin.Listeners
 
+	// Stats returns the counters and gauges of the Envoy instance, keyed by stat name.
+	Stats() (map[string]int, error)
+	StatsOrFail(t test.Failer) map[string]int
+
+	// PrometheusStats returns the metrics of the Envoy instance in the Prometheus format, keyed by
+	// metric name. Unlike Stats, this includes the labels of the Istio standard metrics.
+	PrometheusStats() (map[string]*dto.MetricFamily, error)
+	PrometheusStatsOrFail(t test.Failer) map[string]*dto.MetricFamily
+
 	// Logs returns the logs for the sidecar container
 	Logs() (string, error)
 	// LogsOrFail returns the logs for the sidecar container, or aborts if an error is found
@@ -22,10 +22,12 @@ import (
 
 	"istio.io/istio/pkg/test"
 	"istio.io/istio/pkg/test/framework/components/echo"
+	"istio.io/istio/pkg/test/framework/components/echo/common"
 	"istio.io/istio/pkg/test/framework/resource"
 	"istio.io/istio/pkg/test/util/retry"
 
 	kubeCore "k8s.io/api/core/v1"
+	kubeApiMeta "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 var _ echo.Builder = &builder{}
@@ -62,6 +64,10 @@ func (b *builder) Build() error {
 		return fmt.Errorf("wait until callable: %v", err)
 	}
 
+	if err := common.WaitUntilReady(instances...); err != nil {
+		return fmt.Errorf("wait until ready: %v", err)
+	}
+
 	// Success... update the caller's references.
 	for i, inst := range instances {
 		*b.references[i] = inst
@@ -101,6 +107,7 @@ func (b *builder) initializeInstances(instances []echo.Instance) error {
 		serviceName := inst.Config().Service
 		serviceNamespace := inst.Config().Namespace.Name()
 		timeout := inst.Config().ReadinessTimeout
+		workloadCount := inst.Config().WorkloadCount()
 		cluster := inst.(*instance).cluster
 
 		// Run the waits in parallel.
@@ -110,6 +117,19 @@ func (b *builder) initializeInstances(instances []echo.Instance) error {
 			// Wait until all the endpoints are ready for this service
 			_, endpoints, err := cluster.WaitUntilServiceEndpointsAreReady(
 				serviceNamespace, serviceName, retry.Timeout(timeout))
+			if err == nil && readyAddressCount(endpoints) < workloadCount {
+				// The endpoints of pods not created yet are not listed as not ready: wait until there
+				// is one for each replica.
+				err = retry.UntilSuccess(func() error {
+					if endpoints, err = cluster.GetEndpoints(serviceNamespace, serviceName, kubeApiMeta.GetOptions{}); err != nil {
+						return err
+					}
+					if n := readyAddressCount(endpoints); n < workloadCount {
+						return fmt.Errorf("%s/%s: %d of %d endpoints ready", serviceNamespace, serviceName, n, workloadCount)
+					}
+					return nil
+				}, retry.Timeout(timeout))
+			}
 			if err != nil {
 				aggregateErrMux.Lock()
 				aggregateErr = multierror.Append(aggregateErr, err)
@@ -158,3 +178,11 @@ func (b *builder) waitUntilAllCallable(instances []echo.Instance) error {
 
 	return aggregateErr
 }
+
+func readyAddressCount(endpoints *kubeCore.Endpoints) int {
+	count := 0
+	for _, subset := range endpoints.Subsets {
+		count += len(subset.Addresses)
+	}
+	return count
+}
@@ -65,11 +65,14 @@ spec:
 {{- $cluster := .Cluster }}
 {{- range $i, $subset := $subsets }}
 apiVersion: apps/v1
-kind: Deployment
+kind: {{ $.WorkloadKind }}
 metadata:
   name: {{ $.Service }}-{{ $subset.Version }}
 spec:
-  replicas: 1
+{{- if eq $.WorkloadKind "StatefulSet" }}
+  serviceName: {{ $.Service }}
+{{- end }}
+  replicas: {{ if $subset.Replicas }}{{ $subset.Replicas }}{{ else }}1{{ end }}
   selector:
     matchLabels:
       app: {{ $.Service }}
@@ -108,6 +111,10 @@ spec:
           - --metrics=15014
           - --cluster
           - "{{ $cluster }}"
+{{- if ne $.Locality "" }}
+          - --locality
+          - "{{ $.Locality }}"
+{{- end }}
 {{- range $i, $p := $.ContainerPorts }}
 {{- if eq .Protocol "GRPC" }}
           - --grpc
@@ -131,6 +138,10 @@ spec:
 {{- if $p.TLS }}
           - --tls={{ $p.Port }}
 {{- end }}
+{{- end }}
+{{- if $.UDSServer }}
+          - --uds
+          - "{{ $.UDSServer }}"
 {{- end }}
           - --version
           - "{{ $subset.Version }}"
@@ -228,6 +239,11 @@ func generateYAMLWithSettings(cfg echo.Config, settings *image.Settings) (servic
 		}
 	}
 
+	workloadKind := cfg.WorkloadKind
+	if workloadKind == "" {
+		workloadKind = echo.DeploymentKind
+	}
+
 	params := map[string]interface{}{
 		"Hub":                 settings.Hub,
 		"Tag":                 settings.Tag,
@@ -235,6 +251,7 @@ func generateYAMLWithSettings(cfg echo.Config, settings *image.Settings) (servic
 		"Service":             cfg.Service,
 		"Version":             cfg.Version,
 		"Headless":            cfg.Headless,
+		"WorkloadKind":        workloadKind,
 		"Locality":            cfg.Locality,
 		"ServiceAccount":      cfg.ServiceAccount,
 		"Ports":               cfg.Ports,
@@ -244,6 +261,7 @@ func generateYAMLWithSettings(cfg echo.Config, settings *image.Settings) (servic
 		"IncludeInboundPorts": cfg.IncludeInboundPorts,
 		"Subsets":             cfg.Subsets,
 		"TLSSettings":         cfg.TLSSettings,
+		"UDSServer":           cfg.UDSServer,
 		"Cluster":             cfg.ClusterIndex(),
 	}
 
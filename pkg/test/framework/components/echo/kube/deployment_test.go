@@ -53,6 +53,50 @@ func TestDeploymentYAML(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:         "replicas",
+			wantFilePath: "testdata/replicas.yaml",
+			config: echo.Config{
+				Service: "foo",
+				Ports: []echo.Port{
+					{
+						Name:         "http",
+						Protocol:     protocol.HTTP,
+						InstancePort: 8090,
+						ServicePort:  8090,
+					},
+				},
+				Subsets: []echo.SubsetConfig{
+					{
+						Version:  "bar",
+						Replicas: 2,
+					},
+				},
+			},
+		},
+		{
+			name:         "statefulset",
+			wantFilePath: "testdata/statefulset.yaml",
+			config: echo.Config{
+				Service:      "foo",
+				Headless:     true,
+				WorkloadKind: echo.StatefulSetKind,
+				Ports: []echo.Port{
+					{
+						Name:         "http",
+						Protocol:     protocol.HTTP,
+						InstancePort: 8090,
+						ServicePort:  8090,
+					},
+				},
+				Subsets: []echo.SubsetConfig{
+					{
+						Version:  "bar",
+						Replicas: 2,
+					},
+				},
+			},
+		},
 		{
 			name:         "two-workloads-one-nosidecar",
 			wantFilePath: "testdata/two-workloads-one-nosidecar.yaml",
@@ -18,8 +18,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"sort"
 	"strings"
+	"time"
 
+	envoyAdmin "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
 	"github.com/hashicorp/go-multierror"
 
 	"istio.io/istio/pkg/config/constants"
@@ -31,14 +34,20 @@ import (
 	"istio.io/istio/pkg/test/framework/components/echo/common"
 	kubeEnv "istio.io/istio/pkg/test/framework/components/environment/kube"
 	"istio.io/istio/pkg/test/framework/resource"
+	"istio.io/istio/pkg/test/kube"
+	"istio.io/istio/pkg/test/util/retry"
 
 	kubeCore "k8s.io/api/core/v1"
+	kubeApiMeta "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 const (
 	tcpHealthPort     = 3333
 	httpReadinessPort = 8080
 	defaultDomain     = constants.DefaultKubernetesDomain
+
+	// sidecarQuitURL is the endpoint of the status server of the agent of the sidecars making it exit.
+	sidecarQuitURL = "http://localhost:15020/quitquitquit"
 )
 
 var (
@@ -122,6 +131,50 @@ func newInstance(ctx resource.Context, cfg echo.Config) (out *instance, err erro
 	return c, nil
 }
 
+// Attach returns an Instance for the echo service of cfg already deployed in the cluster, e.g. kept by a
+// test run with -istio.test.nocleanup, to call it outside of the lifecycle of the framework. cfg must be
+// the config the service was deployed with, including its Namespace. The Instance is not tracked by any
+// resource.Context: the caller must close it, as an io.Closer.
+func Attach(cluster kubeEnv.Cluster, cfg echo.Config) (echo.Instance, error) {
+	if cfg.Namespace == nil {
+		return nil, fmt.Errorf("no namespace for echo service %s", cfg.Service)
+	}
+	common.AddPortIfMissing(&cfg, protocol.GRPC)
+	// The context is only used to create a missing namespace.
+	if err := common.FillInDefaults(nil, defaultDomain, &cfg); err != nil {
+		return nil, err
+	}
+
+	c := &instance{
+		cfg:     cfg,
+		cluster: cluster,
+	}
+	grpcPort := common.GetPortForProtocol(&cfg, protocol.GRPC)
+	if grpcPort == nil {
+		return nil, errors.New("unable fo find GRPC command port")
+	}
+	c.grpcPort = uint16(grpcPort.InstancePort)
+	if grpcPort.TLS {
+		c.tls = cfg.TLSSettings
+	}
+
+	s, err := cluster.GetService(cfg.Namespace.Name(), cfg.Service)
+	if err != nil {
+		return nil, err
+	}
+	if s.Spec.ClusterIP != kubeCore.ClusterIPNone {
+		c.clusterIP = s.Spec.ClusterIP
+	}
+	endpoints, err := cluster.GetEndpoints(cfg.Namespace.Name(), cfg.Service, kubeApiMeta.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if err := c.initialize(endpoints); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
 // getContainerPorts converts the ports to a port list of container ports.
 // Adds ports for health/readiness if necessary.
 func getContainerPorts(ports []echo.Port) echoCommon.PortList {
@@ -195,6 +248,202 @@ func (c *instance) WorkloadsOrFail(t test.Failer) []echo.Workload {
 	return out
 }
 
+// Pods returns the current state of the pods of the workloads of the given instance, which must have been
+// deployed to Kubernetes.
+func Pods(i echo.Instance) ([]kubeCore.Pod, error) {
+	c, ok := i.(*instance)
+	if !ok {
+		return nil, fmt.Errorf("echo %s is not deployed to Kubernetes", i.Config().Service)
+	}
+	pods := make([]kubeCore.Pod, 0, len(c.workloads))
+	for _, w := range c.workloads {
+		pod, err := c.cluster.GetPod(w.pod.Namespace, w.pod.Name)
+		if err != nil {
+			return nil, err
+		}
+		pods = append(pods, pod)
+	}
+	return pods, nil
+}
+
+// PodHostname returns the stable DNS name of the pod of the given workload, <pod>.<service>.<namespace>.svc.<domain>,
+// which only resolves for the pods of a StatefulSet behind a Headless service.
+func PodHostname(i echo.Instance, w echo.Workload) (string, error) {
+	c, ok := i.(*instance)
+	if !ok {
+		return "", fmt.Errorf("echo %s is not deployed to Kubernetes", i.Config().Service)
+	}
+	if c.cfg.WorkloadKind != echo.StatefulSetKind || !c.cfg.Headless {
+		return "", fmt.Errorf("echo %s is not a headless StatefulSet, its pods have no stable DNS name", c.cfg.Service)
+	}
+	for _, cw := range c.workloads {
+		if cw == w {
+			return fmt.Sprintf("%s.%s", cw.pod.Name, c.cfg.FQDN()), nil
+		}
+	}
+	return "", fmt.Errorf("workload %s is not a workload of echo %s", w.Address(), c.cfg.Service)
+}
+
+// PodConditions returns the current conditions of the pods of the workloads of the given instance, keyed
+// by pod name.
+func PodConditions(i echo.Instance) (map[string][]kubeCore.PodCondition, error) {
+	pods, err := Pods(i)
+	if err != nil {
+		return nil, err
+	}
+	conditions := make(map[string][]kubeCore.PodCondition, len(pods))
+	for _, pod := range pods {
+		conditions[pod.Name] = pod.Status.Conditions
+	}
+	return conditions, nil
+}
+
+// RestartSidecars restarts the sidecar of each workload of the given instance, which must have been deployed
+// to Kubernetes, by asking its agent to quit, so that the container is restarted by the kubelet. It waits
+// until each sidecar has been restarted and its pod is ready again, then until the ReadinessCheck of the
+// instance passes, if any, so that the calls made afterwards go through the reprogrammed proxies.
+func RestartSidecars(i echo.Instance) error {
+	c, ok := i.(*instance)
+	if !ok {
+		return fmt.Errorf("echo %s is not deployed to Kubernetes", i.Config().Service)
+	}
+	for _, w := range c.workloads {
+		if w.sidecar == nil {
+			continue
+		}
+		ns, name := w.pod.Namespace, w.pod.Name
+		restarts, err := proxyRestartCount(c.cluster, ns, name)
+		if err != nil {
+			return err
+		}
+		// The agent may exit before the response is written, so the error of the request is not relevant:
+		// the restart count tells whether it quit.
+		_, _ = c.cluster.Exec(ns, name, proxyContainerName, "curl -fs -X POST "+sidecarQuitURL)
+		_, err = retry.Do(func() (interface{}, bool, error) {
+			count, err := proxyRestartCount(c.cluster, ns, name)
+			if err != nil {
+				return nil, false, err
+			}
+			if count <= restarts {
+				return nil, false, fmt.Errorf("sidecar of %s/%s not restarted yet", ns, name)
+			}
+			pod, err := c.cluster.GetPod(ns, name)
+			if err != nil {
+				return nil, false, err
+			}
+			if err := kube.CheckPodReady(&pod); err != nil {
+				return nil, false, fmt.Errorf("pod %s/%s: %v", ns, name, err)
+			}
+			return nil, true, nil
+		}, retry.Delay(time.Second), retry.Timeout(c.cfg.ReadinessTimeout))
+		if err != nil {
+			return fmt.Errorf("failed restarting the sidecar of %s/%s: %v", ns, name, err)
+		}
+	}
+	return common.WaitUntilReady(i)
+}
+
+// RestartSidecarsOrFail calls RestartSidecars and fails the test on error.
+func RestartSidecarsOrFail(t test.Failer, i echo.Instance) {
+	t.Helper()
+	if err := RestartSidecars(i); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// proxyRestartCount returns the number of restarts of the sidecar container of the given pod.
+func proxyRestartCount(cluster kubeEnv.Cluster, ns, name string) (int32, error) {
+	pod, err := cluster.GetPod(ns, name)
+	if err != nil {
+		return 0, err
+	}
+	for _, s := range pod.Status.ContainerStatuses {
+		if s.Name == proxyContainerName {
+			return s.RestartCount, nil
+		}
+	}
+	return 0, fmt.Errorf("no %s container in pod %s/%s", proxyContainerName, ns, name)
+}
+
+// WorkloadCertSerials returns the serial number of the workload certificate, the one with a SPIFFE identity,
+// served by the sidecar of each workload of the given instance, which must have been deployed to Kubernetes,
+// keyed by pod name. The workloads without sidecar are skipped.
+func WorkloadCertSerials(i echo.Instance) (map[string]string, error) {
+	c, ok := i.(*instance)
+	if !ok {
+		return nil, fmt.Errorf("echo %s is not deployed to Kubernetes", i.Config().Service)
+	}
+	serials := make(map[string]string, len(c.workloads))
+	for _, w := range c.workloads {
+		if w.sidecar == nil {
+			continue
+		}
+		certs := &envoyAdmin.Certificates{}
+		if err := w.sidecar.adminRequest("certs", certs); err != nil {
+			return nil, err
+		}
+		serial := workloadCertSerial(certs)
+		if serial == "" {
+			return nil, fmt.Errorf("no workload certificate served by the sidecar of %s/%s", w.pod.Namespace, w.pod.Name)
+		}
+		serials[w.pod.Name] = serial
+	}
+	return serials, nil
+}
+
+// workloadCertSerial returns the serial number of the first certificate with a SPIFFE URI SAN, or "" if none.
+func workloadCertSerial(certs *envoyAdmin.Certificates) string {
+	for _, cert := range certs.Certificates {
+		for _, details := range cert.CertChain {
+			for _, san := range details.SubjectAltNames {
+				if strings.HasPrefix(san.GetUri(), "spiffe://") {
+					return details.SerialNumber
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// RotateWorkloadCerts rotates the workload certificate of each workload of the given instance, which must
+// have been deployed to Kubernetes, by restarting its sidecar, as RestartSidecars, whose agent then requests
+// a new certificate for a new key. It waits until each sidecar serves a certificate with another serial
+// number, so that the calls made afterwards use the new identities, and returns the serial numbers before
+// and after the rotation, keyed by pod name.
+func RotateWorkloadCerts(i echo.Instance) (before, after map[string]string, err error) {
+	if before, err = WorkloadCertSerials(i); err != nil {
+		return nil, nil, err
+	}
+	if err = RestartSidecars(i); err != nil {
+		return nil, nil, err
+	}
+	_, err = retry.Do(func() (interface{}, bool, error) {
+		if after, err = WorkloadCertSerials(i); err != nil {
+			return nil, false, err
+		}
+		for pod, serial := range before {
+			if after[pod] == serial {
+				return nil, false, fmt.Errorf("certificate of %s not rotated yet, serial number %s", pod, serial)
+			}
+		}
+		return nil, true, nil
+	}, retry.Delay(time.Second), retry.Timeout(i.Config().ReadinessTimeout))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed rotating the certificates of %s: %v", i.Config().Service, err)
+	}
+	return before, after, nil
+}
+
+// RotateWorkloadCertsOrFail calls RotateWorkloadCerts and fails the test on error.
+func RotateWorkloadCertsOrFail(t test.Failer, i echo.Instance) (before, after map[string]string) {
+	t.Helper()
+	before, after, err := RotateWorkloadCerts(i)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return before, after
+}
+
 func (c *instance) WaitUntilCallable(instances ...echo.Instance) error {
 	// Wait for the outbound config to be received by each workload from Pilot.
 	for _, w := range c.workloads {
@@ -247,6 +496,17 @@ func (c *instance) initialize(endpoints *kubeCore.Endpoints) error {
 		return fmt.Errorf("no pods found for service %s/%s/%s", c.cfg.Namespace.Name(), c.cfg.Service, c.cfg.Version)
 	}
 
+	if c.cfg.WorkloadKind == echo.StatefulSetKind {
+		// Order the pods by ordinal, so that the i-th workload of a subset is its pod <service>-<version>-<i>.
+		sort.Slice(workloads, func(i, j int) bool {
+			a, b := workloads[i].pod.Name, workloads[j].pod.Name
+			if len(a) != len(b) {
+				return len(a) < len(b)
+			}
+			return a < b
+		})
+	}
+
 	c.workloads = workloads
 	return nil
 }
@@ -288,3 +548,31 @@ func (c *instance) CallOrFail(t test.Failer, opts echo.CallOptions) appEcho.Pars
 	}
 	return r
 }
+
+func (c *instance) ReceivedRequests(since time.Time,
+	filters ...echo.ReceivedRequestFilter) ([]echoCommon.ReceivedRequest, error) {
+	workloads, _ := c.Workloads()
+	return common.ReceivedRequests(workloads, since, filters...)
+}
+
+func (c *instance) ReceivedRequestsOrFail(t test.Failer, since time.Time,
+	filters ...echo.ReceivedRequestFilter) []echoCommon.ReceivedRequest {
+	t.Helper()
+	out, err := c.ReceivedRequests(since, filters...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func (c *instance) ClearReceivedRequests() error {
+	workloads, _ := c.Workloads()
+	return common.ClearReceivedRequests(workloads)
+}
+
+func (c *instance) ClearReceivedRequestsOrFail(t test.Failer) {
+	t.Helper()
+	if err := c.ClearReceivedRequests(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,214 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kube
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Masterminds/sprig"
+
+	appEcho "istio.io/istio/pkg/test/echo/client"
+	"istio.io/istio/pkg/test/echo/proto"
+	"istio.io/istio/pkg/test/framework/components/echo"
+	kubeEnv "istio.io/istio/pkg/test/framework/components/environment/kube"
+	"istio.io/istio/pkg/test/framework/components/namespace"
+	"istio.io/istio/pkg/test/framework/image"
+	"istio.io/istio/pkg/test/framework/resource"
+	"istio.io/istio/pkg/test/util/retry"
+	"istio.io/istio/pkg/test/util/tmpl"
+
+	kubeCore "k8s.io/api/core/v1"
+)
+
+const (
+	jobYAML = `
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: {{ .Name }}
+spec:
+  backoffLimit: 0
+  template:
+    metadata:
+      labels:
+        app: {{ .Name }}
+{{- if .Annotations }}
+      annotations:
+{{- range $name, $value := .Annotations }}
+        {{ $name.Name }}: {{ printf "%q" $value.Value }}
+{{- end }}
+{{- end }}
+    spec:
+{{- if .ServiceAccount }}
+      serviceAccountName: {{ .ServiceAccount }}
+{{- end }}
+      restartPolicy: Never
+      containers:
+      - name: app
+        image: {{ .Hub }}/app:{{ .Tag }}
+        imagePullPolicy: {{ .PullPolicy }}
+        securityContext:
+          runAsUser: 1
+        command:
+        - /bin/sh
+        - -c
+        - |
+{{- if .Sidecar }}
+          until curl -fs http://localhost:15021/healthz/ready; do sleep 1; done
+{{- end }}
+          /usr/local/bin/client --url {{ printf "%q" .URL }}{{ if .Headers }} --headers {{ printf "%q" .Headers }}{{ end }}
+          code=$?
+{{- if .Sidecar }}
+          curl -fs -X POST http://localhost:15020/quitquitquit
+{{- end }}
+          exit $code
+`
+
+	jobContainerName   = "app"
+	defaultJobTimeout  = time.Minute * 2
+	jobPollingInterval = time.Second
+)
+
+var jobTemplate *template.Template
+
+func init() {
+	jobTemplate = template.New("echo_job")
+	if _, err := jobTemplate.Funcs(sprig.TxtFuncMap()).Parse(jobYAML); err != nil {
+		panic(fmt.Sprintf("unable to parse echo job template: %v", err))
+	}
+}
+
+// JobConfig defines a one-shot caller: a Kubernetes Job running the echo client once.
+type JobConfig struct {
+	// Name of the Job. Required.
+	Name string
+
+	// Namespace of the Job. Required.
+	Namespace namespace.Instance
+
+	// URL the client calls. Required.
+	URL string
+
+	// Headers sent with the request. Keys and values must not contain ',' or ':'.
+	Headers http.Header
+
+	// ServiceAccount (optional) the pod runs as.
+	ServiceAccount string
+
+	// Annotations of the pod. If the sidecar is injected (the default), the client waits until the
+	// sidecar is ready before calling, and stops the sidecar once done so that the Job completes.
+	Annotations echo.Annotations
+
+	// Timeout to wait for the Job to complete. If not provided, 2 minutes is used.
+	Timeout time.Duration
+
+	// Cluster to be used in a multicluster environment
+	Cluster resource.Cluster
+}
+
+// JobResult is the outcome of a one-shot caller.
+type JobResult struct {
+	// ExitCode of the client.
+	ExitCode int
+
+	// Output of the client.
+	Output string
+
+	// Responses parsed from Output.
+	Responses appEcho.ParsedResponses
+}
+
+// RunJob runs the one-shot caller defined by cfg, waits for it to complete and returns its result.
+// The Job is deleted before returning.
+func RunJob(ctx resource.Context, cfg JobConfig) (JobResult, error) {
+	settings, err := image.SettingsFromCommandLine()
+	if err != nil {
+		return JobResult{}, err
+	}
+	yaml, err := generateJobYAML(cfg, settings)
+	if err != nil {
+		return JobResult{}, err
+	}
+
+	cluster := kubeEnv.ClusterOrDefault(cfg.Cluster, ctx.Environment())
+	ns := cfg.Namespace.Name()
+	if _, err = cluster.ApplyContents(ns, yaml); err != nil {
+		return JobResult{}, fmt.Errorf("failed deploying job %s: %v", cfg.Name, err)
+	}
+	defer func() { _ = cluster.DeleteContents(ns, yaml) }()
+
+	timeout := cfg.Timeout
+	if timeout == 0 {
+		timeout = defaultJobTimeout
+	}
+	var pod kubeCore.Pod
+	var state *kubeCore.ContainerStateTerminated
+	_, err = retry.Do(func() (interface{}, bool, error) {
+		pods, err := cluster.GetPods(ns, "job-name="+cfg.Name)
+		if err != nil {
+			return nil, false, err
+		}
+		if len(pods) == 0 {
+			return nil, false, fmt.Errorf("no pod for job %s", cfg.Name)
+		}
+		pod = pods[0]
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.Name == jobContainerName && status.State.Terminated != nil {
+				state = status.State.Terminated
+				return nil, true, nil
+			}
+		}
+		return nil, false, fmt.Errorf("job %s has not completed", cfg.Name)
+	}, retry.Delay(jobPollingInterval), retry.Timeout(timeout))
+	if err != nil {
+		return JobResult{}, err
+	}
+
+	output, err := cluster.Logs(ns, pod.Name, jobContainerName, false)
+	if err != nil {
+		return JobResult{}, fmt.Errorf("failed getting logs of job %s: %v", cfg.Name, err)
+	}
+	return JobResult{
+		ExitCode:  int(state.ExitCode),
+		Output:    output,
+		Responses: appEcho.ParseForwardedResponse(&proto.ForwardEchoResponse{Output: []string{output}}),
+	}, nil
+}
+
+func generateJobYAML(cfg JobConfig, settings *image.Settings) (string, error) {
+	headers := make([]string, 0, len(cfg.Headers))
+	for k := range cfg.Headers {
+		headers = append(headers, k+":"+cfg.Headers.Get(k))
+	}
+	sort.Strings(headers)
+
+	params := map[string]interface{}{
+		"Hub":            settings.Hub,
+		"Tag":            settings.Tag,
+		"PullPolicy":     settings.PullPolicy,
+		"Name":           cfg.Name,
+		"URL":            cfg.URL,
+		"Headers":        strings.Join(headers, ","),
+		"ServiceAccount": cfg.ServiceAccount,
+		"Annotations":    cfg.Annotations,
+		"Sidecar":        cfg.Annotations.GetBool(echo.SidecarInject),
+	}
+	return tmpl.Execute(jobTemplate, params)
+}
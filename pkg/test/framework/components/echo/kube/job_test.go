@@ -0,0 +1,69 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kube
+
+import (
+	"net/http"
+	"testing"
+
+	testutil "istio.io/istio/pilot/test/util"
+	"istio.io/istio/pkg/test/framework/components/echo"
+)
+
+func TestJobYAML(t *testing.T) {
+	testCase := []struct {
+		name         string
+		wantFilePath string
+		config       JobConfig
+	}{
+		{
+			name:         "sidecar",
+			wantFilePath: "testdata/job.yaml",
+			config: JobConfig{
+				Name: "caller",
+				URL:  "http://b:80/",
+				Headers: http.Header{
+					"Authorization": []string{"Bearer token"},
+					"X-Test":        []string{"value"},
+				},
+				ServiceAccount: "a",
+				Annotations:    echo.NewAnnotations().Set(echo.SidecarProxyConfig, "drainDuration: 1s"),
+			},
+		},
+		{
+			name:         "nosidecar",
+			wantFilePath: "testdata/job-nosidecar.yaml",
+			config: JobConfig{
+				Name:        "caller",
+				URL:         "http://b:80/",
+				Annotations: echo.NewAnnotations().SetBool(echo.SidecarInject, false),
+			},
+		},
+	}
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := generateJobYAML(tc.config, settings)
+			if err != nil {
+				t.Fatalf("failed to generate yaml %v", err)
+			}
+			gotBytes := []byte(got)
+			wantBytes := testutil.ReadGoldenFile(gotBytes, tc.wantFilePath, t)
+			if testutil.Refresh() {
+				testutil.RefreshGoldenFile(gotBytes, tc.wantFilePath, t)
+			}
+			testutil.CompareBytes(gotBytes, wantBytes, tc.wantFilePath, t)
+		})
+	}
+}
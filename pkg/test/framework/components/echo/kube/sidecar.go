@@ -23,6 +23,7 @@ import (
 	"github.com/golang/protobuf/jsonpb"
 	"github.com/golang/protobuf/proto"
 	"github.com/golang/protobuf/ptypes"
+	dto "github.com/prometheus/client_model/go"
 
 	"istio.io/istio/pkg/test"
 	"istio.io/istio/pkg/test/framework/components/echo"
@@ -161,14 +162,56 @@ func (s *sidecar) ListenersOrFail(t test.Failer) *envoyAdmin.Listeners {
 	return listeners
 }
 
-func (s *sidecar) adminRequest(path string, out proto.Message) error {
+func (s *sidecar) Stats() (map[string]int, error) {
+	response, err := s.adminRequestRaw("stats?format=json")
+	if err != nil {
+		return nil, err
+	}
+	return common.ParseStats(response)
+}
+
+func (s *sidecar) StatsOrFail(t test.Failer) map[string]int {
+	t.Helper()
+	stats, err := s.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stats
+}
+
+func (s *sidecar) PrometheusStats() (map[string]*dto.MetricFamily, error) {
+	response, err := s.adminRequestRaw("stats/prometheus")
+	if err != nil {
+		return nil, err
+	}
+	return common.ParsePrometheusStats(response)
+}
+
+func (s *sidecar) PrometheusStatsOrFail(t test.Failer) map[string]*dto.MetricFamily {
+	t.Helper()
+	stats, err := s.PrometheusStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stats
+}
+
+func (s *sidecar) adminRequestRaw(path string) (string, error) {
 	// Exec onto the pod and make a curl request to the admin port, writing
 	command := fmt.Sprintf("pilot-agent request GET %s", path)
 	response, err := s.cluster.Exec(s.podNamespace, s.podName, proxyContainerName, command)
 	if err != nil {
-		return fmt.Errorf("failed exec on pod %s/%s: %v. Command: %s. Output:\n%s",
+		return "", fmt.Errorf("failed exec on pod %s/%s: %v. Command: %s. Output:\n%s",
 			s.podNamespace, s.podName, err, command, response)
 	}
+	return response, nil
+}
+
+func (s *sidecar) adminRequest(path string, out proto.Message) error {
+	response, err := s.adminRequestRaw(path)
+	if err != nil {
+		return err
+	}
 
 	jspb := jsonpb.Unmarshaler{AllowUnknownFields: true}
 	if err := jspb.Unmarshal(strings.NewReader(response), out); err != nil {
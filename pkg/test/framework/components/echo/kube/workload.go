@@ -15,7 +15,13 @@
 package kube
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
 
 	"istio.io/istio/pkg/test"
 	"istio.io/istio/pkg/test/echo/common"
@@ -34,6 +40,7 @@ import (
 
 const (
 	appContainerName = "app"
+	appMetricsPort   = 15014
 )
 
 var (
@@ -103,7 +110,8 @@ func (w *workload) Close() (err error) {
 	if w.forwarder != nil {
 		err = multierror.Append(err, w.forwarder.Close()).ErrorOrNil()
 	}
-	if w.ctx.Settings().FailOnDeprecation && w.sidecar != nil {
+	// The workloads of an attached Instance have no context.
+	if w.ctx != nil && w.ctx.Settings().FailOnDeprecation && w.sidecar != nil {
 		err = multierror.Append(err, w.checkDeprecation()).ErrorOrNil()
 	}
 	return
@@ -139,3 +147,73 @@ func (w *workload) LogsOrFail(t test.Failer) string {
 	}
 	return logs
 }
+
+func (w *workload) RequestCount(port int) (int, error) {
+	metrics, err := w.metricsRequest(http.MethodGet, "/metrics")
+	if err != nil {
+		return 0, err
+	}
+	return common.ParseRequestCount(bytes.NewReader(metrics), port)
+}
+
+func (w *workload) RequestCountOrFail(t test.Failer, port int) int {
+	t.Helper()
+	count, err := w.RequestCount(port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+func (w *workload) ReceivedRequests(since time.Time) ([]common.ReceivedRequest, error) {
+	path := common.ReceivedRequestsPath
+	if !since.IsZero() {
+		path += "?" + url.Values{common.ReceivedRequestsSinceParam: {since.Format(time.RFC3339Nano)}}.Encode()
+	}
+	body, err := w.metricsRequest(http.MethodGet, path)
+	if err != nil {
+		return nil, err
+	}
+	var out []common.ReceivedRequest
+	if err := json.Unmarshal(body, &out); err != nil {
+		return nil, fmt.Errorf("failed parsing the requests received by pod %s/%s: %v", w.pod.Namespace, w.pod.Name, err)
+	}
+	return out, nil
+}
+
+func (w *workload) ClearReceivedRequests() error {
+	_, err := w.metricsRequest(http.MethodDelete, common.ReceivedRequestsPath)
+	return err
+}
+
+// metricsRequest sends a request to the metrics port of the app container, which the sidecar does not
+// intercept, through a port forwarding to the pod, and returns the body of the response.
+func (w *workload) metricsRequest(method, path string) ([]byte, error) {
+	forwarder, err := w.cluster.NewPortForwarder(w.pod, 0, appMetricsPort)
+	if err != nil {
+		return nil, fmt.Errorf("new port forwarder: %v", err)
+	}
+	if err = forwarder.Start(); err != nil {
+		return nil, fmt.Errorf("forwarder start: %v", err)
+	}
+	defer func() { _ = forwarder.Close() }()
+
+	req, err := http.NewRequest(method, fmt.Sprintf("http://%s%s", forwarder.Address(), path), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed %s %s of pod %s/%s: %v", method, path, w.pod.Namespace, w.pod.Name, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed %s %s of pod %s/%s: status %d, %s",
+			method, path, w.pod.Namespace, w.pod.Name, resp.StatusCode, string(body))
+	}
+	return body, nil
+}
@@ -0,0 +1,55 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package echo
+
+import (
+	"fmt"
+)
+
+// ReadinessCheck is a custom readiness predicate for an Instance. It is evaluated repeatedly until it
+// returns true. If an error is returned, the check is retried and the error is reported if the
+// instance never becomes ready.
+type ReadinessCheck func(Instance) (bool, error)
+
+// ListenerReadinessCheck returns a ReadinessCheck that waits until the sidecar of every workload of
+// the instance has the listener with the given name.
+func ListenerReadinessCheck(listenerName string) ReadinessCheck {
+	return func(i Instance) (bool, error) {
+		workloads, err := i.Workloads()
+		if err != nil {
+			return false, err
+		}
+		for _, w := range workloads {
+			if w.Sidecar() == nil {
+				return false, fmt.Errorf("workload %s has no sidecar", w.Address())
+			}
+			listeners, err := w.Sidecar().Listeners()
+			if err != nil {
+				return false, err
+			}
+			found := false
+			for _, l := range listeners.ListenerStatuses {
+				if l.Name == listenerName {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false, nil
+			}
+		}
+		return true, nil
+	}
+}
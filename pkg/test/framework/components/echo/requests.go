@@ -0,0 +1,81 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package echo
+
+import (
+	"net/http"
+	"strings"
+
+	echoCommon "istio.io/istio/pkg/test/echo/common"
+)
+
+// ReceivedRequestFilter selects the requests returned by Instance.ReceivedRequests.
+type ReceivedRequestFilter func(echoCommon.ReceivedRequest) bool
+
+// WithPathPrefix selects the requests with a path, including the query, starting with the given prefix.
+func WithPathPrefix(prefix string) ReceivedRequestFilter {
+	return func(r echoCommon.ReceivedRequest) bool {
+		return strings.HasPrefix(r.Path, prefix)
+	}
+}
+
+// WithMethod selects the requests with the given method.
+func WithMethod(method string) ReceivedRequestFilter {
+	return func(r echoCommon.ReceivedRequest) bool {
+		return r.Method == method
+	}
+}
+
+// WithInstancePort selects the requests received on the given instance port.
+func WithInstancePort(port int) ReceivedRequestFilter {
+	return func(r echoCommon.ReceivedRequest) bool {
+		return r.Port == port
+	}
+}
+
+// WithHeader selects the requests with the given header value, or with the header if value is empty.
+func WithHeader(name, value string) ReceivedRequestFilter {
+	return func(r echoCommon.ReceivedRequest) bool {
+		values, ok := r.Headers[http.CanonicalHeaderKey(name)]
+		if !ok {
+			return false
+		}
+		if value == "" {
+			return true
+		}
+		for _, v := range values {
+			if v == value {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// FilterReceivedRequests returns the requests matching all the filters, in order.
+func FilterReceivedRequests(requests []echoCommon.ReceivedRequest,
+	filters ...ReceivedRequestFilter) []echoCommon.ReceivedRequest {
+	out := make([]echoCommon.ReceivedRequest, 0, len(requests))
+outer:
+	for _, r := range requests {
+		for _, f := range filters {
+			if !f(r) {
+				continue outer
+			}
+		}
+		out = append(out, r)
+	}
+	return out
+}
@@ -21,10 +21,14 @@ import (
 	"os"
 	"path/filepath"
 
+	"istio.io/istio/pkg/config/schema/collections"
+	configResource "istio.io/istio/pkg/config/schema/resource"
 	"istio.io/istio/pkg/test"
 	"istio.io/istio/pkg/test/framework/resource"
 	"istio.io/istio/pkg/test/kube"
 	"istio.io/istio/pkg/test/scopes"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 var _ resource.Cluster = Cluster{}
@@ -47,12 +51,17 @@ func (c Cluster) ApplyConfig(ns string, yamlText ...string) error {
 	return nil
 }
 
-func (c Cluster) ApplyConfigOrFail(t test.Failer, ns string, yamlText ...string) {
+func (c Cluster) ApplyConfigOrFail(t test.Failer, ns string, yamlText ...string) []resource.ConfigHandle {
 	t.Helper()
 	err := c.ApplyConfig(ns, yamlText...)
 	if err != nil {
 		t.Fatalf("ApplyConfigOrFail: %v", err)
 	}
+	handles, err := resource.ConfigHandles(ns, yamlText...)
+	if err != nil {
+		t.Fatalf("ApplyConfigOrFail: %v", err)
+	}
+	return handles
 }
 
 func (c Cluster) DeleteConfig(ns string, yamlText ...string) error {
@@ -74,6 +83,37 @@ func (c Cluster) DeleteConfigOrFail(t test.Failer, ns string, yamlText ...string
 	}
 }
 
+func (c Cluster) DeleteConfigHandles(handles ...resource.ConfigHandle) error {
+	for _, h := range handles {
+		if err := c.DeleteContents(h.Namespace, h.YAML()); err != nil {
+			return fmt.Errorf("delete %v: %v", h, err)
+		}
+		scopes.Framework.Debugf("Deleted config: %v", h)
+	}
+	return nil
+}
+
+func (c Cluster) GetStatus(h resource.ConfigHandle) (map[string]interface{}, error) {
+	s, found := collections.All.FindByGroupVersionKind(configResource.GroupVersionKind{
+		Group:   h.Group,
+		Version: h.Version,
+		Kind:    h.Kind,
+	})
+	if !found {
+		return nil, fmt.Errorf("unknown kind of config %v", h)
+	}
+	obj, err := c.GetUnstructured(schema.GroupVersionResource{
+		Group:    h.Group,
+		Version:  h.Version,
+		Resource: s.Resource().Plural(),
+	}, h.Namespace, h.Name)
+	if err != nil {
+		return nil, err
+	}
+	status, _ := obj.Object["status"].(map[string]interface{})
+	return status, nil
+}
+
 func (c Cluster) ApplyConfigDir(ns string, configDir string) error {
 	return filepath.Walk(configDir, func(path string, info os.FileInfo, err error) error {
 		if info.IsDir() {
@@ -15,6 +15,7 @@
 package native
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -63,12 +64,17 @@ func (c Cluster) ApplyConfig(ns string, yamlText ...string) error {
 	return nil
 }
 
-func (c Cluster) ApplyConfigOrFail(t test.Failer, ns string, yamlText ...string) {
+func (c Cluster) ApplyConfigOrFail(t test.Failer, ns string, yamlText ...string) []resource.ConfigHandle {
 	t.Helper()
 	err := c.ApplyConfig(ns, yamlText...)
 	if err != nil {
 		t.Fatalf("ApplyConfigOrFail: %v", err)
 	}
+	handles, err := resource.ConfigHandles(ns, yamlText...)
+	if err != nil {
+		t.Fatalf("ApplyConfigOrFail: %v", err)
+	}
+	return handles
 }
 
 func (c Cluster) DeleteConfig(ns string, yamlText ...string) error {
@@ -95,6 +101,20 @@ func (c Cluster) DeleteConfigOrFail(t test.Failer, ns string, yamlText ...string
 	}
 }
 
+func (c Cluster) DeleteConfigHandles(handles ...resource.ConfigHandle) error {
+	for _, h := range handles {
+		if err := c.cache.Delete(h.YAML()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetStatus is not supported, as the file-based config read by Galley has no status.
+func (c Cluster) GetStatus(h resource.ConfigHandle) (map[string]interface{}, error) {
+	return nil, fmt.Errorf("no status of %v in the native environment", h)
+}
+
 func (c Cluster) ApplyConfigDir(ns string, configDir string) error {
 	return filepath.Walk(configDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -0,0 +1,115 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ingress
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// HandshakeFailureReason is the reason of a TLS handshake failure, as far as the client can tell it.
+type HandshakeFailureReason string
+
+const (
+	// UnknownHandshakeFailure is a failure the client cannot tell the reason of, e.g. the gateway closed
+	// the connection without an alert.
+	UnknownHandshakeFailure HandshakeFailureReason = "unknown"
+	// ClientCertificateRequired means the gateway requires a client certificate and none was sent.
+	ClientCertificateRequired HandshakeFailureReason = "client certificate required"
+	// ClientCertificateInvalid means the gateway rejected the client certificate, e.g. signed by an
+	// unknown CA.
+	ClientCertificateInvalid HandshakeFailureReason = "client certificate invalid"
+	// ServerCertificateInvalid means the client rejected the certificate of the gateway.
+	ServerCertificateInvalid HandshakeFailureReason = "server certificate invalid"
+)
+
+// The Op of the net.OpError of a TLS alert received from the peer.
+const remoteErrorOp = "remote error"
+
+// HandshakeError is returned by Call when the TLS handshake with the gateway fails. With TLS 1.3, the
+// client completes the handshake before the gateway checks the client certificate, so the rejection of the
+// certificate is reported on the first read of the response and is returned as a HandshakeError too.
+type HandshakeError struct {
+	Reason HandshakeFailureReason
+	Err    error
+}
+
+func (e *HandshakeError) Error() string {
+	return fmt.Sprintf("TLS handshake failed (%s): %v", e.Reason, e.Err)
+}
+
+func (e *HandshakeError) Unwrap() error {
+	return e.Err
+}
+
+// AsHandshakeError returns the HandshakeError in the chain of err, if any.
+func AsHandshakeError(err error) (*HandshakeError, bool) {
+	var he *HandshakeError
+	if errors.As(err, &he) {
+		return he, true
+	}
+	return nil, false
+}
+
+// ExpectHandshakeFailure calls through the ingress and returns nil if the TLS handshake fails for the
+// given reason, or for any reason if the reason is empty. A response, even an error one, is a failure
+// of the expectation: the request was rejected at the HTTP level instead of at TLS.
+func ExpectHandshakeFailure(i Instance, options CallOptions, reason HandshakeFailureReason) error {
+	resp, err := i.Call(options)
+	if err == nil {
+		return fmt.Errorf("got response code %d, want a TLS handshake failure", resp.Code)
+	}
+	he, ok := AsHandshakeError(err)
+	if !ok {
+		return fmt.Errorf("want a TLS handshake failure, got: %v", err)
+	}
+	if reason != "" && he.Reason != reason {
+		return fmt.Errorf("want a TLS handshake failure for %s, got: %v", reason, he)
+	}
+	return nil
+}
+
+// toHandshakeError returns err as a HandshakeError if it is a TLS failure, or as is otherwise.
+// handshaking tells whether err was returned by the handshake itself, and sentCert whether the client
+// sent a certificate.
+func toHandshakeError(err error, handshaking, sentCert bool) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := AsHandshakeError(err); ok {
+		return err
+	}
+	var unknownAuthority x509.UnknownAuthorityError
+	var invalid x509.CertificateInvalidError
+	var hostname x509.HostnameError
+	if errors.As(err, &unknownAuthority) || errors.As(err, &invalid) || errors.As(err, &hostname) {
+		return &HandshakeError{Reason: ServerCertificateInvalid, Err: err}
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == remoteErrorOp {
+		// The gateway sent an alert. Whatever the alert, the certificate is the reason of the rejection,
+		// since the gateway only requests one from clients it is configured to verify.
+		if sentCert {
+			return &HandshakeError{Reason: ClientCertificateInvalid, Err: err}
+		}
+		return &HandshakeError{Reason: ClientCertificateRequired, Err: err}
+	}
+	if handshaking && !(errors.As(err, &opErr) && opErr.Op == "dial") {
+		return &HandshakeError{Reason: UnknownHandshakeFailure, Err: err}
+	}
+	return err
+}
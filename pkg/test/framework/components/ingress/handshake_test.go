@@ -0,0 +1,86 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ingress
+
+import (
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"net/url"
+	"testing"
+)
+
+func TestToHandshakeError(t *testing.T) {
+	alert := &net.OpError{Op: remoteErrorOp, Err: errors.New("tls: bad certificate")}
+	cases := []struct {
+		name        string
+		err         error
+		handshaking bool
+		sentCert    bool
+		// The expected reason, or empty if err is not expected to be a HandshakeError.
+		want HandshakeFailureReason
+	}{
+		{
+			name:        "server certificate",
+			err:         x509.UnknownAuthorityError{},
+			handshaking: true,
+			want:        ServerCertificateInvalid,
+		},
+		{
+			name: "client certificate required",
+			err:  &url.Error{Op: "Get", URL: "https://example.com", Err: alert},
+			want: ClientCertificateRequired,
+		},
+		{
+			name:     "client certificate invalid",
+			err:      &url.Error{Op: "Get", URL: "https://example.com", Err: alert},
+			sentCert: true,
+			want:     ClientCertificateInvalid,
+		},
+		{
+			name:        "closed during handshake",
+			err:         io.EOF,
+			handshaking: true,
+			want:        UnknownHandshakeFailure,
+		},
+		{
+			name:        "dial",
+			err:         &net.OpError{Op: "dial", Err: errors.New("connection refused")},
+			handshaking: true,
+		},
+		{
+			name: "after handshake",
+			err:  io.EOF,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := toHandshakeError(c.err, c.handshaking, c.sentCert)
+			he, ok := AsHandshakeError(err)
+			switch {
+			case c.want == "" && ok:
+				t.Errorf("got %v, want no handshake error", he)
+			case c.want != "" && !ok:
+				t.Errorf("got %v, want a handshake error", err)
+			case ok && he.Reason != c.want:
+				t.Errorf("got reason %q, want %q", he.Reason, c.want)
+			}
+			if !errors.Is(err, c.err) {
+				t.Errorf("got %v, want it to wrap %v", err, c.err)
+			}
+		})
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"time"
 
 	"istio.io/istio/pkg/test"
+	"istio.io/istio/pkg/test/envoy/accesslog"
 	"istio.io/istio/pkg/test/framework/components/istio"
 	"istio.io/istio/pkg/test/framework/resource"
 	"istio.io/istio/pkg/test/framework/resource/environment"
@@ -45,9 +46,17 @@ type CallOptions struct {
 	// Path specifies the URL path for the request.
 	Path string
 
+	// Method specifies the HTTP method of the request. If not provided, GET is used. For CONNECT,
+	// the request target is Host (in authority form) and Path is ignored.
+	Method string
+
 	// Headers indicates headers that should be sent in the request. Ignored for WebSocket calls.
 	Headers http.Header
 
+	// BasicAuth, if set, sends the credentials in an "Authorization: Basic" header. It must not be combined
+	// with an Authorization header in Headers, e.g. with a bearer token.
+	BasicAuth *BasicAuth
+
 	// Timeout used for each individual request. Must be > 0, otherwise 1 minute is used.
 	Timeout time.Duration
 
@@ -66,14 +75,26 @@ type CallOptions struct {
 	CallType CallType
 }
 
+// BasicAuth are the credentials of HTTP Basic authentication.
+type BasicAuth struct {
+	User     string
+	Password string
+}
+
 // sanitize checks and fills fields in CallOptions. Returns error on failures, and nil otherwise.
 func (o *CallOptions) sanitize() error {
 	if o.Timeout <= 0 {
 		o.Timeout = DefaultRequestTimeout
 	}
+	if o.Method == "" {
+		o.Method = http.MethodGet
+	}
 	if !strings.HasPrefix(o.Path, "/") {
 		o.Path = "/" + o.Path
 	}
+	if o.BasicAuth != nil && o.Headers.Get(authorizationHeader) != "" {
+		return fmt.Errorf("BasicAuth and Authorization header are mutually exclusive")
+	}
 	if len(o.Address.IP) == 0 {
 		return fmt.Errorf("address is not set")
 	}
@@ -100,6 +121,11 @@ type Instance interface {
 
 	// ProxyStats returns proxy stats, or error if failure happens.
 	ProxyStats() (map[string]int, error)
+
+	// AccessLogs returns the access log entries of all the gateway pods which start at or after since, as
+	// logged by the proxies (so allow for clock skew). The access logs must be enabled in accesslog.Format,
+	// e.g. with istio.EnableAccessLogs.
+	AccessLogs(since time.Time) (accesslog.Entries, error)
 }
 
 type Config struct {
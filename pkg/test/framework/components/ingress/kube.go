@@ -27,6 +27,7 @@ import (
 	"time"
 
 	"istio.io/istio/pkg/test"
+	"istio.io/istio/pkg/test/envoy/accesslog"
 	"istio.io/istio/pkg/test/framework/components/environment/kube"
 	"istio.io/istio/pkg/test/framework/resource"
 	"istio.io/istio/pkg/test/scopes"
@@ -40,6 +41,8 @@ const (
 
 	proxyContainerName = "istio-proxy"
 	proxyAdminPort     = 15000
+
+	authorizationHeader = "Authorization"
 )
 
 var (
@@ -252,11 +255,11 @@ func (c *kubeComponent) createClient(options CallOptions) (*http.Client, error)
 				tc, err := tls.DialWithDialer(&net.Dialer{Timeout: options.Timeout}, netw, addr, tlsConfig)
 				if err != nil {
 					scopes.Framework.Errorf("TLS dial fail: %v", err)
-					return nil, err
+					return nil, toHandshakeError(err, true, options.CallType == Mtls)
 				}
 				if err := tc.Handshake(); err != nil {
 					scopes.Framework.Errorf("SSL handshake fail: %v", err)
-					return nil, err
+					return nil, toHandshakeError(err, true, options.CallType == Mtls)
 				}
 				return tc, nil
 			}}
@@ -267,12 +270,17 @@ func (c *kubeComponent) createClient(options CallOptions) (*http.Client, error)
 
 // createRequest returns a request for client to send, or nil and error if request is failed to generate.
 func (c *kubeComponent) createRequest(options CallOptions) (*http.Request, error) {
-	url := "http://" + options.Address.String() + options.Path
+	path := options.Path
+	if options.Method == http.MethodConnect {
+		// The request target of CONNECT is the authority (req.Host) instead of the path.
+		path = ""
+	}
+	url := "http://" + options.Address.String() + path
 	if options.CallType != PlainText {
-		url = "https://" + options.Host + ":" + strconv.Itoa(options.Address.Port) + options.Path
+		url = "https://" + options.Host + ":" + strconv.Itoa(options.Address.Port) + path
 	}
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequest(options.Method, url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -282,6 +290,9 @@ func (c *kubeComponent) createRequest(options CallOptions) (*http.Request, error
 	if options.Headers != nil {
 		req.Header = options.Headers.Clone()
 	}
+	if options.BasicAuth != nil {
+		req.SetBasicAuth(options.BasicAuth.User, options.BasicAuth.Password)
+	}
 
 	scopes.Framework.Debugf("Created a request to send %v", req)
 	return req, nil
@@ -304,12 +315,20 @@ func (c *kubeComponent) Call(options CallOptions) (CallResponse, error) {
 
 	resp, err := client.Do(req)
 	if err != nil {
+		if options.CallType != PlainText {
+			err = toHandshakeError(err, false, options.CallType == Mtls)
+		}
 		return CallResponse{}, err
 	}
 	scopes.Framework.Debugf("Received response from %q: %v", req.URL, resp.StatusCode)
 
 	defer func() { _ = resp.Body.Close() }()
 
+	if options.Method == http.MethodConnect {
+		// On success the body is the tunnel, which stays open until closed: only report the status.
+		return CallResponse{Code: resp.StatusCode}, nil
+	}
+
 	var ba []byte
 	ba, err = ioutil.ReadAll(resp.Body)
 	if err != nil {
@@ -357,6 +376,22 @@ func (c *kubeComponent) adminRequest(path string) (string, error) {
 	return c.env.KubeClusters[0].Exec(podNs, podName, proxyContainerName, command)
 }
 
+func (c *kubeComponent) AccessLogs(since time.Time) (accesslog.Entries, error) {
+	pods, err := c.cluster.GetPods(c.namespace, fmt.Sprintf("istio=%s", istioLabel))
+	if err != nil {
+		return nil, fmt.Errorf("unable to get ingress gateway pods: %v", err)
+	}
+	var entries accesslog.Entries
+	for _, pod := range pods {
+		logs, err := c.cluster.Logs(pod.Namespace, pod.Name, proxyContainerName, false)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get logs of ingress gateway pod %s: %v", pod.Name, err)
+		}
+		entries = append(entries, accesslog.Parse(pod.Name, logs, since)...)
+	}
+	return entries, nil
+}
+
 type statEntry struct {
 	Name  string      `json:"name"`
 	Value json.Number `json:"value"`
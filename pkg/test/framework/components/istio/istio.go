@@ -15,6 +15,9 @@
 package istio
 
 import (
+	meshconfig "istio.io/api/mesh/v1alpha1"
+
+	"istio.io/istio/pkg/test"
 	"istio.io/istio/pkg/test/framework/components/environment/kube"
 	"istio.io/istio/pkg/test/framework/resource"
 	"istio.io/istio/pkg/test/framework/resource/environment"
@@ -26,6 +29,15 @@ type Instance interface {
 	resource.Resource
 
 	Settings() Config
+
+	// MeshConfig returns the mesh config of the deployment, with defaults applied.
+	MeshConfig() (*meshconfig.MeshConfig, error)
+
+	// PatchMeshConfig merges patch, a YAML fragment of the mesh config, into the mesh config and waits
+	// until istiod picks it up. The original mesh config is restored when ctx is closed. Only one patch
+	// may be active at a time; a second one fails until the first is rolled back.
+	PatchMeshConfig(ctx resource.Context, patch string) error
+	PatchMeshConfigOrFail(t test.Failer, ctx resource.Context, patch string)
 }
 
 // SetupConfigFn is a setup function that specifies the overrides of the configuration to deploy Istio.
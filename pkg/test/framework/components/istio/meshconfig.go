@@ -0,0 +1,218 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package istio
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/ghodss/yaml"
+	kubeApiCore "k8s.io/api/core/v1"
+
+	meshconfig "istio.io/api/mesh/v1alpha1"
+
+	"istio.io/istio/pkg/config/mesh"
+	"istio.io/istio/pkg/test"
+	"istio.io/istio/pkg/test/envoy/accesslog"
+	"istio.io/istio/pkg/test/framework/components/environment/kube"
+	"istio.io/istio/pkg/test/framework/resource"
+	"istio.io/istio/pkg/test/scopes"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+const (
+	meshConfigMapName = "istio"
+	meshConfigMapKey  = "mesh"
+	// The path the mesh config map is mounted at in the istiod container.
+	meshConfigPath      = "/etc/istio/config/mesh"
+	istiodLabelSelector = "istio=pilot"
+	istiodContainerName = "discovery"
+
+	meshConfigPropagationTimeout = 3 * time.Minute
+	meshConfigPropagationDelay   = 2 * time.Second
+)
+
+// MeshConfig implements Instance. The mesh config is read from the config cluster.
+func (i *operatorComponent) MeshConfig() (*meshconfig.MeshConfig, error) {
+	cm, err := i.meshConfigMap()
+	if err != nil {
+		return nil, err
+	}
+	return mesh.ApplyMeshConfigDefaults(cm.Data[meshConfigMapKey])
+}
+
+// PatchMeshConfig implements Instance.
+func (i *operatorComponent) PatchMeshConfig(ctx resource.Context, patch string) error {
+	i.meshPatchMu.Lock()
+	defer i.meshPatchMu.Unlock()
+	if i.meshPatch != nil {
+		return fmt.Errorf("mesh config is already patched by %s, concurrent patches are not supported", i.meshPatch.id)
+	}
+
+	cm, err := i.meshConfigMap()
+	if err != nil {
+		return err
+	}
+	original := cm.Data[meshConfigMapKey]
+	patched, err := mergeMeshConfig(original, patch)
+	if err != nil {
+		return err
+	}
+	if err := i.writeMeshConfig(patched); err != nil {
+		return err
+	}
+
+	p := &meshConfigPatch{
+		component: i,
+		original:  original,
+	}
+	p.id = ctx.TrackResource(p)
+	i.meshPatch = p
+	return nil
+}
+
+// PatchMeshConfigOrFail implements Instance.
+func (i *operatorComponent) PatchMeshConfigOrFail(t test.Failer, ctx resource.Context, patch string) {
+	t.Helper()
+	if err := i.PatchMeshConfig(ctx, patch); err != nil {
+		t.Fatalf("PatchMeshConfigOrFail: %v", err)
+	}
+}
+
+func (i *operatorComponent) meshConfigMap() (*kubeApiCore.ConfigMap, error) {
+	cluster := kube.ClusterOrDefault(nil, i.environment)
+	cm, err := cluster.GetConfigMap(meshConfigMapName, i.settings.ConfigNamespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mesh config map %s/%s: %v", i.settings.ConfigNamespace, meshConfigMapName, err)
+	}
+	return cm, nil
+}
+
+// writeMeshConfig stores the mesh config in the config map and waits until all the istiod pods see it.
+func (i *operatorComponent) writeMeshConfig(data string) error {
+	cm, err := i.meshConfigMap()
+	if err != nil {
+		return err
+	}
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data[meshConfigMapKey] = data
+	cluster := kube.ClusterOrDefault(nil, i.environment)
+	if _, err := cluster.UpdateConfigMap(cm); err != nil {
+		return fmt.Errorf("failed to update mesh config map %s/%s: %v", cm.Namespace, cm.Name, err)
+	}
+
+	// The config map volume is synced by the kubelet, after which istiod reloads the file. Wait until the
+	// file in every istiod pod has the new content.
+	_, err = retry.Do(func() (interface{}, bool, error) {
+		pods, err := cluster.GetPods(i.settings.ConfigNamespace, istiodLabelSelector)
+		if err != nil {
+			return nil, false, err
+		}
+		if len(pods) == 0 {
+			return nil, false, fmt.Errorf("no istiod pod in namespace %s", i.settings.ConfigNamespace)
+		}
+		for _, pod := range pods {
+			out, err := cluster.Exec(pod.Namespace, pod.Name, istiodContainerName, "cat "+meshConfigPath)
+			if err != nil {
+				return nil, false, fmt.Errorf("failed to read mesh config of %s: %v", pod.Name, err)
+			}
+			if strings.TrimSpace(out) != strings.TrimSpace(data) {
+				return nil, false, fmt.Errorf("istiod pod %s has not picked up the mesh config yet", pod.Name)
+			}
+		}
+		return nil, true, nil
+	}, retry.Delay(meshConfigPropagationDelay), retry.Timeout(meshConfigPropagationTimeout))
+	return err
+}
+
+// mergeMeshConfig applies the YAML patch to the mesh config as a JSON merge patch (RFC 7386): maps
+// are merged, lists and scalars are replaced and null removes a field. The result must be a valid
+// mesh config.
+func mergeMeshConfig(original, patch string) (string, error) {
+	originalJSON, err := yaml.YAMLToJSON([]byte(original))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse mesh config: %v", err)
+	}
+	patchJSON, err := yaml.YAMLToJSON([]byte(patch))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse mesh config patch: %v", err)
+	}
+	mergedJSON, err := jsonpatch.MergePatch(originalJSON, patchJSON)
+	if err != nil {
+		return "", fmt.Errorf("failed to apply mesh config patch: %v", err)
+	}
+	merged, err := yaml.JSONToYAML(mergedJSON)
+	if err != nil {
+		return "", err
+	}
+	if _, err := mesh.ApplyMeshConfigDefaults(string(merged)); err != nil {
+		return "", fmt.Errorf("patched mesh config is invalid: %v", err)
+	}
+	return string(merged), nil
+}
+
+// meshConfigPatch restores the original mesh config when it is closed.
+type meshConfigPatch struct {
+	id        resource.ID
+	component *operatorComponent
+	original  string
+}
+
+var _ resource.Resource = &meshConfigPatch{}
+var _ io.Closer = &meshConfigPatch{}
+
+// ID implements resource.Instance
+func (p *meshConfigPatch) ID() resource.ID {
+	return p.id
+}
+
+// Close implements io.Closer
+func (p *meshConfigPatch) Close() error {
+	i := p.component
+	i.meshPatchMu.Lock()
+	defer i.meshPatchMu.Unlock()
+	scopes.Framework.Infof("Rolling back mesh config patch %s", p.id)
+	if err := i.writeMeshConfig(p.original); err != nil {
+		return fmt.Errorf("failed to roll back mesh config: %v", err)
+	}
+	i.meshPatch = nil
+	return nil
+}
+
+// EnableAccessLogs enables the JSON access logs of the proxies in accesslog.Format, by patching the mesh
+// config of the Istio deployment until ctx is closed, unless they are already enabled.
+func EnableAccessLogs(ctx resource.Context, i Instance) error {
+	mc, err := i.MeshConfig()
+	if err != nil {
+		return err
+	}
+	if accesslog.Enabled(mc) {
+		return nil
+	}
+	return i.PatchMeshConfig(ctx, accesslog.MeshConfigPatch)
+}
+
+// EnableAccessLogsOrFail calls EnableAccessLogs and fails the test on error.
+func EnableAccessLogsOrFail(t test.Failer, ctx resource.Context, i Instance) {
+	t.Helper()
+	if err := EnableAccessLogs(ctx, i); err != nil {
+		t.Fatalf("EnableAccessLogsOrFail: %v", err)
+	}
+}
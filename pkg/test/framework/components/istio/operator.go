@@ -23,6 +23,7 @@ import (
 	"path"
 	"path/filepath"
 	"regexp"
+	"sync"
 	"time"
 
 	"github.com/hashicorp/go-multierror"
@@ -46,6 +47,10 @@ type operatorComponent struct {
 	// installManifest includes the yamls use to install Istio. These can be deleted on cleanup
 	// The key is the cluster name
 	installManifest map[string]string
+
+	// meshPatch is the active mesh config patch, if any. Only one patch may be active at a time.
+	meshPatchMu sync.Mutex
+	meshPatch   *meshConfigPatch
 }
 
 var _ io.Closer = &operatorComponent{}
@@ -27,8 +27,9 @@ type ConfigManager interface {
 	// ApplyConfig applies the given config yaml text via Galley.
 	ApplyConfig(ns string, yamlText ...string) error
 
-	// ApplyConfigOrFail applies the given config yaml text via Galley.
-	ApplyConfigOrFail(t test.Failer, ns string, yamlText ...string)
+	// ApplyConfigOrFail applies the given config yaml text via Galley, and returns the handles of its
+	// documents.
+	ApplyConfigOrFail(t test.Failer, ns string, yamlText ...string) []ConfigHandle
 
 	// DeleteConfig deletes the given config yaml text via Galley.
 	DeleteConfig(ns string, yamlText ...string) error
@@ -36,6 +37,12 @@ type ConfigManager interface {
 	// DeleteConfigOrFail deletes the given config yaml text via Galley.
 	DeleteConfigOrFail(t test.Failer, ns string, yamlText ...string)
 
+	// DeleteConfigHandles deletes the documents of the applied config with the given handles only.
+	DeleteConfigHandles(handles ...ConfigHandle) error
+
+	// GetStatus returns the status of the applied document with the given handle.
+	GetStatus(handle ConfigHandle) (map[string]interface{}, error)
+
 	// ApplyConfigDir recursively applies all the config files in the specified directory
 	ApplyConfigDir(ns string, configDir string) error
 
@@ -0,0 +1,79 @@
+//  Copyright Istio Authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package resource
+
+import (
+	"fmt"
+
+	"istio.io/istio/pkg/test/util/yml"
+)
+
+// ConfigHandle identifies a single document of the config applied by ApplyConfigOrFail, so that it can be
+// deleted or inspected on its own, without the rest of the config.
+type ConfigHandle struct {
+	Group     string
+	Version   string
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+// APIVersion returns the apiVersion of the resource, e.g. security.istio.io/v1beta1.
+func (h ConfigHandle) APIVersion() string {
+	if h.Group == "" {
+		return h.Version
+	}
+	return h.Group + "/" + h.Version
+}
+
+// YAML returns a document identifying the resource, without any spec, e.g. to delete it.
+func (h ConfigHandle) YAML() string {
+	return fmt.Sprintf("apiVersion: %s\nkind: %s\nmetadata:\n  name: %s\n  namespace: %s\n",
+		h.APIVersion(), h.Kind, h.Name, h.Namespace)
+}
+
+func (h ConfigHandle) String() string {
+	return fmt.Sprintf("%s/%s/%s", h.Kind, h.Namespace, h.Name)
+}
+
+// ConfigHandles returns the handles of the documents of the given config yaml text, in order. The documents
+// without a namespace are given ns, as they are applied to it.
+func ConfigHandles(ns string, yamlText ...string) ([]ConfigHandle, error) {
+	var out []ConfigHandle
+	for _, y := range yamlText {
+		parts, err := yml.Parse(y)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range parts {
+			d := p.Descriptor
+			if d.Kind == "" || d.Metadata.Name == "" {
+				return nil, fmt.Errorf("config without kind or name:\n%s", p.Contents)
+			}
+			h := ConfigHandle{
+				Group:     d.Group,
+				Version:   d.APIVersion,
+				Kind:      d.Kind,
+				Namespace: d.Metadata.Namespace,
+				Name:      d.Metadata.Name,
+			}
+			if h.Namespace == "" {
+				h.Namespace = ns
+			}
+			out = append(out, h)
+		}
+	}
+	return out, nil
+}
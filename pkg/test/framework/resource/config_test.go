@@ -0,0 +1,64 @@
+//  Copyright Istio Authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package resource
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConfigHandles(t *testing.T) {
+	got, err := ConfigHandles("default", `
+apiVersion: security.istio.io/v1beta1
+kind: RequestAuthentication
+metadata:
+  name: authn
+spec: {}
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: svc
+  namespace: other
+`, `
+apiVersion: security.istio.io/v1beta1
+kind: AuthorizationPolicy
+metadata:
+  name: authz
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ConfigHandle{
+		{Group: "security.istio.io", Version: "v1beta1", Kind: "RequestAuthentication", Namespace: "default", Name: "authn"},
+		{Version: "v1", Kind: "Service", Namespace: "other", Name: "svc"},
+		{Group: "security.istio.io", Version: "v1beta1", Kind: "AuthorizationPolicy", Namespace: "default", Name: "authz"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	handles, err := ConfigHandles("default", got[1].YAML())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(handles, got[1:2]) {
+		t.Errorf("YAML of %v parsed as %+v", got[1], handles)
+	}
+
+	if _, err := ConfigHandles("default", "apiVersion: v1\nkind: Service\n"); err == nil {
+		t.Error("expected an error for a config without name")
+	}
+}
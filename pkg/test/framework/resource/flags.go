@@ -18,6 +18,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strconv"
 
 	"istio.io/istio/pkg/test/framework/label"
 	"istio.io/istio/pkg/test/framework/resource/environment"
@@ -60,8 +61,9 @@ func init() {
 	flag.StringVar(&settingsFromCommandLine.Environment, "istio.test.env", settingsFromCommandLine.Environment,
 		fmt.Sprintf("Specify the environment to run the tests against. Allowed values are: %v", environment.Names()))
 
-	flag.BoolVar(&settingsFromCommandLine.NoCleanup, "istio.test.nocleanup", settingsFromCommandLine.NoCleanup,
-		"Do not cleanup resources after test completion")
+	flag.Var(&noCleanupFlag{settingsFromCommandLine}, "istio.test.nocleanup",
+		"Do not cleanup resources after test completion. With 'onfailure', only the resources of the tests that "+
+			"fail are kept, e.g. -istio.test.nocleanup=onfailure")
 
 	flag.BoolVar(&settingsFromCommandLine.CIMode, "istio.test.ci", settingsFromCommandLine.CIMode,
 		"Enable CI Mode. Additional logging and state dumping will be enabled.")
@@ -75,3 +77,43 @@ func init() {
 	flag.BoolVar(&settingsFromCommandLine.FailOnDeprecation, "istio.test.deprecation_failure", settingsFromCommandLine.FailOnDeprecation,
 		"Make tests fail if any usage of deprecated stuff (e.g. Envoy flags) is detected.")
 }
+
+// noCleanupOnFailure is the value of the -istio.test.nocleanup flag keeping the resources of failed tests only.
+const noCleanupOnFailure = "onfailure"
+
+// noCleanupFlag is the -istio.test.nocleanup flag. It is a boolean flag, which also accepts "onfailure".
+type noCleanupFlag struct {
+	s *Settings
+}
+
+var _ flag.Value = &noCleanupFlag{}
+
+func (f *noCleanupFlag) String() string {
+	if f.s == nil {
+		return "false"
+	}
+	if f.s.NoCleanupOnFailure {
+		return noCleanupOnFailure
+	}
+	return strconv.FormatBool(f.s.NoCleanup)
+}
+
+func (f *noCleanupFlag) Set(value string) error {
+	if value == noCleanupOnFailure {
+		f.s.NoCleanup = false
+		f.s.NoCleanupOnFailure = true
+		return nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("must be a boolean or %q", noCleanupOnFailure)
+	}
+	f.s.NoCleanup = b
+	f.s.NoCleanupOnFailure = false
+	return nil
+}
+
+// IsBoolFlag allows the flag to be set without a value, like a boolean flag.
+func (f *noCleanupFlag) IsBoolFlag() bool {
+	return true
+}
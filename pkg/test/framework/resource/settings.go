@@ -43,6 +43,10 @@ type Settings struct {
 	// Do not cleanup the resources after the test run.
 	NoCleanup bool
 
+	// Do not cleanup the resources of a test if it fails, for post-mortem debugging. The resources of the
+	// tests that succeed are cleaned up.
+	NoCleanupOnFailure bool
+
 	// Indicates that the tests are running in CI Mode
 	CIMode bool
 
@@ -96,6 +100,11 @@ func DefaultSettings() *Settings {
 	}
 }
 
+// SkipCleanup returns true if the resources of a test must not be cleaned up, given whether it failed.
+func (s *Settings) SkipCleanup(failed bool) bool {
+	return s.NoCleanup || (s.NoCleanupOnFailure && failed)
+}
+
 // String implements fmt.Stringer
 func (s *Settings) String() string {
 	result := ""
@@ -104,6 +113,7 @@ func (s *Settings) String() string {
 	result += fmt.Sprintf("TestID:            %s\n", s.TestID)
 	result += fmt.Sprintf("RunID:             %s\n", s.RunID.String())
 	result += fmt.Sprintf("NoCleanup:         %v\n", s.NoCleanup)
+	result += fmt.Sprintf("NoCleanupOnFailure: %v\n", s.NoCleanupOnFailure)
 	result += fmt.Sprintf("BaseDir:           %s\n", s.BaseDir)
 	result += fmt.Sprintf("Selector:          %v\n", s.Selector)
 	result += fmt.Sprintf("FailOnDeprecation: %v\n", s.FailOnDeprecation)
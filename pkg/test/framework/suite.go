@@ -52,6 +52,10 @@ var (
 	rtMu sync.Mutex
 )
 
+// TestHook is run around each top-level test of a suite. It is called before the test function and
+// returns a function, which may be nil, called once the test function has returned.
+type TestHook func(ctx TestContext) func()
+
 // mRunFn abstracts testing.M.run, so that the framework itself can be tested.
 type mRunFn func(ctx *suiteContext) int
 
@@ -65,6 +69,7 @@ type Suite struct {
 
 	requireFns []resource.SetupFn
 	setupFns   []resource.SetupFn
+	testHooks  []TestHook
 
 	getSettingsFn func(string) (*resource.Settings, error)
 }
@@ -201,6 +206,14 @@ func (s *Suite) runSetupFn(fn resource.SetupFn, ctx SuiteContext) (err error) {
 	return
 }
 
+// AroundEachTest registers a hook run around each top-level test of the suite, e.g. to check the tests
+// do not leak state to each other. Hooks are run in the order they are registered, and the functions they
+// return in reverse order, once the resources of the test are cleaned up.
+func (s *Suite) AroundEachTest(hook TestHook) *Suite {
+	s.testHooks = append(s.testHooks, hook)
+	return s
+}
+
 // SetupOnEnv runs the given setup function conditionally, based on the current environment.
 func (s *Suite) SetupOnEnv(e environment.Name, fn resource.SetupFn) *Suite {
 	s.Setup(func(ctx resource.Context) error {
@@ -243,6 +256,7 @@ func (s *Suite) run() (errLevel int) {
 	}
 
 	ctx := rt.suiteContext()
+	ctx.testHooks = s.testHooks
 
 	// Skip the test if its explicitly skipped
 	if s.isSkipped() {
@@ -16,7 +16,9 @@ package framework
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 
@@ -295,6 +297,95 @@ func TestSuite_Setup(t *testing.T) {
 	g.Expect(runSkipped).To(BeFalse())
 }
 
+func TestSuite_AroundEachTest(t *testing.T) {
+	defer cleanupRT()
+	g := NewGomegaWithT(t)
+
+	var calls []string
+	runFn := func(ctx *suiteContext) int {
+		NewTest(t).Run(func(ctx TestContext) {
+			calls = append(calls, "test")
+			ctx.NewSubTest("sub").Run(func(ctx TestContext) {
+				calls = append(calls, "subtest")
+			})
+		})
+		return 0
+	}
+
+	s := newSuite("tid", runFn, defaultExitFn, defaultSettingsFn)
+	s.AroundEachTest(func(ctx TestContext) func() {
+		calls = append(calls, "before-1")
+		return func() {
+			calls = append(calls, "after-1")
+		}
+	})
+	s.AroundEachTest(func(ctx TestContext) func() {
+		calls = append(calls, "before-2")
+		return nil
+	})
+	s.AroundEachTest(func(ctx TestContext) func() {
+		calls = append(calls, "before-3")
+		return func() {
+			calls = append(calls, "after-3")
+		}
+	})
+	s.Run()
+
+	g.Expect(calls).To(Equal([]string{"before-1", "before-2", "before-3", "test", "subtest", "after-3", "after-1"}))
+}
+
+func TestSuite_AroundEachTestAfterCleanup(t *testing.T) {
+	defer cleanupRT()
+	g := NewGomegaWithT(t)
+
+	var calls []string
+	runFn := func(ctx *suiteContext) int {
+		NewTest(t).Run(func(ctx TestContext) {
+			ctx.TrackResource(&fakeResource{close: func() {
+				calls = append(calls, "close")
+			}})
+		})
+		return 0
+	}
+
+	s := newSuite("tid", runFn, defaultExitFn, defaultSettingsFn)
+	s.AroundEachTest(func(ctx TestContext) func() {
+		return func() {
+			calls = append(calls, "after")
+		}
+	})
+	s.Run()
+
+	g.Expect(calls).To(Equal([]string{"close", "after"}))
+}
+
+func TestSuite_TestArtifacts(t *testing.T) {
+	defer cleanupRT()
+	g := NewGomegaWithT(t)
+
+	var workDir, dir, artifact string
+	runFn := func(ctx *suiteContext) int {
+		NewTest(t).Run(func(ctx TestContext) {
+			workDir = ctx.WorkDir()
+			dir = ctx.CreateDirectoryOrFail("configs")
+			// Creating an existing directory is not an error.
+			ctx.CreateDirectoryOrFail("configs")
+			artifact = ctx.WriteArtifactOrFail("configs/policy.yaml", []byte("kind: Policy"))
+		})
+		return 0
+	}
+
+	s := newSuite("tid", runFn, defaultExitFn, defaultSettingsFn)
+	s.Run()
+
+	g.Expect(workDir).To(HaveSuffix(t.Name()))
+	g.Expect(dir).To(Equal(filepath.Join(workDir, "configs")))
+	g.Expect(artifact).To(Equal(filepath.Join(dir, "policy.yaml")))
+	data, err := ioutil.ReadFile(artifact)
+	g.Expect(err).To(BeNil())
+	g.Expect(string(data)).To(Equal("kind: Policy"))
+}
+
 func TestSuite_SetupFail(t *testing.T) {
 	defer cleanupRT()
 	g := NewGomegaWithT(t)
@@ -504,7 +595,7 @@ func (f fakeCluster) ApplyConfig(ns string, yamlText ...string) error {
 	panic("implement me")
 }
 
-func (f fakeCluster) ApplyConfigOrFail(t test.Failer, ns string, yamlText ...string) {
+func (f fakeCluster) ApplyConfigOrFail(t test.Failer, ns string, yamlText ...string) []resource.ConfigHandle {
 	panic("implement me")
 }
 
@@ -516,6 +607,14 @@ func (f fakeCluster) DeleteConfigOrFail(t test.Failer, ns string, yamlText ...st
 	panic("implement me")
 }
 
+func (f fakeCluster) DeleteConfigHandles(handles ...resource.ConfigHandle) error {
+	panic("implement me")
+}
+
+func (f fakeCluster) GetStatus(handle resource.ConfigHandle) (map[string]interface{}, error) {
+	panic("implement me")
+}
+
 func (f fakeCluster) ApplyConfigDir(ns string, configDir string) error {
 	panic("implement me")
 }
@@ -531,3 +630,18 @@ func (f fakeCluster) Index() resource.ClusterIndex {
 func (f fakeCluster) String() string {
 	return fmt.Sprintf("fake_cluster_%d", f.index)
 }
+
+var _ resource.Resource = &fakeResource{}
+
+type fakeResource struct {
+	close func()
+}
+
+func (r *fakeResource) ID() resource.ID {
+	return fakeID("resource")
+}
+
+func (r *fakeResource) Close() error {
+	r.close()
+	return nil
+}
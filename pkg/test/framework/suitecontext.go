@@ -55,6 +55,8 @@ type suiteContext struct {
 
 	suiteLabels label.Set
 
+	testHooks []TestHook
+
 	outcomeMu    sync.RWMutex
 	testOutcomes []TestOutcome
 }
@@ -177,10 +179,12 @@ func (s *suiteContext) ApplyConfig(ns string, yamlText ...string) error {
 	return nil
 }
 
-func (s *suiteContext) ApplyConfigOrFail(t test.Failer, ns string, yamlText ...string) {
+func (s *suiteContext) ApplyConfigOrFail(t test.Failer, ns string, yamlText ...string) []resource.ConfigHandle {
+	var handles []resource.ConfigHandle
 	for _, c := range s.Environment().Clusters() {
-		c.ApplyConfigOrFail(t, ns, yamlText...)
+		handles = c.ApplyConfigOrFail(t, ns, yamlText...)
 	}
+	return handles
 }
 
 func (s *suiteContext) DeleteConfig(ns string, yamlText ...string) error {
@@ -198,6 +202,24 @@ func (s *suiteContext) DeleteConfigOrFail(t test.Failer, ns string, yamlText ...
 	}
 }
 
+func (s *suiteContext) DeleteConfigHandles(handles ...resource.ConfigHandle) error {
+	for _, c := range s.Environment().Clusters() {
+		if err := c.DeleteConfigHandles(handles...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetStatus returns the status of the document with the given handle in the first cluster.
+func (s *suiteContext) GetStatus(handle resource.ConfigHandle) (map[string]interface{}, error) {
+	clusters := s.Environment().Clusters()
+	if len(clusters) == 0 {
+		return nil, fmt.Errorf("no cluster to get the status of %v from", handle)
+	}
+	return clusters[0].GetStatus(handle)
+}
+
 func (s *suiteContext) ApplyConfigDir(ns string, configDir string) error {
 	for _, c := range s.Environment().Clusters() {
 		if err := c.ApplyConfigDir(ns, configDir); err != nil {
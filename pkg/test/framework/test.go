@@ -238,8 +238,22 @@ func (t *Test) doRun(ctx *testContext, fn func(ctx TestContext), parallel bool)
 	start := time.Now()
 
 	scopes.CI.Infof("=== BEGIN: Test: '%s[%s]' ===", rt.suiteContext().Settings().TestID, t.goTest.Name())
+	var afterFns []func()
+	if t.parent == nil {
+		for _, hook := range t.s.testHooks {
+			if after := hook(ctx); after != nil {
+				afterFns = append(afterFns, after)
+			}
+		}
+	}
 	defer func() {
 		doneFn := func() {
+			ctx.Done()
+			// The hooks run once the resources of the test are cleaned up, so that they see the state
+			// left behind by the test.
+			for i := len(afterFns) - 1; i >= 0; i-- {
+				afterFns[i]()
+			}
 			message := "passed"
 			if t.goTest.Failed() {
 				message = "failed"
@@ -251,7 +265,6 @@ func (t *Test) doRun(ctx *testContext, fn func(ctx TestContext), parallel bool)
 				t.goTest.Name(),
 				end.Sub(start))
 			rt.suiteContext().registerOutcome(t)
-			ctx.Done()
 		}
 		if t.hasParallelChildren {
 			// If a child is running in parallel, it won't continue until this test returns.
@@ -43,12 +43,18 @@ type TestContext interface {
 	// If this TestContext was not created by a Test or if that Test is not running, this method will panic.
 	NewSubTest(name string) *Test
 
-	// WorkDir allocated for this test.
+	// WorkDir allocated for this test. It is a folder of the run's artifacts directory named after the test, so
+	// sub-tests and parallel tests each get their own.
 	WorkDir() string
 
-	// CreateDirectoryOrFail creates a new sub directory with the given name in the workdir, or fails the test.
+	// CreateDirectoryOrFail creates a sub directory with the given name in the workdir, if it does not already
+	// exist, and returns its path, or fails the test.
 	CreateDirectoryOrFail(name string) string
 
+	// WriteArtifactOrFail writes data to the file with the given name in the workdir, e.g. a rendered config or a
+	// response body, and returns its path, or fails the test. The name may contain sub directories.
+	WriteArtifactOrFail(name string, data []byte) string
+
 	// CreateTmpDirectoryOrFail creates a new temporary directory with the given prefix in the workdir, or fails the test.
 	CreateTmpDirectoryOrFail(prefix string) string
 
@@ -152,7 +158,7 @@ func (c *testContext) Environment() resource.Environment {
 
 func (c *testContext) CreateDirectory(name string) (string, error) {
 	dir := filepath.Join(c.workDir, name)
-	err := os.Mkdir(dir, os.ModePerm)
+	err := os.MkdirAll(dir, os.ModePerm)
 	if err != nil {
 		scopes.Framework.Errorf("Error creating dir: runID='%v', prefix='%s', workDir='%v', err='%v'",
 			c.suite.settings.RunID, name, c.workDir, err)
@@ -170,6 +176,26 @@ func (c *testContext) CreateDirectoryOrFail(name string) string {
 	return tmp
 }
 
+func (c *testContext) WriteArtifact(name string, data []byte) (string, error) {
+	file := filepath.Join(c.workDir, name)
+	if err := os.MkdirAll(filepath.Dir(file), os.ModePerm); err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(file, data, 0644); err != nil {
+		return "", err
+	}
+	scopes.Framework.Debugf("Wrote an artifact: runID='%v', name='%s'", c.suite.settings.RunID, file)
+	return file, nil
+}
+
+func (c *testContext) WriteArtifactOrFail(name string, data []byte) string {
+	file, err := c.WriteArtifact(name, data)
+	if err != nil {
+		c.Fatalf("Error writing artifact %q: %v", name, err)
+	}
+	return file
+}
+
 func (c *testContext) CreateTmpDirectory(prefix string) (string, error) {
 	dir, err := ioutil.TempDir(c.workDir, prefix)
 	if err != nil {
@@ -191,13 +217,18 @@ func (c *testContext) ApplyConfig(ns string, yamlText ...string) error {
 	return nil
 }
 
-func (c *testContext) ApplyConfigOrFail(t test.Failer, ns string, yamlText ...string) {
+func (c *testContext) ApplyConfigOrFail(t test.Failer, ns string, yamlText ...string) []resource.ConfigHandle {
+	var handles []resource.ConfigHandle
 	for _, cc := range c.Environment().Clusters() {
-		cc.ApplyConfigOrFail(t, ns, yamlText...)
+		handles = cc.ApplyConfigOrFail(t, ns, yamlText...)
 	}
+	return handles
 }
 
 func (c *testContext) DeleteConfig(ns string, yamlText ...string) error {
+	if c.keepConfig(nil) {
+		return nil
+	}
 	for _, cc := range c.Environment().Clusters() {
 		if err := cc.DeleteConfig(ns, yamlText...); err != nil {
 			return err
@@ -207,11 +238,51 @@ func (c *testContext) DeleteConfig(ns string, yamlText ...string) error {
 }
 
 func (c *testContext) DeleteConfigOrFail(t test.Failer, ns string, yamlText ...string) {
+	if c.keepConfig(t) {
+		return
+	}
 	for _, cc := range c.Environment().Clusters() {
 		cc.DeleteConfigOrFail(t, ns, yamlText...)
 	}
 }
 
+// keepConfig returns true if the config must not be deleted, as the test (or the subtest t, if any) failed
+// and -istio.test.nocleanup=onfailure is set.
+func (c *testContext) keepConfig(t test.Failer) bool {
+	if !c.suite.settings.NoCleanupOnFailure {
+		return false
+	}
+	failed := c.Failed()
+	if f, ok := t.(interface{ Failed() bool }); ok && f.Failed() {
+		failed = true
+	}
+	if failed {
+		scopes.Framework.Infof("Test %q failed, keeping its config (-istio.test.nocleanup=onfailure)", c.id)
+	}
+	return failed
+}
+
+func (c *testContext) DeleteConfigHandles(handles ...resource.ConfigHandle) error {
+	if c.keepConfig(nil) {
+		return nil
+	}
+	for _, cc := range c.Environment().Clusters() {
+		if err := cc.DeleteConfigHandles(handles...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetStatus returns the status of the document with the given handle in the first cluster.
+func (c *testContext) GetStatus(handle resource.ConfigHandle) (map[string]interface{}, error) {
+	clusters := c.Environment().Clusters()
+	if len(clusters) == 0 {
+		return nil, fmt.Errorf("no cluster to get the status of %v from", handle)
+	}
+	return clusters[0].GetStatus(handle)
+}
+
 func (c *testContext) ApplyConfigDir(ns string, configDir string) error {
 	for _, cc := range c.Environment().Clusters() {
 		if err := cc.ApplyConfigDir(ns, configDir); err != nil {
@@ -276,7 +347,10 @@ func (c *testContext) Done() {
 	}
 
 	scopes.Framework.Debugf("Begin cleaning up testContext: %q", c.id)
-	if err := c.scope.done(c.suite.settings.NoCleanup); err != nil {
+	if c.suite.settings.NoCleanupOnFailure && c.Failed() {
+		scopes.Framework.Infof("Test %q failed, keeping its resources (-istio.test.nocleanup=onfailure)", c.id)
+	}
+	if err := c.scope.done(c.suite.settings.SkipCleanup(c.Failed())); err != nil {
 		c.Logf("error scope cleanup: %v", err)
 		if c.Settings().FailOnDeprecation {
 			if errors.IsOrContainsDeprecatedError(err) {
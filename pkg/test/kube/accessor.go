@@ -447,6 +447,11 @@ func (a *Accessor) GetConfigMap(name, ns string) (*kubeApiCore.ConfigMap, error)
 	return a.set.CoreV1().ConfigMaps(ns).Get(context.TODO(), name, kubeApiMeta.GetOptions{})
 }
 
+// UpdateConfigMap updates the given config map.
+func (a *Accessor) UpdateConfigMap(cm *kubeApiCore.ConfigMap) (*kubeApiCore.ConfigMap, error) {
+	return a.set.CoreV1().ConfigMaps(cm.Namespace).Update(context.TODO(), cm, kubeApiMeta.UpdateOptions{})
+}
+
 // DeleteConfigMap deletes the config resource with the given name and namespace.
 func (a *Accessor) DeleteConfigMap(name, ns string) error {
 	return a.set.CoreV1().ConfigMaps(ns).Delete(context.TODO(), name, kubeApiMeta.DeleteOptions{})
@@ -627,6 +632,16 @@ func (a *Accessor) GetUnstructured(gvr schema.GroupVersionResource, namespace, n
 	return u, nil
 }
 
+// ListUnstructured returns all the unstructured k8s resource objects of the provided schema in the namespace.
+func (a *Accessor) ListUnstructured(gvr schema.GroupVersionResource, namespace string) ([]unstructured.Unstructured, error) {
+	l, err := a.dynClient.Resource(gvr).Namespace(namespace).List(context.TODO(), kubeApiMeta.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list resources of type %v: %v", gvr, err)
+	}
+
+	return l.Items, nil
+}
+
 // DeleteUnstructured deletes an unstructured k8s resource object based on the provided schema, namespace, and name.
 func (a *Accessor) DeleteUnstructured(gvr schema.GroupVersionResource, namespace, name string) error {
 	if err := a.dynClient.Resource(gvr).Namespace(namespace).Delete(context.TODO(), name, kubeApiMeta.DeleteOptions{}); err != nil {
@@ -15,7 +15,9 @@
 package retry
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"istio.io/istio/pkg/test"
@@ -44,6 +46,21 @@ type config struct {
 	timeout  time.Duration
 	delay    time.Duration
 	converge int
+	// retryableCodes, if not nil, are the only status codes retried.
+	retryableCodes map[string]struct{}
+}
+
+// retryable returns false if err carries a status code which is not retryable.
+func (cfg *config) retryable(err error) bool {
+	if cfg.retryableCodes == nil {
+		return true
+	}
+	var sc StatusCoder
+	if !errors.As(err, &sc) {
+		return true
+	}
+	_, ok := cfg.retryableCodes[sc.StatusCode()]
+	return ok
 }
 
 // Option for a retry opteration.
@@ -72,6 +89,24 @@ func Converge(successes int) Option {
 	}
 }
 
+// StatusCoder is implemented by the errors carrying the status code of a response, e.g. an HTTP response
+// code, so that RetryableCodes can tell the transient failures from the final ones.
+type StatusCoder interface {
+	StatusCode() string
+}
+
+// RetryableCodes sets the status codes worth a retry, e.g. a transient 503: an error carrying any other
+// status code (see StatusCoder) ends the retries immediately. The errors without a status code, e.g. when
+// no response is received, are always retried.
+func RetryableCodes(codes ...string) Option {
+	return func(cfg *config) {
+		cfg.retryableCodes = make(map[string]struct{}, len(codes))
+		for _, c := range codes {
+			cfg.retryableCodes[c] = struct{}{}
+		}
+	}
+}
+
 // RetriableFunc a function that can be retried.
 type RetriableFunc func() (result interface{}, completed bool, err error)
 
@@ -130,8 +165,81 @@ func Do(fn RetriableFunc, options ...Option) (interface{}, error) {
 		}
 		if err != nil {
 			lasterr = err
+			if !completed && !cfg.retryable(err) {
+				return nil, fmt.Errorf("non-retryable error: %w", err)
+			}
 		}
 
 		<-time.After(cfg.delay)
 	}
 }
+
+// EventuallyThenAlways calls fn every interval until it succeeds, for up to eventuallyTimeout (at least
+// once), then requires it to keep succeeding for the hold duration. This checks a change, e.g. of a policy,
+// both propagates and then holds steadily. If fn fails, the error lists the timeline of the observations.
+func EventuallyThenAlways(fn func() error, eventuallyTimeout, hold, interval time.Duration) error {
+	var timeline observations
+	start := time.Now()
+	for {
+		err := fn()
+		timeline.add(time.Since(start), err)
+		if err == nil {
+			break
+		}
+		if time.Since(start) >= eventuallyTimeout {
+			return fmt.Errorf("no success within %v:\n%s", eventuallyTimeout, timeline)
+		}
+		time.Sleep(interval)
+	}
+
+	converged := time.Now()
+	for time.Since(converged) < hold {
+		time.Sleep(interval)
+		err := fn()
+		timeline.add(time.Since(start), err)
+		if err != nil {
+			return fmt.Errorf("failed again %v after the first success:\n%s", time.Since(converged), timeline)
+		}
+	}
+	return nil
+}
+
+// EventuallyThenAlwaysOrFail calls EventuallyThenAlways, and fails t with Fatalf if it returns an error.
+func EventuallyThenAlwaysOrFail(t test.Failer, fn func() error, eventuallyTimeout, hold, interval time.Duration) {
+	t.Helper()
+	if err := EventuallyThenAlways(fn, eventuallyTimeout, hold, interval); err != nil {
+		t.Fatalf("retry.EventuallyThenAlwaysOrFail: %v", err)
+	}
+}
+
+// observations is a timeline of the results of a function, with the consecutive identical results
+// grouped.
+type observations []observation
+
+type observation struct {
+	first, last time.Duration
+	count       int
+	err         string
+}
+
+func (o *observations) add(at time.Duration, err error) {
+	result := "ok"
+	if err != nil {
+		result = err.Error()
+	}
+	if n := len(*o); n > 0 && (*o)[n-1].err == result {
+		(*o)[n-1].last = at
+		(*o)[n-1].count++
+		return
+	}
+	*o = append(*o, observation{first: at, last: at, count: 1, err: result})
+}
+
+func (o observations) String() string {
+	lines := make([]string, 0, len(o))
+	for _, obs := range o {
+		lines = append(lines, fmt.Sprintf("  %v - %v (%d times): %s",
+			obs.first.Round(time.Millisecond), obs.last.Round(time.Millisecond), obs.count, obs.err))
+	}
+	return strings.Join(lines, "\n")
+}
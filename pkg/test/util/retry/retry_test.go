@@ -16,6 +16,7 @@ package retry
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 )
@@ -49,3 +50,93 @@ func TestConverge(t *testing.T) {
 		}
 	})
 }
+
+func TestEventuallyThenAlways(t *testing.T) {
+	// scripted returns a function returning the given results in order, then the last one forever.
+	scripted := func(results ...error) func() error {
+		i := 0
+		return func() error {
+			err := results[i]
+			if i < len(results)-1 {
+				i++
+			}
+			return err
+		}
+	}
+	fail := fmt.Errorf("not yet")
+
+	t.Run("converge then hold", func(t *testing.T) {
+		err := EventuallyThenAlways(scripted(fail, fail, nil), time.Second, 10*time.Millisecond, time.Millisecond)
+		if err != nil {
+			t.Fatalf("expected success, got %v", err)
+		}
+	})
+
+	t.Run("never converge", func(t *testing.T) {
+		err := EventuallyThenAlways(scripted(fail), 10*time.Millisecond, time.Second, time.Millisecond)
+		if err == nil {
+			t.Fatal("expected no convergence, but test passed")
+		}
+	})
+
+	t.Run("flip back", func(t *testing.T) {
+		flipped := fmt.Errorf("flipped back")
+		err := EventuallyThenAlways(scripted(fail, nil, nil, flipped, nil), time.Second, time.Second, time.Millisecond)
+		if err == nil {
+			t.Fatal("expected a failure within the hold window, but test passed")
+		}
+		// The timeline lists the observations in order, grouping the consecutive identical ones.
+		for _, want := range []string{"(1 times): not yet", "(2 times): ok", "(1 times): flipped back"} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("expected %q in the timeline, got %v", want, err)
+			}
+		}
+		if strings.Index(err.Error(), "not yet") > strings.Index(err.Error(), "flipped back") {
+			t.Errorf("timeline out of order: %v", err)
+		}
+	})
+}
+
+type codeError string
+
+func (e codeError) Error() string {
+	return "got " + string(e)
+}
+
+func (e codeError) StatusCode() string {
+	return string(e)
+}
+
+func TestRetryableCodes(t *testing.T) {
+	cases := []struct {
+		name      string
+		err       error
+		wantCalls int
+	}{
+		{name: "retryable code", err: codeError("503"), wantCalls: 3},
+		{name: "non-retryable code", err: codeError("403"), wantCalls: 1},
+		{name: "wrapped non-retryable code", err: fmt.Errorf("check: %w", codeError("403")), wantCalls: 1},
+		{name: "no code", err: fmt.Errorf("no response"), wantCalls: 3},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			calls := 0
+			err := UntilSuccess(func() error {
+				calls++
+				if calls < 3 {
+					return c.err
+				}
+				return nil
+			}, RetryableCodes("503"), Timeout(time.Second*10), Delay(time.Millisecond))
+			if calls != c.wantCalls {
+				t.Fatalf("got %d calls, want %d", calls, c.wantCalls)
+			}
+			if (err == nil) != (c.wantCalls == 3) {
+				t.Fatalf("got error %v after %d calls", err, calls)
+			}
+			if err != nil && !strings.Contains(err.Error(), c.err.Error()) {
+				t.Fatalf("got error %v, want it to contain %v", err, c.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,331 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jwt
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jws"
+
+	"istio.io/istio/pkg/test/env"
+)
+
+const (
+	// KeyID is the ID of the sample key in jwks.json, used for all tokens in this package.
+	KeyID = "tT_w9LRNrY7wJalGsTYSt7rutZi86Gvyc0EKR4CaQAw"
+
+	// signatureLen is the length of the base64url encoded RS256 signature of the 2048 bit sample key.
+	signatureLen = 342
+)
+
+// Sign mints a token with the given claims, signed by the sample key in key.pem. The token verifies
+// against the jwks.json in this package.
+func Sign(claims map[string]interface{}) (string, error) {
+	key, err := ioutil.ReadFile(filepath.Join(env.IstioSrc, "tests/common/jwt/key.pem"))
+	if err != nil {
+		return "", fmt.Errorf("failed to read key: %v", err)
+	}
+	block, _ := pem.Decode(key)
+	if block == nil {
+		return "", fmt.Errorf("failed to decode key")
+	}
+	privateKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse key: %v", err)
+	}
+	return sign(claims, privateKey, KeyID)
+}
+
+// JWKS returns the JSON Web Key Set in jwks.json, which the tokens of this package verify against.
+func JWKS() (string, error) {
+	jwks, err := ioutil.ReadFile(filepath.Join(env.IstioSrc, "tests/common/jwt/jwks.json"))
+	if err != nil {
+		return "", fmt.Errorf("failed to read jwks: %v", err)
+	}
+	return string(jwks), nil
+}
+
+func sign(claims map[string]interface{}, privateKey *rsa.PrivateKey, keyID string) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal claims: %v", err)
+	}
+	token, err := jws.SignLiteral(payload, jwa.RS256, privateKey, headerWithKeyID(keyID))
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %v", err)
+	}
+	return string(token), nil
+}
+
+// Claims returns the claims of the token, without verifying it.
+func Claims(token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed token: got %d parts, want 3", len(parts))
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode payload: %v", err)
+	}
+	claims := map[string]interface{}{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("failed to parse payload: %v", err)
+	}
+	return claims, nil
+}
+
+// SigningKey is a generated RSA key, e.g. to test key rotation. Its tokens verify against its own JWKS
+// only.
+type SigningKey struct {
+	// ID of the key, set as "kid" in the JWKS and the header of the tokens.
+	ID string
+
+	key *rsa.PrivateKey
+}
+
+// NewSigningKey generates a 2048 bit RSA key with the given ID.
+func NewSigningKey(id string) (*SigningKey, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %v", err)
+	}
+	return &SigningKey{ID: id, key: key}, nil
+}
+
+// Sign mints a token with the given claims, signed by the key.
+func (k *SigningKey) Sign(claims map[string]interface{}) (string, error) {
+	return sign(claims, k.key, k.ID)
+}
+
+// Token mints a valid token for test-issuer-1@istio.io (sub-1, group-1), signed by the key.
+func (k *SigningKey) Token() (string, error) {
+	return k.Sign(map[string]interface{}{
+		"iss":    "test-issuer-1@istio.io",
+		"sub":    "sub-1",
+		"groups": []string{"group-1"},
+		"iat":    time.Now().Unix(),
+		"exp":    time.Now().Add(time.Hour).Unix(),
+	})
+}
+
+// JWKS returns the JSON Web Key Set with the public key of the key.
+func (k *SigningKey) JWKS() string {
+	return fmt.Sprintf(`{"keys":[{"e":"%s","kid":"%s","kty":"RSA","n":"%s"}]}`, k.exponent(), k.ID, k.modulus())
+}
+
+// SPIFFEBundle returns a SPIFFE trust bundle with the public key of the key as its JWT-SVID authority, as
+// served by the bundle endpoint of a trust domain: a JWKS whose key is used for "jwt-svid", with the
+// sequence number and the refresh hint of the bundle.
+func (k *SigningKey) SPIFFEBundle(sequence int, refreshHint time.Duration) string {
+	return fmt.Sprintf(`{"keys":[{"use":"jwt-svid","e":"%s","kid":"%s","kty":"RSA","n":"%s"}],`+
+		`"spiffe_sequence":%d,"spiffe_refresh_hint":%d}`,
+		k.exponent(), k.ID, k.modulus(), sequence, int64(refreshHint/time.Second))
+}
+
+func (k *SigningKey) exponent() string {
+	return base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.key.E)).Bytes())
+}
+
+func (k *SigningKey) modulus() string {
+	return base64.RawURLEncoding.EncodeToString(k.key.N.Bytes())
+}
+
+// Tamper returns the token with its claims replaced by the given ones, keeping its header and signature,
+// so that the signature no longer matches.
+func Tamper(token string, claims map[string]interface{}) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("malformed token: got %d parts, want 3", len(parts))
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal claims: %v", err)
+	}
+	parts[1] = base64.RawURLEncoding.EncodeToString(payload)
+	return strings.Join(parts, "."), nil
+}
+
+func header() []byte {
+	return headerWithKeyID(KeyID)
+}
+
+func headerWithKeyID(keyID string) []byte {
+	return []byte(fmt.Sprintf(`{"alg":"RS256","kid":"%s","typ":"JWT"}`, keyID))
+}
+
+// TokenWithExpiry mints a token for test-issuer-1@istio.io (sub-1, group-1) that expires at exp. Note
+// Envoy accepts tokens up to 60 seconds after their expiry by default, to allow for clock skew.
+func TokenWithExpiry(exp time.Time) (string, error) {
+	return TokenWithIssuedAt(time.Now(), exp)
+}
+
+// TokenWithIssuedAt mints a token for test-issuer-1@istio.io (sub-1, group-1) issued at iat (the "iat"
+// claim) and expiring at exp, e.g. to mint a token which is old but not expired.
+func TokenWithIssuedAt(iat, exp time.Time) (string, error) {
+	return Sign(map[string]interface{}{
+		"iss":    "test-issuer-1@istio.io",
+		"sub":    "sub-1",
+		"groups": []string{"group-1"},
+		"iat":    iat.Unix(),
+		"exp":    exp.Unix(),
+	})
+}
+
+// TokenWithAudiences mints a valid token for test-issuer-1@istio.io (sub-1, group-1) for the given
+// audiences: the "aud" claim is a string with a single audience, an array otherwise.
+func TokenWithAudiences(audiences ...string) (string, error) {
+	claims := map[string]interface{}{
+		"iss":    "test-issuer-1@istio.io",
+		"sub":    "sub-1",
+		"groups": []string{"group-1"},
+		"iat":    time.Now().Unix(),
+		"exp":    time.Now().Add(time.Hour).Unix(),
+	}
+	if len(audiences) == 1 {
+		claims["aud"] = audiences[0]
+	} else {
+		claims["aud"] = audiences
+	}
+	return Sign(claims)
+}
+
+// TokenWithGroups mints a valid token for test-issuer-1@istio.io (sub-1) whose "groups" claim is an array
+// of the given groups, or without a "groups" claim if there are none.
+func TokenWithGroups(groups ...string) (string, error) {
+	claims := map[string]interface{}{
+		"iss": "test-issuer-1@istio.io",
+		"sub": "sub-1",
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	if len(groups) > 0 {
+		claims["groups"] = groups
+	}
+	return Sign(claims)
+}
+
+// TokenLarge mints a valid token for test-issuer-1@istio.io (sub-1, group-1) padded with a "padding"
+// claim so that the encoded token is nBytes long (or one byte longer, as base64 cannot produce every
+// length). If nBytes is smaller than the unpadded token, the unpadded token is returned.
+//
+// Envoy limits the total size of the request headers (60 KiB by default). The expected behavior is:
+//   - a token that keeps the headers below the limit is validated as usual (200 if otherwise valid).
+//   - a token that pushes the headers over the limit is rejected by the HTTP codec with 431 before
+//     any filter runs, so the response is never a 401 and the proxy must not crash or reset.
+func TokenLarge(nBytes int) (string, error) {
+	claims := map[string]interface{}{
+		"iss":     "test-issuer-1@istio.io",
+		"sub":     "sub-1",
+		"groups":  []string{"group-1"},
+		"iat":     time.Now().Unix(),
+		"exp":     time.Now().Add(time.Hour).Unix(),
+		"padding": "",
+	}
+	encodedLen := func() (int, error) {
+		payload, err := json.Marshal(claims)
+		if err != nil {
+			return 0, err
+		}
+		return base64.RawURLEncoding.EncodedLen(len(header())) + 1 +
+			base64.RawURLEncoding.EncodedLen(len(payload)) + 1 + signatureLen, nil
+	}
+
+	n, err := encodedLen()
+	if err != nil {
+		return "", err
+	}
+	if n < nBytes {
+		// Every 3 bytes of padding add 4 encoded bytes. Grow to just below the target, then one byte at a
+		// time until the target is reached.
+		padding := (nBytes - n) * 3 / 4
+		for ; ; padding++ {
+			claims["padding"] = strings.Repeat("x", padding)
+			if n, err = encodedLen(); err != nil {
+				return "", err
+			}
+			if n >= nBytes {
+				break
+			}
+		}
+	}
+	return Sign(claims)
+}
+
+// ClaimShape is the type of the values of the extra claims of a token minted by TokenWithClaimsOf.
+type ClaimShape string
+
+const (
+	// StringClaims are claims with a string value.
+	StringClaims ClaimShape = "string"
+	// ArrayClaims are claims with an array of three strings as value.
+	ArrayClaims ClaimShape = "array"
+	// MapClaims are claims with a map of a string and a number as value.
+	MapClaims ClaimShape = "map"
+)
+
+// TokenWithClaims mints a valid token for test-issuer-1@istio.io (sub-1, group-1) with n extra claims
+// named "claim-0" to "claim-<n-1>". The extra claims cycle through a string, an array of strings and a
+// map, to cover the value types the JWT filter parses.
+func TokenWithClaims(n int) (string, error) {
+	shapes := []ClaimShape{StringClaims, ArrayClaims, MapClaims}
+	return tokenWithClaims(n, func(i int) ClaimShape { return shapes[i%len(shapes)] })
+}
+
+// TokenWithClaimsOf is TokenWithClaims with the extra claims all of the given shape.
+func TokenWithClaimsOf(n int, shape ClaimShape) (string, error) {
+	switch shape {
+	case StringClaims, ArrayClaims, MapClaims:
+	default:
+		return "", fmt.Errorf("unknown claim shape %q", shape)
+	}
+	return tokenWithClaims(n, func(int) ClaimShape { return shape })
+}
+
+func tokenWithClaims(n int, shapeOf func(i int) ClaimShape) (string, error) {
+	claims := map[string]interface{}{
+		"iss":    "test-issuer-1@istio.io",
+		"sub":    "sub-1",
+		"groups": []string{"group-1"},
+		"iat":    time.Now().Unix(),
+		"exp":    time.Now().Add(time.Hour).Unix(),
+	}
+	for i := 0; i < n; i++ {
+		value := fmt.Sprintf("value-%d", i)
+		switch shapeOf(i) {
+		case StringClaims:
+			claims[fmt.Sprintf("claim-%d", i)] = value
+		case ArrayClaims:
+			claims[fmt.Sprintf("claim-%d", i)] = []string{value + "-a", value + "-b", value + "-c"}
+		case MapClaims:
+			claims[fmt.Sprintf("claim-%d", i)] = map[string]interface{}{
+				"name":  value,
+				"index": i,
+			}
+		}
+	}
+	return Sign(claims)
+}
@@ -96,3 +96,40 @@ const (
 
 	TokenInvalid = "TokenInvalid"
 )
+
+// Name returns a short name of the given token, e.g. to describe test cases: the name of the sample
+// token of this package, "none" for an empty token, or "other" for any other token, e.g. a minted one.
+func Name(token string) string {
+	switch token {
+	case "":
+		return "none"
+	case TokenIssuer1:
+		return "issuer-1"
+	case TokenIssuer1WithAud:
+		return "issuer-1-with-aud"
+	case TokenIssuer1WithAzp:
+		return "issuer-1-with-azp"
+	case TokenIssuer2:
+		return "issuer-2"
+	case TokenIssuer2WithSpaceDelimitedScope:
+		return "issuer-2-with-scope"
+	case TokenExpired:
+		return "expired"
+	case TokenInvalid:
+		return "invalid"
+	default:
+		return "other"
+	}
+}
+
+// FromName returns the sample token of this package with the given name, as returned by Name, and
+// whether there is one. The name "none" is the empty token.
+func FromName(name string) (string, bool) {
+	for _, token := range []string{"", TokenIssuer1, TokenIssuer1WithAud, TokenIssuer1WithAzp, TokenIssuer2,
+		TokenIssuer2WithSpaceDelimitedScope, TokenExpired, TokenInvalid} {
+		if Name(token) == name {
+			return token, true
+		}
+	}
+	return "", false
+}
@@ -16,9 +16,11 @@ package jwt
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/lestrrat-go/jwx/jwa"
 	"github.com/lestrrat-go/jwx/jwk"
@@ -118,3 +120,337 @@ func TestSampleJwtToken(t *testing.T) {
 		}
 	}
 }
+
+func TestTokenLarge(t *testing.T) {
+	key := getKey("jwks.json", t)
+	for _, size := range []int{1024, 1025, 1026, 1027, 60 * 1024} {
+		token, err := TokenLarge(size)
+		if err != nil {
+			t.Fatalf("TokenLarge(%d): %v", size, err)
+		}
+		if len(token) < size || len(token) > size+1 {
+			t.Errorf("TokenLarge(%d): got token of %d bytes", size, len(token))
+		}
+		payload, err := jws.Verify([]byte(token), jwa.RS256, key)
+		if err != nil {
+			t.Fatalf("TokenLarge(%d): failed to verify token: %v", size, err)
+		}
+		claims := map[string]interface{}{}
+		if err := json.Unmarshal(payload, &claims); err != nil {
+			t.Fatalf("TokenLarge(%d): failed to parse payload: %v", size, err)
+		}
+		if claims["iss"] != "test-issuer-1@istio.io" || claims["sub"] != "sub-1" {
+			t.Errorf("TokenLarge(%d): got claims %v", size, claims)
+		}
+	}
+}
+
+func TestTokenWithClaims(t *testing.T) {
+	key := getKey("jwks.json", t)
+	for _, n := range []int{0, 10, 50, 100} {
+		token, err := TokenWithClaims(n)
+		if err != nil {
+			t.Fatalf("TokenWithClaims(%d): %v", n, err)
+		}
+		payload, err := jws.Verify([]byte(token), jwa.RS256, key)
+		if err != nil {
+			t.Fatalf("TokenWithClaims(%d): failed to verify token: %v", n, err)
+		}
+		claims := map[string]interface{}{}
+		if err := json.Unmarshal(payload, &claims); err != nil {
+			t.Fatalf("TokenWithClaims(%d): failed to parse payload: %v", n, err)
+		}
+		if claims["iss"] != "test-issuer-1@istio.io" || claims["sub"] != "sub-1" {
+			t.Errorf("TokenWithClaims(%d): got claims %v", n, claims)
+		}
+		// 5 registered or group claims plus the extra ones.
+		if len(claims) != n+5 {
+			t.Errorf("TokenWithClaims(%d): got %d claims, want %d", n, len(claims), n+5)
+		}
+		if n >= 3 {
+			if _, ok := claims["claim-1"].([]interface{}); !ok {
+				t.Errorf("TokenWithClaims(%d): claim-1 is %T, want an array", n, claims["claim-1"])
+			}
+			if _, ok := claims["claim-2"].(map[string]interface{}); !ok {
+				t.Errorf("TokenWithClaims(%d): claim-2 is %T, want a map", n, claims["claim-2"])
+			}
+		}
+	}
+}
+
+func TestTokenWithClaimsOf(t *testing.T) {
+	key := getKey("jwks.json", t)
+	cases := []struct {
+		shape ClaimShape
+		check func(v interface{}) bool
+	}{
+		{StringClaims, func(v interface{}) bool { _, ok := v.(string); return ok }},
+		{ArrayClaims, func(v interface{}) bool { _, ok := v.([]interface{}); return ok }},
+		{MapClaims, func(v interface{}) bool { _, ok := v.(map[string]interface{}); return ok }},
+	}
+	for _, tc := range cases {
+		token, err := TokenWithClaimsOf(10, tc.shape)
+		if err != nil {
+			t.Fatalf("TokenWithClaimsOf(%s): %v", tc.shape, err)
+		}
+		payload, err := jws.Verify([]byte(token), jwa.RS256, key)
+		if err != nil {
+			t.Fatalf("TokenWithClaimsOf(%s): failed to verify token: %v", tc.shape, err)
+		}
+		claims := map[string]interface{}{}
+		if err := json.Unmarshal(payload, &claims); err != nil {
+			t.Fatalf("TokenWithClaimsOf(%s): failed to parse payload: %v", tc.shape, err)
+		}
+		for i := 0; i < 10; i++ {
+			name := fmt.Sprintf("claim-%d", i)
+			if !tc.check(claims[name]) {
+				t.Errorf("TokenWithClaimsOf(%s): %s is %T", tc.shape, name, claims[name])
+			}
+		}
+	}
+
+	if _, err := TokenWithClaimsOf(1, "number"); err == nil {
+		t.Error("TokenWithClaimsOf(number): expected error")
+	}
+}
+
+func TestTokenWithExpiry(t *testing.T) {
+	key := getKey("jwks.json", t)
+	exp := time.Now().Add(30 * time.Second)
+	token, err := TokenWithExpiry(exp)
+	if err != nil {
+		t.Fatalf("TokenWithExpiry: %v", err)
+	}
+	payload, err := jws.Verify([]byte(token), jwa.RS256, key)
+	if err != nil {
+		t.Fatalf("TokenWithExpiry: failed to verify token: %v", err)
+	}
+	claims := map[string]interface{}{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		t.Fatalf("TokenWithExpiry: failed to parse payload: %v", err)
+	}
+	if claims["iss"] != "test-issuer-1@istio.io" || claims["sub"] != "sub-1" {
+		t.Errorf("TokenWithExpiry: got claims %v", claims)
+	}
+	if got := claims["exp"]; got != float64(exp.Unix()) {
+		t.Errorf("TokenWithExpiry: got exp %v, want %d", got, exp.Unix())
+	}
+}
+
+func TestTokenWithIssuedAt(t *testing.T) {
+	iat := time.Now().Add(-90 * time.Second)
+	exp := iat.Add(time.Hour)
+	token, err := TokenWithIssuedAt(iat, exp)
+	if err != nil {
+		t.Fatalf("TokenWithIssuedAt: %v", err)
+	}
+	claims, err := Claims(token)
+	if err != nil {
+		t.Fatalf("TokenWithIssuedAt: %v", err)
+	}
+	if got := claims["iat"]; got != float64(iat.Unix()) {
+		t.Errorf("TokenWithIssuedAt: got iat %v, want %d", got, iat.Unix())
+	}
+	if got := claims["exp"]; got != float64(exp.Unix()) {
+		t.Errorf("TokenWithIssuedAt: got exp %v, want %d", got, exp.Unix())
+	}
+}
+
+func TestTokenWithAudiences(t *testing.T) {
+	cases := []struct {
+		audiences []string
+		want      interface{}
+	}{
+		{audiences: []string{"aud-a"}, want: "aud-a"},
+		{audiences: []string{"aud-a", "aud-b"}, want: []interface{}{"aud-a", "aud-b"}},
+	}
+	for _, c := range cases {
+		token, err := TokenWithAudiences(c.audiences...)
+		if err != nil {
+			t.Fatalf("TokenWithAudiences(%v): %v", c.audiences, err)
+		}
+		claims, err := Claims(token)
+		if err != nil {
+			t.Fatalf("TokenWithAudiences(%v): %v", c.audiences, err)
+		}
+		if got := claims["aud"]; !reflect.DeepEqual(got, c.want) {
+			t.Errorf("TokenWithAudiences(%v): got aud %v, want %v", c.audiences, got, c.want)
+		}
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	data, err := JWKS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwks, err := jwk.ParseString(data)
+	if err != nil {
+		t.Fatalf("failed to parse jwks %s: %v", data, err)
+	}
+	if got := jwks.Keys[0].KeyID(); got != KeyID {
+		t.Errorf("got key ID %q, want %q", got, KeyID)
+	}
+}
+
+func TestTokenWithGroups(t *testing.T) {
+	cases := []struct {
+		groups []string
+		want   interface{}
+	}{
+		{groups: []string{"admin", "editor"}, want: []interface{}{"admin", "editor"}},
+		{groups: []string{"viewer"}, want: []interface{}{"viewer"}},
+		{groups: nil, want: nil},
+	}
+	for _, c := range cases {
+		token, err := TokenWithGroups(c.groups...)
+		if err != nil {
+			t.Fatalf("TokenWithGroups(%v): %v", c.groups, err)
+		}
+		claims, err := Claims(token)
+		if err != nil {
+			t.Fatalf("TokenWithGroups(%v): %v", c.groups, err)
+		}
+		if got := claims["groups"]; !reflect.DeepEqual(got, c.want) {
+			t.Errorf("TokenWithGroups(%v): got groups %v, want %v", c.groups, got, c.want)
+		}
+	}
+}
+
+func TestSigningKey(t *testing.T) {
+	key, err := NewSigningKey("rotated")
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwks, err := jwk.ParseString(key.JWKS())
+	if err != nil {
+		t.Fatalf("failed to parse jwks %s: %v", key.JWKS(), err)
+	}
+	if got := jwks.Keys[0].KeyID(); got != "rotated" {
+		t.Errorf("got key ID %q, want %q", got, "rotated")
+	}
+	publicKey, err := jwks.Keys[0].Materialize()
+	if err != nil {
+		t.Fatalf("failed to materialize jwks: %v", err)
+	}
+
+	token, err := key.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jws.Verify([]byte(token), jwa.RS256, publicKey); err != nil {
+		t.Errorf("failed to verify token: %v", err)
+	}
+	if _, err := jws.Verify([]byte(token), jwa.RS256, getKey("jwks.json", t)); err == nil {
+		t.Errorf("token verified against the sample key")
+	}
+}
+
+func TestSPIFFEBundle(t *testing.T) {
+	key, err := NewSigningKey("spiffe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := key.SPIFFEBundle(2, 5*time.Minute)
+	bundle := struct {
+		Keys []struct {
+			Use string `json:"use"`
+		} `json:"keys"`
+		Sequence    int `json:"spiffe_sequence"`
+		RefreshHint int `json:"spiffe_refresh_hint"`
+	}{}
+	if err := json.Unmarshal([]byte(data), &bundle); err != nil {
+		t.Fatalf("failed to parse bundle %s: %v", data, err)
+	}
+	if len(bundle.Keys) != 1 || bundle.Keys[0].Use != "jwt-svid" || bundle.Sequence != 2 || bundle.RefreshHint != 300 {
+		t.Errorf("unexpected bundle %s", data)
+	}
+
+	jwks, err := jwk.ParseString(data)
+	if err != nil {
+		t.Fatalf("failed to parse bundle %s as jwks: %v", data, err)
+	}
+	publicKey, err := jwks.Keys[0].Materialize()
+	if err != nil {
+		t.Fatalf("failed to materialize jwks: %v", err)
+	}
+	token, err := key.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := jws.Verify([]byte(token), jwa.RS256, publicKey); err != nil {
+		t.Errorf("failed to verify token: %v", err)
+	}
+}
+
+func TestTamper(t *testing.T) {
+	claims := map[string]interface{}{"iss": "test-issuer-1@istio.io", "sub": "tampered"}
+	token, err := Tamper(TokenIssuer1, claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Claims(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, claims) {
+		t.Errorf("got claims %v, want %v", got, claims)
+	}
+	if _, err := jws.Verify([]byte(token), jwa.RS256, getKey("jwks.json", t)); err == nil {
+		t.Errorf("tampered token verified against the sample key")
+	}
+	if _, err := Tamper("not-a-token", claims); err == nil {
+		t.Errorf("expected an error for a malformed token")
+	}
+}
+
+func TestClaims(t *testing.T) {
+	claims, err := Claims(TokenIssuer1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"groups": []interface{}{"group-1"},
+		"iss":    "test-issuer-1@istio.io",
+		"sub":    "sub-1",
+		"exp":    4715782722.0,
+	}
+	for k, v := range want {
+		if got := claims[k]; !reflect.DeepEqual(got, v) {
+			t.Errorf("claim %q got value %v but want %v", k, got, v)
+		}
+	}
+
+	if _, err := Claims("not-a-token"); err == nil {
+		t.Errorf("got claims of a malformed token")
+	}
+}
+
+func TestName(t *testing.T) {
+	cases := map[string]string{
+		"":                  "none",
+		TokenIssuer1:        "issuer-1",
+		TokenIssuer2:        "issuer-2",
+		TokenExpired:        "expired",
+		TokenInvalid:        "invalid",
+		TokenIssuer1 + "x":  "other",
+		TokenIssuer1WithAud: "issuer-1-with-aud",
+	}
+	for token, want := range cases {
+		if got := Name(token); got != want {
+			t.Errorf("Name(%.20q): got %s, want %s", token, got, want)
+		}
+	}
+}
+
+func TestFromName(t *testing.T) {
+	for _, token := range []string{"", TokenIssuer1, TokenIssuer2WithSpaceDelimitedScope, TokenExpired, TokenInvalid} {
+		got, ok := FromName(Name(token))
+		if !ok || got != token {
+			t.Errorf("FromName(%q) = %q, %v, want the token", Name(token), got, ok)
+		}
+	}
+	if _, ok := FromName("other"); ok {
+		t.Errorf("FromName(other) found a token")
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// authncheck replays a single authn.TestCase against the echo services of the security tests already
+// deployed in a cluster, e.g. kept with -istio.test.nocleanup, without the lifecycle of the test
+// framework. The request is checked by the same code as in the tests, and dumped with the responses:
+//
+//	authncheck --namespace req-authn-1-2345 --from a --to b --token expired --expect unauthenticated
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"istio.io/istio/pkg/test/echo/common/scheme"
+	"istio.io/istio/pkg/test/framework/components/echo"
+	echokube "istio.io/istio/pkg/test/framework/components/echo/kube"
+	kubeEnv "istio.io/istio/pkg/test/framework/components/environment/kube"
+	"istio.io/istio/pkg/test/kube"
+	"istio.io/istio/tests/common/jwt"
+	"istio.io/istio/tests/integration/security/util"
+	"istio.io/istio/tests/integration/security/util/authn"
+	"istio.io/istio/tests/integration/security/util/connection"
+)
+
+var (
+	kubeConfig = flag.String("kubeconfig", os.Getenv("KUBECONFIG"), "Kubeconfig of the cluster, $KUBECONFIG by default")
+	ns         = flag.String("namespace", "", "Namespace of the echo services")
+	from       = flag.String("from", "a", "Echo service sending the request")
+	to         = flag.String("to", "b", "Echo service receiving the request")
+	portName   = flag.String("port", "http", "Port of the target called")
+	callScheme = flag.String("scheme", string(scheme.HTTP), "Scheme of the call, e.g. http, https or grpc")
+	path       = flag.String("path", "", "Path of the request")
+	token      = flag.String("token", "none",
+		"Bearer token sent: the name of a sample token, e.g. issuer-1 or expired, or the file of a token")
+	expect = flag.String("expect", authn.Allowed.String(),
+		"Expected result, e.g. allowed, unauthenticated or denied")
+)
+
+// namespace is a namespace of the cluster, which the checks do not own.
+type namespace string
+
+func (n namespace) Name() string {
+	return string(n)
+}
+
+func main() {
+	flag.Parse()
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	if *ns == "" {
+		return fmt.Errorf("--namespace is required")
+	}
+	expectResult, err := authn.ParseExpectedResult(*expect)
+	if err != nil {
+		return err
+	}
+	bearer, err := readToken(*token)
+	if err != nil {
+		return err
+	}
+
+	workDir, err := ioutil.TempDir("", "authncheck")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.RemoveAll(workDir) }()
+	accessor, err := kube.NewAccessor(*kubeConfig, workDir)
+	if err != nil {
+		return err
+	}
+	cluster := kubeEnv.Cluster{Accessor: accessor}
+
+	source, err := attach(cluster, *from)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = source.(io.Closer).Close() }()
+	target, err := attach(cluster, *to)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = target.(io.Closer).Close() }()
+
+	c := authn.TestCase{
+		Name: "authncheck",
+		Request: connection.Checker{
+			From: source,
+			Options: echo.CallOptions{
+				Target:   target,
+				PortName: *portName,
+				Scheme:   scheme.Instance(*callScheme),
+				Path:     *path,
+				Token:    bearer,
+			},
+		},
+		ExpectResult: expectResult,
+	}
+	return c.CheckAuthnVerbose(os.Stdout)
+}
+
+// attach attaches to the echo service with the given name, deployed with the config of the tests.
+func attach(cluster kubeEnv.Cluster, service string) (echo.Instance, error) {
+	instance, err := echokube.Attach(cluster, util.EchoConfig(service, namespace(*ns), false, nil, nil))
+	if err != nil {
+		return nil, fmt.Errorf("failed to attach to %s/%s: %v", *ns, service, err)
+	}
+	return instance, nil
+}
+
+// readToken returns the sample token with the given name, or else the token in the file of the given name.
+func readToken(nameOrFile string) (string, error) {
+	if t, ok := jwt.FromName(nameOrFile); ok {
+		return t, nil
+	}
+	b, err := ioutil.ReadFile(nameOrFile)
+	if err != nil {
+		return "", fmt.Errorf("--token %q is neither a sample token nor a readable file: %v", nameOrFile, err)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
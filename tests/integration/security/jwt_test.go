@@ -15,35 +15,77 @@
 package security
 
 import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
+	envoyAdmin "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
+	"github.com/golang/protobuf/ptypes"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc/codes"
+
+	authnmodel "istio.io/istio/pilot/pkg/security/model"
+	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/test/echo/client"
+	"istio.io/istio/pkg/test/echo/common"
 	"istio.io/istio/pkg/test/echo/common/response"
 	"istio.io/istio/pkg/test/echo/common/scheme"
+	"istio.io/istio/pkg/test/env"
+	"istio.io/istio/pkg/test/envoy/accesslog"
 	"istio.io/istio/pkg/test/framework"
 	"istio.io/istio/pkg/test/framework/components/echo"
 	"istio.io/istio/pkg/test/framework/components/echo/echoboot"
+	echokube "istio.io/istio/pkg/test/framework/components/echo/kube"
+	"istio.io/istio/pkg/test/framework/components/environment/kube"
 	"istio.io/istio/pkg/test/framework/components/ingress"
+	"istio.io/istio/pkg/test/framework/components/istio"
 	"istio.io/istio/pkg/test/framework/components/namespace"
+	"istio.io/istio/pkg/test/framework/components/prometheus"
+	"istio.io/istio/pkg/test/framework/resource"
 	"istio.io/istio/pkg/test/framework/resource/environment"
 	"istio.io/istio/pkg/test/util/file"
 	"istio.io/istio/pkg/test/util/retry"
 	"istio.io/istio/pkg/test/util/tmpl"
 	"istio.io/istio/tests/common/jwt"
+	ingressutil "istio.io/istio/tests/integration/security/sds_ingress/util"
 	"istio.io/istio/tests/integration/security/util"
 	"istio.io/istio/tests/integration/security/util/authn"
 	"istio.io/istio/tests/integration/security/util/connection"
+	"istio.io/istio/tests/integration/security/util/egress"
+	"istio.io/istio/tests/integration/security/util/envoyconfig"
+	"istio.io/istio/tests/integration/security/util/extauthz"
+	"istio.io/istio/tests/integration/security/util/jwks"
+	"istio.io/istio/tests/integration/security/util/jwtmatrix"
+	"istio.io/istio/tests/integration/security/util/kiali"
+	"istio.io/istio/tests/integration/security/util/latency"
+	"istio.io/istio/tests/integration/security/util/metrics"
+	"istio.io/istio/tests/integration/security/util/mtlsmode"
+	"istio.io/istio/tests/integration/security/util/timeout"
+	"istio.io/istio/tests/integration/security/util/traffic"
+
+	kubeCore "k8s.io/api/core/v1"
 )
 
 const (
 	authHeaderKey = "Authorization"
+
+	// jwksPrewarmFile is the name of the artifact recording the JWKS pre-warmings of the targets of a
+	// matrix, written to the work dir of its test, under the one of the run with the coverage manifest.
+	jwksPrewarmFile = "jwks-prewarm.json"
 )
 
 // TestRequestAuthentication tests beta authn policy for jwt.
 func TestRequestAuthentication(t *testing.T) {
-	payload1 := strings.Split(jwt.TokenIssuer1, ".")[1]
-	payload2 := strings.Split(jwt.TokenIssuer2, ".")[1]
+	matrix := jwtMatrix(t)
 	framework.NewTest(t).
 		RequiresEnvironment(environment.Kube).
 		Run(func(ctx framework.TestContext) {
@@ -51,222 +93,59 @@ func TestRequestAuthentication(t *testing.T) {
 				Prefix: "req-authn",
 				Inject: true,
 			})
+			// The access logs tell which filter rejected a request.
+			istio.EnableAccessLogsOrFail(t, ctx, ist)
 
 			// Apply the policy.
-			namespaceTmpl := map[string]string{
-				"Namespace": ns.Name(),
-			}
-			jwtPolicies := tmpl.EvaluateAllOrFail(t, namespaceTmpl,
-				file.AsStringOrFail(t, "testdata/requestauthn/a-authn.yaml.tmpl"),
-				file.AsStringOrFail(t, "testdata/requestauthn/b-authn-authz.yaml.tmpl"),
-				file.AsStringOrFail(t, "testdata/requestauthn/c-authn.yaml.tmpl"),
-				file.AsStringOrFail(t, "testdata/requestauthn/e-authn.yaml.tmpl"),
-			)
+			jwtPolicies := matrixPolicies(t, matrix, ns)
 			ctx.ApplyConfigOrFail(t, ns.Name(), jwtPolicies...)
 			defer ctx.DeleteConfigOrFail(t, ns.Name(), jwtPolicies...)
 
-			var a, b, c, d, e echo.Instance
+			var a, b, c, d, e, f echo.Instance
 			echoboot.NewBuilderOrFail(ctx, ctx).
 				With(&a, util.EchoConfig("a", ns, false, nil, p)).
 				With(&b, util.EchoConfig("b", ns, false, nil, p)).
 				With(&c, util.EchoConfig("c", ns, false, nil, p)).
 				With(&d, util.EchoConfig("d", ns, false, nil, p)).
 				With(&e, util.EchoConfig("e", ns, false, nil, p)).
+				With(&f, util.EchoConfig("f", ns, false, nil, p)).
 				BuildOrFail(t)
 
-			testCases := []authn.TestCase{
-				{
-					Name: "valid-token-noauthz",
-					Request: connection.Checker{
-						From: a,
-						Options: echo.CallOptions{
-							Target:   c,
-							PortName: "http",
-							Scheme:   scheme.HTTP,
-							Headers: map[string][]string{
-								authHeaderKey: {"Bearer " + jwt.TokenIssuer1},
-							},
-						},
-					},
-					ExpectResponseCode: response.StatusCodeOK,
-					ExpectHeaders: map[string]string{
-						authHeaderKey:    "",
-						"X-Test-Payload": payload1,
-					},
-				},
-				{
-					Name: "valid-token-2-noauthz",
-					Request: connection.Checker{
-						From: a,
-						Options: echo.CallOptions{
-							Target:   c,
-							PortName: "http",
-							Scheme:   scheme.HTTP,
-							Headers: map[string][]string{
-								authHeaderKey: {"Bearer " + jwt.TokenIssuer2},
-							},
-						},
-					},
-					ExpectResponseCode: response.StatusCodeOK,
-					ExpectHeaders: map[string]string{
-						authHeaderKey:    "",
-						"X-Test-Payload": payload2,
-					},
-				},
-				{
-					Name: "expired-token-noauthz",
-					Request: connection.Checker{
-						From: a,
-						Options: echo.CallOptions{
-							Target:   c,
-							PortName: "http",
-							Scheme:   scheme.HTTP,
-							Headers: map[string][]string{
-								authHeaderKey: {"Bearer " + jwt.TokenExpired},
-							},
-						},
-					},
-					ExpectResponseCode: response.StatusUnauthorized,
-				},
-				{
-					Name: "no-token-noauthz",
-					Request: connection.Checker{
-						From: a,
-						Options: echo.CallOptions{
-							Target:   c,
-							PortName: "http",
-							Scheme:   scheme.HTTP,
-						},
-					},
-					ExpectResponseCode: response.StatusCodeOK,
-				},
-				// Following app b is configured with authorization, only request with valid JWT succeed.
-				{
-					Name: "valid-token",
-					Request: connection.Checker{
-						From: a,
-						Options: echo.CallOptions{
-							Target:   b,
-							PortName: "http",
-							Scheme:   scheme.HTTP,
-							Headers: map[string][]string{
-								authHeaderKey: {"Bearer " + jwt.TokenIssuer1},
-							},
-						},
-					},
-					ExpectResponseCode: response.StatusCodeOK,
-					ExpectHeaders: map[string]string{
-						authHeaderKey: "",
-					},
-				},
-				{
-					Name: "expired-token",
-					Request: connection.Checker{
-						From: a,
-						Options: echo.CallOptions{
-							Target:   b,
-							PortName: "http",
-							Scheme:   scheme.HTTP,
-							Headers: map[string][]string{
-								authHeaderKey: {"Bearer " + jwt.TokenExpired},
-							},
-						},
-					},
-					ExpectResponseCode: response.StatusUnauthorized,
-				},
-				{
-					Name: "no-token",
-					Request: connection.Checker{
-						From: a,
-						Options: echo.CallOptions{
-							Target:   b,
-							PortName: "http",
-							Scheme:   scheme.HTTP,
-						},
-					},
-					ExpectResponseCode: response.StatusCodeForbidden,
-				},
-				{
-					Name: "no-authn-authz",
-					Request: connection.Checker{
-						From: a,
-						Options: echo.CallOptions{
-							Target:   d,
-							PortName: "http",
-							Scheme:   scheme.HTTP,
-						},
-					},
-					ExpectResponseCode: response.StatusCodeOK,
-				},
-				{
-					Name: "valid-token-forward",
-					Request: connection.Checker{
-						From: a,
-						Options: echo.CallOptions{
-							Target:   e,
-							PortName: "http",
-							Scheme:   scheme.HTTP,
-							Headers: map[string][]string{
-								authHeaderKey: {"Bearer " + jwt.TokenIssuer1},
-							},
-						},
-					},
-					ExpectResponseCode: response.StatusCodeOK,
-					ExpectHeaders: map[string]string{
-						authHeaderKey:    "Bearer " + jwt.TokenIssuer1,
-						"X-Test-Payload": payload1,
-					},
-				},
-				{
-					Name: "invalid aud",
-					Request: connection.Checker{
-						From: b,
-						Options: echo.CallOptions{
-							Target:   a,
-							PortName: "http",
-							Scheme:   scheme.HTTP,
-							Headers: map[string][]string{
-								authHeaderKey: {"Bearer " + jwt.TokenIssuer1},
-							},
-						},
-					},
-					ExpectResponseCode: response.StatusCodeForbidden,
-				},
-				{
-					Name: "valid aud",
-					Request: connection.Checker{
-						From: b,
-						Options: echo.CallOptions{
-							Target:   a,
-							PortName: "http",
-							Scheme:   scheme.HTTP,
-							Headers: map[string][]string{
-								authHeaderKey: {"Bearer " + jwt.TokenIssuer1WithAud},
-							},
-						},
-					},
-					ExpectResponseCode: response.StatusCodeOK,
-				},
-				{
-					Name: "verify policies are combined",
-					Request: connection.Checker{
-						From: b,
-						Options: echo.CallOptions{
-							Target:   a,
-							PortName: "http",
-							Scheme:   scheme.HTTP,
-							Headers: map[string][]string{
-								authHeaderKey: {"Bearer " + jwt.TokenIssuer2},
-							},
-						},
-					},
-					ExpectResponseCode: response.StatusCodeOK,
-				},
+			for _, tc := range matrixTestCases(t, ctx, matrix, a, b, c, d, e, f) {
+				// A 401 must come from the JWT filter and a 403 from the authorization policy, not the other.
+				tc.ExpectRejectedBy = tc.ExpectResult.RejectedBy()
+				t.Run(tc.Name, func(t *testing.T) {
+					retry.UntilSuccessOrFail(t, tc.CheckAuthn, authn.RetryOptions()...)
+				})
 			}
-			for _, c := range testCases {
+		})
+}
+
+// TestRequestAuthentication_NegativeMatch tests the authorization policy with notRequestPrincipals
+// combined with beta authn policy for jwt.
+func TestRequestAuthentication_NegativeMatch(t *testing.T) {
+	matrix := jwtMatrix(t)
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-negative-match",
+				Inject: true,
+			})
+
+			policies := matrixPolicies(t, matrix, ns)
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			var a, b echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&b, util.EchoConfig("b", ns, false, nil, p)).
+				BuildOrFail(t)
+
+			for _, c := range matrixTestCases(t, ctx, matrix, a, b) {
 				t.Run(c.Name, func(t *testing.T) {
-					retry.UntilSuccessOrFail(t, c.CheckAuthn,
-						retry.Delay(250*time.Millisecond), retry.Timeout(30*time.Second))
+					retry.UntilSuccessOrFail(t, c.CheckAuthn, authn.RetryOptions()...)
 				})
 			}
 		})
@@ -297,14 +176,9 @@ func TestIngressRequestAuthentication(t *testing.T) {
 				"RootNamespace": rootNamespace,
 			}
 
-			applyPolicy := func(filename string, ns namespace.Instance) []string {
-				policy := tmpl.EvaluateAllOrFail(t, namespaceTmpl, file.AsStringOrFail(t, filename))
-				ctx.ApplyConfigOrFail(t, ns.Name(), policy...)
-				return policy
-			}
-
-			securityPolicies := applyPolicy("testdata/requestauthn/global-jwt.yaml.tmpl", rootNS{})
-			ingressCfgs := applyPolicy("testdata/requestauthn/ingress.yaml.tmpl", ns)
+			securityPolicies := applyPolicy(t, ctx, namespaceTmpl,
+				"testdata/requestauthn/global-jwt.yaml.tmpl", rootNS{})
+			ingressCfgs := applyPolicy(t, ctx, namespaceTmpl, "testdata/requestauthn/ingress.yaml.tmpl", ns)
 
 			defer ctx.DeleteConfigOrFail(t, rootNS{}.Name(), securityPolicies...)
 			defer ctx.DeleteConfigOrFail(t, ns.Name(), ingressCfgs...)
@@ -325,9 +199,7 @@ func TestIngressRequestAuthentication(t *testing.T) {
 							Target:   b,
 							PortName: "http",
 							Scheme:   scheme.HTTP,
-							Headers: map[string][]string{
-								authHeaderKey: {"Bearer " + jwt.TokenExpired},
-							},
+							Token:    jwt.TokenExpired,
 						},
 					},
 					ExpectResponseCode: response.StatusUnauthorized,
@@ -347,19 +219,29 @@ func TestIngressRequestAuthentication(t *testing.T) {
 			}
 			for _, c := range testCases {
 				t.Run(c.Name, func(t *testing.T) {
-					retry.UntilSuccessOrFail(t, c.CheckAuthn,
-						retry.Delay(250*time.Millisecond), retry.Timeout(30*time.Second))
+					retry.UntilSuccessOrFail(t, c.CheckAuthn, authn.RetryOptions()...)
 				})
 			}
 
+			// mintExpiringIn returns a function minting a token of issuer 1 (sub-1) expiring at the given offset
+			// from the time it is called, so that the expiry is relative to the request however late it is sent.
+			mintExpiringIn := func(d time.Duration) func() (string, error) {
+				return func() (string, error) {
+					return jwt.TokenWithExpiry(time.Now().Add(d))
+				}
+			}
+
 			// These test cases verify requests go through ingress will be checked for validate token.
-			ingTestCases := []struct {
-				Name               string
-				Host               string
-				Path               string
-				Token              string
+			type ingTestCase struct {
+				Name  string
+				Host  string
+				Path  string
+				Token string
+				// MintToken, if set, takes precedence over Token: a fresh token is minted for each request.
+				MintToken          func() (string, error)
 				ExpectResponseCode int
-			}{
+			}
+			ingTestCases := []ingTestCase{
 				{
 					Name:               "deny without token",
 					Host:               "example.com",
@@ -387,6 +269,21 @@ func TestIngressRequestAuthentication(t *testing.T) {
 					Token:              jwt.TokenExpired,
 					ExpectResponseCode: 401,
 				},
+				{
+					Name:               "allow with sub-1 token expiring in a minute",
+					Host:               "example.com",
+					Path:               "/",
+					MintToken:          mintExpiringIn(time.Minute),
+					ExpectResponseCode: 200,
+				},
+				{
+					// Beyond the 60 seconds of clock skew the gateway allows.
+					Name:               "deny with sub-1 token expired 5 minutes ago",
+					Host:               "example.com",
+					Path:               "/",
+					MintToken:          mintExpiringIn(-5 * time.Minute),
+					ExpectResponseCode: 401,
+				},
 				{
 					Name:               "allow with sub-1 token on any.com",
 					Host:               "any-request-principlal-ok.com",
@@ -414,21 +311,4629 @@ func TestIngressRequestAuthentication(t *testing.T) {
 					Token:              jwt.TokenIssuer1,
 					ExpectResponseCode: 403,
 				},
-				{
-					Name:               "allow healthz",
+			}
+			// The paths exempted from the token by the policy, and next to them, as the policy changes.
+			for _, c := range authn.PathCasesOrFail(t, securityPolicies...) {
+				code, name := http.StatusOK, "allow without token on "+c.Path
+				if c.RequiresToken {
+					code, name = http.StatusForbidden, "deny without token on "+c.Path
+				}
+				ingTestCases = append(ingTestCases, ingTestCase{
+					Name:               name,
 					Host:               "example.com",
-					Path:               "/healthz",
-					ExpectResponseCode: 200,
-				},
+					Path:               c.Path,
+					ExpectResponseCode: code,
+				}, ingTestCase{
+					Name:               "allow with sub-1 token on " + c.Path,
+					Host:               "example.com",
+					Path:               c.Path,
+					Token:              jwt.TokenIssuer1,
+					ExpectResponseCode: http.StatusOK,
+				})
 			}
 
 			for _, c := range ingTestCases {
 				t.Run(c.Name, func(t *testing.T) {
 					retry.UntilSuccessOrFail(t, func() error {
-						return authn.CheckIngress(ingr, c.Host, c.Path, c.Token, c.ExpectResponseCode)
-					},
-						retry.Delay(250*time.Millisecond), retry.Timeout(30*time.Second))
+						token := c.Token
+						if c.MintToken != nil {
+							var err error
+							if token, err = c.MintToken(); err != nil {
+								return err
+							}
+						}
+						return authn.CheckIngress(ingr, c.Host, c.Path, token, c.ExpectResponseCode)
+					}, authn.RetryOptions()...)
+				})
+			}
+
+			// The gateway logs why the request with an expired token is rejected. The request is told apart
+			// by its path, as the gateway replaces the request ID of external requests.
+			t.Run("access log of expired token", func(t *testing.T) {
+				istio.EnableAccessLogsOrFail(t, ctx, ist)
+				// Allow for clock skew between the test and the gateway pods.
+				since := time.Now().Add(-time.Minute)
+				path := fmt.Sprintf("/expired-token-%d", rand.Int())
+				retry.UntilSuccessOrFail(t, func() error {
+					return authn.CheckIngress(ingr, "example.com", path, jwt.TokenExpired, http.StatusUnauthorized)
+				}, authn.RetryOptions()...)
+				retry.UntilSuccessOrFail(t, func() error {
+					entries, err := ingr.AccessLogs(since)
+					if err != nil {
+						return err
+					}
+					entries = entries.WithHost("example.com").WithPath(path)
+					if len(entries) == 0 {
+						return fmt.Errorf("no access log entry for %s", path)
+					}
+					// With several gateway replicas, each may have logged one of the requests.
+					for _, e := range entries {
+						if e.ResponseCode != http.StatusUnauthorized ||
+							!strings.HasPrefix(e.ResponseCodeDetails, "jwt_authn_access_denied") {
+							return fmt.Errorf("%s logged %s with code %d and details %q, want %d and jwt_authn_access_denied",
+								e.Pod, path, e.ResponseCode, e.ResponseCodeDetails, http.StatusUnauthorized)
+						}
+					}
+					return nil
+				}, retry.Delay(time.Second), retry.Timeout(30*time.Second))
+			})
+		})
+}
+
+// TestJWTWithEnvoyRBACFilter tests the RBAC filter stats of the Envoy sidecar match the requests
+// allowed and denied by an authorization policy based on the JWT claims.
+func TestJWTWithEnvoyRBACFilter(t *testing.T) {
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-rbac-stats",
+				Inject: true,
+			})
+
+			policies := namespacePolicies(t, ns, "testdata/requestauthn/b-authn-authz.yaml.tmpl")
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			var a, b echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&b, util.EchoConfig("b", ns, false, nil, p)).
+				BuildOrFail(t)
+
+			allowed := authn.NewCase(a, b, authn.WithName("valid-token"),
+				authn.WithToken(jwt.TokenIssuer1), authn.WithExpect(authn.Allowed))
+			denied := authn.NewCase(a, b, authn.WithName("no-token"), authn.WithExpect(authn.Denied))
+
+			// Wait for the policy to take effect before recording the stats.
+			for _, c := range []authn.TestCase{allowed, denied} {
+				retry.UntilSuccessOrFail(t, c.CheckAuthn, authn.RetryOptions()...)
+			}
+
+			const count = 5
+			allowedBefore, deniedBefore := rbacStats(t, b)
+			for i := 0; i < count; i++ {
+				if err := allowed.CheckAuthn(); err != nil {
+					t.Fatal(err)
+				}
+				// The denied requests must be rejected by the proxy, not the application.
+				if err := denied.CheckNotReached(); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			retry.UntilSuccessOrFail(t, func() error {
+				allowedAfter, deniedAfter := rbacStats(t, b)
+				if got := allowedAfter - allowedBefore; got != count {
+					return fmt.Errorf("rbac.allowed: got %d, want %d", got, count)
+				}
+				if got := deniedAfter - deniedBefore; got != count {
+					return fmt.Errorf("rbac.denied: got %d, want %d", got, count)
+				}
+				return nil
+			}, retry.Delay(time.Second), retry.Timeout(10*time.Second))
+		})
+}
+
+// rbacStats returns the sum of the allowed and denied counters of the RBAC filter over all the
+// listeners of all the workloads of the given instance.
+func rbacStats(t *testing.T, instance echo.Instance) (allowed int, denied int) {
+	t.Helper()
+	for _, w := range instance.WorkloadsOrFail(t) {
+		for name, value := range w.Sidecar().StatsOrFail(t) {
+			switch {
+			case strings.HasSuffix(name, ".rbac.allowed"):
+				allowed += value
+			case strings.HasSuffix(name, ".rbac.denied"):
+				denied += value
+			}
+		}
+	}
+	return
+}
+
+// TestJWTWithEgressGateway tests the egress gateway enforces JWT on the traffic it proxies from the
+// mesh to an external service, the allowed requests being checked to have gone through the gateway.
+func TestJWTWithEgressGateway(t *testing.T) {
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-egress",
+				Inject: true,
+			})
+			externalNS := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-egress-external",
+			})
+
+			var a, external echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&external, util.EchoConfig("external", externalNS, false, echo.NewAnnotations().
+					SetBool(echo.SidecarInject, false), p)).
+				BuildOrFail(t)
+
+			namespaceTmpl := map[string]string{
+				"Namespace":     ns.Name(),
+				"RootNamespace": rootNamespace,
+			}
+
+			securityPolicies := applyPolicy(t, ctx, namespaceTmpl,
+				"testdata/requestauthn/egress-gateway-jwt.yaml.tmpl", rootNS{})
+			defer ctx.DeleteConfigOrFail(t, rootNS{}.Name(), securityPolicies...)
+
+			gateway := egress.Default(rootNamespace)
+			route, err := gateway.Route(ns.Name(), external, "http")
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx.ApplyConfigOrFail(t, ns.Name(), route)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), route)
+
+			newTestCase := authn.NewCaseFunc(a, external, authn.WithRequest(func(r *connection.Checker) {
+				r.ExpectEgressGateway = gateway.Name
+			}))
+			// The external service has no sidecar, so any rejection comes from the egress gateway.
+			testCases := []authn.TestCase{
+				newTestCase("valid-token", jwt.TokenIssuer1, authn.Allowed),
+				newTestCase("expired-token", jwt.TokenExpired, authn.Unauthenticated),
+				newTestCase("no-token", "", authn.Denied),
+			}
+			for _, c := range testCases {
+				t.Run(c.Name, func(t *testing.T) {
+					retry.UntilSuccessOrFail(t, c.CheckAuthn, authn.RetryOptions()...)
 				})
 			}
 		})
 }
+
+// TestJWTWithChainedGateways tests a token is validated at each hop of a chain of gateways: the ingress
+// gateway, the edge, requires a token of issuer 1 or 2 and forwards it to the egress gateway, used as an
+// internal gateway, which only accepts the tokens of issuer 1 before routing the request to the workload.
+func TestJWTWithChainedGateways(t *testing.T) {
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ingr := ingress.NewOrFail(t, ctx, ingress.Config{
+				Istio: ist,
+			})
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-chained",
+				Inject: true,
+			})
+
+			var b echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&b, util.EchoConfig("b", ns, false, nil, p)).
+				BuildOrFail(t)
+
+			const host = "chained.example.com"
+			namespaceTmpl := map[string]string{
+				"Namespace":     ns.Name(),
+				"RootNamespace": rootNamespace,
+				"Host":          host,
+			}
+
+			securityPolicies := applyPolicy(t, ctx, namespaceTmpl,
+				"testdata/requestauthn/chained-gateways-jwt.yaml.tmpl", rootNS{})
+			routingCfgs := applyPolicy(t, ctx, namespaceTmpl, "testdata/requestauthn/chained-gateways.yaml.tmpl", ns)
+
+			defer ctx.DeleteConfigOrFail(t, rootNS{}.Name(), securityPolicies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), routingCfgs...)
+
+			cases := []struct {
+				name       string
+				token      string
+				expectCode int
+				expectBody string
+				// expectHeaders are the headers b must receive, as authn.TestCase.ExpectHeaders.
+				expectHeaders map[string]string
+			}{
+				{
+					// Denied by the edge, which requires a token.
+					name:       "no-token",
+					expectCode: http.StatusForbidden,
+				},
+				{
+					name:       "expired-token",
+					token:      jwt.TokenExpired,
+					expectCode: http.StatusUnauthorized,
+					expectBody: "Jwt is expired",
+				},
+				{
+					// Forwarded by the edge, re-validated by the internal gateway and forwarded to b.
+					name:          "token-of-both-hops",
+					token:         jwt.TokenIssuer1,
+					expectCode:    http.StatusOK,
+					expectHeaders: map[string]string{authHeaderKey: "Bearer " + jwt.TokenIssuer1},
+				},
+				{
+					// Accepted by the edge, but rejected by the internal gateway, which does not know its issuer.
+					name:       "token-of-edge-only",
+					token:      jwt.TokenIssuer2,
+					expectCode: http.StatusUnauthorized,
+					expectBody: "Jwt issuer is not configured",
+				},
+			}
+			for _, tc := range cases {
+				t.Run(tc.name, func(t *testing.T) {
+					retry.UntilSuccessOrFail(t, func() error {
+						return authn.CheckIngressWithHeaders(ingr, host, "/", tc.token, tc.expectCode, tc.expectBody,
+							tc.expectHeaders)
+					}, authn.RetryOptions()...)
+				})
+			}
+		})
+}
+
+// TestRequestAuthenticationChurn tests repeatedly applying and deleting a RequestAuthentication while
+// traffic flows never breaks the proxy: requests with a valid token must succeed whether or not the
+// policy exists, and the proxy must not reject any of the config updates.
+func TestRequestAuthenticationChurn(t *testing.T) {
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-churn",
+				Inject: true,
+			})
+
+			var a, c echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&c, util.EchoConfig("c", ns, false, nil, p)).
+				BuildOrFail(t)
+
+			policies := namespacePolicies(t, ns, "testdata/requestauthn/c-authn.yaml.tmpl")
+
+			validToken := authn.NewCase(a, c, authn.WithName("valid-token"),
+				authn.WithToken(jwt.TokenIssuer1), authn.WithExpect(authn.Allowed))
+			retry.UntilSuccessOrFail(t, validToken.CheckAuthn, authn.RetryOptions()...)
+
+			rejectedBefore := rejectedUpdates(t, c)
+			g := traffic.Start(validToken.CheckAuthn, 100*time.Millisecond)
+			for i := 0; i < 20; i++ {
+				ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+				time.Sleep(time.Duration(rand.Intn(500)) * time.Millisecond)
+				ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+				time.Sleep(time.Duration(rand.Intn(500)) * time.Millisecond)
+			}
+			result := g.Stop()
+			t.Logf("sent %d requests during the policy churn", result.Total)
+			if err := result.Error(); err != nil {
+				t.Error(err)
+			}
+			if got := rejectedUpdates(t, c) - rejectedBefore; got != 0 {
+				t.Errorf("proxy rejected %d config updates during the policy churn", got)
+			}
+		})
+}
+
+// rejectedUpdates returns the number of xDS updates rejected by the sidecars of all the workloads of
+// the given instance.
+func rejectedUpdates(t *testing.T, instance echo.Instance) int {
+	t.Helper()
+	rejected := 0
+	for _, w := range instance.WorkloadsOrFail(t) {
+		for name, value := range w.Sidecar().StatsOrFail(t) {
+			if strings.HasSuffix(name, ".update_rejected") {
+				rejected += value
+			}
+		}
+	}
+	return rejected
+}
+
+// TestJWTWithConnectMethod tests the ingress gateway enforces JWT on the CONNECT request itself, so
+// that a tunnel is only established with a valid token.
+func TestJWTWithConnectMethod(t *testing.T) {
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ingr := ingress.NewOrFail(t, ctx, ingress.Config{
+				Istio: ist,
+			})
+
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-ingress-connect",
+				Inject: true,
+			})
+
+			namespaceTmpl := map[string]string{
+				"Namespace":     ns.Name(),
+				"RootNamespace": rootNamespace,
+			}
+
+			securityPolicies := applyPolicy(t, ctx, namespaceTmpl,
+				"testdata/requestauthn/ingress-connect.yaml.tmpl", rootNS{})
+			ingressCfgs := applyPolicy(t, ctx, namespaceTmpl, "testdata/requestauthn/ingress.yaml.tmpl", ns)
+
+			defer ctx.DeleteConfigOrFail(t, rootNS{}.Name(), securityPolicies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), ingressCfgs...)
+
+			var b echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&b, util.EchoConfig("b", ns, false, nil, p)).
+				BuildOrFail(t)
+
+			testCases := []struct {
+				Name               string
+				Token              string
+				ExpectResponseCode int
+			}{
+				{
+					Name:               "tunnel with valid token",
+					Token:              jwt.TokenIssuer1,
+					ExpectResponseCode: http.StatusOK,
+				},
+				{
+					Name:               "deny without token",
+					ExpectResponseCode: http.StatusForbidden,
+				},
+				{
+					Name:               "deny with expired token",
+					Token:              jwt.TokenExpired,
+					ExpectResponseCode: http.StatusUnauthorized,
+				},
+			}
+
+			for _, c := range testCases {
+				t.Run(c.Name, func(t *testing.T) {
+					opts := ingress.CallOptions{
+						Host:     "example.com:80",
+						Method:   http.MethodConnect,
+						CallType: ingress.PlainText,
+						Address:  ingr.HTTPAddress(),
+					}
+					if c.Token != "" {
+						opts.Headers = http.Header{
+							authHeaderKey: []string{"Bearer " + c.Token},
+						}
+					}
+					retry.UntilSuccessOrFail(t, func() error {
+						resp, err := ingr.Call(opts)
+						if err != nil {
+							return err
+						}
+						if resp.Code != c.ExpectResponseCode {
+							return fmt.Errorf("CONNECT %s: got response code %d, want %d",
+								opts.Host, resp.Code, c.ExpectResponseCode)
+						}
+						return nil
+					}, authn.RetryOptions()...)
+				})
+			}
+		})
+}
+
+// TestRequestAuthentication_UnreachableJwksURI tests a RequestAuthentication with a jwksUri istiod
+// cannot fetch is accepted with a warning, and fails closed: requests with a token are rejected.
+func TestRequestAuthentication_UnreachableJwksURI(t *testing.T) {
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-unreachable-jwks",
+				Inject: true,
+			})
+
+			const policyName = "request-authn-unreachable-jwks"
+			jwksURI := fmt.Sprintf("http://jwks.%s.svc.cluster.local/jwks.json", ns.Name())
+			namespaceTmpl := map[string]string{
+				"Namespace": ns.Name(),
+				"Name":      policyName,
+				"JwksURI":   jwksURI,
+			}
+			policies := tmpl.EvaluateAllOrFail(t, namespaceTmpl,
+				file.AsStringOrFail(t, "testdata/requestauthn/unreachable-jwks.yaml.tmpl"))
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			var a, b echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&b, util.EchoConfig("b", ns, false, nil, p)).
+				BuildOrFail(t)
+
+			retry.UntilSuccessOrFail(t, func() error {
+				return util.ExpectPolicyWarning(ctx, ns, policyName, jwksURI)
+			}, retry.Delay(time.Second), retry.Timeout(30*time.Second))
+
+			newTestCase := authn.NewCaseFunc(a, b)
+			testCases := []authn.TestCase{
+				newTestCase("valid-token", jwt.TokenIssuer1, authn.Unauthenticated),
+				newTestCase("no-token", "", authn.Allowed),
+			}
+			for _, c := range testCases {
+				t.Run(c.Name, func(t *testing.T) {
+					retry.UntilSuccessOrFail(t, c.CheckAuthn, authn.RetryOptions()...)
+				})
+			}
+		})
+}
+
+// TestRequestAuthentication_DirectPodIP tests JWT is enforced by the destination proxy when the
+// request is sent to the pod IP directly, without going through the service.
+func TestRequestAuthentication_DirectPodIP(t *testing.T) {
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-pod-ip",
+				Inject: true,
+			})
+
+			policies := namespacePolicies(t, ns, "testdata/requestauthn/b-authn-authz.yaml.tmpl")
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			var a, b echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&b, util.EchoConfig("b", ns, false, nil, p)).
+				BuildOrFail(t)
+
+			podName := podNameForWorkload(t, ctx, ns, b.WorkloadsOrFail(t)[0])
+
+			newTestCase := authn.NewCaseFunc(a, b, authn.WithRequest(func(r *connection.Checker) {
+				r.Options.DirectPodIP = true
+			}))
+			testCases := []authn.TestCase{
+				newTestCase("valid-token", jwt.TokenIssuer1, authn.Allowed),
+				newTestCase("expired-token", jwt.TokenExpired, authn.Unauthenticated),
+				newTestCase("no-token", "", authn.Denied),
+			}
+			for _, c := range testCases {
+				t.Run(c.Name, func(t *testing.T) {
+					retry.UntilSuccessOrFail(t, c.CheckAuthn, authn.RetryOptions()...)
+				})
+			}
+
+			// The allowed request must have been received by the pod that was dialed.
+			results := a.CallOrFail(t, testCases[0].Request.Options)
+			if results[0].Hostname != podName {
+				t.Errorf("request to the IP of pod %s received by %s", podName, results[0].Hostname)
+			}
+		})
+}
+
+// podNameForWorkload returns the name of the pod running the given workload, found by its IP.
+func podNameForWorkload(t *testing.T, ctx framework.TestContext, ns namespace.Instance, w echo.Workload) string {
+	t.Helper()
+	pods, err := kube.ClusterOrDefault(nil, ctx.Environment()).GetPods(ns.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, pod := range pods {
+		if pod.Status.PodIP == w.Address() {
+			return pod.Name
+		}
+	}
+	t.Fatalf("no pod with IP %s in namespace %s", w.Address(), ns.Name())
+	return ""
+}
+
+// TestJWTWithServiceMeshObservability tests Kiali reads the RequestAuthentication of a service, which
+// it needs to show the JWT policy in the service graph. Skipped if Kiali is not deployed.
+func TestJWTWithServiceMeshObservability(t *testing.T) {
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			if !kiali.Available(ctx) {
+				ctx.Skip("kiali is not deployed")
+			}
+
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-kiali",
+				Inject: true,
+			})
+
+			policies := namespacePolicies(t, ns, "testdata/requestauthn/b-authn-authz.yaml.tmpl")
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			// The name of the RequestAuthentication in b-authn-authz.yaml.tmpl.
+			const policyName = "requst-authn-for-b"
+			retry.UntilSuccessOrFail(t, func() error {
+				names, err := kiali.RequestAuthentications(ctx, ns.Name())
+				if err != nil {
+					return err
+				}
+				for _, name := range names {
+					if name == policyName {
+						return nil
+					}
+				}
+				return fmt.Errorf("kiali reports RequestAuthentications %v, want %s", names, policyName)
+			}, retry.Delay(time.Second), retry.Timeout(30*time.Second))
+		})
+}
+
+// TestJWTWithOneShotCaller tests a short-lived pod (a Kubernetes Job) calling a JWT protected service
+// once and exiting: the call must not race with the startup of its sidecar.
+func TestJWTWithOneShotCaller(t *testing.T) {
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-one-shot",
+				Inject: true,
+			})
+
+			policies := namespacePolicies(t, ns, "testdata/requestauthn/b-authn-authz.yaml.tmpl")
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			var b echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&b, util.EchoConfig("b", ns, false, nil, p)).
+				BuildOrFail(t)
+
+			// Shut down quickly once the caller is done.
+			annotations := echo.NewAnnotations().Set(echo.SidecarProxyConfig, "drainDuration: 1s")
+			run := func(name, token string) *client.ParsedResponse {
+				t.Helper()
+				cfg := echokube.JobConfig{
+					Name:        name,
+					Namespace:   ns,
+					URL:         fmt.Sprintf("http://%s/", b.Config().FQDN()),
+					Annotations: annotations,
+				}
+				if token != "" {
+					cfg.Headers = http.Header{authHeaderKey: []string{"Bearer " + token}}
+				}
+				result, err := echokube.RunJob(ctx, cfg)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if result.ExitCode != 0 || len(result.Responses) == 0 {
+					t.Fatalf("%s: exit code %d, output:\n%s", name, result.ExitCode, result.Output)
+				}
+				return result.Responses[0]
+			}
+
+			for i := 0; i < 5; i++ {
+				name := fmt.Sprintf("valid-token-%d", i)
+				if got := run(name, jwt.TokenIssuer1).Code; got != response.StatusCodeOK {
+					t.Errorf("%s: got response code %s, want %s", name, got, response.StatusCodeOK)
+				}
+			}
+			if got := run("no-token", "").Code; got != response.StatusCodeForbidden {
+				t.Errorf("no-token: got response code %s, want %s", got, response.StatusCodeForbidden)
+			}
+		})
+}
+
+// TestJWTWithHighCardinalityClaimsPerformance measures the latency of requests through a JWT-protected
+// service with tokens carrying many claims, of each of the value types the JWT filter parses, to catch the
+// performance regressions of the JWT filter on claim-heavy tokens. The latencies are compared to the ones
+// of a token without extra claims, logged as metrics and written to the jwt-claims-latency.json artifact.
+// It only runs when the latency benchmarks are enabled, and only fails on the overhead if a bound is set,
+// see package latency.
+func TestJWTWithHighCardinalityClaimsPerformance(t *testing.T) {
+	const (
+		warmUpRequests = 100
+		samples        = 1000
+		batchSize      = 200
+	)
+
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			if !latency.Enabled() {
+				t.Skip("latency benchmarks are not enabled")
+			}
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-claims-perf",
+				Inject: true,
+			})
+
+			var a, c echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&c, util.EchoConfig("c", ns, false, nil, p)).
+				BuildOrFail(t)
+
+			policies := namespacePolicies(t, ns, "testdata/requestauthn/c-authn.yaml.tmpl")
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			// measure waits for the token to be accepted, which also warms up the connections and the JWKS
+			// cache, then returns the latencies of the samples.
+			measure := func(name, token string) []time.Duration {
+				opts := echo.CallOptions{
+					Target:   c,
+					PortName: "http",
+					Scheme:   scheme.HTTP,
+					Token:    token,
+				}
+				ready := authn.TestCase{
+					Name:         name,
+					Request:      connection.Checker{From: a, Options: opts},
+					ExpectResult: authn.Allowed,
+				}
+				retry.UntilSuccessOrFail(t, ready.CheckAuthn, authn.RetryOptions()...)
+				out, err := latency.Sample(a, opts, warmUpRequests, samples, batchSize)
+				if err != nil {
+					t.Fatalf("%s: %v", name, err)
+				}
+				return out
+			}
+
+			plain, err := jwt.TokenWithClaims(0)
+			if err != nil {
+				t.Fatalf("failed to mint token without extra claims: %v", err)
+			}
+			baseline := measure("no-extra-claims", plain)
+
+			var reports []latency.Comparison
+			for _, claims := range []int{10, 50, 100} {
+				for _, shape := range []jwt.ClaimShape{jwt.StringClaims, jwt.ArrayClaims, jwt.MapClaims} {
+					name := fmt.Sprintf("%d-%s-claims", claims, shape)
+					token, err := jwt.TokenWithClaimsOf(claims, shape)
+					if err != nil {
+						t.Fatalf("failed to mint token with %s: %v", name, err)
+					}
+					report := latency.Compare(t.Name()+"/"+name, warmUpRequests, baseline, measure(name, token))
+					reports = append(reports, report)
+					t.Logf("metric jwt_claims_latency_overhead_ms{claims=%d,shape=%s}: p50=%.3f p90=%.3f p99=%.3f",
+						claims, shape, report.Delta.P50, report.Delta.P90, report.Delta.P99)
+				}
+			}
+
+			out, err := json.MarshalIndent(reports, "", "  ")
+			if err != nil {
+				t.Fatalf("failed to encode the latency reports: %v", err)
+			}
+			ctx.WriteArtifactOrFail("jwt-claims-latency.json", append(out, '\n'))
+			if bound := latency.MaxP90OverheadMs(); bound > 0 {
+				for _, report := range reports {
+					if report.Delta.P90 > bound {
+						t.Errorf("%s: p90 latency %.3fms above the one without extra claims, want at most %.3fms",
+							report.Test, report.Delta.P90, bound)
+					}
+				}
+			}
+		})
+}
+
+// TestJWTFilterLatencyOverhead measures the latency overhead of the JWT filter: the latency percentiles of
+// requests to b without any policy, then with a RequestAuthentication and a valid token, are written with
+// their delta to the jwt-latency.json artifact, for the overhead to be tracked across runs. It only runs when
+// the latency benchmarks are enabled, see package latency.
+func TestJWTFilterLatencyOverhead(t *testing.T) {
+	const (
+		warmUpRequests = 100
+		samples        = 2000
+		// The requests are sent in batches, each of a call, to keep the calls within their timeout.
+		batchSize = 200
+	)
+
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			if !latency.Enabled() {
+				t.Skip("latency benchmarks are not enabled")
+			}
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-latency",
+				Inject: true,
+			})
+
+			var a, b echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&b, util.EchoConfig("b", ns, false, nil, p)).
+				BuildOrFail(t)
+
+			// measure waits for the expected result, warms up the connections and the caches, then returns
+			// the latencies of the samples.
+			measure := func(name, token string) []time.Duration {
+				opts := echo.CallOptions{
+					Target:   b,
+					PortName: "http",
+					Scheme:   scheme.HTTP,
+					Token:    token,
+				}
+				ready := authn.TestCase{
+					Name:         name,
+					Request:      connection.Checker{From: a, Options: opts},
+					ExpectResult: authn.Allowed,
+				}
+				retry.UntilSuccessOrFail(t, ready.CheckAuthn, authn.RetryOptions()...)
+
+				out, err := latency.Sample(a, opts, warmUpRequests, samples, batchSize)
+				if err != nil {
+					t.Fatalf("%s: %v", name, err)
+				}
+				return out
+			}
+
+			baseline := measure("no-policy", "")
+
+			policy := authn.RequestAuthentication{
+				Name:      "latency-for-b",
+				Namespace: ns.Name(),
+				App:       "b",
+				Rules:     []authn.JWTRule{{Issuer: authn.Issuer1, JwksURI: authn.JwksURI1}},
+			}
+			policyYAML := policy.YAMLOrFail(t)
+			ctx.ApplyConfigOrFail(t, ns.Name(), policyYAML)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policyYAML)
+			// The policy is enforced once an invalid token is rejected.
+			enforced := authn.NewCase(a, b, authn.WithName("policy-enforced"),
+				authn.WithToken(jwt.TokenInvalid), authn.WithExpect(authn.Unauthenticated))
+			retry.UntilSuccessOrFail(t, enforced.CheckAuthn, authn.RetryOptions()...)
+			withJWT := measure("valid-token", jwt.TokenIssuer1)
+
+			report := latency.Compare(t.Name(), warmUpRequests, baseline, withJWT)
+			out, err := report.JSON()
+			if err != nil {
+				t.Fatalf("failed to encode the latency report: %v", err)
+			}
+			ctx.WriteArtifactOrFail("jwt-latency.json", out)
+			t.Logf("metric jwt_filter_latency_overhead_ms: p50=%.3f p90=%.3f p99=%.3f",
+				report.Delta.P50, report.Delta.P90, report.Delta.P99)
+		})
+}
+
+// TestJWTWithNewTokenAfterExpiry tests a client is accepted again once it refreshes its expired token,
+// i.e. the JWT filter keeps no negative state about the client or the issuer.
+func TestJWTWithNewTokenAfterExpiry(t *testing.T) {
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-refresh",
+				Inject: true,
+			})
+
+			var a, c echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&c, util.EchoConfig("c", ns, false, nil, p)).
+				BuildOrFail(t)
+
+			policies := namespacePolicies(t, ns, "testdata/requestauthn/c-authn.yaml.tmpl")
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			newCase := authn.NewCaseFunc(a, c)
+
+			shortLived, err := jwt.TokenWithExpiry(time.Now().Add(30 * time.Second))
+			if err != nil {
+				t.Fatal(err)
+			}
+			beforeExpiry := newCase("before-expiry", shortLived, authn.Allowed)
+			retry.UntilSuccessOrFail(t, beforeExpiry.CheckAuthn, authn.RetryOptions()...)
+
+			// Keep using the token until it is rejected. Envoy allows 60 seconds of clock skew after the
+			// expiry, so this takes up to 90 seconds.
+			afterExpiry := newCase("after-expiry", shortLived, authn.Unauthenticated)
+			retry.UntilSuccessOrFail(t, afterExpiry.CheckAuthn,
+				retry.Delay(time.Second), retry.Timeout(2*time.Minute))
+
+			refreshed, err := jwt.TokenWithExpiry(time.Now().Add(time.Hour))
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The policy is already enforced, so the new token must be accepted right away.
+			refreshedCase := newCase("refreshed-token", refreshed, authn.Allowed)
+			if err := refreshedCase.CheckAuthn(); err != nil {
+				t.Errorf("refreshed token rejected after the previous one expired: %v", err)
+			}
+		})
+}
+
+// TestJWKSCaching tests the public keys fetched from the jwksUri of a policy are cached. The JWKS is
+// fetched by istiod, which pushes it inline to the proxies and refreshes it every 20 minutes whatever
+// the Cache-Control of the response. So within the test window, whether the response may be cached or
+// not, a rotated key is not picked up and the JWKS is not fetched again, and an error of the JWKS server
+// does not invalidate the cached key. The fetches are counted with the request log of the JWKS server.
+func TestJWKSCaching(t *testing.T) {
+	const window = 10 * time.Second
+
+	oldKey, err := jwt.NewSigningKey("old-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newKey, err := jwt.NewSigningKey("new-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldToken, err := oldKey.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	newToken, err := newKey.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-jwks-cache",
+				Inject: true,
+			})
+
+			var a, b, jwksServer echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&b, util.EchoConfig("b", ns, false, nil, p)).
+				With(&jwksServer, util.EchoConfig("jwks", ns, false,
+					echo.NewAnnotations().SetBool(echo.SidecarInject, false), p)).
+				BuildOrFail(t)
+			server := jwks.Server{Instance: jwksServer}
+
+			newTestCase := authn.NewCaseFunc(a, b)
+			oldKeyCase := newTestCase("old-key", oldToken, authn.Allowed)
+			newKeyCase := newTestCase("new-key", newToken, authn.Unauthenticated)
+
+			cases := []struct {
+				name    string
+				headers map[string]string
+				// The status code of the JWKS server once the key is rotated.
+				code int
+			}{
+				{
+					name:    "max-age",
+					headers: map[string]string{"Cache-Control": "max-age=5"},
+				},
+				{
+					name:    "no-store",
+					headers: map[string]string{"Cache-Control": "no-store"},
+				},
+				{
+					name: "server-error",
+					code: http.StatusInternalServerError,
+				},
+			}
+			for _, c := range cases {
+				t.Run(c.name, func(t *testing.T) {
+					if err := server.Serve(ctx, c.name, oldKey.JWKS(), c.headers, 0); err != nil {
+						t.Fatal(err)
+					}
+					jwksURI, err := server.URI(c.name)
+					if err != nil {
+						t.Fatal(err)
+					}
+					policies := tmpl.EvaluateAllOrFail(t, map[string]string{
+						"Namespace": ns.Name(),
+						"Name":      "jwks-cache-" + c.name,
+						"JwksURI":   jwksURI,
+					}, file.AsStringOrFail(t, "testdata/requestauthn/remote-jwks.yaml.tmpl"))
+					ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+					defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+					retry.UntilSuccessOrFail(t, oldKeyCase.CheckAuthn, authn.RetryOptions()...)
+					before, err := server.Requests(ctx, c.name)
+					if err != nil {
+						t.Fatal(err)
+					}
+
+					if err := server.Serve(ctx, c.name, newKey.JWKS(), c.headers, c.code); err != nil {
+						t.Fatal(err)
+					}
+					if err := retry.EventuallyThenAlways(oldKeyCase.CheckAuthn, 0, window, time.Second); err != nil {
+						t.Fatalf("cached key stopped verifying after the JWKS changed: %v", err)
+					}
+					if err := newKeyCase.CheckAuthn(); err != nil {
+						t.Errorf("rotated key picked up within %v: %v", window, err)
+					}
+
+					after, err := server.Requests(ctx, c.name)
+					if err != nil {
+						t.Fatal(err)
+					}
+					t.Logf("JWKS fetched %d times before the rotation, %d times in the %v after",
+						len(before), len(after)-len(before), window)
+					if len(before) == 0 {
+						t.Errorf("JWKS never fetched from %s", jwksURI)
+					}
+					if len(after) != len(before) {
+						t.Errorf("JWKS fetched again at %v, want the cached keys to be used", after[len(before):])
+					}
+				})
+			}
+		})
+}
+
+// TestJWTWithClaimToHeaders tests the claims listed in the claimToHeaders of a policy are copied to
+// headers of the request received by the upstream, and no header is added when the token does not have
+// the claim. It is skipped if the cluster does not support claimToHeaders.
+func TestJWTWithClaimToHeaders(t *testing.T) {
+	tokenNoSub, err := jwt.Sign(map[string]interface{}{
+		"iss": "test-issuer-1@istio.io",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	claimToHeaders := map[string]string{"sub": "X-Sub"}
+
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-claim-headers",
+				Inject: true,
+			})
+
+			const policyName = "request-authn-claim-to-headers"
+			policies := tmpl.EvaluateAllOrFail(t, map[string]string{
+				"Namespace": ns.Name(),
+				"Name":      policyName,
+			}, file.AsStringOrFail(t, "testdata/requestauthn/claim-to-headers.yaml.tmpl"))
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			supported, err := util.ClaimToHeadersSupported(ctx, ns, policyName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !supported {
+				t.Skip("claimToHeaders is not supported by the RequestAuthentication CRD of the cluster")
+			}
+
+			var a, b echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&b, util.EchoConfig("b", ns, false, nil, p)).
+				BuildOrFail(t)
+
+			newTestCase := func(name, token string) authn.TestCase {
+				headers, err := authn.ClaimHeaders(token, claimToHeaders)
+				if err != nil {
+					t.Fatal(err)
+				}
+				c := authn.NewCase(a, b, authn.WithName(name), authn.WithToken(token), authn.WithExpect(authn.Allowed))
+				c.ExpectHeaders = headers
+				return c
+			}
+			testCases := []authn.TestCase{
+				newTestCase("claim-copied", jwt.TokenIssuer1),
+				newTestCase("claim-absent", tokenNoSub),
+			}
+			for _, c := range testCases {
+				t.Run(c.Name, func(t *testing.T) {
+					retry.UntilSuccessOrFail(t, c.CheckAuthn, authn.RetryOptions()...)
+				})
+			}
+		})
+}
+
+// TestJWTWithIstiodCrashRecovery tests the proxies keep enforcing JWT with their cached config while
+// istiod is unavailable, and that policy updates take effect again once istiod is back.
+func TestJWTWithIstiodCrashRecovery(t *testing.T) {
+	const (
+		istiodDeployment = "istiod"
+		istiodSelector   = "istio=pilot"
+		// How long the enforcement is checked while istiod is down.
+		outage = 30 * time.Second
+	)
+
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-istiod-down",
+				Inject: true,
+			})
+
+			var a, b, c echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&b, util.EchoConfig("b", ns, false, nil, p)).
+				With(&c, util.EchoConfig("c", ns, false, nil, p)).
+				BuildOrFail(t)
+
+			cPolicies := namespacePolicies(t, ns, "testdata/requestauthn/c-authn.yaml.tmpl")
+			ctx.ApplyConfigOrFail(t, ns.Name(), cPolicies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), cPolicies...)
+
+			newTestCase := func(name string, target echo.Instance, token string, expect authn.ExpectedResult) authn.TestCase {
+				return authn.NewCase(a, target, authn.WithName(name), authn.WithToken(token), authn.WithExpect(expect))
+			}
+			enforced := []authn.TestCase{
+				newTestCase("valid-token", c, jwt.TokenIssuer1, authn.Allowed),
+				newTestCase("expired-token", c, jwt.TokenExpired, authn.Unauthenticated),
+			}
+			for _, tc := range enforced {
+				retry.UntilSuccessOrFail(t, tc.CheckAuthn, authn.RetryOptions()...)
+			}
+
+			cfg, err := istio.DefaultConfig(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cluster := kube.ClusterOrDefault(nil, ctx.Environment())
+			deployment, err := cluster.GetDeployment(cfg.ConfigNamespace, istiodDeployment)
+			if err != nil {
+				t.Fatalf("failed to get the istiod deployment: %v", err)
+			}
+			replicas := 1
+			if deployment.Spec.Replicas != nil && *deployment.Spec.Replicas > 0 {
+				replicas = int(*deployment.Spec.Replicas)
+			}
+			scaledDown := false
+			restore := func() {
+				if !scaledDown {
+					return
+				}
+				scaledDown = false
+				if err := cluster.ScaleDeployment(cfg.ConfigNamespace, istiodDeployment, replicas); err != nil {
+					t.Fatalf("failed to scale istiod back to %d replicas: %v", replicas, err)
+				}
+				if _, err := cluster.WaitUntilPodsAreReady(cluster.NewPodFetch(cfg.ConfigNamespace, istiodSelector)); err != nil {
+					t.Fatalf("istiod not ready after scaling it back: %v", err)
+				}
+			}
+			// Never leave the mesh without control plane, even if the test fails while istiod is down.
+			defer restore()
+
+			if err := cluster.ScaleDeployment(cfg.ConfigNamespace, istiodDeployment, 0); err != nil {
+				t.Fatalf("failed to scale istiod down: %v", err)
+			}
+			scaledDown = true
+			if err := cluster.WaitUntilPodsAreDeleted(cluster.NewPodFetch(cfg.ConfigNamespace, istiodSelector)); err != nil {
+				t.Fatalf("istiod still running after scaling it down: %v", err)
+			}
+
+			checkEnforced := func() error {
+				for _, tc := range enforced {
+					if err := tc.CheckAuthn(); err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+			if err := retry.EventuallyThenAlways(checkEnforced, 0, outage, time.Second); err != nil {
+				t.Fatalf("JWT not enforced after istiod went down: %v", err)
+			}
+
+			restore()
+
+			// b has no policy yet, so this is only enforced if istiod pushes the new policies.
+			bPolicies := namespacePolicies(t, ns, "testdata/requestauthn/b-authn-authz.yaml.tmpl")
+			ctx.ApplyConfigOrFail(t, ns.Name(), bPolicies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), bPolicies...)
+			updated := []authn.TestCase{
+				newTestCase("updated-valid-token", b, jwt.TokenIssuer1, authn.Allowed),
+				newTestCase("updated-no-token", b, "", authn.Denied),
+			}
+			for _, tc := range updated {
+				// Once pushed, the new policies must be enforced steadily.
+				retry.EventuallyThenAlwaysOrFail(t, tc.CheckAuthn, time.Minute, 10*time.Second, 250*time.Millisecond)
+			}
+		})
+}
+
+// TestJWTWithGRPCStream tests JWT is enforced when a gRPC stream is initiated, and that all the messages
+// of an accepted stream are echoed.
+func TestJWTWithGRPCStream(t *testing.T) {
+	const messages = 5
+
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-grpc-stream",
+				Inject: true,
+			})
+
+			var a, c echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&c, util.EchoConfig("c", ns, false, nil, p)).
+				BuildOrFail(t)
+
+			policies := namespacePolicies(t, ns, "testdata/requestauthn/c-authn.yaml.tmpl")
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			stream := func(token string) (client.ParsedResponses, error) {
+				return a.Call(echo.CallOptions{
+					Target:         c,
+					PortName:       "grpc",
+					Scheme:         scheme.GRPC,
+					Token:          token,
+					StreamMessages: messages,
+				})
+			}
+
+			t.Run("valid-token", func(t *testing.T) {
+				retry.UntilSuccessOrFail(t, func() error {
+					responses, err := stream(jwt.TokenIssuer1)
+					if err != nil {
+						return err
+					}
+					if len(responses) != messages {
+						return fmt.Errorf("got %d messages echoed, want %d", len(responses), messages)
+					}
+					return responses.CheckOK()
+				}, authn.RetryOptions()...)
+			})
+			t.Run("expired-token", func(t *testing.T) {
+				retry.UntilSuccessOrFail(t, func() error {
+					responses, err := stream(jwt.TokenExpired)
+					if err == nil {
+						return fmt.Errorf("stream with expired token accepted, %d messages echoed", len(responses))
+					}
+					if !strings.Contains(err.Error(), "Unauthenticated") {
+						return fmt.Errorf("want stream rejected as unauthenticated, got: %v", err)
+					}
+					return nil
+				}, authn.RetryOptions()...)
+			})
+		})
+}
+
+// TestJWTWithResponseTrailersGRPC tests the grpc-status trailer of the gRPC calls: 16 (UNAUTHENTICATED)
+// for the calls rejected by the JWT filter, and 0 for the calls with a valid token. A call without token
+// is not rejected by the JWT filter but denied by the authorization policy, with 7 (PERMISSION_DENIED).
+func TestJWTWithResponseTrailersGRPC(t *testing.T) {
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-grpc-trailers",
+				Inject: true,
+			})
+
+			var a, c echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&c, util.EchoConfig("c", ns, false, nil, p)).
+				BuildOrFail(t)
+
+			policies := namespacePolicies(t, ns, "testdata/requestauthn/c-authn-authz.yaml.tmpl")
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			cases := []struct {
+				name   string
+				token  string
+				expect codes.Code
+			}{
+				{name: "valid-token", token: jwt.TokenIssuer1, expect: codes.OK},
+				{name: "expired-token", token: jwt.TokenExpired, expect: codes.Unauthenticated},
+				{name: "invalid-token", token: jwt.TokenInvalid, expect: codes.Unauthenticated},
+				{name: "no-token", expect: codes.PermissionDenied},
+			}
+			for _, tc := range cases {
+				t.Run(tc.name, func(t *testing.T) {
+					retry.UntilSuccessOrFail(t, func() error {
+						responses, err := a.Call(echo.CallOptions{
+							Target:   c,
+							PortName: "grpc",
+							Scheme:   scheme.GRPC,
+							Token:    tc.token,
+						})
+						if err != nil {
+							// The status of a failed call is returned as the error.
+							got, ok := client.GRPCStatusCode(err)
+							if !ok || got != tc.expect {
+								return fmt.Errorf("want grpc-status %d (%v), got: %v", tc.expect, tc.expect, err)
+							}
+							want := strconv.Itoa(int(tc.expect))
+							if got := client.GRPCTrailers(err).Get("grpc-status"); got != want {
+								return fmt.Errorf("want grpc-status trailer %s, got %q", want, got)
+							}
+							return nil
+						}
+						if tc.expect != codes.OK {
+							return fmt.Errorf("want grpc-status %d (%v), got the call accepted", tc.expect, tc.expect)
+						}
+						for _, r := range responses {
+							if got := r.ResponseTrailers.Get("grpc-status"); got != "0" {
+								return fmt.Errorf("want grpc-status 0, got %q", got)
+							}
+						}
+						return responses.CheckOK()
+					}, authn.RetryOptions()...)
+				})
+			}
+		})
+}
+
+// TestJWTWithCustomCipherSuites tests JWT validation works for a workload whose proxy is restricted to a
+// few TLS cipher suites, set with the ISTIO_META_TLS_CIPHER_SUITES proxy metadata. A handshake failure
+// with the JWKS server would fail all the requests with 401. The metadata is set with the proxy config
+// annotation of the workload rather than the mesh config, so that the other tests are not affected.
+func TestJWTWithCustomCipherSuites(t *testing.T) {
+	const cipherSuites = "ECDHE-ECDSA-AES256-GCM-SHA384,ECDHE-RSA-AES256-GCM-SHA384"
+
+	matrix := jwtMatrix(t)
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-cipher-suites",
+				Inject: true,
+			})
+
+			policies := matrixPolicies(t, matrix, ns)
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			proxyConfig := fmt.Sprintf(`{"proxyMetadata":{"ISTIO_META_TLS_CIPHER_SUITES":%q}}`, cipherSuites)
+			var a, b echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&b, util.EchoConfig("b", ns, false,
+					echo.NewAnnotations().Set(echo.SidecarProxyConfig, proxyConfig), p)).
+				BuildOrFail(t)
+
+			for _, c := range matrixTestCases(t, ctx, matrix, a, b) {
+				t.Run(c.Name, func(t *testing.T) {
+					retry.UntilSuccessOrFail(t, c.CheckAuthn, authn.RetryOptions()...)
+				})
+			}
+		})
+}
+
+// TestJWTWithDeniedRequestMetrics tests the requests denied for lack of a JWT are reported in the Istio
+// standard metrics of the destination with the 403 response code and the principal of the source.
+func TestJWTWithDeniedRequestMetrics(t *testing.T) {
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-metrics",
+				Inject: true,
+			})
+
+			policies := namespacePolicies(t, ns, "testdata/requestauthn/b-authn-authz.yaml.tmpl")
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			var a, b echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&b, util.EchoConfig("b", ns, false, nil, p)).
+				BuildOrFail(t)
+
+			denied := authn.NewCase(a, b, authn.WithName("no-token"), authn.WithExpect(authn.Denied))
+			// Wait for the policy to take effect before recording the metrics.
+			retry.UntilSuccessOrFail(t, denied.CheckAuthn, authn.RetryOptions()...)
+
+			query := metrics.Query{
+				Metric: metrics.RequestsTotal,
+				Labels: map[string]string{
+					"reporter":         "destination",
+					"response_code":    "403",
+					"source_principal": metrics.SourcePrincipal(a),
+				},
+			}
+			if err := metrics.ExpectIncrease(b, query, 1, denied.CheckAuthn); err != nil {
+				t.Fatal(err)
+			}
+		})
+}
+
+// TestJWTWithIngressMTLS tests the ingress gateway validates the client certificate and the JWT of the
+// same request. The gateway requires a client certificate, and a DENY policy rejects the requests to the
+// host without a valid JWT with 403, while an invalid JWT is rejected by the JWT filter with 401. A
+// client without a certificate fails the TLS handshake before any JWT check.
+func TestJWTWithIngressMTLS(t *testing.T) {
+	const (
+		credName = "req-authn-ingress-mtls"
+		host     = "mtls.example.com"
+	)
+
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ingressutil.CreateIngressKubeSecret(t, ctx, []string{credName}, ingress.Mtls, ingressutil.IngressCredentialA)
+			defer ingressutil.DeleteIngressKubeSecret(t, ctx, []string{credName})
+
+			ingr := ingress.NewOrFail(t, ctx, ingress.Config{
+				Istio: ist,
+			})
+
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-ingress-mtls",
+				Inject: true,
+			})
+
+			namespaceTmpl := map[string]string{
+				"Namespace":      ns.Name(),
+				"RootNamespace":  rootNamespace,
+				"CredentialName": credName,
+				"Host":           host,
+			}
+
+			securityPolicies := applyPolicy(t, ctx, namespaceTmpl,
+				"testdata/requestauthn/ingress-mtls-jwt.yaml.tmpl", rootNS{})
+			ingressCfgs := applyPolicy(t, ctx, namespaceTmpl, "testdata/requestauthn/ingress-mtls.yaml.tmpl", ns)
+			defer ctx.DeleteConfigOrFail(t, rootNS{}.Name(), securityPolicies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), ingressCfgs...)
+
+			var b echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&b, util.EchoConfig("b", ns, false, nil, p)).
+				BuildOrFail(t)
+
+			callOptions := func(callType ingress.CallType, token string) ingress.CallOptions {
+				opts := ingress.CallOptions{
+					Host:       host,
+					Path:       "/",
+					CallType:   callType,
+					Address:    ingr.HTTPSAddress(),
+					CaCert:     ingressutil.CaCertA,
+					PrivateKey: ingressutil.TLSClientKeyA,
+					Cert:       ingressutil.TLSClientCertA,
+					Timeout:    time.Second,
+				}
+				if token != "" {
+					opts.Headers = http.Header{authHeaderKey: []string{"Bearer " + token}}
+				}
+				return opts
+			}
+
+			testCases := []struct {
+				Name               string
+				Token              string
+				ExpectResponseCode int
+			}{
+				{
+					Name:               "mtls-with-valid-token",
+					Token:              jwt.TokenIssuer1,
+					ExpectResponseCode: http.StatusOK,
+				},
+				{
+					Name:               "mtls-without-token",
+					ExpectResponseCode: http.StatusForbidden,
+				},
+				{
+					Name:               "mtls-with-expired-token",
+					Token:              jwt.TokenExpired,
+					ExpectResponseCode: http.StatusUnauthorized,
+				},
+			}
+			for _, c := range testCases {
+				t.Run(c.Name, func(t *testing.T) {
+					// The client certificate may take a while to be loaded by the gateway.
+					retry.UntilSuccessOrFail(t, func() error {
+						resp, err := ingr.Call(callOptions(ingress.Mtls, c.Token))
+						if resp.Code != c.ExpectResponseCode {
+							return fmt.Errorf("got response code %d, want %d, err %v", resp.Code, c.ExpectResponseCode, err)
+						}
+						return nil
+					}, retry.Delay(time.Second), retry.Timeout(2*time.Minute))
+				})
+			}
+
+			t.Run("tls-without-client-cert", func(t *testing.T) {
+				err := ingress.ExpectHandshakeFailure(ingr, callOptions(ingress.TLS, jwt.TokenIssuer1),
+					ingress.ClientCertificateRequired)
+				if err != nil {
+					t.Fatal(err)
+				}
+			})
+		})
+}
+
+// TestJWTWithConcurrentRequests tests the JWT filter returns the same outcome for many identical requests
+// sent in parallel, which may surface races in the JWT cache or filter state that sequential requests miss.
+func TestJWTWithConcurrentRequests(t *testing.T) {
+	const (
+		concurrency = 10
+		count       = 10
+	)
+
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-concurrent",
+				Inject: true,
+			})
+
+			policies := namespacePolicies(t, ns, "testdata/requestauthn/b-authn-authz.yaml.tmpl")
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			var a, b echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&b, util.EchoConfig("b", ns, false, nil, p)).
+				BuildOrFail(t)
+
+			newTestCase := authn.NewCaseFunc(a, b)
+			testCases := []authn.TestCase{
+				newTestCase("valid-token", jwt.TokenIssuer1, authn.Allowed),
+				newTestCase("expired-token", jwt.TokenExpired, authn.Unauthenticated),
+				newTestCase("no-token", "", authn.Denied),
+			}
+			for _, c := range testCases {
+				t.Run(c.Name, func(t *testing.T) {
+					// Wait for the policy to take effect before sending the concurrent requests.
+					retry.UntilSuccessOrFail(t, c.CheckAuthn, authn.RetryOptions()...)
+
+					c.Request.Options.Concurrency = concurrency
+					c.Request.Options.Count = count
+					if err := c.CheckAuthn(); err != nil {
+						t.Fatal(err)
+					}
+				})
+			}
+		})
+}
+
+// TestJWTWithReadinessProbe tests the readiness probe of a workload keeps passing when a JWT policy covers
+// the probed port. Kubelet probes do not carry a token, and are exempted from the policy only because the
+// injector rewrites them to go through the pilot-agent instead of the inbound listener of the sidecar.
+func TestJWTWithReadinessProbe(t *testing.T) {
+	const (
+		// The port of the readiness probe of the echo deployment.
+		probePort         = 8080
+		observationWindow = 60 * time.Second
+	)
+
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-probe",
+				Inject: true,
+			})
+
+			policies := namespacePolicies(t, ns, "testdata/requestauthn/b-authn-authz.yaml.tmpl")
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			bConfig := util.EchoConfig("b", ns, false, nil, p)
+			bConfig.Ports[0].InstancePort = probePort
+			var a, b echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&b, bConfig).
+				BuildOrFail(t)
+
+			denied := authn.NewCase(a, b, authn.WithName("no-token"), authn.WithExpect(authn.Denied))
+			retry.UntilSuccessOrFail(t, denied.CheckAuthn, authn.RetryOptions()...)
+
+			gen := traffic.Start(denied.CheckAuthn, time.Second)
+			notReady := podsNotReady(t, b, observationWindow)
+			if err := gen.Stop().Error(); err != nil {
+				t.Errorf("requests without token were not denied during the observation window: %v", err)
+			}
+			if len(notReady) > 0 {
+				t.Fatalf("pods not ready during the observation window:\n%s\nprobe config:\n%s",
+					strings.Join(notReady, "\n"), probeConfig(t, b))
+			}
+		})
+}
+
+// podsNotReady polls the conditions of the pods of the given instance for the duration and returns a
+// description of each time a pod was seen not ready.
+func podsNotReady(t *testing.T, instance echo.Instance, duration time.Duration) []string {
+	t.Helper()
+	var notReady []string
+	for end := time.Now().Add(duration); time.Now().Before(end); time.Sleep(2 * time.Second) {
+		conditions, err := echokube.PodConditions(instance)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for pod, conds := range conditions {
+			for _, c := range conds {
+				if c.Type == kubeCore.PodReady && c.Status != kubeCore.ConditionTrue {
+					notReady = append(notReady, fmt.Sprintf("%s: %s: %s (%s)",
+						time.Now().Format(time.RFC3339), pod, c.Reason, c.Message))
+				}
+			}
+		}
+	}
+	return notReady
+}
+
+// probeConfig returns the readiness probes of the pods of the given instance, and the probes the
+// sidecar runs on their behalf when the injector rewrote them.
+func probeConfig(t *testing.T, instance echo.Instance) string {
+	t.Helper()
+	pods, err := echokube.Pods(instance)
+	if err != nil {
+		return err.Error()
+	}
+	var out []string
+	for _, pod := range pods {
+		for _, c := range pod.Spec.Containers {
+			if c.ReadinessProbe != nil {
+				out = append(out, fmt.Sprintf("%s/%s readinessProbe: %s", pod.Name, c.Name, c.ReadinessProbe.String()))
+			}
+			for _, env := range c.Env {
+				if env.Name == "ISTIO_KUBE_APP_PROBERS" {
+					out = append(out, fmt.Sprintf("%s/%s %s: %s", pod.Name, c.Name, env.Name, env.Value))
+				}
+			}
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+// TestJWTWithTokenInCookie tests a token sent in a cookie is validated, when an EnvoyFilter copies it to
+// the header the JWT rule reads the token from. The Lua filter config depends on the Envoy version, so the
+// EnvoyFilter only applies to the proxy versions it was tested with, and the test is skipped for others.
+func TestJWTWithTokenInCookie(t *testing.T) {
+	const cookieName = "jwt"
+
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-cookie",
+				Inject: true,
+			})
+
+			var a, b echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&b, util.EchoConfig("b", ns, false, nil, p)).
+				BuildOrFail(t)
+
+			skipUntestedCookieFilter(t, b)
+
+			namespaceTmpl := map[string]string{
+				"Namespace":    ns.Name(),
+				"ProxyVersion": cookieFilterProxyVersions,
+				"CookieName":   cookieName,
+				"Header":       "x-jwt-cookie",
+			}
+			policies := tmpl.EvaluateAllOrFail(t, namespaceTmpl,
+				file.AsStringOrFail(t, "testdata/requestauthn/b-cookie-jwt.yaml.tmpl"))
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			newTestCase := func(name string, cookies map[string]string, token string, expect authn.ExpectedResult) authn.TestCase {
+				return authn.NewCase(a, b, authn.WithName(name), authn.WithToken(token), authn.WithExpect(expect),
+					authn.WithRequest(func(r *connection.Checker) {
+						r.Options.Cookies = cookies
+					}))
+			}
+			testCases := []authn.TestCase{
+				newTestCase("valid-token-in-cookie",
+					map[string]string{"session": "abc", cookieName: jwt.TokenIssuer1}, "", authn.Allowed),
+				newTestCase("expired-token-in-cookie",
+					map[string]string{cookieName: jwt.TokenExpired}, "", authn.Unauthenticated),
+				newTestCase("other-cookie", map[string]string{"session": "abc"}, "", authn.Denied),
+				// The JWT rule only reads the token from the cookie.
+				newTestCase("valid-token-in-header", nil, jwt.TokenIssuer1, authn.Denied),
+			}
+			for _, c := range testCases {
+				t.Run(c.Name, func(t *testing.T) {
+					retry.UntilSuccessOrFail(t, c.CheckAuthn, authn.RetryOptions()...)
+				})
+			}
+		})
+}
+
+// cookieFilterProxyVersions matches the proxy versions the EnvoyFilter in b-cookie-jwt.yaml.tmpl was tested
+// with.
+const cookieFilterProxyVersions = `^1\.[67].*`
+
+// skipUntestedCookieFilter skips the test if the sidecar of the given instance has a proxy version the
+// EnvoyFilter in b-cookie-jwt.yaml.tmpl was not tested with.
+func skipUntestedCookieFilter(t *testing.T, instance echo.Instance) {
+	t.Helper()
+	if version := proxyVersion(t, instance); !regexp.MustCompile(cookieFilterProxyVersions).MatchString(version) {
+		t.Skipf("the cookie EnvoyFilter is not tested with proxy version %q (tested: %s)", version,
+			cookieFilterProxyVersions)
+	}
+}
+
+// proxyVersion returns the Istio version of the sidecar of the first workload of the given instance, as
+// reported in the node metadata of its bootstrap config.
+func proxyVersion(t *testing.T, instance echo.Instance) string {
+	t.Helper()
+	cfg := instance.WorkloadsOrFail(t)[0].Sidecar().ConfigOrFail(t)
+	for _, c := range cfg.Configs {
+		bootstrap := &envoyAdmin.BootstrapConfigDump{}
+		if !ptypes.Is(c, bootstrap) {
+			continue
+		}
+		if err := ptypes.UnmarshalAny(c, bootstrap); err != nil {
+			t.Fatal(err)
+		}
+		return bootstrap.GetBootstrap().GetNode().GetMetadata().GetFields()["ISTIO_VERSION"].GetStringValue()
+	}
+	t.Fatal("no bootstrap config found in the config dump")
+	return ""
+}
+
+// TestJWTWithOrphanedAuthorizationPolicy tests an AuthorizationPolicy requiring a request principal fails
+// closed when no RequestAuthentication applies to the workload: no token is validated, so no principal can
+// be established and all the requests are denied with 403, even with a valid token. A 401 would mean a JWT
+// filter rejected the token.
+func TestJWTWithOrphanedAuthorizationPolicy(t *testing.T) {
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-orphaned-authz",
+				Inject: true,
+			})
+
+			policies := namespacePolicies(t, ns, "testdata/requestauthn/b-authz-only.yaml.tmpl")
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			var a, b echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&b, util.EchoConfig("b", ns, false, nil, p)).
+				BuildOrFail(t)
+
+			newTestCase := func(name, token string) authn.TestCase {
+				return authn.NewCase(a, b, authn.WithName(name), authn.WithToken(token), authn.WithExpect(authn.Denied))
+			}
+			testCases := []authn.TestCase{
+				newTestCase("valid-token", jwt.TokenIssuer1),
+				newTestCase("expired-token", jwt.TokenExpired),
+				newTestCase("no-token", ""),
+			}
+			for _, c := range testCases {
+				t.Run(c.Name, func(t *testing.T) {
+					retry.UntilSuccessOrFail(t, c.CheckAuthn, authn.RetryOptions()...)
+				})
+			}
+		})
+}
+
+// TestJWTWithTokenRefreshOnConnection tests a token swapped mid-connection is checked on each request:
+// the proxy must not carry the outcome of the first request over to the next ones of the connection.
+func TestJWTWithTokenRefreshOnConnection(t *testing.T) {
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-refresh-conn",
+				Inject: true,
+			})
+
+			policies := namespacePolicies(t, ns, "testdata/requestauthn/b-authn-authz.yaml.tmpl")
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			var a, b echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&b, util.EchoConfig("b", ns, false, nil, p)).
+				BuildOrFail(t)
+
+			request := connection.Checker{
+				From: a,
+				Options: echo.CallOptions{
+					Target:   b,
+					PortName: "http",
+					Scheme:   scheme.HTTP,
+				},
+			}
+			cases := []struct {
+				name   string
+				tokens []string
+				expect []authn.ExpectedResult
+			}{
+				{
+					name:   "valid-then-expired",
+					tokens: []string{jwt.TokenIssuer1, jwt.TokenExpired},
+					expect: []authn.ExpectedResult{authn.Allowed, authn.Unauthenticated},
+				},
+				{
+					name:   "expired-then-valid",
+					tokens: []string{jwt.TokenExpired, jwt.TokenIssuer1},
+					expect: []authn.ExpectedResult{authn.Unauthenticated, authn.Allowed},
+				},
+			}
+			for _, c := range cases {
+				t.Run(c.name, func(t *testing.T) {
+					refresh := authn.TokenRefresh{
+						Request:       request,
+						Tokens:        c.tokens,
+						ExpectResults: c.expect,
+					}
+					retry.UntilSuccessOrFail(t, refresh.Check, authn.RetryOptions()...)
+				})
+			}
+		})
+}
+
+// TestJWTWithIstioConfigDiff tests a RequestAuthentication only adds the JWT authn filter to the inbound
+// HTTP filter chains of the selected workload, as shown by istioctl proxy-config, guarding against config
+// bloat or filter chain pollution. The Istio authn filter is also expected to change, as it takes the JWT
+// issuers to set the request principal. The other workloads must not be affected.
+func TestJWTWithIstioConfigDiff(t *testing.T) {
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-config-diff",
+				Inject: true,
+			})
+
+			var a, c echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&c, util.EchoConfig("c", ns, false, nil, p)).
+				BuildOrFail(t)
+
+			notJWT := func(s *envoyconfig.Snapshot) (bool, error) {
+				has, err := s.HasHTTPFilter(authnmodel.EnvoyJwtFilterName)
+				return !has, err
+			}
+			beforeA := stableInboundSnapshot(t, a, notJWT)
+			beforeC := stableInboundSnapshot(t, c, notJWT)
+
+			policies := namespacePolicies(t, ns, "testdata/requestauthn/c-authn.yaml.tmpl")
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			afterC := stableInboundSnapshot(t, c, func(s *envoyconfig.Snapshot) (bool, error) {
+				return s.HasHTTPFilter(authnmodel.EnvoyJwtFilterName)
+			})
+			diff, err := envoyconfig.Compare(beforeC, afterC)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Logf("config diff of c:\n%s", diff)
+			if len(diff.AddedHTTPFilters) != 1 || len(diff.AddedHTTPFilters[authnmodel.EnvoyJwtFilterName]) == 0 {
+				t.Errorf("want only %s added, got:\n%s", authnmodel.EnvoyJwtFilterName, diff)
+			}
+			if len(diff.RemovedHTTPFilters) != 0 || len(diff.Other) != 0 {
+				t.Errorf("unexpected config changes:\n%s", diff)
+			}
+			for name := range diff.ChangedHTTPFilters {
+				if name != authnmodel.AuthnFilterName {
+					t.Errorf("unexpected change of HTTP filter %s:\n%s", name, diff)
+				}
+			}
+
+			// The policy selects c only, so the config of a is not expected to change. c already has the
+			// config of the policy, so a would have received it too by now, had it been pushed.
+			afterA := stableInboundSnapshot(t, a, notJWT)
+			if diff, err := envoyconfig.Compare(beforeA, afterA); err != nil {
+				t.Fatal(err)
+			} else if !diff.Empty() {
+				t.Errorf("config of a changed by the policy of c:\n%s", diff)
+			}
+		})
+}
+
+// stableInboundSnapshot returns the inbound config snapshot of the sidecar of the first workload of the
+// given instance, once accepted and unchanged over two consecutive config dumps.
+func stableInboundSnapshot(t *testing.T, instance echo.Instance,
+	accept func(*envoyconfig.Snapshot) (bool, error)) *envoyconfig.Snapshot {
+	t.Helper()
+	sidecar := instance.WorkloadsOrFail(t)[0].Sidecar()
+	var previous *envoyconfig.Snapshot
+	s, err := retry.Do(func() (interface{}, bool, error) {
+		dump, err := sidecar.Config()
+		if err != nil {
+			return nil, false, err
+		}
+		s, err := envoyconfig.Inbound(dump)
+		if err != nil {
+			return nil, false, err
+		}
+		if ok, err := accept(s); err != nil || !ok {
+			previous = nil
+			return nil, false, fmt.Errorf("config of %s not accepted yet: %v", instance.Config().Service, err)
+		}
+		last := previous
+		previous = s
+		if last == nil {
+			return nil, false, fmt.Errorf("config of %s not stable yet", instance.Config().Service)
+		}
+		diff, err := envoyconfig.Compare(last, s)
+		if err != nil {
+			return nil, false, err
+		}
+		if !diff.Empty() {
+			return nil, false, fmt.Errorf("config of %s not stable yet:\n%s", instance.Config().Service, diff)
+		}
+		return s, true, nil
+	}, retry.Delay(2*time.Second), retry.Timeout(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s.(*envoyconfig.Snapshot)
+}
+
+// TestJWTWithHeadRequest tests JWT is enforced on HEAD requests as on GET requests: the response codes
+// must be the same, and the HEAD responses must carry the Content-Length of the GET responses without
+// their body. The policy only validates the tokens, so a request without token is allowed.
+func TestJWTWithHeadRequest(t *testing.T) {
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-head",
+				Inject: true,
+			})
+
+			policies := namespacePolicies(t, ns, "testdata/requestauthn/c-authn.yaml.tmpl")
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			var a, c echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&c, util.EchoConfig("c", ns, false, nil, p)).
+				BuildOrFail(t)
+
+			newTestCase := func(name, method, token string, expect authn.ExpectedResult) authn.TestCase {
+				return authn.NewCase(a, c, authn.WithName(name), authn.WithToken(token), authn.WithExpect(expect),
+					authn.WithRequest(func(r *connection.Checker) {
+						r.Options.Method = method
+					}))
+			}
+			cases := []struct {
+				name   string
+				token  string
+				expect authn.ExpectedResult
+			}{
+				{"valid-token", jwt.TokenIssuer1, authn.Allowed},
+				{"expired-token", jwt.TokenExpired, authn.Unauthenticated},
+				{"no-token", "", authn.Allowed},
+			}
+			for _, tc := range cases {
+				t.Run(tc.name, func(t *testing.T) {
+					get := newTestCase(tc.name+"-get", http.MethodGet, tc.token, tc.expect)
+					head := newTestCase(tc.name+"-head", http.MethodHead, tc.token, tc.expect)
+					retry.UntilSuccessOrFail(t, get.CheckAuthn, authn.RetryOptions()...)
+					retry.UntilSuccessOrFail(t, head.CheckAuthn, authn.RetryOptions()...)
+
+					getResp := a.CallOrFail(t, get.Request.Options)[0]
+					headResp := a.CallOrFail(t, head.Request.Options)[0]
+					if headResp.Code != getResp.Code {
+						t.Errorf("HEAD got response code %s, GET got %s", headResp.Code, getResp.Code)
+					}
+					if strings.Contains(headResp.Body, "body] ") {
+						t.Errorf("HEAD response has a body:\n%s", headResp.Body)
+					}
+					headLength := headResp.ResponseHeaders.Get("Content-Length")
+					if headLength == "" || headLength == "0" {
+						t.Errorf("HEAD response has Content-Length %q, want the length of the GET body", headLength)
+					}
+					// The body of the echo server varies with the request, e.g. with its ID, but the body of
+					// the responses rejected by the proxy does not.
+					if getLength := getResp.ResponseHeaders.Get("Content-Length"); !headResp.IsOK() && headLength != getLength {
+						t.Errorf("HEAD response has Content-Length %s, GET response has %s", headLength, getLength)
+					}
+				})
+			}
+		})
+}
+
+// TestJWTWithSidecarEgress tests JWT is enforced on b whatever the route of the requests of a, as set by
+// a Sidecar resource scoping the egress of a: through the service of b if its namespace is in scope, or
+// through the PassthroughCluster if not. If b is not in scope and outbound traffic is REGISTRY_ONLY, the
+// requests are dropped by the sidecar of a with 502 before any token is checked. The route is told by the
+// X-Forwarded-Client-Cert header received by b: it is only set for mTLS requests, which the passthrough
+// requests are not.
+func TestJWTWithSidecarEgress(t *testing.T) {
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			nsA := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-sidecar-a",
+				Inject: true,
+			})
+			nsB := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-sidecar-b",
+				Inject: true,
+			})
+
+			policies := namespacePolicies(t, nsB, "testdata/requestauthn/b-authn-authz.yaml.tmpl")
+			ctx.ApplyConfigOrFail(t, nsB.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, nsB.Name(), policies...)
+
+			// The outbound listener on port 80 exists whatever the scope, as the gateways in the root
+			// namespace listen on it, so a blackholed HTTP request gets 502 instead of a reset connection.
+			bConfig := util.EchoConfig("b", nsB, false, nil, p)
+			bConfig.Ports[0].ServicePort = 80
+			var a, b echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", nsA, false, nil, p)).
+				With(&b, bConfig).
+				BuildOrFail(t)
+
+			// Only the mTLS requests received by b carry the identity of b in X-Forwarded-Client-Cert.
+			viaService := map[string]string{
+				"X-Forwarded-Client-Cert": fmt.Sprintf("By=spiffe://cluster.local/ns/%s/sa/b", nsB.Name()),
+			}
+			viaPassthrough := map[string]string{
+				"X-Forwarded-Client-Cert": "",
+			}
+			newTestCase := func(name, token string, expect authn.ExpectedResult, headers map[string]string) authn.TestCase {
+				tc := authn.NewCase(a, b, authn.WithName(name), authn.WithToken(token), authn.WithExpect(expect))
+				// Only the requests reaching b have a body to check.
+				if expect == authn.Allowed {
+					tc.ExpectHeaders = headers
+				}
+				return tc
+			}
+			tokenCases := func(route map[string]string) []authn.TestCase {
+				return []authn.TestCase{
+					newTestCase("valid-token", jwt.TokenIssuer1, authn.Allowed, route),
+					newTestCase("expired-token", jwt.TokenExpired, authn.Unauthenticated, route),
+					newTestCase("no-token", "", authn.Denied, route),
+				}
+			}
+			blackholed := []authn.TestCase{
+				newTestCase("valid-token", jwt.TokenIssuer1, authn.Blackholed, nil),
+				newTestCase("expired-token", jwt.TokenExpired, authn.Blackholed, nil),
+				newTestCase("no-token", "", authn.Blackholed, nil),
+			}
+
+			modes := []struct {
+				name                  string
+				egressNamespace       string
+				outboundTrafficPolicy string
+				cases                 []authn.TestCase
+			}{
+				{
+					name:                  "b-in-scope",
+					egressNamespace:       nsB.Name(),
+					outboundTrafficPolicy: "REGISTRY_ONLY",
+					cases:                 tokenCases(viaService),
+				},
+				{
+					name:                  "passthrough",
+					outboundTrafficPolicy: "ALLOW_ANY",
+					cases:                 tokenCases(viaPassthrough),
+				},
+				{
+					name:                  "blackhole",
+					outboundTrafficPolicy: "REGISTRY_ONLY",
+					cases:                 blackholed,
+				},
+			}
+			for _, mode := range modes {
+				t.Run(mode.name, func(t *testing.T) {
+					sidecar := tmpl.EvaluateAllOrFail(t, map[string]string{
+						"Namespace":             nsA.Name(),
+						"RootNamespace":         rootNamespace,
+						"EgressNamespace":       mode.egressNamespace,
+						"OutboundTrafficPolicy": mode.outboundTrafficPolicy,
+					}, file.AsStringOrFail(t, "testdata/requestauthn/sidecar-egress.yaml.tmpl"))
+					ctx.ApplyConfigOrFail(t, nsA.Name(), sidecar...)
+					defer ctx.DeleteConfigOrFail(t, nsA.Name(), sidecar...)
+
+					for _, c := range mode.cases {
+						t.Run(c.Name, func(t *testing.T) {
+							retry.UntilSuccessOrFail(t, c.CheckAuthn, authn.RetryOptions()...)
+						})
+					}
+				})
+			}
+		})
+}
+
+// TestJWTWithQueryParamCollision tests a JWT extracted from a query parameter the application also reads.
+// The proxy validates the parameter and leaves it in the request, so the application still receives it.
+// Any value of the parameter is taken for a token: an application value that is not a JWT is rejected.
+func TestJWTWithQueryParamCollision(t *testing.T) {
+	const param = "token"
+
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-query-param",
+				Inject: true,
+			})
+
+			policies := namespacePolicies(t, ns, "testdata/requestauthn/c-query-param.yaml.tmpl")
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			var a, c echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&c, util.EchoConfig("c", ns, false, nil, p)).
+				BuildOrFail(t)
+
+			cases := []struct {
+				name   string
+				value  string
+				expect authn.ExpectedResult
+			}{
+				{"valid-token", jwt.TokenIssuer1, authn.Allowed},
+				{"expired-token", jwt.TokenExpired, authn.Unauthenticated},
+				{"application-value", "app-value", authn.Unauthenticated},
+				{"no-param", "", authn.Allowed},
+			}
+			for _, tc := range cases {
+				t.Run(tc.name, func(t *testing.T) {
+					path := "/"
+					if tc.value != "" {
+						path += "?" + param + "=" + tc.value
+					}
+					check := authn.TestCase{
+						Name: tc.name,
+						Request: connection.Checker{
+							From: a,
+							Options: echo.CallOptions{
+								Target:   c,
+								PortName: "http",
+								Scheme:   scheme.HTTP,
+								Path:     path,
+							},
+						},
+						ExpectResult: tc.expect,
+					}
+					retry.UntilSuccessOrFail(t, func() error {
+						if err := check.CheckAuthn(); err != nil {
+							return err
+						}
+						if tc.expect != authn.Allowed {
+							return nil
+						}
+						// The application must receive the parameter as sent.
+						resp, err := a.Call(check.Request.Options)
+						if err != nil {
+							return err
+						}
+						if resp[0].URL != path {
+							return fmt.Errorf("application received URL %q, want %q", resp[0].URL, path)
+						}
+						return nil
+					}, authn.RetryOptions()...)
+				})
+			}
+		})
+}
+
+// TestJWTWithCookieCollision tests a JWT copied from a cookie the application also reads. The cookie is
+// left in the request whether or not the original token is forwarded, so the application still receives
+// it; forwardOriginalToken only controls whether the header the token was copied to is forwarded.
+func TestJWTWithCookieCollision(t *testing.T) {
+	const (
+		cookieName = "session"
+		header     = "x-jwt-cookie"
+	)
+
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-cookie-collision",
+				Inject: true,
+			})
+
+			var a, b echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&b, util.EchoConfig("b", ns, false, nil, p)).
+				BuildOrFail(t)
+
+			skipUntestedCookieFilter(t, b)
+
+			for _, forward := range []bool{false, true} {
+				t.Run(fmt.Sprintf("forward-original-token-%t", forward), func(t *testing.T) {
+					namespaceTmpl := map[string]interface{}{
+						"Namespace":            ns.Name(),
+						"ProxyVersion":         cookieFilterProxyVersions,
+						"CookieName":           cookieName,
+						"Header":               header,
+						"ForwardOriginalToken": forward,
+					}
+					policies := tmpl.EvaluateAllOrFail(t, namespaceTmpl,
+						file.AsStringOrFail(t, "testdata/requestauthn/b-cookie-jwt.yaml.tmpl"))
+					ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+					defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+					forwardedToken := ""
+					if forward {
+						forwardedToken = jwt.TokenIssuer1
+					}
+					newTestCase := func(name, value string, expect authn.ExpectedResult, headers map[string]string) authn.TestCase {
+						var cookies map[string]string
+						if value != "" {
+							cookies = map[string]string{cookieName: value}
+						}
+						c := authn.NewCase(a, b, authn.WithName(name), authn.WithExpect(expect),
+							authn.WithRequest(func(r *connection.Checker) {
+								r.Options.Cookies = cookies
+							}))
+						c.ExpectHeaders = headers
+						return c
+					}
+					testCases := []authn.TestCase{
+						// The application still receives its cookie after the token is extracted from it.
+						newTestCase("valid-token", jwt.TokenIssuer1, authn.Allowed, map[string]string{
+							"Cookie":                        cookieName + "=" + jwt.TokenIssuer1,
+							http.CanonicalHeaderKey(header): forwardedToken,
+						}),
+						newTestCase("expired-token", jwt.TokenExpired, authn.Unauthenticated, nil),
+						// Any value of the cookie is taken for a token: application state that is not a JWT
+						// is rejected.
+						newTestCase("application-value", "app-state", authn.Unauthenticated, nil),
+						newTestCase("no-cookie", "", authn.Denied, nil),
+					}
+					for _, c := range testCases {
+						t.Run(c.Name, func(t *testing.T) {
+							retry.UntilSuccessOrFail(t, c.CheckAuthn, authn.RetryOptions()...)
+						})
+					}
+				})
+			}
+		})
+}
+
+// TestJWTWithRetries tests how the retry policy of a VirtualService applies to 401 responses, with two
+// replicas of b. The number of requests each replica receives shows the retries: with retryOn=5xx a 401 is
+// not retried, while with 401 in the retriable status codes it is retried on the other replica (Istio
+// prefers hosts not attempted yet). A 401 from the JWT filter of b is retried too, but never reaches
+// the application. The attempts are also checked from the attempt count seen by the application and
+// from the retries counted by the sidecar of a.
+func TestJWTWithRetries(t *testing.T) {
+	const attempts = 2
+
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-retries",
+				Inject: true,
+			})
+
+			var a, b echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&b, util.EchoConfigWithReplicas("b", ns, 2, nil, p)).
+				BuildOrFail(t)
+			workloads := b.WorkloadsOrFail(t)
+			port := b.Config().Ports[0].InstancePort
+
+			// requestCounts returns the number of requests received by each replica of b.
+			requestCounts := func() ([]int, error) {
+				counts := make([]int, 0, len(workloads))
+				for _, w := range workloads {
+					n, err := w.RequestCount(port)
+					if err != nil {
+						return nil, err
+					}
+					counts = append(counts, n)
+				}
+				return counts, nil
+			}
+
+			type testCase struct {
+				name   string
+				token  string
+				path   string
+				expect authn.ExpectedResult
+				// The number of requests received by each replica, in increasing order.
+				wantCounts []int
+				// The number of attempts seen by the application in the last request, 0 if the response
+				// does not come from the application, and the number of retries of the sidecar of a.
+				wantAttempts int
+				wantRetries  int
+			}
+			configs := []struct {
+				name    string
+				retryOn string
+				cases   []testCase
+			}{
+				{
+					name:    "retry-on-5xx",
+					retryOn: "5xx",
+					cases: []testCase{
+						{"valid-token", jwt.TokenIssuer1, "/", authn.Allowed, []int{0, 1}, 1, 0},
+						{"application-401", jwt.TokenIssuer1, "/?codes=401", authn.Unauthenticated, []int{0, 1}, 1, 0},
+						{"expired-token", jwt.TokenExpired, "/", authn.Unauthenticated, []int{0, 0}, 0, 0},
+						// An error of the application is retried, and the response of the last attempt returned.
+						{"application-503", jwt.TokenIssuer1, "/?codes=503", authn.Unspecified, []int{1, 2}, 3, 2},
+					},
+				},
+				{
+					name:    "retry-on-401",
+					retryOn: "401,retriable-status-codes",
+					cases: []testCase{
+						{"valid-token", jwt.TokenIssuer1, "/", authn.Allowed, []int{0, 1}, 1, 0},
+						// The request and its 2 retries alternate between the replicas.
+						{"application-401", jwt.TokenIssuer1, "/?codes=401", authn.Unauthenticated, []int{1, 2}, 3, 2},
+						{"expired-token", jwt.TokenExpired, "/", authn.Unauthenticated, []int{0, 0}, 0, 2},
+					},
+				},
+			}
+			for _, cfg := range configs {
+				t.Run(cfg.name, func(t *testing.T) {
+					policies := tmpl.EvaluateAllOrFail(t, map[string]interface{}{
+						"Namespace": ns.Name(),
+						"RetryOn":   cfg.retryOn,
+						"Attempts":  attempts,
+					}, file.AsStringOrFail(t, "testdata/requestauthn/b-retries.yaml.tmpl"))
+					ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+					defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+					for _, tc := range cfg.cases {
+						t.Run(tc.name, func(t *testing.T) {
+							c := authn.TestCase{
+								Name: tc.name,
+								Request: connection.Checker{
+									From: a,
+									Options: echo.CallOptions{
+										Target:   b,
+										PortName: "http",
+										Scheme:   scheme.HTTP,
+										Path:     tc.path,
+										Token:    tc.token,
+									},
+								},
+								ExpectResult:   tc.expect,
+								ExpectAttempts: tc.wantAttempts,
+							}
+							if tc.expect == authn.Unspecified {
+								c.ExpectResponseCode = response.StatusCodeUnavailable
+							}
+							retry.UntilSuccessOrFail(t, func() error {
+								before, err := requestCounts()
+								if err != nil {
+									return err
+								}
+								retriesBefore, err := authn.UpstreamRetries(a, b)
+								if err != nil {
+									return err
+								}
+								if err := c.CheckAuthn(); err != nil {
+									return err
+								}
+								after, err := requestCounts()
+								if err != nil {
+									return err
+								}
+								got := make([]int, len(after))
+								for i := range after {
+									got[i] = after[i] - before[i]
+								}
+								sort.Ints(got)
+								if !reflect.DeepEqual(got, tc.wantCounts) {
+									return fmt.Errorf("%s: got %v requests received by the replicas, want %v", c.String(), got, tc.wantCounts)
+								}
+								retriesAfter, err := authn.UpstreamRetries(a, b)
+								if err != nil {
+									return err
+								}
+								if got := retriesAfter - retriesBefore; got != tc.wantRetries {
+									return fmt.Errorf("%s: got %d retries, want %d", c.String(), got, tc.wantRetries)
+								}
+								return nil
+							}, retry.Delay(time.Second), retry.Timeout(time.Minute))
+						})
+					}
+				})
+			}
+		})
+}
+
+// TestJWTWithRequestBodySizeJWT tests the JWT filter validates the token in the headers of requests with
+// a large body (1 MiB) the same way as without a body: the outcome does not change and the body of the
+// accepted requests reaches the application in full. The latencies with and without a body are logged
+// for comparison.
+func TestJWTWithRequestBodySizeJWT(t *testing.T) {
+	const (
+		bodySize = 1024 * 1024
+		count    = 5
+	)
+
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-body",
+				Inject: true,
+			})
+
+			policies := namespacePolicies(t, ns, "testdata/requestauthn/c-authn.yaml.tmpl")
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			var a, c echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&c, util.EchoConfig("c", ns, false, nil, p)).
+				BuildOrFail(t)
+
+			cases := []struct {
+				name   string
+				token  string
+				expect authn.ExpectedResult
+			}{
+				{"valid-token", jwt.TokenIssuer1, authn.Allowed},
+				{"expired-token", jwt.TokenExpired, authn.Unauthenticated},
+				{"invalid-token", jwt.TokenInvalid, authn.Unauthenticated},
+				{"no-token", "", authn.Allowed},
+			}
+			for _, tc := range cases {
+				t.Run(tc.name, func(t *testing.T) {
+					call := func(bodySize int) (time.Duration, error) {
+						start := time.Now()
+						responses, err := a.Call(echo.CallOptions{
+							Target:   c,
+							PortName: "http",
+							Scheme:   scheme.HTTP,
+							Method:   http.MethodPost,
+							Token:    tc.token,
+							BodySize: bodySize,
+							Count:    count,
+						})
+						elapsed := time.Since(start) / count
+						if err != nil {
+							return 0, err
+						}
+						if err := responses.CheckCode(tc.expect.ResponseCode()); err != nil {
+							return 0, fmt.Errorf("body size %d: %v", bodySize, err)
+						}
+						if tc.expect != authn.Allowed {
+							return elapsed, nil
+						}
+						want := ""
+						if bodySize > 0 {
+							want = strconv.Itoa(bodySize)
+						}
+						for i, r := range responses {
+							if r.RequestBodySize != want {
+								return 0, fmt.Errorf("body size %d: response[%d] got body of %q bytes received, want %q",
+									bodySize, i, r.RequestBodySize, want)
+							}
+						}
+						return elapsed, nil
+					}
+
+					retry.UntilSuccessOrFail(t, func() error {
+						withoutBody, err := call(0)
+						if err != nil {
+							return err
+						}
+						withBody, err := call(bodySize)
+						if err != nil {
+							return err
+						}
+						t.Logf("mean latency without body: %v, with a body of %d bytes: %v", withoutBody, bodySize, withBody)
+						return nil
+					}, authn.RetryOptions()...)
+				})
+			}
+		})
+}
+
+// TestJWTWithPilotResourceThrottling tests a JWT policy is enforced within the SLO while istiod is
+// loaded with pushes: many ServiceEntries are applied at the same time, each triggering a full push, right
+// before the policy. The enforcement latency is logged.
+func TestJWTWithPilotResourceThrottling(t *testing.T) {
+	const (
+		batches          = 4
+		entriesPerBatch  = 50
+		enforcementSLO   = 30 * time.Second
+		enforcementDelay = 250 * time.Millisecond
+	)
+
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-push-load",
+				Inject: true,
+			})
+
+			var a, b echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&b, util.EchoConfig("b", ns, false, nil, p)).
+				BuildOrFail(t)
+
+			newTestCase := authn.NewCaseFunc(a, b)
+			// Without a policy, all the requests are allowed.
+			before := newTestCase("no-policy", jwt.TokenExpired, authn.Allowed)
+			retry.UntilSuccessOrFail(t, before.CheckAuthn, authn.RetryOptions()...)
+
+			indexes := make([]int, entriesPerBatch)
+			for i := range indexes {
+				indexes[i] = i
+			}
+			loadTmpl := file.AsStringOrFail(t, "testdata/requestauthn/load-service-entries.yaml.tmpl")
+			var load []string
+			for batch := 0; batch < batches; batch++ {
+				load = append(load, tmpl.EvaluateOrFail(t, loadTmpl,
+					map[string]interface{}{
+						"Namespace": ns.Name(),
+						"Batch":     batch,
+						"Indexes":   indexes,
+					}))
+			}
+			// Only the batches applied are deleted, some may have failed.
+			applied := make([]bool, len(load))
+			defer func() {
+				for i, l := range load {
+					if applied[i] {
+						ctx.DeleteConfigOrFail(t, ns.Name(), l)
+					}
+				}
+			}()
+
+			// Apply the batches at the same time, then the policy right away.
+			g := errgroup.Group{}
+			for i, l := range load {
+				i, l := i, l
+				g.Go(func() error {
+					if err := ctx.ApplyConfig(ns.Name(), l); err != nil {
+						return err
+					}
+					applied[i] = true
+					return nil
+				})
+			}
+			if err := g.Wait(); err != nil {
+				t.Fatalf("failed to apply the ServiceEntries: %v", err)
+			}
+			policies := namespacePolicies(t, ns, "testdata/requestauthn/b-authn-authz.yaml.tmpl")
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			start := time.Now()
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			enforced := []authn.TestCase{
+				newTestCase("valid-token", jwt.TokenIssuer1, authn.Allowed),
+				newTestCase("expired-token", jwt.TokenExpired, authn.Unauthenticated),
+				newTestCase("no-token", "", authn.Denied),
+			}
+			err := retry.UntilSuccess(func() error {
+				for _, c := range enforced {
+					if err := c.CheckAuthn(); err != nil {
+						return err
+					}
+				}
+				return nil
+			}, retry.Delay(enforcementDelay), retry.Timeout(enforcementSLO))
+			enforcementLatency := time.Since(start)
+			t.Logf("metric jwt_policy_enforcement_latency_under_push_load{service_entries=%d}: %v",
+				batches*entriesPerBatch, enforcementLatency)
+			if err != nil {
+				t.Fatalf("JWT policy not enforced within %v under push load: %v", enforcementSLO, err)
+			}
+		})
+}
+
+// TestJWTWithAccessLogResponseFlags tests the sidecar of the target logs why a request was rejected: a
+// request without token is denied by the authorization policy, which the access log records as the RBAC
+// response flag, a request with an invalid token is rejected by the JWT filter, which sets no flag but its
+// response code details, while an allowed request has no response flag.
+func TestJWTWithAccessLogResponseFlags(t *testing.T) {
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			istio.EnableAccessLogsOrFail(t, ctx, ist)
+
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-access-log",
+				Inject: true,
+			})
+
+			policies := namespacePolicies(t, ns, "testdata/requestauthn/b-authn-authz.yaml.tmpl")
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			var a, b echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&b, util.EchoConfig("b", ns, false, nil, p)).
+				BuildOrFail(t)
+
+			testCases := []authn.TestCase{
+				{
+					Name: "no-token",
+					Request: connection.Checker{
+						From: a,
+						Options: echo.CallOptions{
+							Target:   b,
+							PortName: "http",
+							Scheme:   scheme.HTTP,
+						},
+					},
+					ExpectResult:        authn.Denied,
+					ExpectResponseFlags: []string{"RBAC"},
+					ExpectRejectedBy:    accesslog.RBACFilter,
+				},
+				{
+					Name: "invalid-token",
+					Request: connection.Checker{
+						From: a,
+						Options: echo.CallOptions{
+							Target:   b,
+							PortName: "http",
+							Scheme:   scheme.HTTP,
+							Token:    jwt.TokenInvalid,
+						},
+					},
+					ExpectResult:     authn.Unauthenticated,
+					ExpectRejectedBy: accesslog.JWTFilter,
+				},
+				{
+					Name: "valid-token",
+					Request: connection.Checker{
+						From: a,
+						Options: echo.CallOptions{
+							Target:   b,
+							PortName: "http",
+							Scheme:   scheme.HTTP,
+							Token:    jwt.TokenIssuer1,
+						},
+					},
+					ExpectResult: authn.Allowed,
+				},
+			}
+			for _, c := range testCases {
+				t.Run(c.Name, func(t *testing.T) {
+					retry.UntilSuccessOrFail(t, c.CheckAuthn, authn.RetryOptions()...)
+				})
+			}
+		})
+}
+
+// TestJWTWithEnvoyPeerMetadataForwarding tests the dynamic metadata of the JWT filter and of the Istio
+// authn filter do not collide on the sidecar of the target, which also runs the peer metadata exchange of
+// the telemetry: the access log records the payload of the token under its issuer in the metadata of
+// envoy.filters.http.jwt_authn only, and both the SPIFFE identity of the peer and the principal of the
+// token in the metadata of istio_authn.
+func TestJWTWithEnvoyPeerMetadataForwarding(t *testing.T) {
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			istio.EnableAccessLogsOrFail(t, ctx, ist)
+
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-peer-metadata",
+				Inject: true,
+			})
+
+			policies := namespacePolicies(t, ns, "testdata/requestauthn/b-authn-authz.yaml.tmpl")
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			var a, b echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&b, util.EchoConfig("b", ns, false, nil, p)).
+				BuildOrFail(t)
+
+			// The identity of a, without the trust domain.
+			sourcePrincipal := fmt.Sprintf("/ns/%s/sa/a", ns.Name())
+			checkPeer := func(entry accesslog.Entry) error {
+				principal, _ := entry.AuthnMetadata["source.principal"].(string)
+				if !strings.HasSuffix(principal, sourcePrincipal) {
+					return fmt.Errorf("got source.principal %q in %v, want the identity of a", principal, entry.AuthnMetadata)
+				}
+				return nil
+			}
+			testCases := []authn.TestCase{
+				{
+					Name: "valid-token",
+					Request: connection.Checker{
+						From: a,
+						Options: echo.CallOptions{
+							Target:   b,
+							PortName: "http",
+							Scheme:   scheme.HTTP,
+							Token:    jwt.TokenIssuer1,
+						},
+					},
+					ExpectResult: authn.Allowed,
+					CheckAccessLogEntry: func(entry accesslog.Entry) error {
+						if len(entry.JWTMetadata) != 1 {
+							return fmt.Errorf("got JWT metadata %v, want the payload of the token only", entry.JWTMetadata)
+						}
+						claims, _ := entry.JWTMetadata[authn.Issuer1].(map[string]interface{})
+						if claims["sub"] != "sub-1" {
+							return fmt.Errorf("got JWT metadata %v, want the payload of the token under its issuer",
+								entry.JWTMetadata)
+						}
+						want := authn.Issuer1 + "/sub-1"
+						if principal := entry.AuthnMetadata["request.auth.principal"]; principal != want {
+							return fmt.Errorf("got request.auth.principal %v in %v, want %s",
+								principal, entry.AuthnMetadata, want)
+						}
+						return checkPeer(entry)
+					},
+				},
+				{
+					Name: "no-token",
+					Request: connection.Checker{
+						From: a,
+						Options: echo.CallOptions{
+							Target:   b,
+							PortName: "http",
+							Scheme:   scheme.HTTP,
+						},
+					},
+					ExpectResult: authn.Denied,
+					CheckAccessLogEntry: func(entry accesslog.Entry) error {
+						if entry.JWTMetadata != nil {
+							return fmt.Errorf("got JWT metadata %v without token", entry.JWTMetadata)
+						}
+						if principal, ok := entry.AuthnMetadata["request.auth.principal"]; ok {
+							return fmt.Errorf("got request.auth.principal %v without token", principal)
+						}
+						return checkPeer(entry)
+					},
+				},
+			}
+			for _, c := range testCases {
+				t.Run(c.Name, func(t *testing.T) {
+					retry.UntilSuccessOrFail(t, c.CheckAuthn, authn.RetryOptions()...)
+				})
+			}
+		})
+}
+
+// TestJWTWithL7TelemetryReporting tests the denials of the JWT filter and of the authorization policy are
+// reported to Prometheus with their response code and the source and destination workloads: a request
+// with an invalid token is rejected with 401, and a request without token to a service requiring one with
+// 403. Skipped if Prometheus is not deployed.
+func TestJWTWithL7TelemetryReporting(t *testing.T) {
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			if !metrics.PrometheusAvailable(ctx) {
+				ctx.Skip("prometheus is not deployed")
+			}
+			prom := prometheus.NewOrFail(t, ctx, prometheus.Config{})
+
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-telemetry",
+				Inject: true,
+			})
+
+			policies := namespacePolicies(t, ns, "testdata/requestauthn/b-authn-authz.yaml.tmpl",
+				"testdata/requestauthn/c-authn.yaml.tmpl")
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			var a, b, c echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&b, util.EchoConfig("b", ns, false, nil, p)).
+				With(&c, util.EchoConfig("c", ns, false, nil, p)).
+				BuildOrFail(t)
+
+			// The number of denied requests sent by each test case.
+			const count = 5
+			testCases := []struct {
+				name  string
+				to    echo.Instance
+				token string
+				want  authn.ExpectedResult
+			}{
+				{
+					name:  "invalid-token",
+					to:    c,
+					token: jwt.TokenInvalid,
+					want:  authn.Unauthenticated,
+				},
+				{
+					name: "no-token",
+					to:   b,
+					want: authn.Denied,
+				},
+			}
+			for _, tc := range testCases {
+				t.Run(tc.name, func(t *testing.T) {
+					newCase := func(n int) authn.TestCase {
+						return authn.NewCase(a, tc.to, authn.WithToken(tc.token), authn.WithExpect(tc.want),
+							authn.WithRequest(func(r *connection.Checker) {
+								r.Options.Count = n
+							}))
+					}
+					// Wait for the policy to take effect, so that all the requests counted are denied.
+					once := newCase(1)
+					retry.UntilSuccessOrFail(t, once.CheckAuthn, authn.RetryOptions()...)
+
+					query := metrics.Query{
+						Metric: metrics.RequestsTotal,
+						Labels: map[string]string{
+							"reporter":                       "destination",
+							"response_code":                  tc.want.ResponseCode(),
+							"source_workload":                metrics.Workload(a),
+							"source_workload_namespace":      ns.Name(),
+							"destination_workload":           metrics.Workload(tc.to),
+							"destination_workload_namespace": ns.Name(),
+						},
+					}
+					denied := newCase(count)
+					if err := metrics.ExpectPrometheusIncrease(prom, query, count, denied.CheckAuthn); err != nil {
+						t.Fatal(err)
+					}
+				})
+			}
+		})
+}
+
+// TestJWTWithMultipleTokenLocations tests the token is extracted from each location configured in the
+// RequestAuthentication, here two headers with a prefix and two query parameters, and only from those:
+// a token in another location, or without the prefix, is ignored and the request is denied by the
+// authorization policy requiring a request principal.
+func TestJWTWithMultipleTokenLocations(t *testing.T) {
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-locations",
+				Inject: true,
+			})
+
+			policy := authn.RequestAuthentication{
+				Name:      "token-locations-for-b",
+				Namespace: ns.Name(),
+				App:       "b",
+				Rules: []authn.JWTRule{
+					{
+						Issuer:  authn.Issuer1,
+						JwksURI: authn.JwksURI1,
+						FromHeaders: []authn.JWTHeader{
+							{Name: "X-JWT-Assertion", Prefix: "JWT "},
+							{Name: authHeaderKey, Prefix: "Bearer "},
+						},
+						FromParams: []string{"access_token", "token"},
+					},
+				},
+			}
+			policies := append(namespacePolicies(t, ns, "testdata/requestauthn/b-authz-only.yaml.tmpl"),
+				policy.YAMLOrFail(t))
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			var a, b echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&b, util.EchoConfig("b", ns, false, nil, p)).
+				BuildOrFail(t)
+
+			cases := []struct {
+				name   string
+				header string
+				value  string
+				path   string
+				expect authn.ExpectedResult
+			}{
+				{name: "authorization-header", header: authHeaderKey, value: "Bearer " + jwt.TokenIssuer1,
+					expect: authn.Allowed},
+				{name: "custom-header", header: "X-JWT-Assertion", value: "JWT " + jwt.TokenIssuer1,
+					expect: authn.Allowed},
+				{name: "first-param", path: "/?access_token=" + jwt.TokenIssuer1, expect: authn.Allowed},
+				{name: "second-param", path: "/?token=" + jwt.TokenIssuer1, expect: authn.Allowed},
+				{name: "invalid-token-in-custom-header", header: "X-JWT-Assertion", value: "JWT " + jwt.TokenInvalid,
+					expect: authn.Unauthenticated},
+				{name: "invalid-token-in-param", path: "/?access_token=" + jwt.TokenInvalid,
+					expect: authn.Unauthenticated},
+				{name: "custom-header-without-prefix", header: "X-JWT-Assertion", value: jwt.TokenIssuer1,
+					expect: authn.Denied},
+				{name: "unconfigured-header", header: "X-Token", value: jwt.TokenIssuer1, expect: authn.Denied},
+				{name: "unconfigured-param", path: "/?id_token=" + jwt.TokenIssuer1, expect: authn.Denied},
+				{name: "no-token", expect: authn.Denied},
+			}
+			for _, tc := range cases {
+				t.Run(tc.name, func(t *testing.T) {
+					opts := echo.CallOptions{
+						Target:   b,
+						PortName: "http",
+						Scheme:   scheme.HTTP,
+						Path:     tc.path,
+					}
+					if tc.header != "" {
+						opts.Headers = http.Header{}
+						opts.Headers.Set(tc.header, tc.value)
+					}
+					check := authn.TestCase{
+						Name: tc.name,
+						Request: connection.Checker{
+							From:    a,
+							Options: opts,
+						},
+						ExpectResult: tc.expect,
+					}
+					retry.UntilSuccessOrFail(t, check.CheckAuthn, authn.RetryOptions()...)
+				})
+			}
+		})
+}
+
+// TestJWTWithHeaderCaseSensitivity tests the lookup of the token in the headers configured by fromHeaders is
+// case-insensitive, as are the names of the HTTP/1 headers: the token is extracted from the configured header
+// whatever the case of its name as sent, but not from a header with another name.
+func TestJWTWithHeaderCaseSensitivity(t *testing.T) {
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-header-case",
+				Inject: true,
+			})
+
+			policy := authn.RequestAuthentication{
+				Name:      "custom-header-for-b",
+				Namespace: ns.Name(),
+				App:       "b",
+				Rules: []authn.JWTRule{
+					{
+						Issuer:      authn.Issuer1,
+						JwksURI:     authn.JwksURI1,
+						FromHeaders: []authn.JWTHeader{{Name: "X-Custom-Token"}},
+					},
+				},
+			}
+			policies := append(namespacePolicies(t, ns, "testdata/requestauthn/b-authz-only.yaml.tmpl"),
+				policy.YAMLOrFail(t))
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			var a, b echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&b, util.EchoConfig("b", ns, false, nil, p)).
+				BuildOrFail(t)
+
+			cases := []struct {
+				name   string
+				header string
+				token  string
+				expect authn.ExpectedResult
+			}{
+				{name: "canonical-case", header: "X-Custom-Token", token: jwt.TokenIssuer1, expect: authn.Allowed},
+				{name: "lower-case", header: "x-custom-token", token: jwt.TokenIssuer1, expect: authn.Allowed},
+				{name: "upper-case", header: "X-CUSTOM-TOKEN", token: jwt.TokenIssuer1, expect: authn.Allowed},
+				{name: "invalid-token-in-lower-case", header: "x-custom-token", token: jwt.TokenInvalid,
+					expect: authn.Unauthenticated},
+				{name: "invalid-token-in-upper-case", header: "X-CUSTOM-TOKEN", token: jwt.TokenInvalid,
+					expect: authn.Unauthenticated},
+				{name: "other-header", header: "X-Custom-Tokens", token: jwt.TokenIssuer1, expect: authn.Denied},
+				{name: "no-token", expect: authn.Denied},
+			}
+			for _, tc := range cases {
+				t.Run(tc.name, func(t *testing.T) {
+					opts := echo.CallOptions{
+						Target:   b,
+						PortName: "http",
+						Scheme:   scheme.HTTP,
+					}
+					if tc.header != "" {
+						// Not set with Header.Set, which would canonicalize the name.
+						opts.Headers = http.Header{tc.header: []string{tc.token}}
+					}
+					check := authn.TestCase{
+						Name: tc.name,
+						Request: connection.Checker{
+							From:    a,
+							Options: opts,
+						},
+						ExpectResult: tc.expect,
+					}
+					retry.UntilSuccessOrFail(t, check.CheckAuthn, authn.RetryOptions()...)
+				})
+			}
+		})
+}
+
+// TestJWTWithEmptyString tests the requests with an Authorization header carrying no token are handled as
+// requests without token: denied by the authorization policy requiring a request principal, neither
+// rejected by the JWT filter with 401 nor failing with 500. The trailing whitespace of a header value is
+// not part of it in HTTP, so "Bearer " is sent as "Bearer", which does not have the prefix of the token.
+// A malformed token after the prefix is still rejected by the JWT filter.
+func TestJWTWithEmptyString(t *testing.T) {
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-empty",
+				Inject: true,
+			})
+
+			policies := namespacePolicies(t, ns, "testdata/requestauthn/b-authn-authz.yaml.tmpl")
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			var a, b echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&b, util.EchoConfig("b", ns, false, nil, p)).
+				BuildOrFail(t)
+
+			const rbacDenied = "RBAC: access denied"
+			cases := []struct {
+				name       string
+				value      string
+				expect     authn.ExpectedResult
+				expectBody string
+			}{
+				{name: "bearer-trailing-space", value: "Bearer ", expect: authn.Denied, expectBody: rbacDenied},
+				{name: "bearer-only", value: "Bearer", expect: authn.Denied, expectBody: rbacDenied},
+				{name: "empty-value", value: "", expect: authn.Denied, expectBody: rbacDenied},
+				{name: "malformed-token", value: "Bearer x", expect: authn.Unauthenticated,
+					expectBody: "Jwt is not in the form of Header.Payload.Signature"},
+				{name: "valid-token", value: "Bearer " + jwt.TokenIssuer1, expect: authn.Allowed},
+			}
+			for _, tc := range cases {
+				t.Run(tc.name, func(t *testing.T) {
+					check := authn.TestCase{
+						Name: tc.name,
+						Request: connection.Checker{
+							From: a,
+							Options: echo.CallOptions{
+								Target:   b,
+								PortName: "http",
+								Scheme:   scheme.HTTP,
+								Headers:  http.Header{authHeaderKey: {tc.value}},
+							},
+						},
+						ExpectResult: tc.expect,
+						ExpectBody:   tc.expectBody,
+					}
+					if tc.expectBody == rbacDenied {
+						// The exact body of the denial, as RBAC is not configured with a custom one.
+						check.ExpectDenialBody = rbacDenied
+					}
+					retry.UntilSuccessOrFail(t, check.CheckAuthn, authn.RetryOptions()...)
+				})
+			}
+		})
+}
+
+// TestJWTWithURLFragment tests the fragment some clients send in the target of a request, though RFC 7230
+// does not allow it, does not affect the path matched by the policies: /health_check is exempted from the
+// token, with or without a fragment, and the fragment is stripped before the request reaches the workload.
+func TestJWTWithURLFragment(t *testing.T) {
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-fragment",
+				Inject: true,
+			})
+
+			policies := namespacePolicies(t, ns, "testdata/requestauthn/b-health-check.yaml.tmpl")
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			var a, b echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&b, util.EchoConfig("b", ns, false, nil, p)).
+				BuildOrFail(t)
+
+			cases := []struct {
+				name   string
+				path   string
+				token  string
+				expect authn.ExpectedResult
+				// receivedPath, if set, is the path the requests are expected to reach b with.
+				receivedPath string
+			}{
+				{name: "exempted", path: "/health_check", expect: authn.Allowed},
+				{name: "exempted-with-fragment", path: "/health_check#section", expect: authn.Allowed,
+					receivedPath: "/health_check"},
+				{name: "fragment-completing-exempted-path", path: "/health#_check", expect: authn.Denied},
+				{name: "not-exempted-with-fragment", path: "/other#section", expect: authn.Denied},
+				{name: "not-exempted-with-fragment-and-token", path: "/other#section", token: jwt.TokenIssuer1,
+					expect: authn.Allowed, receivedPath: "/other"},
+				{name: "exempted-with-fragment-and-invalid-token", path: "/health_check#section",
+					token: jwt.TokenInvalid, expect: authn.Unauthenticated},
+			}
+			for _, tc := range cases {
+				t.Run(tc.name, func(t *testing.T) {
+					b.ClearReceivedRequestsOrFail(t)
+					check := authn.TestCase{
+						Name: tc.name,
+						Request: connection.Checker{
+							From: a,
+							Options: echo.CallOptions{
+								Target:       b,
+								PortName:     "http",
+								Scheme:       scheme.HTTP,
+								Path:         tc.path,
+								SendFragment: true,
+								Token:        tc.token,
+							},
+						},
+						ExpectResult: tc.expect,
+					}
+					retry.UntilSuccessOrFail(t, check.CheckAuthn, authn.RetryOptions()...)
+					if tc.receivedPath == "" {
+						return
+					}
+					received := b.ReceivedRequestsOrFail(t, time.Time{}, echo.WithPathPrefix(tc.receivedPath))
+					if len(received) == 0 {
+						t.Fatalf("b received no request on %s", tc.receivedPath)
+					}
+					for _, r := range received {
+						if r.Path != tc.receivedPath {
+							t.Errorf("b received a request on %s, want %s", r.Path, tc.receivedPath)
+						}
+					}
+				})
+			}
+		})
+}
+
+// TestJWTWithWildcardPaths tests the prefix and suffix paths exempted from the token by an authorization
+// policy match exactly the paths they should, around the tricky cases of the path matchers, e.g. /api vs
+// /api/ vs /apixyz for /api/*. The cases are derived from the policy, and the requests allowed must reach
+// the workload on the very path they are sent to.
+func TestJWTWithWildcardPaths(t *testing.T) {
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-wildcard-paths",
+				Inject: true,
+			})
+
+			policies := namespacePolicies(t, ns, "testdata/requestauthn/b-wildcard-paths.yaml.tmpl")
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			var a, b echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&b, util.EchoConfig("b", ns, false, nil, p)).
+				BuildOrFail(t)
+
+			for _, c := range authn.WildcardPathCasesOrFail(t, policies...) {
+				c := c
+				expect := authn.Allowed
+				if c.RequiresToken {
+					expect = authn.Denied
+				}
+				t.Run(strings.TrimPrefix(c.Path, "/"), func(t *testing.T) {
+					b.ClearReceivedRequestsOrFail(t)
+					check := authn.TestCase{
+						Name: c.Path,
+						Request: connection.Checker{
+							From: a,
+							Options: echo.CallOptions{
+								Target:   b,
+								PortName: "http",
+								Scheme:   scheme.HTTP,
+								Path:     c.Path,
+							},
+						},
+						ExpectResult: expect,
+					}
+					retry.UntilSuccessOrFail(t, check.CheckAuthn, authn.RetryOptions()...)
+					if c.RequiresToken {
+						return
+					}
+					// Not any request of b, e.g. its readiness probes.
+					received := b.ReceivedRequestsOrFail(t, time.Time{}, echo.WithPathPrefix(c.Path))
+					if len(received) == 0 {
+						t.Fatalf("b received no request on %s", c.Path)
+					}
+					for _, r := range received {
+						if r.Path != c.Path {
+							t.Errorf("b received a request on %s, want %s", r.Path, c.Path)
+						}
+					}
+				})
+			}
+		})
+}
+
+// TestJWTWithNakedClient tests the JWT policy of a workload applies to the requests of a client without
+// sidecar, which are plain text. Under a mesh-wide STRICT mTLS, such a client cannot connect at all.
+func TestJWTWithNakedClient(t *testing.T) {
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-naked",
+				Inject: true,
+			})
+
+			policies := namespacePolicies(t, ns, "testdata/requestauthn/b-authn-authz.yaml.tmpl")
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			var naked, b echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&naked, util.EchoConfig("naked", ns, false, echo.NewAnnotations().
+					SetBool(echo.SidecarInject, false), p)).
+				With(&b, util.EchoConfig("b", ns, false, nil, p)).
+				BuildOrFail(t)
+
+			newTestCase := func(name, token string, expect authn.ExpectedResult) authn.TestCase {
+				return authn.NewCase(naked, b, authn.WithName(name), authn.WithToken(token),
+					authn.WithExpect(mtlsmode.NakedClient(expect)))
+			}
+			testCases := []authn.TestCase{
+				newTestCase("valid-token", jwt.TokenIssuer1, authn.Allowed),
+				newTestCase("invalid-token", jwt.TokenInvalid, authn.Unauthenticated),
+				newTestCase("no-token", "", authn.Denied),
+			}
+			for _, c := range testCases {
+				t.Run(c.Name, func(t *testing.T) {
+					retry.UntilSuccessOrFail(t, c.CheckAuthn, authn.RetryOptions()...)
+				})
+			}
+		})
+}
+
+// TestJWTWithBase64UrlDecodingVariants tests the JWT filter only accepts tokens encoded with base64url:
+// the same token re-encoded with the characters of the standard base64 alphabet is malformed and rejected
+// with 401, while the original token is accepted.
+func TestJWTWithBase64UrlDecodingVariants(t *testing.T) {
+	if jwtmatrix.StdBase64Token == jwt.TokenIssuer1 {
+		t.Fatal("the token has no character specific to base64url")
+	}
+	matrix := jwtMatrix(t)
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-base64",
+				Inject: true,
+			})
+
+			policies := matrixPolicies(t, matrix, ns)
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			var a, c echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&c, util.EchoConfig("c", ns, false, nil, p)).
+				BuildOrFail(t)
+
+			for _, tc := range matrixTestCases(t, ctx, matrix, a, c) {
+				t.Run(tc.Name, func(t *testing.T) {
+					retry.UntilSuccessOrFail(t, tc.CheckAuthn, authn.RetryOptions()...)
+				})
+			}
+		})
+}
+
+// TestJWTWithConnectionChurn tests the requests rejected by the sidecar of the target are never
+// misreported as 503 (UC, upstream connection termination) when the client closes its connection after
+// each request: all the requests must get the response code of the rejection.
+func TestJWTWithConnectionChurn(t *testing.T) {
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-churn",
+				Inject: true,
+			})
+
+			policies := namespacePolicies(t, ns, "testdata/requestauthn/b-authn-authz.yaml.tmpl",
+				"testdata/requestauthn/c-authn.yaml.tmpl")
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			var a, b, c echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&b, util.EchoConfig("b", ns, false, nil, p)).
+				With(&c, util.EchoConfig("c", ns, false, nil, p)).
+				BuildOrFail(t)
+
+			const iterations = 100
+			testCases := []struct {
+				name   string
+				to     echo.Instance
+				token  string
+				expect authn.ExpectedResult
+			}{
+				{name: "no-token", to: b, expect: authn.Denied},
+				{name: "invalid-token", to: c, token: jwt.TokenInvalid, expect: authn.Unauthenticated},
+			}
+			for _, tc := range testCases {
+				t.Run(tc.name, func(t *testing.T) {
+					check := authn.TestCase{
+						Name: tc.name,
+						Request: connection.Checker{
+							From: a,
+							Options: echo.CallOptions{
+								Target:          tc.to,
+								PortName:        "http",
+								Scheme:          scheme.HTTP,
+								Token:           tc.token,
+								CloseConnection: true,
+							},
+						},
+						ExpectResult: tc.expect,
+					}
+					// Wait for the policy to take effect, so that all the requests counted are rejected by it.
+					retry.UntilSuccessOrFail(t, check.CheckAuthn, authn.RetryOptions()...)
+
+					opts := check.Request.Options
+					opts.Count = iterations
+					results, err := a.Call(opts)
+					if err != nil {
+						t.Fatalf("%d requests with churn: %v", iterations, err)
+					}
+					codes := results.Distribution(func(r *client.ParsedResponse) string { return r.Code })
+					// Any other code, 503 in particular, means a rejection was lost to the churn.
+					if codes[tc.expect.ResponseCode()] != iterations {
+						t.Fatalf("%d requests with churn: got response codes %v, want %s only",
+							iterations, codes, tc.expect.ResponseCode())
+					}
+				})
+			}
+		})
+}
+
+// TestJWTWithSubsetPolicy tests an authorization policy selecting one version of a workload only applies
+// to the subset of the DestinationRule the request is routed to: the subset v2 requires a token, while the
+// subset v1 serves the requests without token.
+func TestJWTWithSubsetPolicy(t *testing.T) {
+	matrix := jwtMatrix(t)
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-subsets",
+				Inject: true,
+			})
+
+			policies := matrixPolicies(t, matrix, ns)
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			var a, b echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&b, util.EchoConfigWithVersions("b", ns, []string{"v1", "v2"}, nil, p)).
+				BuildOrFail(t)
+
+			for _, c := range matrixTestCases(t, ctx, matrix, a, b) {
+				t.Run(c.Name, func(t *testing.T) {
+					retry.UntilSuccessOrFail(t, c.CheckAuthn, authn.RetryOptions()...)
+				})
+			}
+		})
+}
+
+// TestJWTWithAudiencePerRule tests a policy configuring the same issuer twice, with a different audience
+// for each rule, bound to different paths by an authorization policy. The JWT filter selects the rules of
+// a token by its issuer only, so the audiences are not bound to the paths by the rules themselves: this
+// pins the behavior, with the reasons of the rejections.
+func TestJWTWithAudiencePerRule(t *testing.T) {
+	matrix := jwtMatrix(t)
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-audiences",
+				Inject: true,
+			})
+
+			policies := matrixPolicies(t, matrix, ns)
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			var a, b echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&b, util.EchoConfig("b", ns, false, nil, p)).
+				BuildOrFail(t)
+
+			for _, c := range matrixTestCases(t, ctx, matrix, a, b) {
+				t.Run(c.Name, func(t *testing.T) {
+					retry.UntilSuccessOrFail(t, c.CheckAuthn, authn.RetryOptions()...)
+				})
+			}
+		})
+}
+
+// TestJWTWithLoopbackBypass pins the exemption of the loopback traffic: the calls of a workload to itself,
+// on localhost or over a unix domain socket, never go through its sidecar, so they reach the application
+// without token although the policies of the workload reject the same calls from another workload. A
+// change of the inbound interception altering the exemption fails this test.
+func TestJWTWithLoopbackBypass(t *testing.T) {
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-loopback",
+				Inject: true,
+			})
+
+			policies := namespacePolicies(t, ns, "testdata/requestauthn/b-authn-authz.yaml.tmpl")
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			var a, b echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&b, util.EchoConfigWithUDS("b", ns, nil, p)).
+				BuildOrFail(t)
+
+			newTestCase := func(name string, from echo.Instance, loopback echo.Loopback, token string,
+				expect authn.ExpectedResult) authn.TestCase {
+				c := authn.NewCase(from, b, authn.WithName(name), authn.WithToken(token), authn.WithExpect(expect),
+					authn.WithRequest(func(r *connection.Checker) {
+						r.Options.Loopback = loopback
+					}))
+				c.ExpectProxyBypass = loopback != ""
+				return c
+			}
+			testCases := []authn.TestCase{
+				newTestCase("external-no-token", a, "", "", authn.Denied),
+				newTestCase("external-valid-token", a, "", jwt.TokenIssuer1, authn.Allowed),
+				newTestCase("localhost-no-token", b, echo.LoopbackLocalhost, "", authn.Allowed),
+				newTestCase("localhost-invalid-token", b, echo.LoopbackLocalhost, jwt.TokenInvalid, authn.Allowed),
+				newTestCase("uds-no-token", b, echo.LoopbackUDS, "", authn.Allowed),
+				newTestCase("uds-invalid-token", b, echo.LoopbackUDS, jwt.TokenInvalid, authn.Allowed),
+			}
+			for _, c := range testCases {
+				t.Run(c.Name, func(t *testing.T) {
+					retry.UntilSuccessOrFail(t, c.CheckAuthn, authn.RetryOptions()...)
+				})
+			}
+		})
+}
+
+// TestJWTWithDeclaredPortProtocol tests the JWT filter and the authorization policies only apply to the
+// ports declared with an L7 protocol: the same HTTP requests are validated on a port declared HTTP, while
+// they bypass the JWT filter on a port declared TCP, where a policy requiring a request principal refuses
+// all the connections.
+func TestJWTWithDeclaredPortProtocol(t *testing.T) {
+	matrix := jwtMatrix(t)
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-declared-protocol",
+				Inject: true,
+			})
+
+			policies := matrixPolicies(t, matrix, ns)
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			declared := []protocol.Instance{protocol.TCP}
+			var a, b, c echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&b, util.EchoConfigWithDeclaredProtocols("b", ns, declared, nil, p)).
+				With(&c, util.EchoConfigWithDeclaredProtocols("c", ns, declared, nil, p)).
+				BuildOrFail(t)
+
+			for _, tc := range matrixTestCases(t, ctx, matrix, a, b, c) {
+				t.Run(tc.Name, func(t *testing.T) {
+					retry.UntilSuccessOrFail(t, tc.CheckAuthn, authn.RetryOptions()...)
+				})
+			}
+		})
+}
+
+// TestJWTWithHTTP2Trailers tests the JWT filter with requests carrying trailers, on a port declared HTTP2 so
+// that the sidecars forward them with HTTP/2 end to end: the trailers of the allowed requests reach the
+// application and come back as the trailers of the response, while a token sent as a trailer is not used.
+// Over HTTP/1.1, the sidecars drop the trailers.
+func TestJWTWithHTTP2Trailers(t *testing.T) {
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-trailers",
+				Inject: true,
+			})
+
+			policies := namespacePolicies(t, ns, "testdata/requestauthn/b-authn-authz.yaml.tmpl")
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			var a, b echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&b, util.EchoConfigWithDeclaredProtocols("b", ns, []protocol.Instance{protocol.HTTP2}, nil, p)).
+				BuildOrFail(t)
+			h2Port := util.DeclaredPortName(protocol.HTTP2)
+
+			checksum := map[string]string{"X-Checksum": "abc"}
+			cases := []struct {
+				name     string
+				portName string
+				http2    bool
+				token    string
+				trailers http.Header
+				expect   authn.ExpectedResult
+				// The trailers the target must receive and reflect, if allowed.
+				expectTrailers map[string]string
+				expectProto    string
+			}{
+				{
+					name:     "http2-valid-token",
+					portName: h2Port,
+					http2:    true,
+					token:    jwt.TokenIssuer1,
+					trailers: http.Header{"X-Checksum": {"abc"}},
+					expect:   authn.Allowed,
+
+					expectTrailers: checksum,
+					expectProto:    "HTTP/2.0",
+				},
+				{
+					name:     "http2-invalid-token",
+					portName: h2Port,
+					http2:    true,
+					token:    jwt.TokenInvalid,
+					trailers: http.Header{"X-Checksum": {"abc"}},
+					expect:   authn.Unauthenticated,
+				},
+				{
+					name:     "http2-no-token",
+					portName: h2Port,
+					http2:    true,
+					trailers: http.Header{"X-Checksum": {"abc"}},
+					expect:   authn.Denied,
+				},
+				{
+					// The JWT filter only looks for the token in the headers.
+					name:     "http2-token-in-trailer",
+					portName: h2Port,
+					http2:    true,
+					trailers: http.Header{authHeaderKey: {"Bearer " + jwt.TokenIssuer1}},
+					expect:   authn.Denied,
+				},
+				{
+					name:     "http1-valid-token",
+					portName: "http",
+					token:    jwt.TokenIssuer1,
+					trailers: http.Header{"X-Checksum": {"abc"}},
+					expect:   authn.Allowed,
+
+					expectTrailers: map[string]string{"X-Checksum": ""},
+					expectProto:    "HTTP/1.1",
+				},
+			}
+			for _, tc := range cases {
+				t.Run(tc.name, func(t *testing.T) {
+					check := authn.TestCase{
+						Name: tc.name,
+						Request: connection.Checker{
+							From: a,
+							Options: echo.CallOptions{
+								Target:   b,
+								PortName: tc.portName,
+								Scheme:   scheme.HTTP,
+								HTTP2:    tc.http2,
+								Token:    tc.token,
+								Trailers: tc.trailers,
+							},
+						},
+						ExpectResult:           tc.expect,
+						ExpectRequestTrailers:  tc.expectTrailers,
+						ExpectResponseTrailers: tc.expectTrailers,
+						ExpectProto:            tc.expectProto,
+					}
+					retry.UntilSuccessOrFail(t, check.CheckAuthn, authn.RetryOptions()...)
+				})
+			}
+		})
+}
+
+// TestJWTWithSchemeHTTPS tests the JWT of the requests sent over HTTPS to a workload terminating TLS itself,
+// compared to the same requests over HTTP. The sidecars pass the TLS of the application through, so the
+// JWT filter validates the tokens sent over HTTP only, while the tokens sent over HTTPS reach the
+// application unaltered, without being consumed by the TLS handshake or by the filter.
+func TestJWTWithSchemeHTTPS(t *testing.T) {
+	matrix := jwtMatrix(t)
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-https",
+				Inject: true,
+			})
+
+			policies := matrixPolicies(t, matrix, ns)
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			certFile := func(f string) string {
+				return file.AsStringOrFail(t, path.Join(env.IstioSrc, "tests/testdata/certs/dns", f))
+			}
+			tls := &common.TLSSettings{
+				RootCert:   certFile("root-cert.pem"),
+				ClientCert: certFile("cert-chain.pem"),
+				Key:        certFile("key.pem"),
+			}
+
+			var a, c echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&c, util.EchoConfigWithHTTPS("c", ns, tls, nil, p)).
+				BuildOrFail(t)
+
+			for _, tc := range matrixTestCases(t, ctx, matrix, a, c) {
+				t.Run(tc.Name, func(t *testing.T) {
+					retry.UntilSuccessOrFail(t, tc.CheckAuthn, authn.RetryOptions()...)
+				})
+			}
+		})
+}
+
+// TestJWTWithRequestTimeoutAndJWKSFetch tests a slow JWKS server never holds the requests: the JWKS is
+// fetched by istiod, which gives up after 5 seconds and pushes the policy without the keys, inline, so
+// that the proxies never wait for a fetch. The JWKS server answers after 10 seconds, and the requests
+// with a token signed by its key must be rejected with 401 within 2 seconds.
+func TestJWTWithRequestTimeoutAndJWKSFetch(t *testing.T) {
+	const (
+		jwksDelay  = 10 * time.Second
+		maxLatency = 2 * time.Second
+		jwksName   = "slow"
+	)
+
+	key, err := jwt.NewSigningKey("slow-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := key.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-slow-jwks",
+				Inject: true,
+			})
+
+			var a, b, jwksServer echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&b, util.EchoConfig("b", ns, false, nil, p)).
+				With(&jwksServer, util.EchoConfig("jwks", ns, false,
+					echo.NewAnnotations().SetBool(echo.SidecarInject, false), p)).
+				BuildOrFail(t)
+			server := jwks.Server{Instance: jwksServer}
+
+			if err := server.ServeDelayed(ctx, jwksName, key.JWKS(), jwksDelay); err != nil {
+				t.Fatal(err)
+			}
+			jwksURI, err := server.URI(jwksName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			policies := tmpl.EvaluateAllOrFail(t, map[string]string{
+				"Namespace": ns.Name(),
+				"Name":      "slow-jwks",
+				"JwksURI":   jwksURI,
+			}, file.AsStringOrFail(t, "testdata/requestauthn/remote-jwks.yaml.tmpl"))
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			newTestCase := authn.NewCaseFunc(a, b, authn.WithRequest(func(r *connection.Checker) {
+				// A request held for the JWKS fails instead of getting a response.
+				r.Options.Timeout = maxLatency
+			}))
+			// The policy is pushed once istiod gives up on the JWKS.
+			pushed := newTestCase("policy-pushed", token, authn.Unauthenticated)
+			retry.UntilSuccessOrFail(t, pushed.CheckAuthn,
+				retry.Delay(time.Second), retry.Timeout(time.Minute))
+
+			for _, c := range []authn.TestCase{
+				newTestCase("token-of-slow-jwks", token, authn.Unauthenticated),
+				newTestCase("no-token", "", authn.Allowed),
+			} {
+				c := c
+				t.Run(c.Name, func(t *testing.T) {
+					for i := 0; i < 5; i++ {
+						start := time.Now()
+						if err := c.CheckAuthn(); err != nil {
+							t.Fatal(err)
+						}
+						if elapsed := time.Since(start); elapsed > maxLatency {
+							t.Errorf("request %d took %v, want at most %v", i, elapsed, maxLatency)
+						}
+					}
+				})
+			}
+
+			requests, err := server.Requests(ctx, jwksName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(requests) == 0 {
+				t.Errorf("JWKS never fetched from %s", jwksURI)
+			}
+		})
+}
+
+// TestJWTWithPolicyAnnotationOnPod tests the JWT policy is enforced on a workload whose sidecar is
+// injected with the resource limits of its pod annotations, which change the injected sidecar.
+func TestJWTWithPolicyAnnotationOnPod(t *testing.T) {
+	const (
+		cpuLimit    = "100m"
+		memoryLimit = "128Mi"
+	)
+
+	matrix := jwtMatrix(t)
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-pod-annotations",
+				Inject: true,
+			})
+
+			policies := matrixPolicies(t, matrix, ns)
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			var a, b echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&b, util.EchoConfig("b", ns, false, echo.NewAnnotations().
+					Set(echo.SidecarProxyCPULimit, cpuLimit).
+					Set(echo.SidecarProxyMemoryLimit, memoryLimit), p)).
+				BuildOrFail(t)
+
+			// The annotations must have been applied, or the test would not tell anything.
+			podName := podNameForWorkload(t, ctx, ns, b.WorkloadsOrFail(t)[0])
+			pod, err := kube.ClusterOrDefault(nil, ctx.Environment()).GetPod(ns.Name(), podName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			found := false
+			for _, c := range pod.Spec.Containers {
+				if c.Name != "istio-proxy" {
+					continue
+				}
+				found = true
+				if got := c.Resources.Limits.Cpu().String(); got != cpuLimit {
+					t.Errorf("sidecar of %s has CPU limit %s, want %s", podName, got, cpuLimit)
+				}
+				if got := c.Resources.Limits.Memory().String(); got != memoryLimit {
+					t.Errorf("sidecar of %s has memory limit %s, want %s", podName, got, memoryLimit)
+				}
+			}
+			if !found {
+				t.Fatalf("no sidecar in pod %s", podName)
+			}
+
+			for _, c := range matrixTestCases(t, ctx, matrix, a, b) {
+				t.Run(c.Name, func(t *testing.T) {
+					retry.UntilSuccessOrFail(t, c.CheckAuthn, authn.RetryOptions()...)
+				})
+			}
+		})
+}
+
+// TestJWTWithRouteTimeout tests the route timeout of a VirtualService does not bypass the JWT and
+// authorization filters: the requests rejected by them are answered at once, without reaching the
+// application, while the allowed ones reach it and are timed out with 504 by the sidecar of the caller.
+func TestJWTWithRouteTimeout(t *testing.T) {
+	const (
+		routeTimeout = time.Second
+		serverDelay  = 5 * time.Second
+	)
+
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-route-timeout",
+				Inject: true,
+			})
+
+			var a, b echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&b, util.EchoConfig("b", ns, false, nil, p)).
+				BuildOrFail(t)
+
+			delayedPath, err := timeout.ServeDelayed(ctx, b, "slow", serverDelay)
+			if err != nil {
+				t.Fatal(err)
+			}
+			vs, err := timeout.VirtualService(b, routeTimeout)
+			if err != nil {
+				t.Fatal(err)
+			}
+			policies := append(namespacePolicies(t, ns, "testdata/requestauthn/b-authn-authz.yaml.tmpl"), vs)
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			cases := []struct {
+				name   string
+				path   string
+				token  string
+				expect authn.ExpectedResult
+			}{
+				{name: "valid-token", path: delayedPath, token: jwt.TokenIssuer1, expect: authn.TimedOut},
+				{name: "valid-token-not-delayed", path: "/", token: jwt.TokenIssuer1, expect: authn.Allowed},
+				{name: "expired-token", path: delayedPath, token: jwt.TokenExpired, expect: authn.Unauthenticated},
+				{name: "no-token", path: delayedPath, expect: authn.Denied},
+			}
+			for _, c := range cases {
+				t.Run(c.name, func(t *testing.T) {
+					tc := authn.TestCase{
+						Name: c.name,
+						Request: connection.Checker{
+							From: a,
+							Options: echo.CallOptions{
+								Target:   b,
+								PortName: "http",
+								Scheme:   scheme.HTTP,
+								Path:     c.path,
+								Token:    c.token,
+							},
+						},
+						ExpectResult: c.expect,
+					}
+					retry.UntilSuccessOrFail(t, tc.CheckAuthn, authn.RetryOptions()...)
+
+					// Once the policies are applied, the rejected requests never wait for the application.
+					b.ClearReceivedRequestsOrFail(t)
+					start := time.Now()
+					if err := tc.CheckAuthn(); err != nil {
+						t.Fatal(err)
+					}
+					elapsed := time.Since(start)
+					received := b.ReceivedRequestsOrFail(t, time.Time{}, echo.WithPathPrefix(delayedPath))
+					switch c.expect {
+					case authn.TimedOut:
+						if len(received) == 0 {
+							t.Errorf("request timed out without reaching b")
+						}
+						if elapsed >= serverDelay {
+							t.Errorf("request timed out after %v, want before the delay of b %v", elapsed, serverDelay)
+						}
+					case authn.Unauthenticated, authn.Denied:
+						if len(received) != 0 {
+							t.Errorf("rejected request received by b: %+v", received)
+						}
+						if elapsed >= routeTimeout {
+							t.Errorf("request rejected after %v, want before the route timeout %v", elapsed, routeTimeout)
+						}
+					}
+				})
+			}
+		})
+}
+
+// TestJWTWithIstioControlPlaneTelemetry tests istiod reports the push of a RequestAuthentication in its
+// metrics: the JWT filter is in the HTTP filters of the listeners, so the listeners are pushed to the
+// proxies, counted by pilot_xds_pushes{type="lds"}.
+func TestJWTWithIstioControlPlaneTelemetry(t *testing.T) {
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-istiod-telemetry",
+				Inject: true,
+			})
+
+			var a, b echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&b, util.EchoConfig("b", ns, false, nil, p)).
+				BuildOrFail(t)
+
+			policies := namespacePolicies(t, ns, "testdata/requestauthn/b-authn-authz.yaml.tmpl")
+			ldsPushes := metrics.Query{
+				Metric: metrics.PilotXDSPushes,
+				Labels: map[string]string{"type": "lds"},
+			}
+			applied := false
+			defer func() {
+				if applied {
+					ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+				}
+			}()
+			if err := metrics.ExpectIstiodIncrease(ctx, ldsPushes, 1, func() error {
+				if err := ctx.ApplyConfig(ns.Name(), policies...); err != nil {
+					return err
+				}
+				applied = true
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			// The pushed config is the one enforced.
+			newTestCase := authn.NewCaseFunc(a, b)
+			for _, c := range []authn.TestCase{
+				newTestCase("valid-token", jwt.TokenIssuer1, authn.Allowed),
+				newTestCase("no-token", "", authn.Denied),
+			} {
+				t.Run(c.Name, func(t *testing.T) {
+					retry.UntilSuccessOrFail(t, c.CheckAuthn, authn.RetryOptions()...)
+				})
+			}
+		})
+}
+
+// TestJWTWithExtAuthz tests the JWT and RBAC filters run before an ext_authz filter inserted by an
+// EnvoyFilter: the requests rejected for their token never reach the ext_authz server, with 401 or 403,
+// while the requests with a valid token are checked by the ext_authz server, which sees their claims and
+// may still deny them with 403. The denials of RBAC and ext_authz are told apart by their body.
+// The same is not tested with an AuthorizationPolicy of action CUSTOM and a provider registered in the
+// extensionProviders of the MeshConfig, neither being in the vendored istio.io/api.
+func TestJWTWithExtAuthz(t *testing.T) {
+	const payloadHeader = "x-test-payload"
+
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-ext-authz",
+				Inject: true,
+			})
+
+			var a, b, server echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&b, util.EchoConfig("b", ns, false, nil, p)).
+				With(&server, extauthz.Config("ext-authz", ns, p)).
+				BuildOrFail(t)
+
+			envoyFilter, err := extauthz.EnvoyFilter(b, server, payloadHeader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			policies := append(namespacePolicies(t, ns, "testdata/requestauthn/b-ext-authz-jwt.yaml.tmpl"), envoyFilter)
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			cases := []struct {
+				name          string
+				token         string
+				deny          bool
+				expect        authn.ExpectedResult
+				denialBody    string
+				checked       bool
+				expectHeaders map[string]string
+				checkHeaders  map[string]string
+			}{
+				{
+					name: "valid-token", token: jwt.TokenIssuer1, expect: authn.Allowed, checked: true,
+					expectHeaders: map[string]string{common.ExtAuthzResultHeader: common.ExtAuthzAllowed},
+					checkHeaders:  map[string]string{payloadHeader: jwtmatrix.Payload(jwt.TokenIssuer1)},
+				},
+				{
+					name: "denied-by-ext-authz", token: jwt.TokenIssuer1, deny: true, expect: authn.Denied,
+					denialBody: common.ExtAuthzDenied, checked: true,
+				},
+				{name: "no-token", expect: authn.Denied, denialBody: "RBAC: access denied"},
+				{name: "expired-token", token: jwt.TokenExpired, expect: authn.Unauthenticated},
+			}
+			for _, c := range cases {
+				t.Run(c.name, func(t *testing.T) {
+					path := "/" + c.name
+					headers := http.Header{}
+					if c.deny {
+						headers.Set(common.ExtAuthzHeader, common.ExtAuthzDeny)
+					}
+					tc := authn.TestCase{
+						Name: c.name,
+						Request: connection.Checker{
+							From: a,
+							Options: echo.CallOptions{
+								Target:   b,
+								PortName: "http",
+								Scheme:   scheme.HTTP,
+								Path:     path,
+								Token:    c.token,
+								Headers:  headers,
+							},
+						},
+						ExpectResult:     c.expect,
+						ExpectDenialBody: c.denialBody,
+						ExpectHeaders:    c.expectHeaders,
+					}
+					retry.UntilSuccessOrFail(t, tc.CheckAuthn, authn.RetryOptions()...)
+
+					// Once the config is applied, the check requests are those of a single request.
+					server.ClearReceivedRequestsOrFail(t)
+					if err := tc.CheckAuthn(); err != nil {
+						t.Fatal(err)
+					}
+					checks, err := extauthz.CheckRequests(server, path)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if !c.checked {
+						if len(checks) != 0 {
+							t.Errorf("request rejected for its token checked by ext_authz: %+v", checks)
+						}
+						return
+					}
+					if len(checks) == 0 {
+						t.Fatalf("request not checked by ext_authz")
+					}
+					for name, value := range c.checkHeaders {
+						if got := checks[0].Headers.Get(name); got != value {
+							t.Errorf("ext_authz got %s=%q, want %q", name, got, value)
+						}
+					}
+				})
+			}
+		})
+}
+
+// TestJWTWithJWKSFromSecret tests a policy with the JWKS held by a Kubernetes secret. The
+// RequestAuthentication cannot reference a secret, so the JWKS is read back from the secret and inlined
+// in the policy, as a controller syncing the policy from the secret would do.
+func TestJWTWithJWKSFromSecret(t *testing.T) {
+	const secretName = "jwks"
+
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-secret-jwks",
+				Inject: true,
+			})
+
+			data, err := jwt.JWKS()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := jwks.CreateSecret(ctx, ns, secretName, data); err != nil {
+				t.Fatal(err)
+			}
+			inline, err := jwks.SecretJWKS(ctx, ns, secretName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			policies := tmpl.EvaluateAllOrFail(t, map[string]string{
+				"Namespace": ns.Name(),
+				"JWKS":      inline,
+			}, file.AsStringOrFail(t, "testdata/requestauthn/b-secret-jwks.yaml.tmpl"))
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			var a, b echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&b, util.EchoConfig("b", ns, false, nil, p)).
+				BuildOrFail(t)
+
+			newTestCase := authn.NewCaseFunc(a, b)
+			for _, c := range []authn.TestCase{
+				newTestCase("valid-token", jwt.TokenIssuer1, authn.Allowed),
+				newTestCase("expired-token", jwt.TokenExpired, authn.Unauthenticated),
+				newTestCase("invalid-token", jwt.TokenInvalid, authn.Unauthenticated),
+				newTestCase("no-token", "", authn.Denied),
+			} {
+				t.Run(c.Name, func(t *testing.T) {
+					retry.UntilSuccessOrFail(t, c.CheckAuthn, authn.RetryOptions()...)
+				})
+			}
+		})
+}
+
+// TestJWTWithMixedHTTPAndHTTPS tests a policy requiring a request principal on a workload with both a
+// plaintext and a TLS port is enforced on each port: the tokens are validated on the http port, while
+// the connections to the https port, whose TLS is terminated by the application, are all refused by the
+// sidecar, which cannot see the token, so that no request reaches the application unchecked.
+func TestJWTWithMixedHTTPAndHTTPS(t *testing.T) {
+	matrix := jwtMatrix(t)
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-mixed-https",
+				Inject: true,
+			})
+
+			policies := matrixPolicies(t, matrix, ns)
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			certFile := func(f string) string {
+				return file.AsStringOrFail(t, path.Join(env.IstioSrc, "tests/testdata/certs/dns", f))
+			}
+			tls := &common.TLSSettings{
+				RootCert:   certFile("root-cert.pem"),
+				ClientCert: certFile("cert-chain.pem"),
+				Key:        certFile("key.pem"),
+			}
+
+			var a, c echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&c, util.EchoConfigWithHTTPS("c", ns, tls, nil, p)).
+				BuildOrFail(t)
+
+			for _, tc := range matrixTestCases(t, ctx, matrix, a, c) {
+				t.Run(tc.Name, func(t *testing.T) {
+					retry.UntilSuccessOrFail(t, tc.CheckAuthn, authn.RetryOptions()...)
+				})
+			}
+		})
+}
+
+// TestJWTWithSidecarRestart tests the policies are enforced as before after the sidecar of the target is
+// restarted, i.e. the restarted proxy is reprogrammed with the JWT and authorization filters.
+func TestJWTWithSidecarRestart(t *testing.T) {
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-sidecar-restart",
+				Inject: true,
+			})
+
+			policies := namespacePolicies(t, ns, "testdata/requestauthn/b-authn-authz.yaml.tmpl")
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			var a, b echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&b, util.EchoConfig("b", ns, false, nil, p)).
+				BuildOrFail(t)
+
+			newTestCase := authn.NewCaseFunc(a, b)
+			cases := []authn.TestCase{
+				newTestCase("valid-token", jwt.TokenIssuer1, authn.Allowed),
+				newTestCase("expired-token", jwt.TokenExpired, authn.Unauthenticated),
+				newTestCase("invalid-token", jwt.TokenInvalid, authn.Unauthenticated),
+				newTestCase("no-token", "", authn.Denied),
+			}
+			run := func(t *testing.T) {
+				for _, c := range cases {
+					t.Run(c.Name, func(t *testing.T) {
+						retry.UntilSuccessOrFail(t, c.CheckAuthn, authn.RetryOptions()...)
+					})
+				}
+			}
+
+			t.Run("before-restart", run)
+			echokube.RestartSidecarsOrFail(t, b)
+			t.Run("after-restart", run)
+		})
+}
+
+// TestJWTWithAuthorizationPolicyRemoved tests removing only the AuthorizationPolicy of a workload, by its
+// handle, leaves the RequestAuthentication applied alongside enforced: the requests without a token are
+// allowed again, while the invalid tokens are still rejected.
+func TestJWTWithAuthorizationPolicyRemoved(t *testing.T) {
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-authz-removed",
+				Inject: true,
+			})
+
+			policies := namespacePolicies(t, ns, "testdata/requestauthn/b-authn-authz.yaml.tmpl")
+			handles := ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			var authnHandles, authzHandles []resource.ConfigHandle
+			for _, h := range handles {
+				if h.Kind == "AuthorizationPolicy" {
+					authzHandles = append(authzHandles, h)
+				} else {
+					authnHandles = append(authnHandles, h)
+				}
+			}
+			defer func() {
+				if err := ctx.DeleteConfigHandles(authnHandles...); err != nil {
+					t.Error(err)
+				}
+			}()
+			if len(authzHandles) == 0 {
+				t.Fatalf("no AuthorizationPolicy in %v", handles)
+			}
+			for _, h := range authnHandles {
+				if _, err := ctx.GetStatus(h); err != nil {
+					t.Errorf("failed to get the status of %v: %v", h, err)
+				}
+			}
+
+			var a, b echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&b, util.EchoConfig("b", ns, false, nil, p)).
+				BuildOrFail(t)
+
+			newTestCase := authn.NewCaseFunc(a, b)
+			run := func(t *testing.T, cases ...authn.TestCase) {
+				for _, c := range cases {
+					t.Run(c.Name, func(t *testing.T) {
+						retry.UntilSuccessOrFail(t, c.CheckAuthn, authn.RetryOptions()...)
+					})
+				}
+			}
+
+			t.Run("with-authz", func(t *testing.T) {
+				run(t,
+					newTestCase("valid-token", jwt.TokenIssuer1, authn.Allowed),
+					newTestCase("invalid-token", jwt.TokenInvalid, authn.Unauthenticated),
+					newTestCase("no-token", "", authn.Denied))
+			})
+			if err := ctx.DeleteConfigHandles(authzHandles...); err != nil {
+				t.Fatal(err)
+			}
+			t.Run("without-authz", func(t *testing.T) {
+				run(t,
+					newTestCase("valid-token", jwt.TokenIssuer1, authn.Allowed),
+					newTestCase("invalid-token", jwt.TokenInvalid, authn.Unauthenticated),
+					newTestCase("no-token", "", authn.Allowed))
+			})
+		})
+}
+
+// TestJWTWithSPIFFEBundleEndpoint tests a SPIFFE bundle endpoint can be used as the JWKS of a policy: the
+// trust bundle, served by a mock of the endpoint, is a JWKS whose key is the JWT-SVID authority of the trust
+// domain, along with SPIFFE fields the JWKS parsers must ignore. The JWT-SVIDs signed by the authority are
+// accepted, while the tampered ones are rejected with 401.
+func TestJWTWithSPIFFEBundleEndpoint(t *testing.T) {
+	const (
+		trustDomain = "spiffe://example.org"
+		audience    = "b"
+	)
+
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-spiffe-bundle",
+				Inject: true,
+			})
+
+			var a, b, bundleServer echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&b, util.EchoConfig("b", ns, false, nil, p)).
+				With(&bundleServer, util.EchoConfig("spiffe-bundle", ns, false,
+					echo.NewAnnotations().SetBool(echo.SidecarInject, false), p)).
+				BuildOrFail(t)
+			server := jwks.Server{Instance: bundleServer}
+
+			authority, err := jwt.NewSigningKey("jwt-svid-authority")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := server.Serve(ctx, "bundle", authority.SPIFFEBundle(1, 5*time.Minute), nil, 0); err != nil {
+				t.Fatal(err)
+			}
+			bundleURI, err := server.URI("bundle")
+			if err != nil {
+				t.Fatal(err)
+			}
+			policies := tmpl.EvaluateAllOrFail(t, map[string]string{
+				"Namespace": ns.Name(),
+				"Issuer":    trustDomain,
+				"Audience":  audience,
+				"JwksURI":   bundleURI,
+			}, file.AsStringOrFail(t, "testdata/requestauthn/spiffe-bundle.yaml.tmpl"))
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			claims := map[string]interface{}{
+				"iss": trustDomain,
+				"sub": fmt.Sprintf("%s/ns/%s/sa/a", trustDomain, ns.Name()),
+				"aud": audience,
+				"iat": time.Now().Unix(),
+				"exp": time.Now().Add(time.Hour).Unix(),
+			}
+			svid, err := authority.Sign(claims)
+			if err != nil {
+				t.Fatal(err)
+			}
+			claims["sub"] = fmt.Sprintf("%s/ns/%s/sa/admin", trustDomain, ns.Name())
+			tampered, err := jwt.Tamper(svid, claims)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, c := range []struct {
+				name   string
+				token  string
+				expect authn.ExpectedResult
+			}{
+				{name: "jwt-svid", token: svid, expect: authn.Allowed},
+				{name: "tampered-jwt-svid", token: tampered, expect: authn.Unauthenticated},
+			} {
+				tc := authn.NewCase(a, b, authn.WithName(c.name), authn.WithToken(c.token), authn.WithExpect(c.expect))
+				t.Run(c.name, func(t *testing.T) {
+					retry.UntilSuccessOrFail(t, tc.CheckAuthn, authn.RetryOptions()...)
+				})
+			}
+
+			requests, err := server.Requests(ctx, "bundle")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(requests) == 0 {
+				t.Errorf("SPIFFE bundle never fetched from %s", bundleURI)
+			}
+		})
+}
+
+// TestJWTWithSubclaimNestedArray tests an AuthorizationPolicy matching an array-valued claim, the groups of
+// the token, allows the requests whose array contains the value, wherever it is, and denies the others.
+func TestJWTWithSubclaimNestedArray(t *testing.T) {
+	matrix := jwtMatrix(t)
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-claim-array",
+				Inject: true,
+			})
+
+			policies := matrixPolicies(t, matrix, ns)
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			var a, b echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&b, util.EchoConfig("b", ns, false, nil, p)).
+				BuildOrFail(t)
+
+			for _, tc := range matrixTestCases(t, ctx, matrix, a, b) {
+				t.Run(tc.Name, func(t *testing.T) {
+					retry.UntilSuccessOrFail(t, tc.CheckAuthn, authn.RetryOptions()...)
+				})
+			}
+		})
+}
+
+// TestJWTWithStatefulSet tests the RequestAuthentication of a workload backed by a StatefulSet is enforced
+// the same way whether the calls address its service or the stable DNS name of its first pod.
+func TestJWTWithStatefulSet(t *testing.T) {
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-statefulset",
+				Inject: true,
+			})
+
+			policies := namespacePolicies(t, ns, "testdata/requestauthn/b-authn-authz.yaml.tmpl")
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			var a, b echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&b, util.EchoConfigWithStatefulSet("b", ns, 2, nil, p)).
+				BuildOrFail(t)
+
+			workloads := b.WorkloadsOrFail(t)
+			for i, w := range workloads {
+				if got, want := podNameForWorkload(t, ctx, ns, w), fmt.Sprintf("b-v1-%d", i); got != want {
+					t.Fatalf("workload %d of b is pod %s, want %s", i, got, want)
+				}
+			}
+			podHost, err := echokube.PodHostname(b, workloads[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, target := range []struct {
+				name string
+				host string
+			}{
+				{name: "service"},
+				{name: "pod-0", host: podHost},
+			} {
+				newTestCase := authn.NewCaseFunc(a, b, authn.WithRequest(func(r *connection.Checker) {
+					r.Options.Host = target.host
+				}))
+				cases := []authn.TestCase{
+					newTestCase("valid-token", jwt.TokenIssuer1, authn.Allowed),
+					newTestCase("invalid-token", jwt.TokenInvalid, authn.Unauthenticated),
+					newTestCase("no-token", "", authn.Denied),
+				}
+				t.Run(target.name, func(t *testing.T) {
+					for _, c := range cases {
+						t.Run(c.Name, func(t *testing.T) {
+							retry.UntilSuccessOrFail(t, c.CheckAuthn, authn.RetryOptions()...)
+						})
+					}
+				})
+			}
+		})
+}
+
+// TestJWTWithCustomPolicyNamespace tests the namespace-wide RequestAuthentication and AuthorizationPolicy of an
+// "admin" namespace, other than the root namespace, only apply to the workloads of that namespace: the same
+// workload deployed in another namespace accepts the requests with or without a token, valid or not.
+func TestJWTWithCustomPolicyNamespace(t *testing.T) {
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-app",
+				Inject: true,
+			})
+			adminNS := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-admin",
+				Inject: true,
+			})
+
+			policies := namespacePolicies(t, adminNS, "testdata/requestauthn/namespace-authn-authz.yaml.tmpl")
+			ctx.ApplyConfigOrFail(t, adminNS.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, adminNS.Name(), policies...)
+
+			var a, b, adminB echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&b, util.EchoConfig("b", ns, false, nil, p)).
+				With(&adminB, util.EchoConfig("b", adminNS, false, nil, p)).
+				BuildOrFail(t)
+
+			newTestCase := func(name string, target echo.Instance, token string, expect authn.ExpectedResult) authn.TestCase {
+				return authn.NewCase(a, target, authn.WithName(name), authn.WithToken(token), authn.WithExpect(expect))
+			}
+			cases := []authn.TestCase{
+				newTestCase("admin/valid-token", adminB, jwt.TokenIssuer1, authn.Allowed),
+				newTestCase("admin/invalid-token", adminB, jwt.TokenInvalid, authn.Unauthenticated),
+				newTestCase("admin/no-token", adminB, "", authn.Denied),
+				newTestCase("app/valid-token", b, jwt.TokenIssuer1, authn.Allowed),
+				newTestCase("app/invalid-token", b, jwt.TokenInvalid, authn.Allowed),
+				newTestCase("app/no-token", b, "", authn.Allowed),
+			}
+			for _, c := range cases {
+				t.Run(c.Name, func(t *testing.T) {
+					retry.UntilSuccessOrFail(t, c.CheckAuthn, authn.RetryOptions()...)
+				})
+			}
+		})
+}
+
+// TestJWTWithCertRotation tests the requests with or without a token keep being enforced as expected over
+// mTLS when the workload certificates of the caller and the target are rotated, and that the serial numbers
+// of the certificates served by their sidecars changed.
+func TestJWTWithCertRotation(t *testing.T) {
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-cert-rotation",
+				Inject: true,
+			})
+
+			policies := namespacePolicies(t, ns, "testdata/requestauthn/b-authn-authz.yaml.tmpl", "testdata/beta-mtls-on.yaml")
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			var a, b echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&b, util.EchoConfig("b", ns, false, nil, p)).
+				BuildOrFail(t)
+
+			newTestCase := authn.NewCaseFunc(a, b)
+			cases := []authn.TestCase{
+				newTestCase("valid-token", jwt.TokenIssuer1, authn.Allowed),
+				newTestCase("invalid-token", jwt.TokenInvalid, authn.Unauthenticated),
+				newTestCase("no-token", "", authn.Denied),
+			}
+			run := func(t *testing.T) {
+				for _, c := range cases {
+					t.Run(c.Name, func(t *testing.T) {
+						retry.UntilSuccessOrFail(t, c.CheckAuthn, authn.RetryOptions()...)
+					})
+				}
+			}
+
+			t.Run("before-rotation", run)
+			for _, i := range []echo.Instance{a, b} {
+				before, after := echokube.RotateWorkloadCertsOrFail(t, i)
+				t.Logf("rotated the certificates of %s: serial numbers %v, then %v", i.Config().Service, before, after)
+			}
+			t.Run("after-rotation", run)
+		})
+}
+
+// TestJWTWithMalformedRequest tests the malformed requests, sent raw to bypass the normalization of the HTTP
+// client, are rejected by the proxies rather than reaching a workload whose authorization policy exempts a
+// path from the token: a bad method, an oversized URI, a path traversing from the exempted path, or an
+// absolute-form target. The responses are checked as received, status line included.
+func TestJWTWithMalformedRequest(t *testing.T) {
+	framework.NewTest(t).
+		RequiresEnvironment(environment.Kube).
+		Run(func(ctx framework.TestContext) {
+			ns := namespace.NewOrFail(t, ctx, namespace.Config{
+				Prefix: "req-authn-malformed",
+				Inject: true,
+			})
+
+			policies := namespacePolicies(t, ns, "testdata/requestauthn/b-health-check.yaml.tmpl")
+			ctx.ApplyConfigOrFail(t, ns.Name(), policies...)
+			defer ctx.DeleteConfigOrFail(t, ns.Name(), policies...)
+
+			var a, b echo.Instance
+			echoboot.NewBuilderOrFail(ctx, ctx).
+				With(&a, util.EchoConfig("a", ns, false, nil, p)).
+				With(&b, util.EchoConfig("b", ns, false, nil, p)).
+				BuildOrFail(t)
+
+			host := b.Config().FQDN()
+			rawRequest := func(requestLine, token string) string {
+				out := requestLine + "\r\nHost: " + host + "\r\n"
+				if token != "" {
+					out += "Authorization: Bearer " + token + "\r\n"
+				}
+				return out + "Connection: close\r\n\r\n"
+			}
+			cases := []struct {
+				name    string
+				request string
+				// The response codes the request may get, e.g. depending on the proxy rejecting it.
+				expectCodes []string
+			}{
+				{
+					name:        "exempted",
+					request:     rawRequest("GET /health_check HTTP/1.1", ""),
+					expectCodes: []string{response.StatusCodeOK},
+				},
+				{
+					name:        "not-exempted",
+					request:     rawRequest("GET /other HTTP/1.1", ""),
+					expectCodes: []string{response.StatusCodeForbidden},
+				},
+				{
+					name:        "not-exempted-with-token",
+					request:     rawRequest("GET /other HTTP/1.1", jwt.TokenIssuer1),
+					expectCodes: []string{response.StatusCodeOK},
+				},
+				{
+					name:        "bad-method",
+					request:     rawRequest("G@T /health_check HTTP/1.1", ""),
+					expectCodes: []string{response.StatusCodeBadRequest},
+				},
+				{
+					name:    "oversized-uri",
+					request: rawRequest("GET /health_check?"+strings.Repeat("x", 100*1024)+" HTTP/1.1", ""),
+					expectCodes: []string{response.StatusCodeBadRequest, response.StatusCodeURITooLong,
+						response.StatusCodeHeaderFieldsTooLarge},
+				},
+				{
+					name:        "traversal-from-exempted",
+					request:     rawRequest("GET /health_check/../other HTTP/1.1", ""),
+					expectCodes: []string{response.StatusCodeBadRequest, response.StatusCodeForbidden},
+				},
+				{
+					name:        "absolute-form",
+					request:     rawRequest("GET http://"+host+"/other HTTP/1.1", ""),
+					expectCodes: []string{response.StatusCodeBadRequest, response.StatusCodeForbidden},
+				},
+				{
+					name:        "invalid-token-on-exempted",
+					request:     rawRequest("GET /health_check HTTP/1.1", jwt.TokenInvalid),
+					expectCodes: []string{response.StatusUnauthorized},
+				},
+			}
+			for _, tc := range cases {
+				t.Run(tc.name, func(t *testing.T) {
+					retry.UntilSuccessOrFail(t, func() error {
+						results, err := a.Call(echo.CallOptions{
+							Target:     b,
+							PortName:   "http",
+							Scheme:     scheme.HTTP,
+							RawRequest: tc.request,
+						})
+						if err != nil {
+							return err
+						}
+						for _, r := range results {
+							found := false
+							for _, code := range tc.expectCodes {
+								if r.Code == code {
+									found = true
+								}
+							}
+							if !found {
+								return fmt.Errorf("got response code %q, want one of %v, raw response:\n%s",
+									r.Code, tc.expectCodes, r.RawHTTPResponse)
+							}
+							if !strings.HasPrefix(r.RawHTTPResponse, "HTTP/1.1 "+r.Code+" ") {
+								return fmt.Errorf("got raw response not starting with the status line of %s:\n%s",
+									r.Code, r.RawHTTPResponse)
+							}
+						}
+						return nil
+					}, authn.RetryOptions()...)
+				})
+			}
+		})
+}
+
+// jwtMatrix returns the matrix of the test in jwtmatrix.Matrices.
+func jwtMatrix(t *testing.T) authn.Matrix {
+	m, ok := jwtmatrix.Matrices[t.Name()]
+	if !ok {
+		t.Fatalf("no JWT matrix for %s", t.Name())
+	}
+	return m
+}
+
+// matrixPolicies returns the policies of the matrix, evaluated for the given namespace.
+func matrixPolicies(t *testing.T, m authn.Matrix, ns namespace.Instance) []string {
+	return namespacePolicies(t, ns, m.Policies...)
+}
+
+// namespacePolicies returns the policy templates of the given files, evaluated for the given namespace.
+func namespacePolicies(t *testing.T, ns namespace.Instance, files ...string) []string {
+	templates := make([]string, 0, len(files))
+	for _, f := range files {
+		templates = append(templates, file.AsStringOrFail(t, f))
+	}
+	return tmpl.EvaluateAllOrFail(t, map[string]string{"Namespace": ns.Name()}, templates...)
+}
+
+// applyPolicy applies the policy template of the given file, evaluated with the given values, to the given
+// namespace and returns the policies. The policies selecting a gateway go to rootNS{}, where its pods run.
+func applyPolicy(t *testing.T, ctx framework.TestContext, values map[string]string, filename string,
+	ns namespace.Instance) []string {
+	policy := tmpl.EvaluateAllOrFail(t, values, file.AsStringOrFail(t, filename))
+	ctx.ApplyConfigOrFail(t, ns.Name(), policy...)
+	return policy
+}
+
+// matrixTestCases returns the test cases of the matrix, once the JWKS is pre-warmed on each target with a case
+// expecting an invalid token to be rejected and one expecting the token of issuer 1 to pass the JWT filter,
+// so that the first cases do not race the fetch of the JWKS once the policies are applied. A failed
+// pre-warming is only logged, the cases retrying anyway. The pre-warmings are recorded in the
+// jwksPrewarmFile artifact of the test.
+func matrixTestCases(t *testing.T, ctx framework.TestContext, m authn.Matrix,
+	instances ...echo.Instance) []authn.TestCase {
+	cases := m.TestCasesOrFail(t, instances...)
+	rejected := map[echo.Instance]authn.TestCase{}
+	accepted := map[echo.Instance]bool{}
+	for _, c := range cases {
+		target := c.Request.Options.Target
+		switch {
+		case c.Request.Options.Token == jwt.TokenInvalid && c.ExpectResult == authn.Unauthenticated:
+			if _, ok := rejected[target]; !ok {
+				rejected[target] = c
+			}
+		case c.Request.Options.Token == jwt.TokenIssuer1 && c.ExpectResult != authn.Unauthenticated &&
+			c.ExpectResult != authn.Refused:
+			accepted[target] = true
+		}
+	}
+	prewarms := []util.JWKSPrewarm{}
+	for _, i := range instances {
+		c, ok := rejected[i]
+		if !ok || !accepted[i] {
+			continue
+		}
+		prewarm, err := util.PrewarmJWKS(c.Request.From, c.Request.Options, "test-issuer-1@istio.io")
+		prewarms = append(prewarms, prewarm)
+		if err != nil {
+			t.Logf("%v", err)
+			continue
+		}
+		t.Logf("metric jwks_prewarm_ms{target=%s}: %d", prewarm.Target, prewarm.DurationMillis)
+	}
+	out, err := json.MarshalIndent(prewarms, "", "  ")
+	if err != nil {
+		t.Fatalf("failed to encode the JWKS pre-warmings: %v", err)
+	}
+	ctx.WriteArtifactOrFail(jwksPrewarmFile, append(out, '\n'))
+	return cases
+}
@@ -0,0 +1,75 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jwtcoverage
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	testutil "istio.io/istio/pilot/test/util"
+	"istio.io/istio/tests/integration/security/util/authn"
+	"istio.io/istio/tests/integration/security/util/jwtmatrix"
+)
+
+// TestCoverageManifest verifies the committed coverage manifest of the JWT tests is up to date, so that
+// the cases added or removed show up in its diff. Run with REFRESH_GOLDEN=true to update it.
+func TestCoverageManifest(t *testing.T) {
+	manifest, err := authn.Manifest(jwtmatrix.Matrices, jwtmatrix.Uncovered)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testutil.CompareContent(manifest, filepath.Join("..", "testdata", jwtmatrix.CoverageFile), t)
+}
+
+// TestCoverageManifestListsAllTests verifies each JWT test is either built from a matrix or listed as
+// uncovered, so that a new test cannot be left out of the coverage manifest.
+func TestCoverageManifestListsAllTests(t *testing.T) {
+	f, err := parser.ParseFile(token.NewFileSet(), filepath.Join("..", "jwt_test.go"), nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]bool{}
+	for _, d := range f.Decls {
+		if fn, ok := d.(*ast.FuncDecl); ok && fn.Recv == nil && strings.HasPrefix(fn.Name.Name, "Test") {
+			tests[fn.Name.Name] = true
+		}
+	}
+
+	listed := map[string]bool{}
+	for name := range jwtmatrix.Matrices {
+		listed[name] = true
+	}
+	for _, name := range jwtmatrix.Uncovered {
+		if listed[name] {
+			t.Errorf("%s has a matrix and is listed as uncovered", name)
+		}
+		listed[name] = true
+	}
+
+	for name := range tests {
+		if !listed[name] {
+			t.Errorf("%s has no matrix and is not listed as uncovered", name)
+		}
+	}
+	for name := range listed {
+		if !tests[name] {
+			t.Errorf("%s is not a test of jwt_test.go", name)
+		}
+	}
+}
@@ -15,6 +15,7 @@
 package security
 
 import (
+	"flag"
 	"testing"
 
 	"istio.io/istio/pkg/test/framework"
@@ -22,6 +23,10 @@ import (
 	"istio.io/istio/pkg/test/framework/components/pilot"
 	"istio.io/istio/pkg/test/framework/resource"
 	"istio.io/istio/pkg/test/framework/resource/environment"
+	"istio.io/istio/tests/integration/security/util/authn"
+	"istio.io/istio/tests/integration/security/util/isolation"
+	"istio.io/istio/tests/integration/security/util/jwtmatrix"
+	"istio.io/istio/tests/integration/security/util/mtlsmode"
 )
 
 var (
@@ -31,8 +36,10 @@ var (
 )
 
 func TestMain(m *testing.M) {
+	// The suite is named after the mTLS mode it runs under, which is given by a flag.
+	flag.Parse()
 	framework.
-		NewSuite("security", m).
+		NewSuite(mtlsmode.SuiteName("security"), m).
 		RequireSingleCluster().
 		SetupOnEnv(environment.Kube, istio.Setup(&ist, setupConfig)).
 		Setup(func(ctx resource.Context) (err error) {
@@ -41,6 +48,13 @@ func TestMain(m *testing.M) {
 			}
 			return nil
 		}).
+		// With -istio.test.security.mtls=strict, all the tests run under a mesh-wide STRICT mTLS.
+		SetupOnEnv(environment.Kube, mtlsmode.Setup(&rootNamespace)).
+		// Some tests apply policies to the root namespace, fail them if they are not cleaned up so that
+		// the outcome of the other tests does not depend on the order they are run in.
+		AroundEachTest(isolation.Verifier(&rootNamespace)).
+		// The coverage manifest of the JWT tests is an artifact of each run.
+		Setup(authn.WriteManifest(jwtmatrix.CoverageFile, jwtmatrix.Matrices, jwtmatrix.Uncovered)).
 		Run()
 }
 
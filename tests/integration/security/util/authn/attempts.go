@@ -0,0 +1,66 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"istio.io/istio/pkg/test/echo/client"
+	"istio.io/istio/pkg/test/framework/components/echo"
+)
+
+// AttemptCountHeader is the header the sidecar of the caller sets on each attempt of a request, with the
+// number of the attempt, starting at 1, as the outbound routes include the attempt count.
+const AttemptCountHeader = "X-Envoy-Attempt-Count"
+
+// AttemptCount returns the attempt count of the request received by the application, echoed in the
+// response, or false if the response does not come from the application.
+func AttemptCount(r *client.ParsedResponse) (int, bool) {
+	value, ok := r.RawResponse[AttemptCountHeader]
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// UpstreamRetries returns the number of retries made by the sidecars of from to the target, whether the
+// attempts reached the application or not, e.g. rejected by the sidecar of the target. The retries are
+// counted by the outbound clusters of the target.
+func UpstreamRetries(from, target echo.Instance) (int, error) {
+	suffix := fmt.Sprintf("||%s.upstream_rq_retry", target.Config().FQDN())
+	retries := 0
+	workloads, err := from.Workloads()
+	if err != nil {
+		return 0, err
+	}
+	for _, w := range workloads {
+		stats, err := w.Sidecar().Stats()
+		if err != nil {
+			return 0, err
+		}
+		for name, value := range stats {
+			if strings.HasPrefix(name, "cluster.outbound|") && strings.HasSuffix(name, suffix) {
+				retries += value
+			}
+		}
+	}
+	return retries, nil
+}
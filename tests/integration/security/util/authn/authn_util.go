@@ -16,58 +16,533 @@ package authn
 
 import (
 	"fmt"
+	"math/rand"
 	"net/http"
+	"regexp"
+	"sort"
 	"strings"
+	"time"
 
+	"istio.io/istio/pkg/test/echo/client"
+	"istio.io/istio/pkg/test/echo/common/response"
+	"istio.io/istio/pkg/test/envoy/accesslog"
 	"istio.io/istio/pkg/test/framework/components/ingress"
+	"istio.io/istio/pkg/test/util/retry"
+	"istio.io/istio/tests/common/jwt"
 	"istio.io/istio/tests/integration/security/util/connection"
 )
 
+// ExpectedResult is the expected outcome of a request, named after the filter that decides it.
+type ExpectedResult int
+
+const (
+	// Unspecified means the outcome is given by TestCase.ExpectResponseCode.
+	Unspecified ExpectedResult = iota
+	// Allowed means the request reaches the application (200).
+	Allowed
+	// Unauthenticated means the request is rejected by the JWT filter (401).
+	Unauthenticated
+	// Denied means the request is rejected by the authorization policy (403).
+	Denied
+	// Blackholed means the request is dropped by the sidecar of the caller, as the destination is not in
+	// the registry of the caller and outbound traffic is REGISTRY_ONLY (502).
+	Blackholed
+	// Refused means the connection is reset by the sidecar of the target without any response, e.g. a
+	// client without sidecar calling a workload requiring mTLS.
+	Refused
+	// HeadersTooLarge means the request is rejected by the HTTP codec of the sidecar of the target, as
+	// its headers exceed the limit of the proxy (431), e.g. with an oversized token.
+	HeadersTooLarge
+	// TimedOut means the request reached the application, which did not answer before the timeout of the
+	// route, e.g. set by a VirtualService, so the sidecar of the caller answered 504.
+	TimedOut
+)
+
+// ResponseCode returns the response code of the expected result.
+func (r ExpectedResult) ResponseCode() string {
+	switch r {
+	case Allowed:
+		return response.StatusCodeOK
+	case Unauthenticated:
+		return response.StatusUnauthorized
+	case Denied:
+		return response.StatusCodeForbidden
+	case Blackholed:
+		return response.StatusCodeBadGateway
+	case HeadersTooLarge:
+		return response.StatusCodeHeaderFieldsTooLarge
+	case TimedOut:
+		return response.StatusCodeGatewayTimeout
+	default:
+		return ""
+	}
+}
+
+func (r ExpectedResult) String() string {
+	switch r {
+	case Allowed:
+		return "allowed"
+	case Unauthenticated:
+		return "unauthenticated"
+	case Denied:
+		return "denied"
+	case Blackholed:
+		return "blackholed"
+	case Refused:
+		return "refused"
+	case HeadersTooLarge:
+		return "headers too large"
+	case TimedOut:
+		return "timed out"
+	default:
+		return "unspecified"
+	}
+}
+
+// RejectedBy returns the filter of the sidecar of the target rejecting the requests with the expected
+// result, as told by accesslog.Entry.RejectedBy, or "" if the result is not a rejection by a filter.
+func (r ExpectedResult) RejectedBy() string {
+	switch r {
+	case Unauthenticated:
+		return accesslog.JWTFilter
+	case Denied:
+		return accesslog.RBACFilter
+	default:
+		return ""
+	}
+}
+
+// ParseExpectedResult returns the expected result with the given name, as returned by String.
+func ParseExpectedResult(name string) (ExpectedResult, error) {
+	for r := Allowed; r <= TimedOut; r++ {
+		if r.String() == name {
+			return r, nil
+		}
+	}
+	return Unspecified, fmt.Errorf("unknown expected result %q", name)
+}
+
 type TestCase struct {
 	Name               string
 	Request            connection.Checker
 	ExpectResponseCode string
+	// ExpectResult, if specified, takes precedence over ExpectResponseCode.
+	ExpectResult ExpectedResult
 	// Use empty value to express the header with such key must not exist.
 	ExpectHeaders map[string]string
+	// ExpectBody, if set, must be contained in the body of the responses, e.g. the reason of a rejection
+	// by the JWT filter, such as "Jwt is expired".
+	ExpectBody string
+	// ExpectDenialBody, if set, is the exact body of the responses, e.g. the custom body of a denial by RBAC
+	// configured with an EnvoyFilter, or "RBAC: access denied" by default.
+	ExpectDenialBody string
+	// ExpectResponseHeaders are the headers the caller must receive in the response. Use empty value to
+	// express the header with such key must not exist. The echo server adds the headers given in the
+	// request as ?resp-header=name:value.
+	ExpectResponseHeaders map[string]string
+	// ExpectRequestTrailers are the trailers the target must receive, sent with CallOptions.Trailers, and
+	// ExpectResponseTrailers the trailers the caller must receive in the response, which the echo server
+	// reflects from the request. Use empty value to express the trailer with such key must not exist.
+	ExpectRequestTrailers  map[string]string
+	ExpectResponseTrailers map[string]string
+	// ExpectProto, if set, is the protocol of the requests received by the target, e.g. HTTP/2.0.
+	ExpectProto string
+	// ExpectAttempts, if not 0, is the number of attempts made by the sidecar of the caller for the
+	// responses of the application, retries included, as given by AttemptCount. The responses not coming
+	// from the application, e.g. rejected by the sidecar of the target, do not tell the attempts: see
+	// UpstreamRetries.
+	ExpectAttempts int
+	// ExpectResponseFlags are the response flags, e.g. RBAC or UAEX, the sidecar of the target must log
+	// for the request. The access logs must be enabled, e.g. with istio.EnableAccessLogs.
+	ExpectResponseFlags []string
+	// ExpectRejectedBy, if set, is the filter, e.g. accesslog.JWTFilter, the sidecar of the target must log
+	// as rejecting the request, so that a rejection by the wrong filter with the right response code, e.g.
+	// a 403 of the JWT filter, is caught. See ExpectedResult.RejectedBy. The access logs must be enabled, as
+	// for ExpectResponseFlags.
+	ExpectRejectedBy string
+	// CheckAccessLogEntry, if set, checks the access log entries of the request logged by the sidecars of
+	// the target, e.g. their dynamic metadata. The access logs must be enabled, as for ExpectResponseFlags.
+	CheckAccessLogEntry func(entry accesslog.Entry) error
+	// ExpectProxyBypass, if set, the requests must reach the application without going through any proxy,
+	// e.g. the loopback calls of a workload to itself. Must not be combined with ExpectResponseFlags.
+	ExpectProxyBypass bool
 }
 
+const (
+	// The access logs are flushed by the proxies periodically, so they are polled for a while.
+	accessLogDelay   = time.Second
+	accessLogTimeout = 30 * time.Second
+)
+
 func (c *TestCase) String() string {
 	return fmt.Sprintf("%s to %s%s expected code %s, headers %v",
 		c.Request.From.Config().Service,
 		c.Request.Options.Target.Config().Service,
 		c.Request.Options.Path,
-		c.ExpectResponseCode,
+		c.expectedResponseCode(),
 		c.ExpectHeaders)
 }
 
-// CheckAuthn checks a request based on ExpectResponseCode.
+func (c *TestCase) expectedResponseCode() string {
+	if c.ExpectResult != Unspecified {
+		return c.ExpectResult.ResponseCode()
+	}
+	return c.ExpectResponseCode
+}
+
+// ResponseCodeError is the error of CheckAuthn when a response has an unexpected code. It implements
+// retry.StatusCoder, so that a check can be retried on some codes only, with retry.RetryableCodes, e.g.
+// retrying a transient 503 while failing on a 403 immediately.
+type ResponseCodeError struct {
+	// Code is the unexpected response code, of the first response if there are several.
+	Code string
+	err  error
+}
+
+var _ retry.StatusCoder = &ResponseCodeError{}
+
+func (e *ResponseCodeError) Error() string {
+	return e.err.Error()
+}
+
+// StatusCode implements retry.StatusCoder.
+func (e *ResponseCodeError) StatusCode() string {
+	return e.Code
+}
+
+// CheckAuthn checks a request based on ExpectResponseCode. If the request is sent several times, e.g.
+// with the Count or Concurrency call options, all the responses must match.
 func (c *TestCase) CheckAuthn() error {
-	results, err := c.Request.From.Call(c.Request.Options)
+	_, err := c.checkAuthn()
+	return err
+}
+
+func (c *TestCase) checkAuthn() (client.ParsedResponses, error) {
+	opts := c.Request.Options
+	var requestID string
+	if len(c.ExpectResponseFlags) > 0 || c.ExpectRejectedBy != "" || c.CheckAccessLogEntry != nil {
+		// Tag the request so that its access log entries can be told apart from the others.
+		requestID = fmt.Sprintf("authn-%d", rand.Int63())
+		opts.Headers = http.Header{}
+		for k, v := range c.Request.Options.Headers {
+			opts.Headers[k] = v
+		}
+		opts.Headers.Set("X-Request-Id", requestID)
+	}
+	results, err := c.Request.From.Call(opts)
+	if c.ExpectResult == Refused {
+		if err == nil && len(results) > 0 {
+			return nil, fmt.Errorf("%s: expect the connection to be refused, got response code %s", c, results[0].Code)
+		}
+		return nil, nil
+	}
 	if len(results) == 0 {
-		return fmt.Errorf("%s: no response", c)
+		return nil, fmt.Errorf("%s: no response", c)
+	}
+	if len(results) == 1 && results[0].Code != c.expectedResponseCode() {
+		return nil, &ResponseCodeError{
+			Code: results[0].Code,
+			err:  fmt.Errorf("%s: got response code %s, err %v", c, results[0].Code, err),
+		}
 	}
-	if results[0].Code != c.ExpectResponseCode {
-		return fmt.Errorf("%s: got response code %s, err %v", c, results[0].Code, err)
+	// With several requests, e.g. sent concurrently, all the responses must match.
+	if err := results.CheckCode(c.expectedResponseCode()); err != nil {
+		e := &ResponseCodeError{err: fmt.Errorf("%s: %v", c, err)}
+		for _, r := range results {
+			if r.Code != c.expectedResponseCode() {
+				e.Code = r.Code
+				break
+			}
+		}
+		return nil, e
 	}
 	// Checking if echo backend see header with the given value by finding them in response body
 	// (given the current behavior of echo convert all headers into key=value in the response body)
-	for k, v := range c.ExpectHeaders {
-		matcher := fmt.Sprintf("%s=%s", k, v)
+	for _, result := range results {
+		if err := checkRequestHeaders(result.Body, c.ExpectHeaders); err != nil {
+			return nil, fmt.Errorf("%s: %v", c, err)
+		}
+		if c.ExpectProxyBypass && result.ThroughProxy() {
+			return nil, fmt.Errorf("%s: expect no proxy on the path, got request ID %s", c, result.ID)
+		}
+		if c.ExpectBody != "" && !strings.Contains(result.Body, c.ExpectBody) {
+			return nil, fmt.Errorf("%s: expect %q in body, got response\n%s", c, c.ExpectBody, result.Body)
+		}
+		if c.ExpectDenialBody != "" && result.ResponseBody != c.ExpectDenialBody {
+			return nil, fmt.Errorf("%s: expect body %q, got %q", c, c.ExpectDenialBody, result.ResponseBody)
+		}
+		if err := checkFields("response header", result.ResponseHeaders, c.ExpectResponseHeaders); err != nil {
+			return nil, fmt.Errorf("%s: %v", c, err)
+		}
+		if err := checkFields("request trailer", result.RequestTrailers, c.ExpectRequestTrailers); err != nil {
+			return nil, fmt.Errorf("%s: %v", c, err)
+		}
+		if err := checkFields("response trailer", result.ResponseTrailers, c.ExpectResponseTrailers); err != nil {
+			return nil, fmt.Errorf("%s: %v", c, err)
+		}
+		if got := result.RawResponse["Proto"]; c.ExpectProto != "" && got != c.ExpectProto {
+			return nil, fmt.Errorf("%s: expect the target to receive %s, got %q", c, c.ExpectProto, got)
+		}
+		if c.ExpectAttempts != 0 {
+			got, ok := AttemptCount(result)
+			if !ok {
+				return nil, fmt.Errorf("%s: expect %d attempts, got no %s in response\n%s",
+					c, c.ExpectAttempts, AttemptCountHeader, result.Body)
+			}
+			if got != c.ExpectAttempts {
+				return nil, fmt.Errorf("%s: expect %d attempts, got %d", c, c.ExpectAttempts, got)
+			}
+		}
+	}
+	if err := c.Request.CheckServedBy(results); err != nil {
+		return nil, fmt.Errorf("%s: %v", c, err)
+	}
+	if err := c.Request.CheckEgressGateway(results); err != nil {
+		return nil, fmt.Errorf("%s: %v", c, err)
+	}
+	if requestID != "" {
+		if err := c.checkAccessLog(requestID); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// checkFields checks the given headers or trailers have the expected values, an empty value meaning the
+// field must not exist.
+func checkFields(kind string, got http.Header, want map[string]string) error {
+	for k, v := range want {
+		value := got.Get(k)
 		if len(v) == 0 {
-			if strings.Contains(results[0].Body, matcher) {
-				return fmt.Errorf("%s: expect header %s does not exist, got response\n%s", c, k, results[0].Body)
+			if value != "" {
+				return fmt.Errorf("expect %s %s does not exist, got %q", kind, k, value)
 			}
-		} else {
-			if !strings.Contains(results[0].Body, matcher) {
-				return fmt.Errorf("%s: expect header %s=%s in body, got response\n%s", c, k, v, results[0].Body)
+		} else if value != v {
+			return fmt.Errorf("expect %s %s=%s, got %q", kind, k, v, value)
+		}
+	}
+	return nil
+}
+
+// checkAccessLog checks all the access log entries of the request logged by the sidecars of the target
+// have the expected response flags and rejecting filter, and pass CheckAccessLogEntry.
+func (c *TestCase) checkAccessLog(requestID string) error {
+	workloads, err := c.Request.Options.Target.Workloads()
+	if err != nil {
+		return err
+	}
+	_, err = retry.Do(func() (interface{}, bool, error) {
+		var entries accesslog.Entries
+		for _, w := range workloads {
+			if w.Sidecar() == nil {
+				continue
+			}
+			logs, err := w.Sidecar().Logs()
+			if err != nil {
+				return nil, false, err
+			}
+			entries = append(entries, accesslog.Parse(w.Sidecar().NodeID(), logs, time.Time{}).WithRequestID(requestID)...)
+		}
+		if len(entries) == 0 {
+			return nil, false, fmt.Errorf("%s: no access log entry for request %s", c, requestID)
+		}
+		for _, entry := range entries {
+			for _, flag := range c.ExpectResponseFlags {
+				if !entry.HasResponseFlag(flag) {
+					return nil, false, fmt.Errorf("%s: expect response flag %s logged by %s, got %v (%s)",
+						c, flag, entry.Pod, entry.ResponseFlags, entry.ResponseCodeDetails)
+				}
+			}
+			if got := entry.RejectedBy(); c.ExpectRejectedBy != "" && got != c.ExpectRejectedBy {
+				return nil, false, fmt.Errorf("%s: expect the request rejected by %s logged by %s, got %q (%s, %v)",
+					c, c.ExpectRejectedBy, entry.Pod, got, entry.ResponseCodeDetails, entry.ResponseFlags)
+			}
+			if c.CheckAccessLogEntry != nil {
+				if err := c.CheckAccessLogEntry(entry); err != nil {
+					return nil, false, fmt.Errorf("%s: access log entry of %s: %v", c, entry.Pod, err)
+				}
+			}
+		}
+		return nil, true, nil
+	}, retry.Delay(accessLogDelay), retry.Timeout(accessLogTimeout))
+	return err
+}
+
+// TokenRefresh is a client swapping its token mid-connection: the request is sent once per token, in
+// order and on the same connection, and each response must match the expected result of its token.
+type TokenRefresh struct {
+	Request       connection.Checker
+	Tokens        []string
+	ExpectResults []ExpectedResult
+}
+
+// Check sends the requests of the refresh and checks their response codes, in order.
+func (r *TokenRefresh) Check() error {
+	if len(r.Tokens) != len(r.ExpectResults) {
+		return fmt.Errorf("got %d tokens but %d expected results", len(r.Tokens), len(r.ExpectResults))
+	}
+	opts := r.Request.Options
+	opts.HeaderSets = make([]http.Header, 0, len(r.Tokens))
+	for _, token := range r.Tokens {
+		headers := http.Header{}
+		if token != "" {
+			headers.Set("Authorization", "Bearer "+token)
+		}
+		opts.HeaderSets = append(opts.HeaderSets, headers)
+	}
+	results, err := r.Request.From.Call(opts)
+	if len(results) != len(r.Tokens) {
+		return fmt.Errorf("got %d responses for %d tokens, err %v", len(results), len(r.Tokens), err)
+	}
+	for i, result := range results {
+		if expected := r.ExpectResults[i].ResponseCode(); result.Code != expected {
+			return fmt.Errorf("request %d (%s): got response code %s, want %s",
+				i, r.ExpectResults[i], result.Code, expected)
+		}
+	}
+	return nil
+}
+
+// CheckNotReached checks a request based on ExpectResponseCode, and verifies the request was rejected
+// by the proxy without reaching the target application: the response must not carry the fields written
+// by the echo application, and the number of requests received by the target workloads must not change.
+func (c *TestCase) CheckNotReached() error {
+	workloads, err := c.Request.Options.Target.Workloads()
+	if err != nil {
+		return err
+	}
+	port, err := c.targetInstancePort()
+	if err != nil {
+		return err
+	}
+	requestCount := func() (int, error) {
+		count := 0
+		for _, w := range workloads {
+			n, err := w.RequestCount(port)
+			if err != nil {
+				return 0, err
 			}
+			count += n
 		}
+		return count, nil
+	}
+
+	before, err := requestCount()
+	if err != nil {
+		return err
+	}
+	results, err := c.checkAuthn()
+	if err != nil {
+		return err
+	}
+	if len(results) > 0 && results[0].Hostname != "" {
+		return fmt.Errorf("%s: expect request not reaching the application, got response from %s\n%s",
+			c, results[0].Hostname, results[0].Body)
+	}
+	after, err := requestCount()
+	if err != nil {
+		return err
+	}
+	if after != before {
+		return fmt.Errorf("%s: expect request not reaching the application, got %d requests received on port %d",
+			c, after-before, port)
 	}
 	return nil
 }
 
+func (c *TestCase) targetInstancePort() (int, error) {
+	if c.Request.Options.Port != nil {
+		return c.Request.Options.Port.InstancePort, nil
+	}
+	for _, p := range c.Request.Options.Target.Config().Ports {
+		if p.Name == c.Request.Options.PortName {
+			return p.InstancePort, nil
+		}
+	}
+	return 0, fmt.Errorf("%s: no port named %s", c, c.Request.Options.PortName)
+}
+
+// Outcome returns the outcome of the request of the test case: the response code of the first response,
+// or the error of the call.
+func (c *TestCase) Outcome() string {
+	results, err := c.Request.From.Call(c.Request.Options)
+	if err != nil {
+		return fmt.Sprintf("error: %v", err)
+	}
+	if len(results) == 0 {
+		return "no response"
+	}
+	return results[0].Code
+}
+
+// Outcomes returns the outcome of each test case, by name.
+func Outcomes(cases []TestCase) map[string]string {
+	outcomes := make(map[string]string, len(cases))
+	for _, c := range cases {
+		outcomes[c.Name] = c.Outcome()
+	}
+	return outcomes
+}
+
+// DiffOutcomes compares the outcomes of the same test cases run twice, e.g. before and after applying a
+// policy that must not change them. It returns an error listing the test cases with a different outcome.
+func DiffOutcomes(baseline, outcomes map[string]string) error {
+	var diffs []string
+	for name, want := range baseline {
+		if got, ok := outcomes[name]; !ok {
+			diffs = append(diffs, fmt.Sprintf("%s: not run, baseline %s", name, want))
+		} else if got != want {
+			diffs = append(diffs, fmt.Sprintf("%s: got %s, baseline %s", name, got, want))
+		}
+	}
+	for name, got := range outcomes {
+		if _, ok := baseline[name]; !ok {
+			diffs = append(diffs, fmt.Sprintf("%s: got %s, not in baseline", name, got))
+		}
+	}
+	if len(diffs) == 0 {
+		return nil
+	}
+	sort.Strings(diffs)
+	return fmt.Errorf("outcomes differ from the baseline:\n%s", strings.Join(diffs, "\n"))
+}
+
+// ClaimHeaders returns the headers the upstream is expected to receive for the token when the claims
+// are copied to headers, given as a map of claim name to header name: the value of the claim for each
+// header, or an empty value if the token does not have the claim. The result can be used as
+// TestCase.ExpectHeaders.
+func ClaimHeaders(token string, claimToHeaders map[string]string) (map[string]string, error) {
+	claims, err := jwt.Claims(token)
+	if err != nil {
+		return nil, err
+	}
+	headers := make(map[string]string, len(claimToHeaders))
+	for claim, header := range claimToHeaders {
+		headers[header] = ""
+		if value, ok := claims[claim]; ok {
+			headers[header] = fmt.Sprintf("%v", value)
+		}
+	}
+	return headers, nil
+}
+
 // CheckIngress checks a request for the ingress gateway.
 func CheckIngress(ingr ingress.Instance, host string, path string, token string, expectResponseCode int) error {
+	return CheckIngressWithBody(ingr, host, path, token, expectResponseCode, "")
+}
+
+// CheckIngressWithBody checks a request for the ingress gateway as CheckIngress, and if expectBody is set,
+// the exact body of the response, e.g. the custom body of a denial.
+func CheckIngressWithBody(ingr ingress.Instance, host string, path string, token string, expectResponseCode int,
+	expectBody string) error {
+	return CheckIngressWithHeaders(ingr, host, path, token, expectResponseCode, expectBody, nil)
+}
+
+// CheckIngressWithHeaders checks a request for the ingress gateway as CheckIngressWithBody, and the headers
+// of the request received by the workload behind the gateway, as TestCase.ExpectHeaders, e.g. the token
+// forwarded by the gateways.
+func CheckIngressWithHeaders(ingr ingress.Instance, host string, path string, token string, expectResponseCode int,
+	expectBody string, expectHeaders map[string]string) error {
 	endpointAddress := ingr.HTTPAddress()
 	opts := ingress.CallOptions{
 		Host:     host,
@@ -87,5 +562,32 @@ func CheckIngress(ingr ingress.Instance, host string, path string, token string,
 	if response.Code != expectResponseCode {
 		return fmt.Errorf("got response code %d, err %s", response.Code, err)
 	}
+	if expectBody != "" && response.Body != expectBody {
+		return fmt.Errorf("got response body %q, want %q", response.Body, expectBody)
+	}
+	return checkRequestHeaders(response.Body, expectHeaders)
+}
+
+// checkRequestHeaders checks the headers of the request the echo server writes in the body of its response,
+// as key=value. An empty value expresses the header must not exist. The keys are matched as whole header
+// names, e.g. Authorization does not match Proxy-Authorization or X-Authorization.
+func checkRequestHeaders(body string, expectHeaders map[string]string) error {
+	for k, v := range expectHeaders {
+		if len(v) == 0 {
+			if headerField(k, "").MatchString(body) {
+				return fmt.Errorf("expect header %s does not exist, got response\n%s", k, body)
+			}
+		} else {
+			if !headerField(k, v).MatchString(body) {
+				return fmt.Errorf("expect header %s=%s in body, got response\n%s", k, v, body)
+			}
+		}
+	}
 	return nil
 }
+
+// headerField matches the field of the header with the given name and value prefix in the body of a
+// response, either at the start of a line or after the [id body] prefix of the forwarder.
+func headerField(name, value string) *regexp.Regexp {
+	return regexp.MustCompile(`(?m)(^|[\s\]])` + regexp.QuoteMeta(name+"="+value))
+}
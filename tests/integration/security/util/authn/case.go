@@ -0,0 +1,95 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"time"
+
+	"istio.io/istio/pkg/test/echo/common/scheme"
+	"istio.io/istio/pkg/test/framework/components/echo"
+	"istio.io/istio/pkg/test/util/retry"
+	"istio.io/istio/tests/integration/security/util/connection"
+)
+
+const (
+	// The policies take a few seconds to be pushed to the proxies, the JWKS to be fetched.
+	checkDelay   = 250 * time.Millisecond
+	checkTimeout = 30 * time.Second
+)
+
+// CaseOption sets a field of a TestCase built by NewCase.
+type CaseOption func(c *TestCase)
+
+// WithName sets the name of the test case.
+func WithName(name string) CaseOption {
+	return func(c *TestCase) {
+		c.Name = name
+	}
+}
+
+// WithToken sets the bearer token of the request, none if empty.
+func WithToken(token string) CaseOption {
+	return func(c *TestCase) {
+		c.Request.Options.Token = token
+	}
+}
+
+// WithExpect sets the expected result of the test case.
+func WithExpect(expect ExpectedResult) CaseOption {
+	return func(c *TestCase) {
+		c.ExpectResult = expect
+	}
+}
+
+// WithRequest sets the other fields of the request, e.g. its path or call options.
+func WithRequest(fn func(r *connection.Checker)) CaseOption {
+	return func(c *TestCase) {
+		fn(&c.Request)
+	}
+}
+
+// NewCase returns the test case of a call from an instance to the port "http" of another, with scheme.HTTP,
+// the options setting the rest.
+func NewCase(from, to echo.Instance, opts ...CaseOption) TestCase {
+	c := TestCase{
+		Request: connection.Checker{
+			From: from,
+			Options: echo.CallOptions{
+				Target:   to,
+				PortName: "http",
+				Scheme:   scheme.HTTP,
+			},
+		},
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// NewCaseFunc returns a function building the test cases of a test with NewCase, from their name, token
+// and expected result, the given options being applied to all of them.
+func NewCaseFunc(from, to echo.Instance, opts ...CaseOption) func(name, token string, expect ExpectedResult) TestCase {
+	return func(name, token string, expect ExpectedResult) TestCase {
+		return NewCase(from, to, append([]CaseOption{WithName(name), WithToken(token), WithExpect(expect)},
+			opts...)...)
+	}
+}
+
+// RetryOptions returns the options of the retries of the checks of the JWT tests, until the policies are
+// in force, with the given options overriding the defaults.
+func RetryOptions(options ...retry.Option) []retry.Option {
+	return append([]retry.Option{retry.Delay(checkDelay), retry.Timeout(checkTimeout)}, options...)
+}
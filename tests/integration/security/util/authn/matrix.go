@@ -0,0 +1,204 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"sort"
+
+	"istio.io/istio/pkg/test"
+	"istio.io/istio/pkg/test/echo/common/scheme"
+	"istio.io/istio/pkg/test/framework/components/echo"
+	"istio.io/istio/pkg/test/framework/resource"
+	"istio.io/istio/tests/common/jwt"
+	"istio.io/istio/tests/integration/security/util/connection"
+)
+
+// Case is a test case of a Matrix. Unlike TestCase, it refers to the echo instances by service name, so
+// that the cases can be listed without deploying anything, e.g. in the coverage manifest.
+type Case struct {
+	Name string
+	// From and To are the services of the caller and of the target.
+	From string
+	To   string
+	// PortName is the port of the target called, "http" if empty.
+	PortName string
+	// Scheme of the call, scheme.HTTP if empty.
+	Scheme scheme.Instance
+	Path   string
+	// Headers sent with the request.
+	Headers map[string]string
+	// Token, if set, is sent as a bearer token.
+	Token string
+	// MintToken, if set, takes precedence over Token: the token is minted when the test case is built.
+	MintToken func() (string, error)
+
+	Expect        ExpectedResult
+	ExpectHeaders map[string]string
+	ExpectBody    string
+	// ExpectVersion, if set, is the version of the workloads that must serve the request, e.g. the subset
+	// of a DestinationRule it is routed to.
+	ExpectVersion string
+}
+
+// Matrix is the test cases of a test, with the policies the test applies.
+type Matrix struct {
+	// Policies are the paths of the policy templates applied by the test.
+	Policies []string
+	Cases    []Case
+}
+
+// TestCases returns the test cases of the matrix, sent between the given instances.
+func (m Matrix) TestCases(instances ...echo.Instance) ([]TestCase, error) {
+	byService := make(map[string]echo.Instance, len(instances))
+	for _, i := range instances {
+		byService[i.Config().Service] = i
+	}
+	out := make([]TestCase, 0, len(m.Cases))
+	for _, c := range m.Cases {
+		from, ok := byService[c.From]
+		if !ok {
+			return nil, fmt.Errorf("case %s: no instance for the source %s", c.Name, c.From)
+		}
+		to, ok := byService[c.To]
+		if !ok {
+			return nil, fmt.Errorf("case %s: no instance for the destination %s", c.Name, c.To)
+		}
+		token := c.Token
+		if c.MintToken != nil {
+			var err error
+			if token, err = c.MintToken(); err != nil {
+				return nil, fmt.Errorf("case %s: failed to mint the token: %v", c.Name, err)
+			}
+		}
+		headers := http.Header{}
+		for k, v := range c.Headers {
+			headers.Set(k, v)
+		}
+		tc := NewCase(from, to, WithName(c.Name), WithToken(token), WithExpect(c.Expect),
+			WithRequest(func(r *connection.Checker) {
+				if c.PortName != "" {
+					r.Options.PortName = c.PortName
+				}
+				if c.Scheme != "" {
+					r.Options.Scheme = c.Scheme
+				}
+				r.Options.Path = c.Path
+				r.Options.Headers = headers
+				r.ExpectServedBy = c.ExpectVersion
+			}))
+		tc.ExpectHeaders = c.ExpectHeaders
+		tc.ExpectBody = c.ExpectBody
+		out = append(out, tc)
+	}
+	return out, nil
+}
+
+// TestCasesOrFail calls TestCases and fails the test on error.
+func (m Matrix) TestCasesOrFail(t test.Failer, instances ...echo.Instance) []TestCase {
+	t.Helper()
+	out, err := m.TestCases(instances...)
+	if err != nil {
+		t.Fatalf("Matrix.TestCasesOrFail: %v", err)
+	}
+	return out
+}
+
+// Coverage is an entry of the coverage manifest, describing a test case of a matrix.
+type Coverage struct {
+	Test     string   `json:"test"`
+	Case     string   `json:"case"`
+	Policies []string `json:"policies"`
+	Source   string   `json:"source"`
+	// Destination is the service called, with the path if any.
+	Destination string `json:"destination"`
+	// Port and Scheme of the call, if not the default "http" port and HTTP.
+	Port   string `json:"port,omitempty"`
+	Scheme string `json:"scheme,omitempty"`
+	// Headers are the names of the headers sent, if any.
+	Headers []string `json:"headers,omitempty"`
+	// Token is the name of the token, as given by jwt.Name, or "minted".
+	Token  string `json:"token"`
+	Expect string `json:"expect"`
+}
+
+// CoverageManifest is the coverage manifest of the matrices of a set of tests.
+type CoverageManifest struct {
+	Cases []Coverage `json:"cases"`
+	// Uncovered are the tests whose cases are not built from a matrix, so not listed in Cases.
+	Uncovered []string `json:"uncovered"`
+}
+
+// Manifest returns the coverage manifest of the matrices, keyed by the name of their test, and of the
+// uncovered tests, as indented JSON: an entry per case, ordered by test and in the order of the cases of
+// each test, then the sorted uncovered tests.
+func Manifest(matrices map[string]Matrix, uncovered []string) ([]byte, error) {
+	tests := make([]string, 0, len(matrices))
+	for name := range matrices {
+		tests = append(tests, name)
+	}
+	sort.Strings(tests)
+
+	entries := []Coverage{}
+	for _, name := range tests {
+		m := matrices[name]
+		for _, c := range m.Cases {
+			token := jwt.Name(c.Token)
+			if c.MintToken != nil {
+				token = "minted"
+			}
+			var headers []string
+			for k := range c.Headers {
+				headers = append(headers, http.CanonicalHeaderKey(k))
+			}
+			sort.Strings(headers)
+			entries = append(entries, Coverage{
+				Test:        name,
+				Case:        c.Name,
+				Policies:    m.Policies,
+				Source:      c.From,
+				Destination: c.To + c.Path,
+				Port:        c.PortName,
+				Scheme:      string(c.Scheme),
+				Headers:     headers,
+				Token:       token,
+				Expect:      c.Expect.String(),
+			})
+		}
+	}
+	sortedUncovered := append([]string{}, uncovered...)
+	sort.Strings(sortedUncovered)
+	out, err := json.MarshalIndent(CoverageManifest{Cases: entries, Uncovered: sortedUncovered}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+// WriteManifest returns a setup function writing the coverage manifest of the matrices and of the uncovered
+// tests to the given file of the work dir of the suite, as an artifact of the run.
+func WriteManifest(fileName string, matrices map[string]Matrix, uncovered []string) resource.SetupFn {
+	return func(ctx resource.Context) error {
+		manifest, err := Manifest(matrices, uncovered)
+		if err != nil {
+			return fmt.Errorf("failed to generate the coverage manifest: %v", err)
+		}
+		return ioutil.WriteFile(filepath.Join(ctx.Settings().RunDir(), fileName), manifest, 0644)
+	}
+}
@@ -0,0 +1,336 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ghodss/yaml"
+
+	"istio.io/istio/pkg/test"
+	"istio.io/istio/pkg/test/util/yml"
+)
+
+// PathCase is a path of the workloads selected by AuthorizationPolicies, derived from their path rules.
+type PathCase struct {
+	Path string
+	// RequiresToken tells whether a GET request on the path is denied without a token.
+	RequiresToken bool
+}
+
+// The fields of an AuthorizationPolicy the path cases are derived from.
+type authorizationPolicy struct {
+	Kind string `json:"kind"`
+	Spec struct {
+		Action string `json:"action"`
+		Rules  []struct {
+			From []struct {
+				Source struct {
+					RequestPrincipals []string `json:"requestPrincipals"`
+				} `json:"source"`
+			} `json:"from"`
+			To []struct {
+				Operation operation `json:"operation"`
+			} `json:"to"`
+		} `json:"rules"`
+	} `json:"spec"`
+}
+
+type operation struct {
+	Hosts      []string `json:"hosts"`
+	NotHosts   []string `json:"notHosts"`
+	Ports      []string `json:"ports"`
+	NotPorts   []string `json:"notPorts"`
+	Methods    []string `json:"methods"`
+	NotMethods []string `json:"notMethods"`
+	Paths      []string `json:"paths"`
+	NotPaths   []string `json:"notPaths"`
+}
+
+// pathsOnly tells whether the operation only constrains the path of the GET requests.
+func (o operation) pathsOnly() bool {
+	if len(o.Hosts)+len(o.NotHosts)+len(o.Ports)+len(o.NotPorts)+len(o.NotMethods) > 0 {
+		return false
+	}
+	if len(o.Methods) == 0 {
+		return true
+	}
+	for _, m := range o.Methods {
+		if m == http.MethodGet {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPath tells whether the path matches the paths and notPaths of the operation.
+func (o operation) matchPath(path string) bool {
+	for _, pattern := range o.NotPaths {
+		if matchPath(pattern, path) {
+			return false
+		}
+	}
+	if len(o.Paths) == 0 {
+		return true
+	}
+	for _, pattern := range o.Paths {
+		if matchPath(pattern, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// PathCases returns the path cases of the ALLOW AuthorizationPolicies of the given YAML, the other
+// resources being ignored, so that the cases follow the policies as they change. The paths of the rules
+// without request principals are exempted from the token, unless excluded by the notPaths of the rule.
+// The paths of the rules with request principals, the notPaths of the rules without, and a path next to
+// each exempted one but not matched by it (e.g. /healthz/x for /healthz, /api for /api/*) require a token,
+// unless exempted by another rule. Wildcard paths are replaced by a path they match, e.g. /api/x for
+// /api/*. Only the operations constraining nothing but the paths of GET requests are considered, as the
+// cases are sent to any host and port. The cases are ordered as the paths appear in the policies.
+func PathCases(policies ...string) ([]PathCase, error) {
+	exempted, required, err := parsePathRules(policies)
+	if err != nil {
+		return nil, err
+	}
+	out := newPathCases(exempted)
+	for _, op := range exempted {
+		for _, pattern := range op.Paths {
+			out.add(samplePath(pattern), false)
+		}
+	}
+	for _, op := range exempted {
+		for _, pattern := range op.NotPaths {
+			out.add(samplePath(pattern), true)
+		}
+	}
+	for _, pattern := range required {
+		out.add(samplePath(pattern), true)
+	}
+	for _, op := range exempted {
+		for _, pattern := range op.Paths {
+			out.add(neighbourPath(pattern), true)
+		}
+	}
+	return out.cases, nil
+}
+
+// PathCasesOrFail calls PathCases and fails the test on error.
+func PathCasesOrFail(t test.Failer, policies ...string) []PathCase {
+	t.Helper()
+	out, err := PathCases(policies...)
+	if err != nil {
+		t.Fatalf("PathCasesOrFail: %v", err)
+	}
+	return out
+}
+
+// WildcardPathCases returns the path cases around the paths exempted from the token by the ALLOW
+// AuthorizationPolicies of the given YAML, as PathCases, which commonly expose the bugs of the path
+// matchers: for /api/*, /api/ and /api/x/y are exempted, while /api, /apixyz and /API/x require a token.
+// Whether a path requires a token is given by all the exempted paths, not only the one it is derived from.
+func WildcardPathCases(policies ...string) ([]PathCase, error) {
+	exempted, _, err := parsePathRules(policies)
+	if err != nil {
+		return nil, err
+	}
+	out := newPathCases(exempted)
+	for _, op := range exempted {
+		for _, pattern := range op.Paths {
+			for _, path := range trickyPaths(pattern) {
+				out.add(path, !out.isExempted(path))
+			}
+		}
+	}
+	return out.cases, nil
+}
+
+// WildcardPathCasesOrFail calls WildcardPathCases and fails the test on error.
+func WildcardPathCasesOrFail(t test.Failer, policies ...string) []PathCase {
+	t.Helper()
+	out, err := WildcardPathCases(policies...)
+	if err != nil {
+		t.Fatalf("WildcardPathCasesOrFail: %v", err)
+	}
+	return out
+}
+
+// parsePathRules returns the operations exempting paths from the token, and the paths requiring one, of
+// the ALLOW AuthorizationPolicies of the given YAML.
+func parsePathRules(policies []string) ([]operation, []string, error) {
+	var exempted []operation
+	var required []string
+	for _, policy := range policies {
+		parts, err := yml.Parse(policy)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, part := range parts {
+			if part.Descriptor.Kind != "AuthorizationPolicy" {
+				continue
+			}
+			var p authorizationPolicy
+			if err := yaml.Unmarshal([]byte(part.Contents), &p); err != nil {
+				return nil, nil, fmt.Errorf("failed parsing AuthorizationPolicy %s: %v", part.Descriptor.Metadata.Name, err)
+			}
+			if p.Spec.Action != "" && p.Spec.Action != "ALLOW" {
+				continue
+			}
+			for _, rule := range p.Spec.Rules {
+				withPrincipals := false
+				for _, from := range rule.From {
+					if len(from.Source.RequestPrincipals) > 0 {
+						withPrincipals = true
+					}
+				}
+				for _, to := range rule.To {
+					op := to.Operation
+					if !op.pathsOnly() {
+						continue
+					}
+					if withPrincipals {
+						required = append(required, op.Paths...)
+						continue
+					}
+					exempted = append(exempted, op)
+				}
+			}
+		}
+	}
+	return exempted, required, nil
+}
+
+// pathCases collects the path cases, each path once, given the operations exempting paths from the token.
+type pathCases struct {
+	exempted []operation
+	seen     map[string]bool
+	cases    []PathCase
+}
+
+func newPathCases(exempted []operation) *pathCases {
+	return &pathCases{exempted: exempted, seen: map[string]bool{}}
+}
+
+func (p *pathCases) isExempted(path string) bool {
+	for _, op := range p.exempted {
+		if op.matchPath(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// add adds the case, unless the path is not absolute, already added, or does not require a token as
+// expected.
+func (p *pathCases) add(path string, requiresToken bool) {
+	if !strings.HasPrefix(path, "/") || p.seen[path] || requiresToken == p.isExempted(path) {
+		return
+	}
+	p.seen[path] = true
+	p.cases = append(p.cases, PathCase{Path: path, RequiresToken: requiresToken})
+}
+
+// matchPath tells whether the path matches the pattern of an AuthorizationPolicy: exact, with a prefix
+// (/api/*), with a suffix (*.html) or any path (*).
+func matchPath(pattern, path string) bool {
+	switch {
+	case pattern == "*":
+		return true
+	case strings.HasSuffix(pattern, "*"):
+		return strings.HasPrefix(path, strings.TrimSuffix(pattern, "*"))
+	case strings.HasPrefix(pattern, "*"):
+		return strings.HasSuffix(path, strings.TrimPrefix(pattern, "*"))
+	default:
+		return path == pattern
+	}
+}
+
+// samplePath returns a path matching the pattern.
+func samplePath(pattern string) string {
+	switch {
+	case pattern == "*":
+		return "/"
+	case strings.HasSuffix(pattern, "*"):
+		return strings.TrimSuffix(pattern, "*") + "x"
+	case strings.HasPrefix(pattern, "*"):
+		return "/x" + strings.TrimPrefix(pattern, "*")
+	default:
+		return pattern
+	}
+}
+
+// neighbourPath returns a path close to the pattern but not matching it, or "" if every path matches.
+func neighbourPath(pattern string) string {
+	switch {
+	case pattern == "*":
+		return ""
+	case strings.HasSuffix(pattern, "*"):
+		prefix := strings.TrimSuffix(pattern, "*")
+		if trimmed := strings.TrimSuffix(prefix, "/"); trimmed != prefix && trimmed != "" {
+			return trimmed
+		}
+		return "/not" + prefix
+	case strings.HasPrefix(pattern, "*"):
+		suffix := strings.TrimPrefix(pattern, "*")
+		return "/x" + suffix + "x"
+	default:
+		return strings.TrimSuffix(pattern, "/") + "/x"
+	}
+}
+
+// trickyPaths returns the paths around the pattern which commonly expose the bugs of the path matchers:
+// the pattern with or without its trailing slash, with the wildcard matching nothing, one or several
+// segments, extended without a separator, or with another case.
+func trickyPaths(pattern string) []string {
+	switch {
+	case pattern == "*":
+		return []string{"/", "/x", "/x/y"}
+	case strings.HasSuffix(pattern, "*"):
+		prefix := strings.TrimSuffix(pattern, "*")
+		trimmed := strings.TrimSuffix(prefix, "/")
+		return []string{
+			prefix,
+			prefix + "x",
+			prefix + "x/y",
+			trimmed,
+			trimmed + "xyz",
+			trimmed + "/",
+			strings.ToUpper(prefix) + "x",
+			"/x" + prefix,
+		}
+	case strings.HasPrefix(pattern, "*"):
+		suffix := strings.TrimPrefix(pattern, "*")
+		return []string{
+			"/x" + suffix,
+			"/x/y" + suffix,
+			"/x" + suffix + "/",
+			"/x" + suffix + "x",
+			"/x" + strings.ToUpper(suffix),
+		}
+	default:
+		trimmed := strings.TrimSuffix(pattern, "/")
+		return []string{
+			pattern,
+			trimmed,
+			trimmed + "/",
+			trimmed + "xyz",
+			trimmed + "/x",
+			strings.ToUpper(pattern),
+		}
+	}
+}
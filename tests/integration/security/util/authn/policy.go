@@ -0,0 +1,118 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"fmt"
+
+	"istio.io/istio/pkg/test"
+	"istio.io/istio/pkg/test/util/tmpl"
+)
+
+const (
+	// Issuer1 is the issuer of the tokens of tests/common/jwt, e.g. jwt.TokenIssuer1.
+	Issuer1 = "test-issuer-1@istio.io"
+	// JwksURI1 is the URI of the JWKS validating the tokens of Issuer1.
+	JwksURI1 = "https://raw.githubusercontent.com/istio/istio/master/tests/common/jwt/jwks.json"
+
+	requestAuthenticationYAML = `apiVersion: security.istio.io/v1beta1
+kind: RequestAuthentication
+metadata:
+  name: {{ .Name }}
+  namespace: {{ .Namespace }}
+spec:
+{{- if .App }}
+  selector:
+    matchLabels:
+      app: {{ .App }}
+{{- end }}
+  jwtRules:
+{{- range .Rules }}
+  - issuer: {{ printf "%q" .Issuer }}
+    jwksUri: {{ printf "%q" .JwksURI }}
+{{- if .FromHeaders }}
+    fromHeaders:
+{{- range .FromHeaders }}
+    - name: {{ printf "%q" .Name }}
+{{- if .Prefix }}
+      prefix: {{ printf "%q" .Prefix }}
+{{- end }}
+{{- end }}
+{{- end }}
+{{- if .FromParams }}
+    fromParams:
+{{- range .FromParams }}
+    - {{ printf "%q" . }}
+{{- end }}
+{{- end }}
+{{- if .ForwardOriginalToken }}
+    forwardOriginalToken: true
+{{- end }}
+{{- end }}
+`
+)
+
+// JWTHeader is a header a token is extracted from.
+type JWTHeader struct {
+	Name string
+	// Prefix (optional) the value starts with, removed to get the token, e.g. "Bearer ".
+	Prefix string
+}
+
+// JWTRule is a jwtRule of a RequestAuthentication. The token is extracted from the given headers and
+// query parameters, or from the Authorization header with the Bearer prefix if none is given.
+type JWTRule struct {
+	Issuer               string
+	JwksURI              string
+	FromHeaders          []JWTHeader
+	FromParams           []string
+	ForwardOriginalToken bool
+}
+
+// RequestAuthentication builds a RequestAuthentication policy.
+type RequestAuthentication struct {
+	Name      string
+	Namespace string
+	// App (optional) selects the workloads with this app label. The policy applies to all the workloads
+	// of the namespace if empty.
+	App   string
+	Rules []JWTRule
+}
+
+// YAML returns the policy as YAML, to be applied with ApplyConfig.
+func (r RequestAuthentication) YAML() (string, error) {
+	if r.Name == "" || r.Namespace == "" {
+		return "", fmt.Errorf("RequestAuthentication requires a name and a namespace, got %q and %q", r.Name, r.Namespace)
+	}
+	if len(r.Rules) == 0 {
+		return "", fmt.Errorf("RequestAuthentication %s requires at least one rule", r.Name)
+	}
+	for _, rule := range r.Rules {
+		if rule.Issuer == "" || rule.JwksURI == "" {
+			return "", fmt.Errorf("RequestAuthentication %s: rules require an issuer and a JWKS URI", r.Name)
+		}
+	}
+	return tmpl.Evaluate(requestAuthenticationYAML, r)
+}
+
+// YAMLOrFail calls YAML and fails the test on error.
+func (r RequestAuthentication) YAMLOrFail(t test.Failer) string {
+	t.Helper()
+	out, err := r.YAML()
+	if err != nil {
+		t.Fatalf("RequestAuthentication.YAMLOrFail: %v", err)
+	}
+	return out
+}
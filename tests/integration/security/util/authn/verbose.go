@@ -0,0 +1,88 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package authn
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"istio.io/istio/pkg/test/echo/client"
+	"istio.io/istio/pkg/test/framework/components/echo"
+)
+
+// A JWT: the base64url encoded header, payload and signature, the header always starting with {".
+var tokenRegex = regexp.MustCompile(`eyJ[\w-]*\.[\w-]*\.[\w-]*`)
+
+// CheckAuthnVerbose checks the request as CheckAuthn, and writes to w the request sent and the responses
+// received, the tokens redacted, e.g. to debug a single case against a live cluster.
+func (c *TestCase) CheckAuthnVerbose(w io.Writer) error {
+	recorder := &recordingInstance{Instance: c.Request.From}
+	verbose := *c
+	verbose.Request.From = recorder
+	err := verbose.CheckAuthn()
+
+	opts := c.Request.Options
+	_, _ = fmt.Fprintf(w, "Request: %s -> %s port %s scheme %s path %q\n",
+		c.Request.From.Config().Service, opts.Target.Config().Service, opts.PortName, opts.Scheme, opts.Path)
+	if opts.Token != "" {
+		_, _ = fmt.Fprintf(w, "  Token: %s\n", redactTokens(opts.Token))
+	}
+	names := make([]string, 0, len(opts.Headers))
+	for name := range opts.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		_, _ = fmt.Fprintf(w, "  %s: %s\n", name, redactTokens(strings.Join(opts.Headers[name], ",")))
+	}
+	if recorder.err != nil {
+		_, _ = fmt.Fprintf(w, "Call error: %s\n", redactTokens(recorder.err.Error()))
+	}
+	for i, r := range recorder.results {
+		_, _ = fmt.Fprintf(w, "Response[%d]: code %s, served by %s (version %s) in %v\n",
+			i, r.Code, r.Hostname, r.Version, r.Latency)
+		for _, line := range strings.Split(strings.TrimSpace(r.Body), "\n") {
+			_, _ = fmt.Fprintf(w, "  %s\n", redactTokens(line))
+		}
+	}
+	if err != nil {
+		_, _ = fmt.Fprintf(w, "FAIL: %s\n", redactTokens(err.Error()))
+	} else {
+		_, _ = fmt.Fprintf(w, "PASS: %s\n", redactTokens(c.String()))
+	}
+	return err
+}
+
+// redactTokens replaces the JWTs in s by their first characters and length.
+func redactTokens(s string) string {
+	return tokenRegex.ReplaceAllStringFunc(s, func(token string) string {
+		return fmt.Sprintf("%s...<redacted %d bytes>", token[:10], len(token))
+	})
+}
+
+// recordingInstance records the results of the calls of an echo instance.
+type recordingInstance struct {
+	echo.Instance
+	results client.ParsedResponses
+	err     error
+}
+
+func (r *recordingInstance) Call(opts echo.CallOptions) (client.ParsedResponses, error) {
+	r.results, r.err = r.Instance.Call(opts)
+	return r.results, r.err
+}
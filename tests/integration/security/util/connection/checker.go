@@ -19,8 +19,11 @@ import (
 	"time"
 
 	"istio.io/istio/pkg/test"
+	"istio.io/istio/pkg/test/echo/client"
+	"istio.io/istio/pkg/test/echo/common/response"
 	"istio.io/istio/pkg/test/framework/components/echo"
 	"istio.io/istio/pkg/test/util/retry"
+	"istio.io/istio/tests/integration/security/util/egress"
 )
 
 // Checker is a test utility for testing the network connectivity between two endpoints.
@@ -28,15 +31,33 @@ type Checker struct {
 	From          echo.Instance
 	Options       echo.CallOptions
 	ExpectSuccess bool
+	// ExpectServedBy, if set, is the hostname (pod name) or the version of the workload that must serve the
+	// requests reaching the application, e.g. with a policy or a subset scoped to one version.
+	ExpectServedBy string
+	// ExpectEgressGateway, if set, is the name of the egress gateway that must have proxied the requests
+	// reaching the application, routed through it as set up by the egress package.
+	ExpectEgressGateway string
+	// ExpectConnectionFailure, if set, is the way the connection must fail at L4, with no L7 response, e.g.
+	// reset by a sidecar denying a TCP port. ExpectSuccess is ignored.
+	ExpectConnectionFailure client.ConnectionFailure
 }
 
 // Check whether the target endpoint is reachable from the source.
 func (c *Checker) Check() error {
 	results, err := c.From.Call(c.Options)
+	if c.ExpectConnectionFailure != "" {
+		return c.CheckConnectionFailure(results, err)
+	}
 	if c.ExpectSuccess {
 		if err == nil {
 			err = results.CheckOK()
 		}
+		if err == nil {
+			err = c.CheckServedBy(results)
+		}
+		if err == nil {
+			err = c.CheckEgressGateway(results)
+		}
 		if err != nil {
 			return fmt.Errorf("%s to %s:%s using %s: expected success but failed: %v",
 				c.From.Config().Service, c.Options.Target.Config().Service, c.Options.PortName, c.Options.Scheme, err)
@@ -52,6 +73,63 @@ func (c *Checker) Check() error {
 	return nil
 }
 
+// CheckServedBy checks the responses reaching the application were served by ExpectServedBy, if set.
+func (c *Checker) CheckServedBy(results client.ParsedResponses) error {
+	if c.ExpectServedBy == "" {
+		return nil
+	}
+	for i, r := range results {
+		if r.Code != response.StatusCodeOK {
+			continue
+		}
+		if r.Hostname != c.ExpectServedBy && r.Version != c.ExpectServedBy {
+			return fmt.Errorf("response[%d] served by pod %s of version %s, want %s",
+				i, r.Hostname, r.Version, c.ExpectServedBy)
+		}
+	}
+	return nil
+}
+
+// CheckEgressGateway checks the responses reaching the application were proxied by ExpectEgressGateway,
+// if set.
+func (c *Checker) CheckEgressGateway(results client.ParsedResponses) error {
+	if c.ExpectEgressGateway == "" {
+		return nil
+	}
+	for i, r := range results {
+		if r.Code != response.StatusCodeOK {
+			continue
+		}
+		if got := r.RawResponse[egress.Header]; got != c.ExpectEgressGateway {
+			return fmt.Errorf("response[%d] proxied by egress gateway %q, want %s", i, got, c.ExpectEgressGateway)
+		}
+	}
+	return nil
+}
+
+// CheckConnectionFailure checks the call failed at L4 as ExpectConnectionFailure, given its results and
+// error.
+func (c *Checker) CheckConnectionFailure(results client.ParsedResponses, err error) error {
+	prefix := fmt.Sprintf("%s to %s:%s using %s: expected connection %s",
+		c.From.Config().Service, c.Options.Target.Config().Service, c.Options.PortName, c.Options.Scheme,
+		c.ExpectConnectionFailure)
+	if err == nil {
+		code := ""
+		if len(results) > 0 {
+			code = results[0].Code
+		}
+		return fmt.Errorf("%s, got a response with code %q", prefix, code)
+	}
+	got, ok := client.ConnectionFailureOf(err)
+	if !ok {
+		return fmt.Errorf("%s, failed otherwise: %v", prefix, err)
+	}
+	if got != c.ExpectConnectionFailure {
+		return fmt.Errorf("%s, got connection %s: %v", prefix, got, err)
+	}
+	return nil
+}
+
 func (c *Checker) CheckOrFail(t test.Failer) {
 	if err := retry.UntilSuccess(c.Check, retry.Delay(time.Millisecond*100)); err != nil {
 		t.Fatal(err)
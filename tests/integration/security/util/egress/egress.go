@@ -0,0 +1,125 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package egress routes the calls from the mesh to a service through an egress gateway, which marks the
+// requests it proxies with a header echoed back by the application, so that the traversal can be checked.
+package egress
+
+import (
+	"fmt"
+
+	"istio.io/istio/pkg/test/framework/components/echo"
+	"istio.io/istio/pkg/test/util/tmpl"
+)
+
+// Header is the request header set by the egress gateway to its name on the requests it proxies, as
+// echoed back by the application.
+const Header = "Handled-By-Egress-Gateway"
+
+const routeTmpl = `apiVersion: networking.istio.io/v1alpha3
+kind: Gateway
+metadata:
+  name: egress-for-{{ .Service }}
+  namespace: {{ .Namespace }}
+spec:
+  selector:
+    istio: {{ .Selector }}
+  servers:
+  - port:
+      number: 80
+      name: http
+      protocol: HTTP
+    hosts:
+    - {{ .Host }}
+---
+apiVersion: networking.istio.io/v1alpha3
+kind: VirtualService
+metadata:
+  name: {{ .Service }}-through-egress-gateway
+  namespace: {{ .Namespace }}
+spec:
+  hosts:
+  - {{ .Host }}
+  gateways:
+  - egress-for-{{ .Service }}
+  - mesh
+  http:
+  - match:
+    - gateways:
+      - mesh
+      port: {{ .Port }}
+    route:
+    - destination:
+        host: {{ .Gateway }}
+        port:
+          number: 80
+  - match:
+    - gateways:
+      - egress-for-{{ .Service }}
+      port: 80
+    route:
+    - destination:
+        host: {{ .Host }}
+        port:
+          number: {{ .Port }}
+    headers:
+      request:
+        set:
+          {{ .Header }}: {{ .Name }}
+`
+
+// Gateway is an egress gateway deployment.
+type Gateway struct {
+	// Name of the service of the gateway.
+	Name string
+	// Namespace of the gateway.
+	Namespace string
+	// Selector is the value of the "istio" label of the pods of the gateway.
+	Selector string
+}
+
+// Default returns the default egress gateway, installed in the given root namespace.
+func Default(rootNamespace string) Gateway {
+	return Gateway{
+		Name:      "istio-egressgateway",
+		Namespace: rootNamespace,
+		Selector:  "egressgateway",
+	}
+}
+
+// Route returns the config routing the calls from the mesh to the port of target with the given name
+// through the gateway, to be applied in the given namespace, e.g. of the callers. The requests proxied by
+// the gateway have Header set to the name of the gateway.
+func (g Gateway) Route(namespace string, target echo.Instance, portName string) (string, error) {
+	cfg := target.Config()
+	var port *echo.Port
+	for i, p := range cfg.Ports {
+		if p.Name == portName {
+			port = &cfg.Ports[i]
+		}
+	}
+	if port == nil {
+		return "", fmt.Errorf("no port %q in %s", portName, cfg.Service)
+	}
+	return tmpl.Evaluate(routeTmpl, map[string]interface{}{
+		"Service":   cfg.Service,
+		"Namespace": namespace,
+		"Selector":  g.Selector,
+		"Host":      cfg.FQDN(),
+		"Port":      port.ServicePort,
+		"Gateway":   fmt.Sprintf("%s.%s.svc.cluster.local", g.Name, g.Namespace),
+		"Header":    Header,
+		"Name":      g.Name,
+	})
+}
@@ -0,0 +1,351 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package envoyconfig snapshots the inbound Envoy configuration of a sidecar, as shown by
+// istioctl proxy-config, and diffs the snapshots to check the changes a policy makes to it.
+package envoyconfig
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	envoyAdmin "github.com/envoyproxy/go-control-plane/envoy/admin/v3"
+	cluster "github.com/envoyproxy/go-control-plane/envoy/config/cluster/v3"
+	core "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	listener "github.com/envoyproxy/go-control-plane/envoy/config/listener/v3"
+	hcm "github.com/envoyproxy/go-control-plane/envoy/extensions/filters/network/http_connection_manager/v3"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/any"
+)
+
+const inboundClusterPrefix = "inbound|"
+
+// Snapshot is the inbound configuration of a sidecar: its inbound listeners and clusters, by name.
+type Snapshot struct {
+	Listeners map[string]*listener.Listener
+	Clusters  map[string]*cluster.Cluster
+}
+
+// Inbound returns the snapshot of the inbound configuration of the given config dump.
+func Inbound(dump *envoyAdmin.ConfigDump) (*Snapshot, error) {
+	s := &Snapshot{
+		Listeners: map[string]*listener.Listener{},
+		Clusters:  map[string]*cluster.Cluster{},
+	}
+	for _, c := range dump.Configs {
+		switch {
+		case ptypes.Is(c, &envoyAdmin.ListenersConfigDump{}):
+			listeners := &envoyAdmin.ListenersConfigDump{}
+			if err := ptypes.UnmarshalAny(c, listeners); err != nil {
+				return nil, err
+			}
+			for _, l := range listeners.DynamicListeners {
+				if l.ActiveState == nil {
+					continue
+				}
+				out := &listener.Listener{}
+				if err := ptypes.UnmarshalAny(l.ActiveState.Listener, out); err != nil {
+					return nil, fmt.Errorf("listener %s: %v", l.Name, err)
+				}
+				if out.TrafficDirection == core.TrafficDirection_INBOUND {
+					s.Listeners[out.Name] = out
+				}
+			}
+		case ptypes.Is(c, &envoyAdmin.ClustersConfigDump{}):
+			clusters := &envoyAdmin.ClustersConfigDump{}
+			if err := ptypes.UnmarshalAny(c, clusters); err != nil {
+				return nil, err
+			}
+			for _, dc := range clusters.DynamicActiveClusters {
+				out := &cluster.Cluster{}
+				if err := ptypes.UnmarshalAny(dc.Cluster, out); err != nil {
+					return nil, err
+				}
+				if strings.HasPrefix(out.Name, inboundClusterPrefix) {
+					s.Clusters[out.Name] = out
+				}
+			}
+		}
+	}
+	if len(s.Listeners) == 0 {
+		return nil, fmt.Errorf("no inbound listener found in the config dump")
+	}
+	return s, nil
+}
+
+// HasHTTPFilter returns true if an inbound HTTP filter chain of the snapshot has the named filter.
+func (s *Snapshot) HasHTTPFilter(name string) (bool, error) {
+	for _, l := range s.Listeners {
+		for _, fc := range l.FilterChains {
+			filters, err := httpFilters(fc)
+			if err != nil {
+				return false, err
+			}
+			for _, f := range filters {
+				if f.Name == name {
+					return true, nil
+				}
+			}
+		}
+	}
+	return false, nil
+}
+
+// Diff is the difference between two snapshots of the same sidecar.
+type Diff struct {
+	// AddedHTTPFilters, RemovedHTTPFilters and ChangedHTTPFilters map the names of the HTTP filters
+	// added to, removed from or changed in the inbound HTTP filter chains to these chains, named
+	// "<listener>/<index of the chain>".
+	AddedHTTPFilters   map[string][]string
+	RemovedHTTPFilters map[string][]string
+	ChangedHTTPFilters map[string][]string
+	// Other lists the other changes, e.g. to the clusters or to the filter chains themselves.
+	Other []string
+}
+
+// Empty returns true if the snapshots are the same.
+func (d *Diff) Empty() bool {
+	return len(d.AddedHTTPFilters) == 0 && len(d.RemovedHTTPFilters) == 0 &&
+		len(d.ChangedHTTPFilters) == 0 && len(d.Other) == 0
+}
+
+func (d *Diff) String() string {
+	var out []string
+	for _, change := range []struct {
+		kind    string
+		filters map[string][]string
+	}{
+		{"added", d.AddedHTTPFilters},
+		{"removed", d.RemovedHTTPFilters},
+		{"changed", d.ChangedHTTPFilters},
+	} {
+		for _, name := range sortedKeys(change.filters) {
+			out = append(out, fmt.Sprintf("HTTP filter %s %s in %s", name, change.kind,
+				strings.Join(change.filters[name], ", ")))
+		}
+	}
+	out = append(out, d.Other...)
+	if len(out) == 0 {
+		return "no change"
+	}
+	return strings.Join(out, "\n")
+}
+
+// Compare returns the changes from before to after.
+func Compare(before, after *Snapshot) (*Diff, error) {
+	d := &Diff{
+		AddedHTTPFilters:   map[string][]string{},
+		RemovedHTTPFilters: map[string][]string{},
+		ChangedHTTPFilters: map[string][]string{},
+	}
+
+	for _, name := range clusterNames(before, after) {
+		b, a := before.Clusters[name], after.Clusters[name]
+		switch {
+		case b == nil:
+			d.Other = append(d.Other, "cluster "+name+" added")
+		case a == nil:
+			d.Other = append(d.Other, "cluster "+name+" removed")
+		case !proto.Equal(b, a):
+			d.Other = append(d.Other, "cluster "+name+" changed")
+		}
+	}
+
+	for _, name := range listenerNames(before, after) {
+		b, a := before.Listeners[name], after.Listeners[name]
+		switch {
+		case b == nil:
+			d.Other = append(d.Other, "listener "+name+" added")
+		case a == nil:
+			d.Other = append(d.Other, "listener "+name+" removed")
+		default:
+			if err := d.compareListeners(b, a); err != nil {
+				return nil, fmt.Errorf("listener %s: %v", name, err)
+			}
+		}
+	}
+	return d, nil
+}
+
+func (d *Diff) compareListeners(before, after *listener.Listener) error {
+	b := proto.Clone(before).(*listener.Listener)
+	a := proto.Clone(after).(*listener.Listener)
+	b.FilterChains, a.FilterChains = nil, nil
+	if !proto.Equal(b, a) {
+		d.Other = append(d.Other, "listener "+before.Name+" changed")
+	}
+	if len(before.FilterChains) != len(after.FilterChains) {
+		d.Other = append(d.Other, fmt.Sprintf("listener %s: %d filter chains, was %d",
+			before.Name, len(after.FilterChains), len(before.FilterChains)))
+		return nil
+	}
+	for i := range before.FilterChains {
+		if err := d.compareFilterChains(fmt.Sprintf("%s/%d", before.Name, i),
+			before.FilterChains[i], after.FilterChains[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Diff) compareFilterChains(chain string, before, after *listener.FilterChain) error {
+	b := proto.Clone(before).(*listener.FilterChain)
+	a := proto.Clone(after).(*listener.FilterChain)
+	b.Filters, a.Filters = nil, nil
+	if !proto.Equal(b, a) {
+		d.Other = append(d.Other, "filter chain "+chain+" changed")
+	}
+	if len(before.Filters) != len(after.Filters) {
+		d.Other = append(d.Other, "network filters of "+chain+" changed")
+		return nil
+	}
+	for i := range before.Filters {
+		bf, af := before.Filters[i], after.Filters[i]
+		if bf.Name != af.Name {
+			d.Other = append(d.Other, "network filters of "+chain+" changed")
+			return nil
+		}
+		if ptypes.Is(bf.GetTypedConfig(), &hcm.HttpConnectionManager{}) {
+			if err := d.compareHTTPConnectionManagers(chain, bf.GetTypedConfig(), af.GetTypedConfig()); err != nil {
+				return err
+			}
+			continue
+		}
+		if !anyEqual(bf.GetTypedConfig(), af.GetTypedConfig()) {
+			d.Other = append(d.Other, "network filter "+bf.Name+" of "+chain+" changed")
+		}
+	}
+	return nil
+}
+
+func (d *Diff) compareHTTPConnectionManagers(chain string, before, after *any.Any) error {
+	b, a := &hcm.HttpConnectionManager{}, &hcm.HttpConnectionManager{}
+	if err := ptypes.UnmarshalAny(before, b); err != nil {
+		return err
+	}
+	if err := ptypes.UnmarshalAny(after, a); err != nil {
+		return err
+	}
+	beforeFilters, afterFilters := b.HttpFilters, a.HttpFilters
+	b.HttpFilters, a.HttpFilters = nil, nil
+	if !proto.Equal(b, a) {
+		d.Other = append(d.Other, "HTTP connection manager of "+chain+" changed")
+	}
+
+	beforeByName := map[string]*hcm.HttpFilter{}
+	for _, f := range beforeFilters {
+		beforeByName[f.Name] = f
+	}
+	afterByName := map[string]*hcm.HttpFilter{}
+	// The names of the filters kept, in order, to check they were not reordered.
+	var kept []string
+	for _, f := range afterFilters {
+		afterByName[f.Name] = f
+		bf, ok := beforeByName[f.Name]
+		if !ok {
+			d.AddedHTTPFilters[f.Name] = append(d.AddedHTTPFilters[f.Name], chain)
+			continue
+		}
+		kept = append(kept, f.Name)
+		if !anyEqual(bf.GetTypedConfig(), f.GetTypedConfig()) {
+			d.ChangedHTTPFilters[f.Name] = append(d.ChangedHTTPFilters[f.Name], chain)
+		}
+	}
+	var wasKept []string
+	for _, f := range beforeFilters {
+		if _, ok := afterByName[f.Name]; !ok {
+			d.RemovedHTTPFilters[f.Name] = append(d.RemovedHTTPFilters[f.Name], chain)
+			continue
+		}
+		wasKept = append(wasKept, f.Name)
+	}
+	if strings.Join(kept, ",") != strings.Join(wasKept, ",") {
+		d.Other = append(d.Other, "HTTP filters of "+chain+" reordered")
+	}
+	return nil
+}
+
+// httpFilters returns the HTTP filters of the filter chain, if it has an HTTP connection manager.
+func httpFilters(fc *listener.FilterChain) ([]*hcm.HttpFilter, error) {
+	for _, f := range fc.Filters {
+		if !ptypes.Is(f.GetTypedConfig(), &hcm.HttpConnectionManager{}) {
+			continue
+		}
+		cm := &hcm.HttpConnectionManager{}
+		if err := ptypes.UnmarshalAny(f.GetTypedConfig(), cm); err != nil {
+			return nil, err
+		}
+		return cm.HttpFilters, nil
+	}
+	return nil, nil
+}
+
+// anyEqual compares the messages of the given Any by value if their type is known, so that they are not
+// reported as changed when they are only serialized differently, e.g. with the entries of a map in
+// another order. Unknown types are compared by their serialization.
+func anyEqual(a, b *any.Any) bool {
+	if a == nil || b == nil || a.TypeUrl != b.TypeUrl {
+		return a == nil && b == nil
+	}
+	am, err := ptypes.Empty(a)
+	if err != nil {
+		return bytes.Equal(a.Value, b.Value)
+	}
+	bm := proto.Clone(am)
+	if ptypes.UnmarshalAny(a, am) != nil || ptypes.UnmarshalAny(b, bm) != nil {
+		return bytes.Equal(a.Value, b.Value)
+	}
+	return proto.Equal(am, bm)
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func listenerNames(snapshots ...*Snapshot) []string {
+	set := map[string]bool{}
+	for _, s := range snapshots {
+		for name := range s.Listeners {
+			set[name] = true
+		}
+	}
+	return sortedSet(set)
+}
+
+func clusterNames(snapshots ...*Snapshot) []string {
+	set := map[string]bool{}
+	for _, s := range snapshots {
+		for name := range s.Clusters {
+			set[name] = true
+		}
+	}
+	return sortedSet(set)
+}
+
+func sortedSet(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,115 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package extauthz sets up an echo instance as the HTTP ext_authz server of the sidecar of another
+// instance, the echo server answering the check requests on common.ExtAuthzPathPrefix. The ext_authz
+// filter is inserted by an EnvoyFilter right before the router, i.e. after the JWT and RBAC filters.
+package extauthz
+
+import (
+	"fmt"
+	"time"
+
+	echoCommon "istio.io/istio/pkg/test/echo/common"
+	"istio.io/istio/pkg/test/framework/components/echo"
+	"istio.io/istio/pkg/test/framework/components/namespace"
+	"istio.io/istio/pkg/test/framework/components/pilot"
+	"istio.io/istio/pkg/test/util/tmpl"
+	"istio.io/istio/tests/integration/security/util"
+)
+
+const portName = "http"
+
+const envoyFilterTmpl = `apiVersion: networking.istio.io/v1alpha3
+kind: EnvoyFilter
+metadata:
+  name: ext-authz-for-{{ .Target }}
+  namespace: {{ .Namespace }}
+spec:
+  workloadSelector:
+    labels:
+      app: {{ .Target }}
+  configPatches:
+  - applyTo: HTTP_FILTER
+    match:
+      context: SIDECAR_INBOUND
+      listener:
+        filterChain:
+          filter:
+            name: "envoy.http_connection_manager"
+            subFilter:
+              name: "envoy.router"
+    patch:
+      operation: INSERT_BEFORE
+      value:
+        name: envoy.ext_authz
+        typed_config:
+          "@type": type.googleapis.com/envoy.config.filter.http.ext_authz.v2.ExtAuthz
+          http_service:
+            server_uri:
+              uri: http://{{ .ServerHost }}:{{ .ServerPort }}
+              cluster: outbound|{{ .ServerPort }}||{{ .ServerHost }}
+              timeout: 5s
+            path_prefix: {{ .PathPrefix }}
+            authorization_request:
+              allowed_headers:
+                patterns:
+{{- range .ForwardHeaders }}
+                - exact: {{ . }}
+{{- end }}
+            authorization_response:
+              allowed_upstream_headers:
+                patterns:
+                - exact: {{ .ResultHeader }}
+`
+
+// Config returns the config of an echo instance serving as ext_authz server. It has no sidecar, so that
+// the check requests are received as sent by the sidecars.
+func Config(name string, ns namespace.Instance, p pilot.Instance) echo.Config {
+	return util.EchoConfig(name, ns, false, echo.NewAnnotations().SetBool(echo.SidecarInject, false), p)
+}
+
+// EnvoyFilter returns the EnvoyFilter inserting the ext_authz filter in the inbound sidecar of target, to
+// be applied in the namespace of target. The check requests are sent to server, with the header
+// echoCommon.ExtAuthzHeader deciding the answer and the given headers, e.g. the payload of a JWT. The
+// requests allowed reach target with the echoCommon.ExtAuthzResultHeader of the answer.
+func EnvoyFilter(target, server echo.Instance, forwardHeaders ...string) (string, error) {
+	port, err := httpPort(server)
+	if err != nil {
+		return "", err
+	}
+	return tmpl.Evaluate(envoyFilterTmpl, map[string]interface{}{
+		"Target":         target.Config().Service,
+		"Namespace":      target.Config().Namespace.Name(),
+		"ServerHost":     server.Config().FQDN(),
+		"ServerPort":     port.ServicePort,
+		"PathPrefix":     echoCommon.ExtAuthzPathPrefix,
+		"ForwardHeaders": append([]string{echoCommon.ExtAuthzHeader}, forwardHeaders...),
+		"ResultHeader":   echoCommon.ExtAuthzResultHeader,
+	})
+}
+
+// CheckRequests returns the check requests received by server for the requests on the given path.
+func CheckRequests(server echo.Instance, path string) ([]echoCommon.ReceivedRequest, error) {
+	return server.ReceivedRequests(time.Time{}, echo.WithPathPrefix(echoCommon.ExtAuthzPathPrefix+path))
+}
+
+func httpPort(server echo.Instance) (echo.Port, error) {
+	for _, p := range server.Config().Ports {
+		if p.Name == portName {
+			return p, nil
+		}
+	}
+	return echo.Port{}, fmt.Errorf("no port %q in %s", portName, server.Config().Service)
+}
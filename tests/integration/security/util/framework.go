@@ -15,7 +15,10 @@
 package util
 
 import (
+	"strings"
+
 	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/test/echo/common"
 	"istio.io/istio/pkg/test/framework/components/echo"
 	"istio.io/istio/pkg/test/framework/components/namespace"
 	"istio.io/istio/pkg/test/framework/components/pilot"
@@ -59,3 +62,94 @@ func EchoConfig(name string, ns namespace.Instance, headless bool, annos echo.An
 	}
 	return out
 }
+
+// EchoConfigWithLocality returns the config of EchoConfig, deployed in the given locality, formatted as
+// region.zone.subzone. The workloads report it in their responses, e.g. to check locality load balancing.
+func EchoConfigWithLocality(name string, ns namespace.Instance, locality string, annos echo.Annotations,
+	p pilot.Instance) echo.Config {
+	out := EchoConfig(name, ns, false, annos, p)
+	out.Locality = locality
+	return out
+}
+
+// EchoConfigWithReplicas returns the config of EchoConfig, with the given number of pods.
+func EchoConfigWithReplicas(name string, ns namespace.Instance, replicas int, annos echo.Annotations,
+	p pilot.Instance) echo.Config {
+	out := EchoConfig(name, ns, false, annos, p)
+	out.Subsets[0].Replicas = replicas
+	return out
+}
+
+// EchoConfigWithStatefulSet returns the config of EchoConfig, headless and backed by a StatefulSet with the
+// given number of pods, so that each pod has a stable DNS name, see echokube.PodHostname.
+func EchoConfigWithStatefulSet(name string, ns namespace.Instance, replicas int, annos echo.Annotations,
+	p pilot.Instance) echo.Config {
+	out := EchoConfigWithReplicas(name, ns, replicas, annos, p)
+	out.Headless = true
+	out.Ports[0].ServicePort = 8090
+	out.WorkloadKind = echo.StatefulSetKind
+	return out
+}
+
+// EchoConfigWithVersions returns the config of EchoConfig, with one subset per version. The pods of each
+// subset have its version label and report it in their responses, e.g. to check which subset of a
+// DestinationRule served a request.
+func EchoConfigWithVersions(name string, ns namespace.Instance, versions []string, annos echo.Annotations,
+	p pilot.Instance) echo.Config {
+	out := EchoConfig(name, ns, false, annos, p)
+	out.Subsets = make([]echo.SubsetConfig, 0, len(versions))
+	for _, v := range versions {
+		out.Subsets = append(out.Subsets, echo.SubsetConfig{
+			Version:     v,
+			Annotations: annos,
+		})
+	}
+	return out
+}
+
+// EchoConfigWithHTTPS returns the config of EchoConfig, with an additional "https" port on which the
+// application terminates TLS with the given settings.
+func EchoConfigWithHTTPS(name string, ns namespace.Instance, tls *common.TLSSettings, annos echo.Annotations,
+	p pilot.Instance) echo.Config {
+	out := EchoConfig(name, ns, false, annos, p)
+	out.Ports = append(out.Ports, echo.Port{
+		Name:     "https",
+		Protocol: protocol.HTTPS,
+		TLS:      true,
+	})
+	out.TLSSettings = tls
+	return out
+}
+
+// EchoUDSServer is the path of the UDS server of EchoConfigWithUDS.
+const EchoUDSServer = "/tmp/echo.sock"
+
+// EchoConfigWithUDS returns the config of EchoConfig, with the application also serving HTTP on the unix
+// domain socket EchoUDSServer, which the workload can call without going through its sidecar.
+func EchoConfigWithUDS(name string, ns namespace.Instance, annos echo.Annotations, p pilot.Instance) echo.Config {
+	out := EchoConfig(name, ns, false, annos, p)
+	out.UDSServer = EchoUDSServer
+	return out
+}
+
+// DeclaredPortName returns the name of the port of EchoConfigWithDeclaredProtocols declared with the given
+// protocol, e.g. "tcp-http" for TCP.
+func DeclaredPortName(declared protocol.Instance) string {
+	return strings.ToLower(string(declared)) + "-http"
+}
+
+// EchoConfigWithDeclaredProtocols returns the config of EchoConfig, with an additional port per given
+// protocol: the application serves HTTP on it, while the Service declares it with the protocol, by the
+// prefix of its name (see DeclaredPortName). The sidecars handle the traffic of a port as declared, e.g. the
+// HTTP filters such as the JWT filter do not apply to a port declared TCP.
+func EchoConfigWithDeclaredProtocols(name string, ns namespace.Instance, declared []protocol.Instance,
+	annos echo.Annotations, p pilot.Instance) echo.Config {
+	out := EchoConfig(name, ns, false, annos, p)
+	for _, d := range declared {
+		out.Ports = append(out.Ports, echo.Port{
+			Name:     DeclaredPortName(d),
+			Protocol: protocol.HTTP,
+		})
+	}
+	return out
+}
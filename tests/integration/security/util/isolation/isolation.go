@@ -0,0 +1,154 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package isolation checks tests do not leak security relevant cluster state to each other, so that the
+// outcome of a test does not depend on the tests run before it.
+package isolation
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"istio.io/istio/pkg/test/framework"
+	"istio.io/istio/pkg/test/framework/components/environment/kube"
+	"istio.io/istio/pkg/test/framework/components/istio"
+	"istio.io/istio/pkg/test/framework/resource"
+	"istio.io/istio/pkg/test/framework/resource/environment"
+)
+
+const (
+	meshConfigMapName = "istio"
+	// The name of the report written to the work dir of a test that leaked state.
+	reportFileName = "leaked-state.txt"
+)
+
+// The kinds of resources which, in the root namespace, apply to every workload of the mesh.
+var rootNamespaceKinds = []schema.GroupVersionResource{
+	{Group: "security.istio.io", Version: "v1beta1", Resource: "authorizationpolicies"},
+	{Group: "security.istio.io", Version: "v1beta1", Resource: "peerauthentications"},
+	{Group: "security.istio.io", Version: "v1beta1", Resource: "requestauthentications"},
+	{Group: "networking.istio.io", Version: "v1alpha3", Resource: "envoyfilters"},
+	{Group: "networking.istio.io", Version: "v1alpha3", Resource: "gateways"},
+	{Group: "networking.istio.io", Version: "v1alpha3", Resource: "virtualservices"},
+	{Group: "networking.istio.io", Version: "v1alpha3", Resource: "destinationrules"},
+}
+
+// State is a snapshot of the security relevant cluster state.
+type State struct {
+	// Resources maps "<resource>/<namespace>/<name>" of each resource in the root namespace to the hash of
+	// its spec.
+	Resources map[string]string
+
+	// MeshConfig is the hash of the mesh config.
+	MeshConfig string
+}
+
+// Snapshot takes a snapshot of the security relevant cluster state.
+func Snapshot(ctx resource.Context, rootNamespace string) (State, error) {
+	cfg, err := istio.DefaultConfig(ctx)
+	if err != nil {
+		return State{}, err
+	}
+	cluster := kube.ClusterOrDefault(nil, ctx.Environment())
+
+	s := State{Resources: map[string]string{}}
+	for _, gvr := range rootNamespaceKinds {
+		resources, err := cluster.ListUnstructured(gvr, rootNamespace)
+		if err != nil {
+			return State{}, err
+		}
+		for _, r := range resources {
+			h, err := hash(r.Object["spec"])
+			if err != nil {
+				return State{}, err
+			}
+			s.Resources[fmt.Sprintf("%s/%s/%s", gvr.Resource, r.GetNamespace(), r.GetName())] = h
+		}
+	}
+
+	cm, err := cluster.GetConfigMap(meshConfigMapName, cfg.ConfigNamespace)
+	if err != nil {
+		return State{}, fmt.Errorf("failed to get mesh config: %v", err)
+	}
+	if s.MeshConfig, err = hash(cm.Data); err != nil {
+		return State{}, err
+	}
+	return s, nil
+}
+
+func hash(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(b)), nil
+}
+
+// Diff returns a description of each difference from before to after, sorted, or nil if there is none.
+func Diff(before, after State) []string {
+	var diffs []string
+	for name, h := range after.Resources {
+		if bh, ok := before.Resources[name]; !ok {
+			diffs = append(diffs, "added "+name)
+		} else if bh != h {
+			diffs = append(diffs, "modified "+name)
+		}
+	}
+	for name := range before.Resources {
+		if _, ok := after.Resources[name]; !ok {
+			diffs = append(diffs, "deleted "+name)
+		}
+	}
+	sort.Strings(diffs)
+	if before.MeshConfig != after.MeshConfig {
+		diffs = append(diffs, "modified mesh config")
+	}
+	return diffs
+}
+
+// Verifier returns a hook that fails each test leaking security relevant cluster state, i.e. leaving the
+// resources of the root namespace or the mesh config different from what they were before the test. The
+// leaks are listed in the failure message and in a report in the work dir of the test. rootNamespace is
+// read when each test starts, so it may be set by the suite setup.
+func Verifier(rootNamespace *string) framework.TestHook {
+	return func(ctx framework.TestContext) func() {
+		if ctx.Environment().EnvironmentName() != environment.Kube {
+			return nil
+		}
+		before, err := Snapshot(ctx, *rootNamespace)
+		if err != nil {
+			ctx.Logf("skipping isolation check: failed to snapshot the cluster state: %v", err)
+			return nil
+		}
+		return func() {
+			after, err := Snapshot(ctx, *rootNamespace)
+			if err != nil {
+				ctx.Errorf("failed to snapshot the cluster state after the test: %v", err)
+				return
+			}
+			diffs := Diff(before, after)
+			if len(diffs) == 0 {
+				return
+			}
+			report := ctx.WriteArtifactOrFail(reportFileName, []byte(strings.Join(diffs, "\n")+"\n"))
+			ctx.Errorf("test leaked cluster state to the next tests (report: %s):\n%s",
+				report, strings.Join(diffs, "\n"))
+		}
+	}
+}
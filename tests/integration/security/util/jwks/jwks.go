@@ -0,0 +1,143 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package jwks mocks a JWKS server with an echo instance, serving JSON Web Key Sets with configurable
+// response headers and status codes, and logging the requests for them. It also holds JWKS in Kubernetes
+// secrets.
+package jwks
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"istio.io/istio/pkg/test/echo/common"
+	"istio.io/istio/pkg/test/framework/components/echo"
+	"istio.io/istio/pkg/test/framework/components/environment/kube"
+	"istio.io/istio/pkg/test/framework/resource"
+)
+
+const portName = "http"
+
+// Server serves the JWKS from an echo instance with an HTTP port named "http". The instance should not
+// have a sidecar, so that istiod can fetch the JWKS over plain HTTP.
+type Server struct {
+	echo.Instance
+}
+
+// URI returns the in-cluster URI of the JWKS with the given name, to be used as jwksUri in policies.
+func (s Server) URI(name string) (string, error) {
+	port, err := s.port()
+	if err != nil {
+		return "", err
+	}
+	cfg := s.Config()
+	return fmt.Sprintf("http://%s.%s.svc.cluster.local:%d%s%s",
+		cfg.Service, cfg.Namespace.Name(), port.ServicePort, common.DocumentPathPrefix, name), nil
+}
+
+// Serve serves jwks under the given name, with the given response headers, e.g. Cache-Control. If code
+// is not 0, the response has this status code instead of 200.
+func (s Server) Serve(ctx resource.Context, name, jwks string, headers map[string]string, code int) error {
+	body, err := json.Marshal(common.Document{
+		Code:    code,
+		Headers: headers,
+		Body:    jwks,
+	})
+	if err != nil {
+		return err
+	}
+	return s.do(ctx, http.MethodPut, common.DocumentPathPrefix+name, body, nil)
+}
+
+// ServeDelayed serves jwks under the given name as Serve, each response being held for delay, e.g. to
+// make the fetches of istiod time out.
+func (s Server) ServeDelayed(ctx resource.Context, name, jwks string, delay time.Duration) error {
+	body, err := json.Marshal(common.Document{
+		Body:  jwks,
+		Delay: delay,
+	})
+	if err != nil {
+		return err
+	}
+	return s.do(ctx, http.MethodPut, common.DocumentPathPrefix+name, body, nil)
+}
+
+// Requests returns the times the JWKS with the given name was requested, in order.
+func (s Server) Requests(ctx resource.Context, name string) ([]time.Time, error) {
+	var requests []time.Time
+	if err := s.do(ctx, http.MethodGet, common.DocumentPathPrefix+name+common.DocumentRequestsSuffix, nil, &requests); err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
+func (s Server) port() (echo.Port, error) {
+	for _, p := range s.Config().Ports {
+		if p.Name == portName {
+			return p, nil
+		}
+	}
+	return echo.Port{}, fmt.Errorf("no port %q in %s", portName, s.Config().Service)
+}
+
+// do sends a request to the echo instance directly, through a port forwarding to its pod, and decodes
+// the JSON response into out if it is not nil.
+func (s Server) do(ctx resource.Context, method, path string, body []byte, out interface{}) error {
+	port, err := s.port()
+	if err != nil {
+		return err
+	}
+	cfg := s.Config()
+	cluster := kube.ClusterOrDefault(cfg.Cluster, ctx.Environment())
+	pods, err := cluster.GetPods(cfg.Namespace.Name(), "app="+cfg.Service)
+	if err != nil {
+		return err
+	}
+	if len(pods) == 0 {
+		return fmt.Errorf("no pod for %s", cfg.Service)
+	}
+	forwarder, err := cluster.NewPortForwarder(pods[0], 0, uint16(port.InstancePort))
+	if err != nil {
+		return fmt.Errorf("new port forwarder: %v", err)
+	}
+	if err = forwarder.Start(); err != nil {
+		return fmt.Errorf("forwarder start: %v", err)
+	}
+	defer func() { _ = forwarder.Close() }()
+
+	req, err := http.NewRequest(method, "http://"+forwarder.Address()+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s: status %d, %s", method, path, resp.StatusCode, string(respBody))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(respBody, out)
+}
@@ -0,0 +1,73 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jwks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	kubeApiCore "k8s.io/api/core/v1"
+	kubeApiMeta "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/istio/pkg/test/framework"
+	"istio.io/istio/pkg/test/framework/components/environment/kube"
+	"istio.io/istio/pkg/test/framework/components/namespace"
+)
+
+// SecretKey is the key of the JWKS in the data of the secrets created by CreateSecret.
+const SecretKey = "jwks"
+
+// CreateSecret creates a secret with the given name in the namespace, holding jwks under SecretKey. The
+// secret is deleted when the test context completes.
+func CreateSecret(ctx framework.TestContext, ns namespace.Instance, name, jwks string) error {
+	cluster := kube.ClusterOrDefault(nil, ctx.Environment())
+	secret := &kubeApiCore.Secret{
+		ObjectMeta: kubeApiMeta.ObjectMeta{
+			Name:      name,
+			Namespace: ns.Name(),
+		},
+		Data: map[string][]byte{
+			SecretKey: []byte(jwks),
+		},
+	}
+	if err := cluster.CreateSecret(ns.Name(), secret); err != nil {
+		return fmt.Errorf("failed to create secret %s/%s: %v", ns.Name(), name, err)
+	}
+	ctx.WhenDone(func() error {
+		return cluster.DeleteSecret(ns.Name(), name)
+	})
+	return nil
+}
+
+// SecretJWKS returns the JWKS held by the secret with the given name in the namespace, compacted to a
+// single line so that it can be inlined in the jwks field of a RequestAuthentication.
+func SecretJWKS(ctx framework.TestContext, ns namespace.Instance, name string) (string, error) {
+	cluster := kube.ClusterOrDefault(nil, ctx.Environment())
+	secret, err := cluster.GetSecret(ns.Name()).Get(context.TODO(), name, kubeApiMeta.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get secret %s/%s: %v", ns.Name(), name, err)
+	}
+	data, ok := secret.Data[SecretKey]
+	if !ok {
+		return "", fmt.Errorf("no %q in secret %s/%s", SecretKey, ns.Name(), name)
+	}
+	var out bytes.Buffer
+	if err := json.Compact(&out, data); err != nil {
+		return "", fmt.Errorf("invalid jwks in secret %s/%s: %v", ns.Name(), name, err)
+	}
+	return out.String(), nil
+}
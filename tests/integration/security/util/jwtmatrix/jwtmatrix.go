@@ -0,0 +1,446 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package jwtmatrix holds the test cases of the JWT tests of the security package built from matrices, and
+// lists the tests which are not, so that the coverage manifest tells what is and is not covered.
+package jwtmatrix
+
+import (
+	"fmt"
+	"strings"
+
+	"istio.io/istio/pkg/config/protocol"
+	"istio.io/istio/pkg/test/echo/common/scheme"
+	"istio.io/istio/tests/common/jwt"
+	"istio.io/istio/tests/integration/security/util"
+	"istio.io/istio/tests/integration/security/util/authn"
+)
+
+// CoverageFile is the name of the coverage manifest of Matrices, written to the work dir of each run and
+// committed in the testdata of the security tests, where it is refreshed with REFRESH_GOLDEN=true.
+const CoverageFile = "jwt-coverage.json"
+
+// Matrices are the test cases of the JWT tests, keyed by test name. The tests build their cases from
+// their matrix, so that the coverage manifest lists exactly what they run.
+var Matrices = map[string]authn.Matrix{
+	"TestRequestAuthentication": {
+		Policies: []string{
+			"testdata/requestauthn/a-authn.yaml.tmpl",
+			"testdata/requestauthn/b-authn-authz.yaml.tmpl",
+			"testdata/requestauthn/c-authn.yaml.tmpl",
+			"testdata/requestauthn/e-authn.yaml.tmpl",
+			"testdata/requestauthn/f-empty-jwt-rules.yaml.tmpl",
+		},
+		Cases: []authn.Case{
+			{
+				Name: "valid-token-noauthz", From: "a", To: "c", Token: jwt.TokenIssuer1, Expect: authn.Allowed,
+				ExpectHeaders: map[string]string{authHeaderKey: "", "X-Test-Payload": Payload(jwt.TokenIssuer1)},
+			},
+			{
+				Name: "valid-token-2-noauthz", From: "a", To: "c", Token: jwt.TokenIssuer2, Expect: authn.Allowed,
+				ExpectHeaders: map[string]string{authHeaderKey: "", "X-Test-Payload": Payload(jwt.TokenIssuer2)},
+			},
+			{Name: "expired-token-noauthz", From: "a", To: "c", Token: jwt.TokenExpired, Expect: authn.Unauthenticated},
+			// Envoy rejects request headers larger than 60 KiB (by default) with 431.
+			{Name: "large-token-noauthz", From: "a", To: "c", MintToken: mintLargeToken(32 * 1024), Expect: authn.Allowed},
+			{
+				Name: "oversized-token-noauthz", From: "a", To: "c", MintToken: mintLargeToken(96 * 1024),
+				Expect: authn.HeadersTooLarge,
+			},
+			{Name: "no-token-noauthz", From: "a", To: "c", Expect: authn.Allowed},
+			// Following app b is configured with authorization, only request with valid JWT succeed.
+			{
+				Name: "valid-token", From: "a", To: "b", Token: jwt.TokenIssuer1, Expect: authn.Allowed,
+				ExpectHeaders: map[string]string{authHeaderKey: ""},
+			},
+			{Name: "expired-token", From: "a", To: "b", Token: jwt.TokenExpired, Expect: authn.Unauthenticated},
+			{Name: "no-token", From: "a", To: "b", Expect: authn.Denied},
+			{Name: "no-authn-authz", From: "a", To: "d", Expect: authn.Allowed},
+			{
+				Name: "valid-token-forward", From: "a", To: "e", Token: jwt.TokenIssuer1, Expect: authn.Allowed,
+				ExpectHeaders: map[string]string{
+					authHeaderKey:    "Bearer " + jwt.TokenIssuer1,
+					"X-Test-Payload": Payload(jwt.TokenIssuer1),
+				},
+			},
+			// The JWT filter removes the token from Authorization only, leaving the headers of similar names.
+			{
+				Name: "authorization-like-headers", From: "a", To: "c", Token: jwt.TokenIssuer1,
+				Headers: authorizationLikeHeaders, Expect: authn.Allowed,
+				ExpectHeaders: map[string]string{
+					authHeaderKey:         "",
+					"Proxy-Authorization": authorizationLikeHeaders["Proxy-Authorization"],
+					"X-Authorization":     authorizationLikeHeaders["X-Authorization"],
+				},
+			},
+			{
+				Name: "authorization-like-headers-forward", From: "a", To: "e", Token: jwt.TokenIssuer1,
+				Headers: authorizationLikeHeaders, Expect: authn.Allowed,
+				ExpectHeaders: map[string]string{
+					authHeaderKey:         "Bearer " + jwt.TokenIssuer1,
+					"Proxy-Authorization": authorizationLikeHeaders["Proxy-Authorization"],
+					"X-Authorization":     authorizationLikeHeaders["X-Authorization"],
+				},
+			},
+			// App f has a RequestAuthentication without any JWT rule, so no token is validated there: whatever
+			// the token, even malformed, the request has no request principal and is denied by authorization,
+			// never rejected with 401.
+			emptyJWTRulesCase("valid-token", jwt.TokenIssuer1),
+			emptyJWTRulesCase("expired-token", jwt.TokenExpired),
+			emptyJWTRulesCase("invalid-token", jwt.TokenInvalid),
+			emptyJWTRulesCase("malformed-token", malformedToken),
+			emptyJWTRulesCase("no-token", ""),
+			{Name: "invalid aud", From: "b", To: "a", Token: jwt.TokenIssuer1, Expect: authn.Denied},
+			{Name: "valid aud", From: "b", To: "a", Token: jwt.TokenIssuer1WithAud, Expect: authn.Allowed},
+			{Name: "verify policies are combined", From: "b", To: "a", Token: jwt.TokenIssuer2, Expect: authn.Allowed},
+		},
+	},
+	"TestRequestAuthentication_NegativeMatch": {
+		Policies: []string{"testdata/requestauthn/negative-match.yaml.tmpl"},
+		Cases: []authn.Case{
+			// Only requests without a token are allowed on /public: a valid token establishes a request
+			// principal and is denied, an invalid token is rejected before authorization.
+			negativeMatchCase("/public", "", authn.Allowed),
+			negativeMatchCase("/public", jwt.TokenIssuer1, authn.Denied),
+			negativeMatchCase("/public", jwt.TokenIssuer2, authn.Denied),
+			negativeMatchCase("/public", jwt.TokenExpired, authn.Unauthenticated),
+
+			// Issuer-2 is excluded on /private. Note that requests without a token are allowed as well,
+			// since the absence of a request principal never matches the excluded issuer.
+			negativeMatchCase("/private", jwt.TokenIssuer1, authn.Allowed),
+			negativeMatchCase("/private", jwt.TokenIssuer2, authn.Denied),
+			negativeMatchCase("/private", "", authn.Allowed),
+			negativeMatchCase("/private", jwt.TokenExpired, authn.Unauthenticated),
+
+			// Any other path is denied regardless of the token.
+			negativeMatchCase("/other", "", authn.Denied),
+			negativeMatchCase("/other", jwt.TokenIssuer1, authn.Denied),
+		},
+	},
+	"TestJWTWithBase64UrlDecodingVariants": {
+		Policies: []string{"testdata/requestauthn/c-authn.yaml.tmpl"},
+		Cases: []authn.Case{
+			{Name: "standard-base64", From: "a", To: "c", Token: StdBase64Token, Expect: authn.Unauthenticated},
+			{Name: "base64url", From: "a", To: "c", Token: jwt.TokenIssuer1, Expect: authn.Allowed},
+		},
+	},
+	"TestJWTWithAudiencePerRule": {
+		Policies: []string{
+			"testdata/requestauthn/b-audience-rules.yaml.tmpl",
+			"testdata/requestauthn/b-audience-paths.yaml.tmpl",
+		},
+		Cases: []authn.Case{
+			// The rule of a token is selected by its issuer only: a token for either audience is accepted
+			// on both paths by the JWT filter, and only the authorization policy tells the paths apart.
+			audienceCase("/a", []string{"aud-a"}, authn.Allowed),
+			audienceCase("/a", []string{"aud-b"}, authn.Denied),
+			audienceCase("/b", []string{"aud-a"}, authn.Denied),
+			audienceCase("/b", []string{"aud-b"}, authn.Allowed),
+			audienceCase("/a", []string{"aud-a", "aud-b"}, authn.Allowed),
+			audienceCase("/b", []string{"aud-a", "aud-b"}, authn.Allowed),
+			// A token for no audience of the rules is rejected by the JWT filter, whatever the path.
+			audienceCase("/a", []string{"aud-c"}, authn.Unauthenticated),
+			audienceCase("/b", []string{"aud-c"}, authn.Unauthenticated),
+		},
+	},
+	"TestJWTWithDeclaredPortProtocol": {
+		Policies: []string{
+			"testdata/requestauthn/b-authn-authz.yaml.tmpl",
+			"testdata/requestauthn/c-authn.yaml.tmpl",
+		},
+		Cases: []authn.Case{
+			// The JWT filter of the HTTP chain removes the token it validates, while the TCP chain passes
+			// the request through as sent, which tells which chain handled it.
+			{
+				Name: "http-valid-token", From: "a", To: "c", Token: jwt.TokenIssuer1, Expect: authn.Allowed,
+				ExpectHeaders: map[string]string{authHeaderKey: ""},
+			},
+			{Name: "http-invalid-token", From: "a", To: "c", Token: jwt.TokenInvalid, Expect: authn.Unauthenticated},
+			{
+				Name: "tcp-valid-token", From: "a", To: "c", PortName: tcpDeclaredPort, Token: jwt.TokenIssuer1,
+				Expect: authn.Allowed, ExpectHeaders: map[string]string{authHeaderKey: "Bearer " + jwt.TokenIssuer1},
+			},
+			{
+				Name: "tcp-invalid-token", From: "a", To: "c", PortName: tcpDeclaredPort, Token: jwt.TokenInvalid,
+				Expect: authn.Allowed, ExpectHeaders: map[string]string{authHeaderKey: "Bearer " + jwt.TokenInvalid},
+			},
+			// The authorization policy of b requires a request principal, which the TCP chain never has:
+			// the connections are refused whatever the token.
+			{Name: "authz-http-valid-token", From: "a", To: "b", Token: jwt.TokenIssuer1, Expect: authn.Allowed},
+			{Name: "authz-http-no-token", From: "a", To: "b", Expect: authn.Denied},
+			{
+				Name: "authz-tcp-valid-token", From: "a", To: "b", PortName: tcpDeclaredPort, Token: jwt.TokenIssuer1,
+				Expect: authn.Refused,
+			},
+		},
+	},
+	"TestJWTWithSchemeHTTPS": {
+		Policies: []string{"testdata/requestauthn/c-authn.yaml.tmpl"},
+		Cases: []authn.Case{
+			{
+				Name: "http-valid-token", From: "a", To: "c", Token: jwt.TokenIssuer1, Expect: authn.Allowed,
+				ExpectHeaders: map[string]string{authHeaderKey: "", "X-Test-Payload": Payload(jwt.TokenIssuer1)},
+			},
+			{Name: "http-invalid-token", From: "a", To: "c", Token: jwt.TokenInvalid, Expect: authn.Unauthenticated},
+			// The TLS of the application is passed through by the sidecars: the token reaches the
+			// application as sent, and is neither validated nor removed by the JWT filter.
+			{
+				Name: "https-valid-token", From: "a", To: "c", PortName: "https", Scheme: scheme.HTTPS,
+				Token: jwt.TokenIssuer1, Expect: authn.Allowed,
+				ExpectHeaders: map[string]string{authHeaderKey: "Bearer " + jwt.TokenIssuer1, "X-Test-Payload": ""},
+			},
+			{
+				Name: "https-invalid-token", From: "a", To: "c", PortName: "https", Scheme: scheme.HTTPS,
+				Token: jwt.TokenInvalid, Expect: authn.Allowed,
+				ExpectHeaders: map[string]string{authHeaderKey: "Bearer " + jwt.TokenInvalid},
+			},
+		},
+	},
+	"TestJWTWithMixedHTTPAndHTTPS": {
+		Policies: []string{"testdata/requestauthn/c-authn-authz.yaml.tmpl"},
+		Cases: []authn.Case{
+			{
+				Name: "http-valid-token", From: "a", To: "c", Token: jwt.TokenIssuer1, Expect: authn.Allowed,
+				ExpectHeaders: map[string]string{authHeaderKey: "", "X-Test-Payload": Payload(jwt.TokenIssuer1)},
+			},
+			{Name: "http-invalid-token", From: "a", To: "c", Token: jwt.TokenInvalid, Expect: authn.Unauthenticated},
+			{Name: "http-no-token", From: "a", To: "c", Expect: authn.Denied},
+			// The sidecars cannot see the token inside the TLS of the application, so the rule requiring a
+			// request principal never matches on the https port: every connection is refused, whatever the
+			// token, instead of reaching the application unchecked.
+			{
+				Name: "https-valid-token", From: "a", To: "c", PortName: "https", Scheme: scheme.HTTPS,
+				Token: jwt.TokenIssuer1, Expect: authn.Refused,
+			},
+			{
+				Name: "https-invalid-token", From: "a", To: "c", PortName: "https", Scheme: scheme.HTTPS,
+				Token: jwt.TokenInvalid, Expect: authn.Refused,
+			},
+			{Name: "https-no-token", From: "a", To: "c", PortName: "https", Scheme: scheme.HTTPS, Expect: authn.Refused},
+		},
+	},
+	"TestJWTWithSubclaimNestedArray": {
+		Policies: []string{"testdata/requestauthn/b-claim-groups.yaml.tmpl"},
+		Cases: []authn.Case{
+			groupsCase(authn.Allowed, "admin", "editor"),
+			groupsCase(authn.Allowed, "editor", "admin"),
+			groupsCase(authn.Allowed, "admin"),
+			groupsCase(authn.Denied, "viewer"),
+			// The values of the array are matched as a whole, not as substrings.
+			groupsCase(authn.Denied, "administrators", "admins"),
+			groupsCase(authn.Denied),
+			{Name: "no-token", From: "a", To: "b", Expect: authn.Denied},
+		},
+	},
+	"TestJWTWithCustomCipherSuites": {
+		Policies: []string{"testdata/requestauthn/b-authn-authz.yaml.tmpl"},
+		Cases: []authn.Case{
+			{Name: "valid-token", From: "a", To: "b", Token: jwt.TokenIssuer1, Expect: authn.Allowed},
+			{Name: "expired-token", From: "a", To: "b", Token: jwt.TokenExpired, Expect: authn.Unauthenticated},
+			{Name: "no-token", From: "a", To: "b", Expect: authn.Denied},
+		},
+	},
+	"TestJWTWithPolicyAnnotationOnPod": {
+		Policies: []string{"testdata/requestauthn/b-authn-authz.yaml.tmpl"},
+		Cases: []authn.Case{
+			{Name: "valid-token", From: "a", To: "b", Token: jwt.TokenIssuer1, Expect: authn.Allowed},
+			{Name: "expired-token", From: "a", To: "b", Token: jwt.TokenExpired, Expect: authn.Unauthenticated},
+			{Name: "invalid-token", From: "a", To: "b", Token: jwt.TokenInvalid, Expect: authn.Unauthenticated},
+			{Name: "no-token", From: "a", To: "b", Expect: authn.Denied},
+		},
+	},
+	"TestJWTWithSubsetPolicy": {
+		Policies: []string{"testdata/requestauthn/b-subsets.yaml.tmpl"},
+		Cases: []authn.Case{
+			{Name: "v1-without-token", From: "a", To: "b", Expect: authn.Allowed, ExpectVersion: "v1"},
+			{Name: "v1-with-token", From: "a", To: "b", Token: jwt.TokenIssuer1, Expect: authn.Allowed, ExpectVersion: "v1"},
+			{Name: "v2-without-token", From: "a", To: "b", Headers: v2Subset, Expect: authn.Denied},
+			{
+				Name: "v2-with-token", From: "a", To: "b", Headers: v2Subset, Token: jwt.TokenIssuer1,
+				Expect: authn.Allowed, ExpectVersion: "v2",
+			},
+			{
+				Name: "v2-with-invalid-token", From: "a", To: "b", Headers: v2Subset, Token: jwt.TokenInvalid,
+				Expect: authn.Unauthenticated,
+			},
+		},
+	},
+}
+
+// Uncovered are the JWT tests which do not build their cases from a matrix, e.g. because they call
+// through a gateway, or check more than the result of a call. They are listed as such in the coverage
+// manifest.
+var Uncovered = []string{
+	"TestIngressRequestAuthentication",
+	"TestJWKSCaching",
+	"TestJWTFilterLatencyOverhead",
+	"TestJWTWithAccessLogResponseFlags",
+	"TestJWTWithAuthorizationPolicyRemoved",
+	"TestJWTWithCertRotation",
+	"TestJWTWithChainedGateways",
+	"TestJWTWithClaimToHeaders",
+	"TestJWTWithConcurrentRequests",
+	"TestJWTWithConnectMethod",
+	"TestJWTWithConnectionChurn",
+	"TestJWTWithCookieCollision",
+	"TestJWTWithCustomPolicyNamespace",
+	"TestJWTWithDeniedRequestMetrics",
+	"TestJWTWithEgressGateway",
+	"TestJWTWithEmptyString",
+	"TestJWTWithEnvoyPeerMetadataForwarding",
+	"TestJWTWithEnvoyRBACFilter",
+	"TestJWTWithExtAuthz",
+	"TestJWTWithGRPCStream",
+	"TestJWTWithHTTP2Trailers",
+	"TestJWTWithHeadRequest",
+	"TestJWTWithHeaderCaseSensitivity",
+	"TestJWTWithHighCardinalityClaimsPerformance",
+	"TestJWTWithIngressMTLS",
+	"TestJWTWithIstioConfigDiff",
+	"TestJWTWithIstioControlPlaneTelemetry",
+	"TestJWTWithIstiodCrashRecovery",
+	"TestJWTWithJWKSFromSecret",
+	"TestJWTWithL7TelemetryReporting",
+	"TestJWTWithLoopbackBypass",
+	"TestJWTWithMalformedRequest",
+	"TestJWTWithMultipleTokenLocations",
+	"TestJWTWithNakedClient",
+	"TestJWTWithNewTokenAfterExpiry",
+	"TestJWTWithOneShotCaller",
+	"TestJWTWithOrphanedAuthorizationPolicy",
+	"TestJWTWithPilotResourceThrottling",
+	"TestJWTWithQueryParamCollision",
+	"TestJWTWithReadinessProbe",
+	"TestJWTWithRequestBodySizeJWT",
+	"TestJWTWithRequestTimeoutAndJWKSFetch",
+	"TestJWTWithResponseTrailersGRPC",
+	"TestJWTWithRetries",
+	"TestJWTWithRouteTimeout",
+	"TestJWTWithSPIFFEBundleEndpoint",
+	"TestJWTWithServiceMeshObservability",
+	"TestJWTWithSidecarEgress",
+	"TestJWTWithSidecarRestart",
+	"TestJWTWithStatefulSet",
+	"TestJWTWithTokenInCookie",
+	"TestJWTWithTokenRefreshOnConnection",
+	"TestJWTWithURLFragment",
+	"TestJWTWithWildcardPaths",
+	"TestRequestAuthenticationChurn",
+	"TestRequestAuthentication_DirectPodIP",
+	"TestRequestAuthentication_UnreachableJwksURI",
+}
+
+const authHeaderKey = "Authorization"
+
+// StdBase64Token is jwt.TokenIssuer1 with the characters of the standard base64 alphabet, '+' and '/',
+// where base64url uses '-' and '_'.
+var StdBase64Token = strings.NewReplacer("-", "+", "_", "/").Replace(jwt.TokenIssuer1)
+
+// tcpDeclaredPort is the port of util.EchoConfigWithDeclaredProtocols serving HTTP, declared TCP.
+var tcpDeclaredPort = util.DeclaredPortName(protocol.TCP)
+
+// v2Subset are the headers routing a request to the subset v2 in b-subsets.yaml.tmpl.
+var v2Subset = map[string]string{"X-Subset": "v2"}
+
+// authorizationLikeHeaders are headers named like Authorization, which the JWT filter must leave as sent.
+var authorizationLikeHeaders = map[string]string{
+	"Proxy-Authorization": "Basic cHJveHk6c2VjcmV0",
+	"X-Authorization":     "custom-credential",
+}
+
+// malformedToken is a bearer token which is not a JWT at all.
+const malformedToken = "not-a-jwt"
+
+// Payload returns the payload of the token, as forwarded by the JWT filter.
+func Payload(token string) string {
+	return strings.Split(token, ".")[1]
+}
+
+func mintLargeToken(nBytes int) func() (string, error) {
+	return func() (string, error) {
+		return jwt.TokenLarge(nBytes)
+	}
+}
+
+func negativeMatchCase(path, token string, expect authn.ExpectedResult) authn.Case {
+	tokenName := jwt.Name(token)
+	if token == "" {
+		tokenName = "no-token"
+	}
+	return authn.Case{
+		Name:   fmt.Sprintf("%s-%s[%s]", path, tokenName, expect),
+		From:   "a",
+		To:     "b",
+		Path:   path,
+		Token:  token,
+		Expect: expect,
+	}
+}
+
+// emptyJWTRulesCase returns a case of TestRequestAuthentication calling f, whose RequestAuthentication has
+// no JWT rule, with the given token. The request is checked to be denied by authorization, with its reason.
+func emptyJWTRulesCase(name, token string) authn.Case {
+	return authn.Case{
+		Name:       "empty-jwt-rules-" + name,
+		From:       "a",
+		To:         "f",
+		Token:      token,
+		Expect:     authn.Denied,
+		ExpectBody: "RBAC: access denied",
+	}
+}
+
+// groupsCase returns a case of TestJWTWithSubclaimNestedArray, with a token whose "groups" claim is an
+// array of the given groups, or without a "groups" claim if there are none.
+func groupsCase(expect authn.ExpectedResult, groups ...string) authn.Case {
+	name := "no-groups"
+	if len(groups) > 0 {
+		name = "groups-" + strings.Join(groups, ",")
+	}
+	c := authn.Case{
+		Name: fmt.Sprintf("%s[%s]", name, expect),
+		From: "a",
+		To:   "b",
+		MintToken: func() (string, error) {
+			return jwt.TokenWithGroups(groups...)
+		},
+		Expect: expect,
+	}
+	if expect == authn.Denied {
+		c.ExpectBody = "RBAC: access denied"
+	}
+	return c
+}
+
+// audienceCase returns a case of TestJWTWithAudiencePerRule, with a token for the given audiences. The
+// rejections are checked with the reason given by the filter in the body of the response.
+func audienceCase(path string, audiences []string, expect authn.ExpectedResult) authn.Case {
+	c := authn.Case{
+		Name: fmt.Sprintf("%s-%s[%s]", path, strings.Join(audiences, ","), expect),
+		From: "a",
+		To:   "b",
+		Path: path,
+		MintToken: func() (string, error) {
+			return jwt.TokenWithAudiences(audiences...)
+		},
+		Expect: expect,
+	}
+	switch expect {
+	case authn.Unauthenticated:
+		c.ExpectBody = "Audiences in Jwt are not allowed"
+	case authn.Denied:
+		c.ExpectBody = "RBAC: access denied"
+	}
+	return c
+}
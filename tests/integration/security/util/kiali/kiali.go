@@ -0,0 +1,135 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package kiali is a minimal client of the Kiali API, used to check the Istio config Kiali reads
+// for the service graph.
+package kiali
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	kubeApiMeta "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"istio.io/istio/pkg/test/framework/components/environment/kube"
+	"istio.io/istio/pkg/test/framework/components/istio"
+	"istio.io/istio/pkg/test/framework/resource"
+)
+
+const (
+	appLabelSelector = "app=kiali"
+	port             = 20001
+	// The root context path of the Kiali API, as configured by the Istio addon.
+	apiRoot = "/kiali/api"
+	// The secret holding the login credentials, as created by the Istio addon.
+	secretName = "kiali"
+)
+
+// Available returns true if Kiali is deployed in the telemetry namespace.
+func Available(ctx resource.Context) bool {
+	cfg, err := istio.DefaultConfig(ctx)
+	if err != nil {
+		return false
+	}
+	pods, err := kube.ClusterOrDefault(nil, ctx.Environment()).GetPods(cfg.TelemetryNamespace, appLabelSelector)
+	return err == nil && len(pods) > 0
+}
+
+// RequestAuthentications returns the names of the RequestAuthentication policies Kiali reports in the
+// Istio config of the given namespace.
+func RequestAuthentications(ctx resource.Context, namespace string) ([]string, error) {
+	var config struct {
+		RequestAuthentications []struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+		} `json:"requestAuthentications"`
+	}
+	if err := get(ctx, fmt.Sprintf("/namespaces/%s/istio?objects=requestauthentications", namespace), &config); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(config.RequestAuthentications))
+	for _, p := range config.RequestAuthentications {
+		names = append(names, p.Metadata.Name)
+	}
+	return names, nil
+}
+
+// get logs in to Kiali with the credentials of the Kiali secret, then calls the API at path and
+// decodes the JSON response into out.
+func get(ctx resource.Context, path string, out interface{}) error {
+	cfg, err := istio.DefaultConfig(ctx)
+	if err != nil {
+		return err
+	}
+	cluster := kube.ClusterOrDefault(nil, ctx.Environment())
+	pods, err := cluster.GetPods(cfg.TelemetryNamespace, appLabelSelector)
+	if err != nil {
+		return err
+	}
+	if len(pods) == 0 {
+		return fmt.Errorf("no kiali pod in namespace %s", cfg.TelemetryNamespace)
+	}
+	secret, err := cluster.GetSecret(cfg.TelemetryNamespace).Get(context.TODO(), secretName, kubeApiMeta.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get kiali secret: %v", err)
+	}
+
+	forwarder, err := cluster.NewPortForwarder(pods[0], 0, port)
+	if err != nil {
+		return fmt.Errorf("new port forwarder: %v", err)
+	}
+	if err = forwarder.Start(); err != nil {
+		return fmt.Errorf("forwarder start: %v", err)
+	}
+	defer func() { _ = forwarder.Close() }()
+	baseURL := "http://" + forwarder.Address() + apiRoot
+
+	req, err := http.NewRequest(http.MethodGet, baseURL+"/authenticate", nil)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(string(secret.Data["username"]), string(secret.Data["passphrase"]))
+	var login struct {
+		Token string `json:"token"`
+	}
+	if err := do(req, &login); err != nil {
+		return fmt.Errorf("failed to log in to kiali: %v", err)
+	}
+
+	if req, err = http.NewRequest(http.MethodGet, baseURL+path, nil); err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+login.Token)
+	return do(req, out)
+}
+
+func do(req *http.Request, out interface{}) error {
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s: status %d, %s", req.Method, req.URL.Path, resp.StatusCode, string(body))
+	}
+	return json.Unmarshal(body, out)
+}
@@ -0,0 +1,185 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package latency compares the latency distributions of requests, e.g. with and without a filter on the
+// path, for the benchmark-style tests. These tests are slow and only run when enabled with the
+// -istio.test.security.latency flag, which defaults to the ISTIO_TEST_SECURITY_LATENCY environment variable.
+// The latencies are only reported, unless a bound is set with -istio.test.security.latency.maxP90OverheadMs.
+package latency
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"istio.io/istio/pkg/test/echo/client"
+	"istio.io/istio/pkg/test/framework/components/echo"
+)
+
+const envVar = "ISTIO_TEST_SECURITY_LATENCY"
+
+var (
+	enabled          bool
+	maxP90OverheadMs float64
+)
+
+func init() {
+	if v := os.Getenv(envVar); v != "" {
+		var err error
+		if enabled, err = strconv.ParseBool(v); err != nil {
+			panic(fmt.Sprintf("invalid %s: %v", envVar, err))
+		}
+	}
+	flag.BoolVar(&enabled, "istio.test.security.latency", enabled,
+		fmt.Sprintf("Run the latency benchmarks of the security tests. Defaults to $%s, or false.", envVar))
+	flag.Float64Var(&maxP90OverheadMs, "istio.test.security.latency.maxP90OverheadMs", 0,
+		"Fail the latency benchmarks whose p90 overhead is above this bound, in milliseconds. None if 0.")
+}
+
+// Enabled returns whether the latency benchmarks run. The flags must be parsed.
+func Enabled() bool {
+	if !flag.Parsed() {
+		panic("flag.Parse must be called before this function")
+	}
+	return enabled
+}
+
+// MaxP90OverheadMs returns the bound of the p90 overhead of the latency benchmarks, in milliseconds, or 0 if
+// the overhead is only reported. The flags must be parsed.
+func MaxP90OverheadMs() float64 {
+	if !flag.Parsed() {
+		panic("flag.Parse must be called before this function")
+	}
+	return maxP90OverheadMs
+}
+
+// Percentiles of a latency distribution, in milliseconds.
+type Percentiles struct {
+	P50 float64 `json:"p50_ms"`
+	P90 float64 `json:"p90_ms"`
+	P99 float64 `json:"p99_ms"`
+}
+
+// Distribution summarizes the latencies of a run of requests.
+type Distribution struct {
+	Samples int `json:"samples"`
+	Percentiles
+}
+
+// NewDistribution returns the distribution of the given latencies, with the nearest-rank percentiles.
+func NewDistribution(latencies []time.Duration) Distribution {
+	sorted := append([]time.Duration(nil), latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	percentile := func(p float64) float64 {
+		if len(sorted) == 0 {
+			return 0
+		}
+		rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+		if rank < 1 {
+			rank = 1
+		}
+		return float64(sorted[rank-1]) / float64(time.Millisecond)
+	}
+	return Distribution{
+		Samples: len(sorted),
+		Percentiles: Percentiles{
+			P50: percentile(50),
+			P90: percentile(90),
+			P99: percentile(99),
+		},
+	}
+}
+
+// Latencies returns the latencies of the responses, as measured by the caller. It fails if the latency of a
+// response is unknown.
+func Latencies(responses client.ParsedResponses) ([]time.Duration, error) {
+	out := make([]time.Duration, 0, len(responses))
+	for i, r := range responses {
+		if r.Latency <= 0 {
+			return nil, fmt.Errorf("response %d: no latency reported by the caller", i)
+		}
+		out = append(out, r.Latency)
+	}
+	return out, nil
+}
+
+// Sample sends warmUp requests, then returns the latencies of samples requests, sent in calls of batchSize
+// requests to keep the calls within their timeout. It fails if a request is not answered with 200.
+func Sample(from echo.Instance, opts echo.CallOptions, warmUp, samples, batchSize int) ([]time.Duration, error) {
+	opts.Count = warmUp
+	if _, err := from.Call(opts); err != nil {
+		return nil, fmt.Errorf("warm-up failed: %v", err)
+	}
+	out := make([]time.Duration, 0, samples)
+	opts.Count = batchSize
+	for len(out) < samples {
+		responses, err := from.Call(opts)
+		if err != nil {
+			return nil, fmt.Errorf("call failed: %v", err)
+		}
+		if err := responses.CheckOK(); err != nil {
+			return nil, err
+		}
+		latencies, err := Latencies(responses)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, latencies...)
+	}
+	return out, nil
+}
+
+// Comparison is the report of a benchmark, comparing the latencies of the requests to the same target
+// without and with the feature under test. Its JSON schema is stable, so that the reports of the runs can
+// be tracked over time.
+type Comparison struct {
+	Test string `json:"test"`
+	// WarmUp is the number of requests sent and discarded before each measurement.
+	WarmUp   int          `json:"warm_up"`
+	Baseline Distribution `json:"baseline"`
+	Subject  Distribution `json:"subject"`
+	// Delta is the subject percentiles minus the baseline ones.
+	Delta Percentiles `json:"delta"`
+}
+
+// Compare returns the comparison of the latencies without (baseline) and with (subject) the feature under
+// test.
+func Compare(test string, warmUp int, baseline, subject []time.Duration) Comparison {
+	b, s := NewDistribution(baseline), NewDistribution(subject)
+	return Comparison{
+		Test:     test,
+		WarmUp:   warmUp,
+		Baseline: b,
+		Subject:  s,
+		Delta: Percentiles{
+			P50: s.P50 - b.P50,
+			P90: s.P90 - b.P90,
+			P99: s.P99 - b.P99,
+		},
+	}
+}
+
+// JSON returns the comparison as indented JSON, to be written as an artifact of the test.
+func (c Comparison) JSON() ([]byte, error) {
+	out, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
@@ -0,0 +1,107 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"istio.io/istio/pkg/test/framework/components/echo/common"
+	"istio.io/istio/pkg/test/framework/components/environment/kube"
+	"istio.io/istio/pkg/test/framework/components/istio"
+	"istio.io/istio/pkg/test/framework/resource"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+const (
+	// PilotXDSPushes is the counter of the xDS pushes of istiod, by type, e.g. lds for the listeners
+	// holding the HTTP filters such as the JWT filter.
+	PilotXDSPushes = "pilot_xds_pushes"
+
+	istiodSelector       = "istio=pilot"
+	istiodMonitoringPort = 15014
+)
+
+// IstiodValue returns the sum of the counters selected by q over all the istiod pods, read from their
+// monitoring port through port forwardings, so that Prometheus is not needed.
+func (q Query) IstiodValue(ctx resource.Context) (float64, error) {
+	cfg, err := istio.DefaultConfig(ctx)
+	if err != nil {
+		return 0, err
+	}
+	cluster := kube.ClusterOrDefault(nil, ctx.Environment())
+	pods, err := cluster.GetPods(cfg.ConfigNamespace, istiodSelector)
+	if err != nil {
+		return 0, err
+	}
+	if len(pods) == 0 {
+		return 0, fmt.Errorf("no istiod pod in %s", cfg.ConfigNamespace)
+	}
+	value := 0.0
+	for _, pod := range pods {
+		stats, err := func() (string, error) {
+			forwarder, err := cluster.NewPortForwarder(pod, 0, istiodMonitoringPort)
+			if err != nil {
+				return "", fmt.Errorf("new port forwarder: %v", err)
+			}
+			if err := forwarder.Start(); err != nil {
+				return "", fmt.Errorf("forwarder start: %v", err)
+			}
+			defer func() { _ = forwarder.Close() }()
+			resp, err := http.Get("http://" + forwarder.Address() + "/metrics")
+			if err != nil {
+				return "", err
+			}
+			defer func() { _ = resp.Body.Close() }()
+			body, err := ioutil.ReadAll(resp.Body)
+			return string(body), err
+		}()
+		if err != nil {
+			return 0, fmt.Errorf("failed to read the metrics of %s: %v", pod.Name, err)
+		}
+		families, err := common.ParsePrometheusStats(stats)
+		if err != nil {
+			return 0, fmt.Errorf("failed to parse the metrics of %s: %v", pod.Name, err)
+		}
+		value += q.sum(families)
+	}
+	return value, nil
+}
+
+// ExpectIstiodIncrease runs change, e.g. applying a policy, and verifies the value selected by q on istiod
+// increased by at least n. The value is read before running change, and is then retried until istiod has
+// pushed the change.
+func ExpectIstiodIncrease(ctx resource.Context, q Query, n float64, change func() error,
+	options ...retry.Option) error {
+	before, err := q.IstiodValue(ctx)
+	if err != nil {
+		return err
+	}
+	if err := change(); err != nil {
+		return err
+	}
+	options = append([]retry.Option{retry.Delay(defaultDelay), retry.Timeout(defaultTimeout)}, options...)
+	return retry.UntilSuccess(func() error {
+		after, err := q.IstiodValue(ctx)
+		if err != nil {
+			return err
+		}
+		if got := after - before; got < n {
+			return fmt.Errorf("%s: got an increase of %v on istiod, want at least %v", q, got, n)
+		}
+		return nil
+	}, options...)
+}
@@ -0,0 +1,132 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package metrics checks the Istio standard metrics reported by the sidecars of echo instances. The
+// metrics are configured by the telemetry filters of the default installation, so no additional config
+// is applied.
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+
+	"istio.io/istio/pkg/test/framework/components/echo"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+const (
+	// RequestsTotal is the counter of the requests handled by a sidecar.
+	RequestsTotal = "istio_requests_total"
+
+	// The sidecars refresh the Prometheus stats asynchronously, so a request may take a few seconds
+	// to be reflected in the metrics.
+	defaultDelay   = time.Second
+	defaultTimeout = 30 * time.Second
+)
+
+// Query selects the series of a metric with the given label values. Labels not listed in Labels
+// may have any value.
+type Query struct {
+	Metric string
+	Labels map[string]string
+}
+
+// SourcePrincipal returns the value of the source_principal label of the requests sent by the given
+// instance, which runs as the service account named after its service.
+func SourcePrincipal(from echo.Instance) string {
+	cfg := from.Config()
+	return fmt.Sprintf("spiffe://cluster.local/ns/%s/sa/%s", cfg.Namespace.Name(), cfg.Service)
+}
+
+// Value returns the sum of the counters selected by q over all the workloads of the given instance.
+func (q Query) Value(instance echo.Instance) (float64, error) {
+	workloads, err := instance.Workloads()
+	if err != nil {
+		return 0, err
+	}
+	value := 0.0
+	for _, w := range workloads {
+		families, err := w.Sidecar().PrometheusStats()
+		if err != nil {
+			return 0, err
+		}
+		value += q.sum(families)
+	}
+	return value, nil
+}
+
+// sum returns the sum of the counters selected by q in the given metric families.
+func (q Query) sum(families map[string]*dto.MetricFamily) float64 {
+	family, ok := families[q.Metric]
+	if !ok {
+		return 0
+	}
+	value := 0.0
+	for _, m := range family.Metric {
+		labels := make(map[string]string, len(m.Label))
+		for _, l := range m.Label {
+			labels[l.GetName()] = l.GetValue()
+		}
+		if q.matches(labels) {
+			value += m.GetCounter().GetValue() + m.GetUntyped().GetValue()
+		}
+	}
+	return value
+}
+
+func (q Query) matches(labels map[string]string) bool {
+	for name, value := range q.Labels {
+		if labels[name] != value {
+			return false
+		}
+	}
+	return true
+}
+
+func (q Query) String() string {
+	labels := make([]string, 0, len(q.Labels))
+	for name, value := range q.Labels {
+		labels = append(labels, fmt.Sprintf("%s=%q", name, value))
+	}
+	sort.Strings(labels)
+	return fmt.Sprintf("%s{%s}", q.Metric, strings.Join(labels, ","))
+}
+
+// ExpectIncrease runs check, e.g. an authn.TestCase, and verifies the value selected by q on the
+// given instance increased by at least n. The value is read before running check, so that earlier
+// requests are not counted, and is then retried until the sidecars have updated the metrics.
+func ExpectIncrease(instance echo.Instance, q Query, n float64, check func() error, options ...retry.Option) error {
+	before, err := q.Value(instance)
+	if err != nil {
+		return err
+	}
+	if err := check(); err != nil {
+		return err
+	}
+	options = append([]retry.Option{retry.Delay(defaultDelay), retry.Timeout(defaultTimeout)}, options...)
+	return retry.UntilSuccess(func() error {
+		after, err := q.Value(instance)
+		if err != nil {
+			return err
+		}
+		if got := after - before; got < n {
+			return fmt.Errorf("%s: got an increase of %v, want at least %v", q, got, n)
+		}
+		return nil
+	}, options...)
+}
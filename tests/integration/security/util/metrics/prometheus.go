@@ -0,0 +1,104 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"istio.io/istio/pkg/test/framework/components/echo"
+	"istio.io/istio/pkg/test/framework/components/environment/kube"
+	"istio.io/istio/pkg/test/framework/components/istio"
+	"istio.io/istio/pkg/test/framework/components/prometheus"
+	"istio.io/istio/pkg/test/framework/resource"
+	"istio.io/istio/pkg/test/util/retry"
+)
+
+const (
+	prometheusLabelSelector = "app=prometheus"
+
+	// Prometheus scrapes the sidecars every 15 seconds by default, so the metrics reach it well after
+	// the sidecars have reported them.
+	prometheusDelay   = 5 * time.Second
+	prometheusTimeout = 2 * time.Minute
+)
+
+// PrometheusAvailable returns true if Prometheus is deployed in the telemetry namespace.
+func PrometheusAvailable(ctx resource.Context) bool {
+	cfg, err := istio.DefaultConfig(ctx)
+	if err != nil {
+		return false
+	}
+	pods, err := kube.ClusterOrDefault(nil, ctx.Environment()).GetPods(cfg.TelemetryNamespace, prometheusLabelSelector)
+	return err == nil && len(pods) > 0
+}
+
+// Workload returns the value of the source_workload and destination_workload labels of the given
+// instance: the name of the deployment of its first subset.
+func Workload(instance echo.Instance) string {
+	cfg := instance.Config()
+	version := cfg.Version
+	if len(cfg.Subsets) > 0 && cfg.Subsets[0].Version != "" {
+		version = cfg.Subsets[0].Version
+	}
+	return fmt.Sprintf("%s-%s", cfg.Service, version)
+}
+
+// PrometheusValue returns the sum of the series selected by q, as collected by Prometheus. The value is
+// 0 if no series matches.
+func (q Query) PrometheusValue(prom prometheus.Instance) (float64, error) {
+	query := fmt.Sprintf("sum(%s)", q)
+	v, _, err := prom.API().Query(context.Background(), query, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to query Prometheus with %s: %v", query, err)
+	}
+	vector, ok := v.(model.Vector)
+	if !ok {
+		return 0, fmt.Errorf("query %s returned a %s, want a vector", query, v.Type())
+	}
+	value := 0.0
+	for _, sample := range vector {
+		value += float64(sample.Value)
+	}
+	return value, nil
+}
+
+// ExpectPrometheusIncrease runs check, which must send exactly n requests selected by q, and verifies the
+// value of q collected by Prometheus increases by exactly n. The value is read before running check, and
+// is then retried until Prometheus has scraped the sidecars.
+func ExpectPrometheusIncrease(prom prometheus.Instance, q Query, n float64, check func() error,
+	options ...retry.Option) error {
+	before, err := q.PrometheusValue(prom)
+	if err != nil {
+		return err
+	}
+	if err := check(); err != nil {
+		return err
+	}
+	options = append([]retry.Option{retry.Delay(prometheusDelay), retry.Timeout(prometheusTimeout)}, options...)
+	return retry.UntilSuccess(func() error {
+		after, err := q.PrometheusValue(prom)
+		if err != nil {
+			return err
+		}
+		if got := after - before; got != n {
+			return fmt.Errorf("%s: got an increase of %v in Prometheus, want %v", q, got, n)
+		}
+		return nil
+	}, options...)
+}
@@ -0,0 +1,154 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package mtlsmode runs a test suite under a mesh-wide mTLS mode, so that the same tests can be run with
+// the default PERMISSIVE mode and with STRICT mTLS. The mode is selected with the
+// -istio.test.security.mtls flag, which defaults to the ISTIO_TEST_SECURITY_MTLS environment variable.
+package mtlsmode
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"istio.io/istio/pkg/test/framework/resource"
+	"istio.io/istio/pkg/test/scopes"
+	"istio.io/istio/tests/integration/security/util/authn"
+)
+
+// Mode is a mesh-wide mTLS mode.
+type Mode string
+
+const (
+	// Permissive is the default mode of the mesh: the workloads accept both mTLS and plain text.
+	Permissive Mode = "permissive"
+	// Strict is the mode set by a mesh-wide STRICT PeerAuthentication: the workloads with a sidecar only
+	// accept mTLS.
+	Strict Mode = "strict"
+
+	envVar = "ISTIO_TEST_SECURITY_MTLS"
+
+	// The name of the mesh-wide PeerAuthentication, which must be the only one in the root namespace.
+	policyName = "default"
+	policyYAML = `apiVersion: security.istio.io/v1beta1
+kind: PeerAuthentication
+metadata:
+  name: %s
+spec:
+  mtls:
+    mode: STRICT
+`
+)
+
+var current = Permissive
+
+func init() {
+	if v := os.Getenv(envVar); v != "" {
+		if err := current.Set(v); err != nil {
+			panic(fmt.Sprintf("invalid %s: %v", envVar, err))
+		}
+	}
+	flag.Var(&current, "istio.test.security.mtls",
+		fmt.Sprintf("Mesh-wide mTLS mode the security tests run under, %q or %q. Defaults to $%s, or %q.",
+			Permissive, Strict, envVar, Permissive))
+}
+
+var _ flag.Value = &current
+
+// String implements flag.Value.
+func (m *Mode) String() string {
+	return string(*m)
+}
+
+// Set implements flag.Value.
+func (m *Mode) Set(value string) error {
+	switch Mode(value) {
+	case Permissive, Strict:
+		*m = Mode(value)
+		return nil
+	default:
+		return fmt.Errorf("must be %q or %q", Permissive, Strict)
+	}
+}
+
+// Current returns the mode the tests run under. The flags must be parsed.
+func Current() Mode {
+	if !flag.Parsed() {
+		panic("flag.Parse must be called before this function")
+	}
+	return current
+}
+
+// SuiteName returns the name of the suite run under the current mode: name for the default mode, or name
+// suffixed with the mode otherwise, so that the results and artifacts of each mode can be told apart.
+func SuiteName(name string) string {
+	if m := Current(); m != Permissive {
+		return fmt.Sprintf("%s_%s", name, m)
+	}
+	return name
+}
+
+// Setup returns a setup function applying the current mode to the mesh, with a mesh-wide PeerAuthentication
+// in the root namespace. The policy is deleted when the suite is done. rootNamespace is read when the setup
+// function runs, so it may be set by an earlier setup function.
+func Setup(rootNamespace *string) resource.SetupFn {
+	return func(ctx resource.Context) error {
+		m := Current()
+		scopes.Framework.Infof("Running the tests with the mesh-wide mTLS mode %s", m)
+		if m != Strict {
+			return nil
+		}
+		p := &meshPolicy{
+			ctx:       ctx,
+			namespace: *rootNamespace,
+			yaml:      fmt.Sprintf(policyYAML, policyName),
+		}
+		if err := ctx.ApplyConfig(p.namespace, p.yaml); err != nil {
+			return fmt.Errorf("failed to apply the mesh-wide PeerAuthentication: %v", err)
+		}
+		p.id = ctx.TrackResource(p)
+		return nil
+	}
+}
+
+// NakedClient returns the expected result of a request from a client without sidecar to a workload with
+// a sidecar: permissive, which is expected with the default mode, or Refused under STRICT mTLS.
+func NakedClient(permissive authn.ExpectedResult) authn.ExpectedResult {
+	if Current() == Strict {
+		return authn.Refused
+	}
+	return permissive
+}
+
+// meshPolicy deletes the mesh-wide PeerAuthentication when it is closed.
+type meshPolicy struct {
+	id        resource.ID
+	ctx       resource.Context
+	namespace string
+	yaml      string
+}
+
+var _ resource.Resource = &meshPolicy{}
+var _ io.Closer = &meshPolicy{}
+
+// ID implements resource.Instance
+func (p *meshPolicy) ID() resource.ID {
+	return p.id
+}
+
+// Close implements io.Closer
+func (p *meshPolicy) Close() error {
+	return p.ctx.DeleteConfig(p.namespace, p.yaml)
+}
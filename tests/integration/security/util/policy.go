@@ -0,0 +1,99 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"istio.io/istio/pkg/test/framework/components/environment/kube"
+	"istio.io/istio/pkg/test/framework/components/istio"
+	"istio.io/istio/pkg/test/framework/components/namespace"
+	"istio.io/istio/pkg/test/framework/resource"
+)
+
+const (
+	istiodLabelSelector = "istio=pilot"
+	istiodContainerName = "discovery"
+)
+
+var requestAuthenticationGVR = schema.GroupVersionResource{
+	Group:    "security.istio.io",
+	Version:  "v1beta1",
+	Resource: "requestauthentications",
+}
+
+// ExpectPolicyWarning checks the RequestAuthentication resourceName in the namespace was accepted and
+// istiod reported a warning containing substr for it. Such warnings do not fail the apply, so they are
+// looked up in the status of the resource (written when istiod analysis is enabled) and, failing that,
+// in the istiod logs.
+func ExpectPolicyWarning(ctx resource.Context, ns namespace.Instance, resourceName, substr string) error {
+	cluster := kube.ClusterOrDefault(nil, ctx.Environment())
+	policy, err := cluster.GetUnstructured(requestAuthenticationGVR, ns.Name(), resourceName)
+	if err != nil {
+		return fmt.Errorf("failed to get RequestAuthentication %s/%s: %v", ns.Name(), resourceName, err)
+	}
+	if status, ok := policy.Object["status"]; ok && strings.Contains(fmt.Sprintf("%v", status), substr) {
+		return nil
+	}
+
+	cfg, err := istio.DefaultConfig(ctx)
+	if err != nil {
+		return err
+	}
+	pods, err := cluster.GetPods(cfg.ConfigNamespace, istiodLabelSelector)
+	if err != nil {
+		return fmt.Errorf("failed to get istiod pods: %v", err)
+	}
+	for _, pod := range pods {
+		logs, err := cluster.Logs(pod.Namespace, pod.Name, istiodContainerName, false)
+		if err != nil {
+			return fmt.Errorf("failed to get logs of %s: %v", pod.Name, err)
+		}
+		for _, line := range strings.Split(logs, "\n") {
+			if strings.Contains(line, substr) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("no warning containing %q found for RequestAuthentication %s/%s",
+		substr, ns.Name(), resourceName)
+}
+
+// ClaimToHeadersSupported returns true if the claimToHeaders of the RequestAuthentication resourceName in
+// the namespace were kept by the cluster. The RequestAuthentication CRD of older versions does not have
+// this field, so it is pruned when the resource is stored.
+func ClaimToHeadersSupported(ctx resource.Context, ns namespace.Instance, resourceName string) (bool, error) {
+	cluster := kube.ClusterOrDefault(nil, ctx.Environment())
+	policy, err := cluster.GetUnstructured(requestAuthenticationGVR, ns.Name(), resourceName)
+	if err != nil {
+		return false, fmt.Errorf("failed to get RequestAuthentication %s/%s: %v", ns.Name(), resourceName, err)
+	}
+	rules, _, err := unstructured.NestedSlice(policy.Object, "spec", "jwtRules")
+	if err != nil {
+		return false, err
+	}
+	for _, rule := range rules {
+		if r, ok := rule.(map[string]interface{}); ok {
+			if _, ok := r["claimToHeaders"]; ok {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
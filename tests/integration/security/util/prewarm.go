@@ -0,0 +1,91 @@
+// Copyright 2019 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"fmt"
+	"time"
+
+	"istio.io/istio/pkg/test/echo/common/response"
+	"istio.io/istio/pkg/test/framework/components/echo"
+	"istio.io/istio/pkg/test/util/retry"
+	"istio.io/istio/tests/common/jwt"
+)
+
+const (
+	prewarmDelay   = 250 * time.Millisecond
+	prewarmTimeout = 30 * time.Second
+)
+
+// JWKSPrewarm is the record of a PrewarmJWKS, to follow the latency of the JWKS fetches over the runs.
+type JWKSPrewarm struct {
+	Source         string `json:"source"`
+	Target         string `json:"target"`
+	Issuer         string `json:"issuer"`
+	DurationMillis int64  `json:"durationMillis"`
+	Attempts       int    `json:"attempts"`
+	Error          string `json:"error,omitempty"`
+}
+
+// PrewarmJWKS sends throwaway requests from the given instance with the call options, e.g. to the port and
+// path of a test case, until the JWT policy of the target is in force and the JWKS of the issuer fetched:
+// a token with an invalid signature is rejected with 401, while a token of the issuer, signed by the sample
+// key of tests/common/jwt, is not. It is meant to be called right after the policies are applied, so that
+// the first test cases do not race the fetch. The record of the pre-warming is returned, with the error if
+// it timed out.
+func PrewarmJWKS(from echo.Instance, opts echo.CallOptions, issuer string) (JWKSPrewarm, error) {
+	report := JWKSPrewarm{
+		Source: from.Config().Service,
+		Target: opts.Target.Config().Service,
+		Issuer: issuer,
+	}
+	token, err := jwt.Sign(map[string]interface{}{
+		"iss":    issuer,
+		"sub":    "sub-1",
+		"groups": []string{"group-1"},
+		"iat":    time.Now().Unix(),
+		"exp":    time.Now().Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return report, err
+	}
+	code := func(token string) string {
+		opts.Token = token
+		results, err := from.Call(opts)
+		if err != nil || len(results) == 0 {
+			return fmt.Sprintf("error (%v)", err)
+		}
+		return results[0].Code
+	}
+
+	start := time.Now()
+	_, err = retry.Do(func() (interface{}, bool, error) {
+		report.Attempts++
+		if got := code(jwt.TokenInvalid); got != response.StatusUnauthorized {
+			return nil, false, fmt.Errorf("JWT policy not in force yet, got %s for an invalid token", got)
+		}
+		// Allowed or denied by the authorization policy, the token passed the JWT filter.
+		if got := code(token); got != response.StatusCodeOK && got != response.StatusCodeForbidden {
+			return nil, false, fmt.Errorf("JWKS of %s not fetched yet, got %s for its token", issuer, got)
+		}
+		return nil, true, nil
+	}, retry.Delay(prewarmDelay), retry.Timeout(prewarmTimeout))
+	report.DurationMillis = time.Since(start).Milliseconds()
+	if err != nil {
+		err = fmt.Errorf("failed pre-warming the JWKS of %s on %s: %v", issuer, report.Target, err)
+		report.Error = err.Error()
+	}
+	return report, err
+}
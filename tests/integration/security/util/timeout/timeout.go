@@ -0,0 +1,65 @@
+// Copyright 2020 Istio Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package timeout sets up requests timed out by the sidecars: an echo instance serves a document
+// answered after a delay, and a VirtualService routes the requests to the instance with a shorter
+// timeout, so that the sidecar of the caller answers 504 to the requests reaching the application.
+package timeout
+
+import (
+	"fmt"
+	"time"
+
+	"istio.io/istio/pkg/test/echo/common"
+	"istio.io/istio/pkg/test/framework/components/echo"
+	"istio.io/istio/pkg/test/framework/resource"
+	"istio.io/istio/pkg/test/util/tmpl"
+	"istio.io/istio/tests/integration/security/util/jwks"
+)
+
+const virtualServiceTmpl = `apiVersion: networking.istio.io/v1alpha3
+kind: VirtualService
+metadata:
+  name: timeout-for-{{ .Service }}
+  namespace: {{ .Namespace }}
+spec:
+  hosts:
+  - {{ .Service }}
+  http:
+  - timeout: {{ .Timeout }}
+    route:
+    - destination:
+        host: {{ .Service }}
+`
+
+// VirtualService returns a VirtualService routing the HTTP requests to the service of target with the
+// given timeout, to be applied in the namespace of target.
+func VirtualService(target echo.Instance, timeout time.Duration) (string, error) {
+	cfg := target.Config()
+	return tmpl.Evaluate(virtualServiceTmpl, map[string]string{
+		"Service":   cfg.Service,
+		"Namespace": cfg.Namespace.Name(),
+		// A protobuf duration, in seconds.
+		"Timeout": fmt.Sprintf("%gs", timeout.Seconds()),
+	})
+}
+
+// ServeDelayed serves a document under the given name from target, its HTTP port named "http" answering
+// the requests for it after delay, and returns the path of the document, e.g. for CallOptions.Path.
+func ServeDelayed(ctx resource.Context, target echo.Instance, name string, delay time.Duration) (string, error) {
+	if err := (jwks.Server{Instance: target}).ServeDelayed(ctx, name, "delayed", delay); err != nil {
+		return "", err
+	}
+	return common.DocumentPathPrefix + name, nil
+}
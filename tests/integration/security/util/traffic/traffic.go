@@ -0,0 +1,107 @@
+//  Copyright 2020 Istio Authors
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package traffic
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Generator repeatedly runs a check in the background, e.g. sending a request and verifying the
+// response, and aggregates the results until it is stopped.
+type Generator struct {
+	check    func() error
+	interval time.Duration
+
+	stop chan struct{}
+	done chan struct{}
+
+	mu     sync.Mutex
+	result Result
+}
+
+// Result is the aggregated result of the checks run by a Generator.
+type Result struct {
+	Total    int
+	Failures []error
+}
+
+// Error returns an error listing the failures, or nil if all the checks succeeded.
+func (r Result) Error() error {
+	if len(r.Failures) == 0 {
+		return nil
+	}
+	msgs := make([]string, 0, len(r.Failures))
+	for _, err := range r.Failures {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Errorf("%d of %d checks failed:\n%s", len(r.Failures), r.Total, strings.Join(msgs, "\n"))
+}
+
+// Start runs check every interval in the background until Stop is called. A panic in check is
+// recovered and recorded as a failure.
+func Start(check func() error, interval time.Duration) *Generator {
+	g := &Generator{
+		check:    check,
+		interval: interval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go g.run()
+	return g
+}
+
+func (g *Generator) run() {
+	defer close(g.done)
+	ticker := time.NewTicker(g.interval)
+	defer ticker.Stop()
+	for {
+		g.record(g.safeCheck())
+		select {
+		case <-g.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (g *Generator) safeCheck() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("check panicked: %v", r)
+		}
+	}()
+	return g.check()
+}
+
+func (g *Generator) record(err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.result.Total++
+	if err != nil {
+		g.result.Failures = append(g.result.Failures, err)
+	}
+}
+
+// Stop stops the generator, waits for the in-flight check to complete and returns the result.
+func (g *Generator) Stop() Result {
+	close(g.stop)
+	<-g.done
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.result
+}
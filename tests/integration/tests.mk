@@ -96,3 +96,12 @@ test.integration.kube.reachability: | $(JUNIT_REPORT)
 	${_INTEGRATION_TEST_FLAGS} \
 	--test.run=TestReachability \
 	2>&1 | tee >($(JUNIT_REPORT) > $(JUNIT_OUT))
+
+# Runs the security suite a second time, under a mesh-wide STRICT mTLS.
+.PHONY: test.integration.kube.security.strict
+test.integration.kube.security.strict: | $(JUNIT_REPORT)
+	PATH=${PATH}:${ISTIO_OUT} $(GO) test -p 1 ${T} ./tests/integration/security/ -timeout 30m \
+	--istio.test.env kube \
+	--istio.test.security.mtls strict \
+	${_INTEGRATION_TEST_FLAGS} ${_INTEGRATION_TEST_SELECT_FLAGS} \
+	2>&1 | tee >($(JUNIT_REPORT) > $(JUNIT_OUT))